@@ -50,6 +50,7 @@ import (
 	"fmt"
 	"github.com/alexandre-normand/slackscot"
 	"github.com/alexandre-normand/slackscot/schedule"
+	"time"
 )
 
 // ActionBuilder holds the action to build
@@ -57,6 +58,16 @@ type ActionBuilder struct {
 	action slackscot.ActionDefinition
 }
 
+// ReactionActionBuilder holds the reaction action to build
+type ReactionActionBuilder struct {
+	reactionAction slackscot.ReactionActionDefinition
+}
+
+// InteractionActionBuilder holds the interaction action to build
+type InteractionActionBuilder struct {
+	interactionAction slackscot.InteractionActionDefinition
+}
+
 // ScheduledActionBuilder holds the scheduled action to build
 type ScheduledActionBuilder struct {
 	scheduledAction slackscot.ScheduledActionDefinition
@@ -131,6 +142,25 @@ func (ab *ActionBuilder) WithAnswerer(answerer slackscot.Answerer) *ActionBuilde
 	return ab
 }
 
+// WithContextAnswerer sets the action's context-aware answerer function. When set, it is used instead
+// of the answerer
+func (ab *ActionBuilder) WithContextAnswerer(answerer slackscot.ContextAnswerer) *ActionBuilder {
+	ab.action.ContextAnswer = answerer
+	return ab
+}
+
+// WithTimeout sets the maximum execution time of the action after which its answer is abandoned
+func (ab *ActionBuilder) WithTimeout(timeout time.Duration) *ActionBuilder {
+	ab.action.Timeout = timeout
+	return ab
+}
+
+// WithRequiredRoles sets the roles a user must have to run the action
+func (ab *ActionBuilder) WithRequiredRoles(roles ...string) *ActionBuilder {
+	ab.action.RequiredRoles = roles
+	return ab
+}
+
 // Hidden sets the action to hidden
 func (ab *ActionBuilder) Hidden() *ActionBuilder {
 	ab.action.Hidden = true
@@ -142,6 +172,115 @@ func (ab *ActionBuilder) Build() slackscot.ActionDefinition {
 	return ab.action
 }
 
+// NewReactionAction returns a new ReactionActionBuilder to build a new ReactionActionDefinition
+func NewReactionAction() (rab *ReactionActionBuilder) {
+	rab = new(ReactionActionBuilder)
+	rab.reactionAction = slackscot.ReactionActionDefinition{Hidden: false}
+
+	rab.reactionAction.Match = func(r *slackscot.IncomingReaction) bool {
+		return true
+	}
+	rab.reactionAction.Answer = func(r *slackscot.IncomingReaction) *slackscot.Answer {
+		return nil
+	}
+
+	return rab
+}
+
+// WithMatcher sets the reaction action's matcher function
+func (rab *ReactionActionBuilder) WithMatcher(matcher slackscot.ReactionMatcher) *ReactionActionBuilder {
+	rab.reactionAction.Match = matcher
+	return rab
+}
+
+// WithUsage sets the reaction action usage
+func (rab *ReactionActionBuilder) WithUsage(usage string) *ReactionActionBuilder {
+	rab.reactionAction.Usage = usage
+	return rab
+}
+
+// WithDescription sets the reaction action description
+func (rab *ReactionActionBuilder) WithDescription(description string) *ReactionActionBuilder {
+	rab.reactionAction.Description = description
+	return rab
+}
+
+// WithDescriptionf sets the reaction action description delegating format and arguments to fmt.Sprintf
+func (rab *ReactionActionBuilder) WithDescriptionf(format string, a ...interface{}) *ReactionActionBuilder {
+	rab.reactionAction.Description = fmt.Sprintf(format, a...)
+	return rab
+}
+
+// WithAnswerer sets the reaction action's answerer function
+func (rab *ReactionActionBuilder) WithAnswerer(answerer slackscot.ReactionAnswerer) *ReactionActionBuilder {
+	rab.reactionAction.Answer = answerer
+	return rab
+}
+
+// Hidden sets the reaction action to hidden
+func (rab *ReactionActionBuilder) Hidden() *ReactionActionBuilder {
+	rab.reactionAction.Hidden = true
+	return rab
+}
+
+// Build returns the ReactionActionDefinition
+func (rab *ReactionActionBuilder) Build() slackscot.ReactionActionDefinition {
+	return rab.reactionAction
+}
+
+// NewInteractionAction returns a new InteractionActionBuilder to build a new InteractionActionDefinition
+func NewInteractionAction() (iab *InteractionActionBuilder) {
+	iab = new(InteractionActionBuilder)
+	iab.interactionAction = slackscot.InteractionActionDefinition{}
+
+	iab.interactionAction.Match = func(i *slackscot.IncomingInteraction) bool {
+		return true
+	}
+	iab.interactionAction.Answer = func(i *slackscot.IncomingInteraction) *slackscot.Answer {
+		return nil
+	}
+
+	return iab
+}
+
+// WithMatcher sets the interaction action's matcher function
+func (iab *InteractionActionBuilder) WithMatcher(matcher slackscot.InteractionMatcher) *InteractionActionBuilder {
+	iab.interactionAction.Match = matcher
+	return iab
+}
+
+// WithActionID sets the interaction action's matcher function to match interactions with components of the
+// given action id (without its plugin namespace)
+func (iab *InteractionActionBuilder) WithActionID(actionID string) *InteractionActionBuilder {
+	iab.interactionAction.Match = func(i *slackscot.IncomingInteraction) bool {
+		return i.ActionID == actionID
+	}
+	return iab
+}
+
+// WithDescription sets the interaction action description
+func (iab *InteractionActionBuilder) WithDescription(description string) *InteractionActionBuilder {
+	iab.interactionAction.Description = description
+	return iab
+}
+
+// WithDescriptionf sets the interaction action description delegating format and arguments to fmt.Sprintf
+func (iab *InteractionActionBuilder) WithDescriptionf(format string, a ...interface{}) *InteractionActionBuilder {
+	iab.interactionAction.Description = fmt.Sprintf(format, a...)
+	return iab
+}
+
+// WithAnswerer sets the interaction action's answerer function
+func (iab *InteractionActionBuilder) WithAnswerer(answerer slackscot.InteractionAnswerer) *InteractionActionBuilder {
+	iab.interactionAction.Answer = answerer
+	return iab
+}
+
+// Build returns the InteractionActionDefinition
+func (iab *InteractionActionBuilder) Build() slackscot.InteractionActionDefinition {
+	return iab.interactionAction
+}
+
 // NewScheduledAction returns a new ScheduledActionBuilder to build a new ScheduledActionDefinition
 func NewScheduledAction() (sab *ScheduledActionBuilder) {
 	sab = new(ScheduledActionBuilder)
@@ -1,11 +1,13 @@
 package actions_test
 
 import (
+	"context"
 	"github.com/alexandre-normand/slackscot"
 	"github.com/alexandre-normand/slackscot/actions"
 	"github.com/alexandre-normand/slackscot/schedule"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestNewCommandWithDefaults(t *testing.T) {
@@ -42,6 +44,32 @@ func TestNewActionWithAnswerer(t *testing.T) {
 	assert.Equal(t, &slackscot.Answer{Text: "fake answer"}, action.Answer(&slackscot.IncomingMessage{}))
 }
 
+func TestNewActionWithContextAnswerer(t *testing.T) {
+	action := actions.NewHearAction().
+		WithContextAnswerer(func(ctx context.Context, m *slackscot.IncomingMessage) *slackscot.Answer {
+			return &slackscot.Answer{Text: "fake context answer"}
+		}).
+		Build()
+
+	assert.Equal(t, &slackscot.Answer{Text: "fake context answer"}, action.ContextAnswer(context.Background(), &slackscot.IncomingMessage{}))
+}
+
+func TestNewActionWithTimeout(t *testing.T) {
+	action := actions.NewHearAction().
+		WithTimeout(time.Duration(2) * time.Second).
+		Build()
+
+	assert.Equal(t, time.Duration(2)*time.Second, action.Timeout)
+}
+
+func TestNewActionWithRequiredRoles(t *testing.T) {
+	action := actions.NewCommand().
+		WithRequiredRoles("moderator", slackscot.AdminRole).
+		Build()
+
+	assert.Equal(t, []string{"moderator", slackscot.AdminRole}, action.RequiredRoles)
+}
+
 func TestNewActionWithUsage(t *testing.T) {
 	action := actions.NewHearAction().
 		WithUsage("make something").
@@ -74,6 +102,76 @@ func TestNewHiddenAction(t *testing.T) {
 	assert.True(t, action.Hidden)
 }
 
+func TestNewReactionActionWithDefaults(t *testing.T) {
+	action := actions.NewReactionAction().Build()
+	assert.False(t, action.Hidden)
+	assert.True(t, action.Match(&slackscot.IncomingReaction{}))
+	assert.Nil(t, action.Answer(&slackscot.IncomingReaction{}))
+}
+
+func TestNewReactionAction(t *testing.T) {
+	action := actions.NewReactionAction().
+		WithMatcher(func(r *slackscot.IncomingReaction) bool {
+			return r.Emoji == "pushpin"
+		}).
+		WithAnswerer(func(r *slackscot.IncomingReaction) *slackscot.Answer {
+			return &slackscot.Answer{Text: "pinned"}
+		}).
+		WithUsage(":pushpin: a message").
+		WithDescriptionf("Pin a message on %s", "#general").
+		Hidden().
+		Build()
+
+	assert.True(t, action.Hidden)
+	assert.Equal(t, ":pushpin: a message", action.Usage)
+	assert.Equal(t, "Pin a message on #general", action.Description)
+	assert.False(t, action.Match(&slackscot.IncomingReaction{Emoji: "+1"}))
+	assert.True(t, action.Match(&slackscot.IncomingReaction{Emoji: "pushpin"}))
+	assert.Equal(t, &slackscot.Answer{Text: "pinned"}, action.Answer(&slackscot.IncomingReaction{}))
+}
+
+func TestNewReactionActionWithDescription(t *testing.T) {
+	action := actions.NewReactionAction().
+		WithDescription("Pin a message").
+		Build()
+
+	assert.Equal(t, "Pin a message", action.Description)
+}
+
+func TestNewInteractionActionWithDefaults(t *testing.T) {
+	action := actions.NewInteractionAction().Build()
+	assert.True(t, action.Match(&slackscot.IncomingInteraction{}))
+	assert.Nil(t, action.Answer(&slackscot.IncomingInteraction{}))
+}
+
+func TestNewInteractionAction(t *testing.T) {
+	action := actions.NewInteractionAction().
+		WithMatcher(func(i *slackscot.IncomingInteraction) bool {
+			return i.Value == "yes"
+		}).
+		WithAnswerer(func(i *slackscot.IncomingInteraction) *slackscot.Answer {
+			return &slackscot.Answer{Text: "confirmed"}
+		}).
+		WithDescriptionf("Confirm a %s", "reservation").
+		Build()
+
+	assert.Equal(t, "Confirm a reservation", action.Description)
+	assert.False(t, action.Match(&slackscot.IncomingInteraction{Value: "no"}))
+	assert.True(t, action.Match(&slackscot.IncomingInteraction{Value: "yes"}))
+	assert.Equal(t, &slackscot.Answer{Text: "confirmed"}, action.Answer(&slackscot.IncomingInteraction{}))
+}
+
+func TestNewInteractionActionWithActionID(t *testing.T) {
+	action := actions.NewInteractionAction().
+		WithActionID("confirm").
+		WithDescription("Confirm a reservation").
+		Build()
+
+	assert.Equal(t, "Confirm a reservation", action.Description)
+	assert.False(t, action.Match(&slackscot.IncomingInteraction{ActionID: "cancel"}))
+	assert.True(t, action.Match(&slackscot.IncomingInteraction{ActionID: "confirm"}))
+}
+
 func TestNewScheduledActionWithDefaults(t *testing.T) {
 	action := actions.NewScheduledAction().Build()
 
@@ -0,0 +1,391 @@
+package actions
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ArgType is the type of a command argument
+type ArgType int
+
+// Command argument types
+const (
+	IntArg      ArgType = iota // A whole number
+	UserArg                    // A user mention (i.e. <@U012AB3CD>), parsed as the user id
+	ChannelArg                 // A channel mention (i.e. <#C012AB3CD|general>), parsed as the channel id
+	EmojiArg                   // An emoji (i.e. :bird:), parsed as the emoji name without colons
+	DurationArg                // A duration as understood by time.ParseDuration (i.e. 1h30m)
+	StringArg                  // A word or a "quoted string", parsed without the quotes
+)
+
+var (
+	userMentionRegex    = regexp.MustCompile("^<@([A-Z0-9]+)(\\|[^>]*)?>$")
+	channelMentionRegex = regexp.MustCompile("^<#([A-Z0-9]+)(\\|[^>]*)?>$")
+	emojiRegex          = regexp.MustCompile("^:([a-z0-9_+'\\-]+):(:skin-tone-[2-6]:)?$")
+)
+
+// argExpectations are the descriptions of what is expected of arguments by type, used in error messages
+var argExpectations = map[ArgType]string{
+	IntArg:      "a number",
+	UserArg:     "a user mention (i.e. @someone)",
+	ChannelArg:  "a channel mention (i.e. #general)",
+	EmojiArg:    "an emoji (i.e. :bird:)",
+	DurationArg: "a duration (i.e. 1h30m)",
+	StringArg:   "some text",
+}
+
+// argSpec defines a command argument
+type argSpec struct {
+	name     string
+	argType  ArgType
+	optional bool
+	variadic bool
+}
+
+// usage returns the usage representation of an argument
+func (as argSpec) usage() string {
+	switch {
+	case as.optional && as.variadic:
+		return fmt.Sprintf("[%s...]", as.name)
+	case as.optional:
+		return fmt.Sprintf("[%s]", as.name)
+	case as.variadic:
+		return fmt.Sprintf("<%s>...", as.name)
+	default:
+		return fmt.Sprintf("<%s>", as.name)
+	}
+}
+
+// CommandSpec declaratively defines the format of a command: literal words followed by typed arguments. Required
+// arguments come first followed by optional ones and, finally, an optional variadic argument.
+//
+// For example, the spec of a command to give karma to users with an optional reason could be:
+//
+//	actions.NewCommandSpec("karma", "give").
+//		WithVariadicArg("user", actions.UserArg).
+//		WithOptionalArg("reason", actions.StringArg)
+//
+// with the usage "karma give <user>... [reason]" matching messages like "karma give @alphonse @ignatius "for the coffee"".
+// Since a variadic argument consumes all following arguments of its type, the optional reason is only set if the text
+// following the users isn't a user mention
+type CommandSpec struct {
+	literals []string
+	args     []argSpec
+}
+
+// NewCommandSpec returns a new CommandSpec for a command starting with the given literal words
+func NewCommandSpec(literals ...string) (cs *CommandSpec) {
+	cs = new(CommandSpec)
+	cs.literals = literals
+	cs.args = make([]argSpec, 0)
+
+	return cs
+}
+
+// WithArg adds a required argument
+func (cs *CommandSpec) WithArg(name string, argType ArgType) *CommandSpec {
+	cs.args = append(cs.args, argSpec{name: name, argType: argType})
+	return cs
+}
+
+// WithOptionalArg adds an optional argument
+func (cs *CommandSpec) WithOptionalArg(name string, argType ArgType) *CommandSpec {
+	cs.args = append(cs.args, argSpec{name: name, argType: argType, optional: true})
+	return cs
+}
+
+// WithVariadicArg adds an argument taking one or more values
+func (cs *CommandSpec) WithVariadicArg(name string, argType ArgType) *CommandSpec {
+	cs.args = append(cs.args, argSpec{name: name, argType: argType, variadic: true})
+	return cs
+}
+
+// WithOptionalVariadicArg adds an argument taking zero or more values
+func (cs *CommandSpec) WithOptionalVariadicArg(name string, argType ArgType) *CommandSpec {
+	cs.args = append(cs.args, argSpec{name: name, argType: argType, optional: true, variadic: true})
+	return cs
+}
+
+// Usage returns the usage of the command (i.e. "top [count]")
+func (cs *CommandSpec) Usage() string {
+	parts := append([]string{}, cs.literals...)
+	for _, as := range cs.args {
+		parts = append(parts, as.usage())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Matches returns true if the text starts with the literal words of the command (compared without regard to case).
+// Note that a text matching a command might still have invalid arguments
+func (cs *CommandSpec) Matches(text string) bool {
+	tokens := tokenize(text)
+	if len(tokens) < len(cs.literals) {
+		return false
+	}
+
+	for i, l := range cs.literals {
+		if !strings.EqualFold(tokens[i].value, l) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Parse parses the arguments of the command. The text is expected to match the command (see Matches). If arguments are
+// missing, invalid or unexpected, the error is an *ArgsError describing the problem
+func (cs *CommandSpec) Parse(text string) (args *Args, err error) {
+	if !cs.Matches(text) {
+		return nil, cs.newArgsError("Expected `%s`", strings.Join(cs.literals, " "))
+	}
+
+	args = newArgs()
+	tokens := tokenize(text)[len(cs.literals):]
+
+	for i, as := range cs.args {
+		for len(tokens) > 0 {
+			v, err := parseArg(as.argType, tokens[0])
+			if err != nil {
+				// Optional arguments and variadic arguments already having a value end on the first token of another type unless
+				// they're the last argument (in which case the token can't be anything else)
+				isLast := i == len(cs.args)-1
+				if !isLast && (as.optional || (as.variadic && args.Has(as.name))) {
+					break
+				}
+
+				return nil, cs.newArgsError("Invalid %s `%s`: expected %s", as.name, tokens[0].value, argExpectations[as.argType])
+			}
+
+			args.values[as.name] = append(args.values[as.name], v)
+			tokens = tokens[1:]
+
+			if !as.variadic {
+				break
+			}
+		}
+
+		if !as.optional && !args.Has(as.name) {
+			return nil, cs.newArgsError("Missing %s: expected %s", as.name, argExpectations[as.argType])
+		}
+	}
+
+	if len(tokens) > 0 {
+		return nil, cs.newArgsError("Unexpected `%s`", tokens[0].value)
+	}
+
+	return args, nil
+}
+
+// newArgsError returns a new ArgsError with the command's usage
+func (cs *CommandSpec) newArgsError(format string, a ...interface{}) (err *ArgsError) {
+	return &ArgsError{Reason: fmt.Sprintf(format, a...), Usage: cs.Usage()}
+}
+
+// ArgsError is the error of parsing command arguments that don't follow their spec
+type ArgsError struct {
+	Reason string
+	Usage  string
+}
+
+// Error returns the reason of the error along with the command usage
+func (e *ArgsError) Error() string {
+	return fmt.Sprintf("%s. Usage: `%s`", e.Reason, e.Usage)
+}
+
+// Args holds the values of the parsed arguments of a command by name
+type Args struct {
+	values map[string][]interface{}
+}
+
+// newArgs returns new empty Args
+func newArgs() (args *Args) {
+	args = new(Args)
+	args.values = make(map[string][]interface{})
+
+	return args
+}
+
+// Has returns true if the argument has a value. This is always true for required arguments
+func (args *Args) Has(name string) bool {
+	return len(args.values[name]) > 0
+}
+
+// Int returns the value of an IntArg or 0 if it has no value
+func (args *Args) Int(name string) int {
+	if ints := args.Ints(name); len(ints) > 0 {
+		return ints[0]
+	}
+
+	return 0
+}
+
+// Ints returns the values of a variadic IntArg
+func (args *Args) Ints(name string) (ints []int) {
+	ints = make([]int, 0)
+	for _, v := range args.values[name] {
+		if i, ok := v.(int); ok {
+			ints = append(ints, i)
+		}
+	}
+
+	return ints
+}
+
+// Duration returns the value of a DurationArg or 0 if it has no value
+func (args *Args) Duration(name string) time.Duration {
+	if durations := args.Durations(name); len(durations) > 0 {
+		return durations[0]
+	}
+
+	return 0
+}
+
+// Durations returns the values of a variadic DurationArg
+func (args *Args) Durations(name string) (durations []time.Duration) {
+	durations = make([]time.Duration, 0)
+	for _, v := range args.values[name] {
+		if d, ok := v.(time.Duration); ok {
+			durations = append(durations, d)
+		}
+	}
+
+	return durations
+}
+
+// String returns the value of a StringArg, UserArg (the user id), ChannelArg (the channel id) or EmojiArg (the emoji name)
+// or an empty string if it has no value
+func (args *Args) String(name string) string {
+	if strs := args.Strings(name); len(strs) > 0 {
+		return strs[0]
+	}
+
+	return ""
+}
+
+// Strings returns the values of a variadic StringArg, UserArg, ChannelArg or EmojiArg
+func (args *Args) Strings(name string) (strs []string) {
+	strs = make([]string, 0)
+	for _, v := range args.values[name] {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+
+	return strs
+}
+
+// token is a word of a command or a quoted string
+type token struct {
+	value  string
+	quoted bool
+}
+
+// isQuote returns true if the rune is a straight or curly double quote (as slack clients often convert straight
+// quotes to curly ones)
+func isQuote(r rune) bool {
+	return r == '"' || r == '“' || r == '”'
+}
+
+// tokenize splits a text into words and "quoted strings"
+func tokenize(text string) (tokens []token) {
+	tokens = make([]token, 0)
+
+	var current strings.Builder
+	inQuotes := false
+	inToken := false
+
+	for _, r := range text {
+		switch {
+		case isQuote(r):
+			if inQuotes {
+				tokens = append(tokens, token{value: current.String(), quoted: true})
+				current.Reset()
+				inQuotes = false
+				inToken = false
+			} else if !inToken {
+				inQuotes = true
+			} else {
+				current.WriteRune(r)
+			}
+		case unicode.IsSpace(r) && !inQuotes:
+			if inToken {
+				tokens = append(tokens, token{value: current.String()})
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	// An unterminated quote is considered part of the last word
+	if inQuotes {
+		tokens = append(tokens, token{value: "\"" + current.String()})
+	} else if inToken {
+		tokens = append(tokens, token{value: current.String()})
+	}
+
+	return tokens
+}
+
+// parseArg parses a token as an argument of the given type
+func parseArg(argType ArgType, t token) (v interface{}, err error) {
+	if t.quoted && argType != StringArg {
+		return nil, fmt.Errorf("Unexpected quoted string [%s]", t.value)
+	}
+
+	switch argType {
+	case IntArg:
+		return strconv.Atoi(t.value)
+	case DurationArg:
+		return time.ParseDuration(t.value)
+	case UserArg:
+		return matchFirstGroup(userMentionRegex, t.value)
+	case ChannelArg:
+		return matchFirstGroup(channelMentionRegex, t.value)
+	case EmojiArg:
+		return matchFirstGroup(emojiRegex, t.value)
+	default:
+		return t.value, nil
+	}
+}
+
+// matchFirstGroup returns the first group of the regular expression matching the value
+func matchFirstGroup(regex *regexp.Regexp, value string) (group string, err error) {
+	matches := regex.FindStringSubmatch(value)
+	if matches == nil {
+		return "", fmt.Errorf("[%s] doesn't match [%s]", value, regex)
+	}
+
+	return matches[1], nil
+}
+
+// ArgsAnswerer is the Answerer of a command defined by a CommandSpec. It receives the parsed arguments of the command
+type ArgsAnswerer func(m *slackscot.IncomingMessage, args *Args) *slackscot.Answer
+
+// WithSpec sets the action's matcher, usage and answerer from a CommandSpec. The action matches messages starting
+// with the spec's literal words and the answerer gets the parsed arguments. If the arguments don't follow the spec,
+// the answer is the error describing what's wrong along with the usage
+func (ab *ActionBuilder) WithSpec(spec *CommandSpec, answerer ArgsAnswerer) *ActionBuilder {
+	ab.action.Match = func(m *slackscot.IncomingMessage) bool {
+		return spec.Matches(m.NormalizedText)
+	}
+	ab.action.Usage = spec.Usage()
+	ab.action.Answer = func(m *slackscot.IncomingMessage) *slackscot.Answer {
+		args, err := spec.Parse(m.NormalizedText)
+		if err != nil {
+			return &slackscot.Answer{Text: err.Error()}
+		}
+
+		return answerer(m, args)
+	}
+
+	return ab
+}
@@ -0,0 +1,143 @@
+package actions_test
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/actions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandSpecUsage(t *testing.T) {
+	spec := actions.NewCommandSpec("remind").
+		WithArg("who", actions.UserArg).
+		WithArg("in", actions.DurationArg).
+		WithOptionalArg("what", actions.StringArg)
+	assert.Equal(t, "remind <who> <in> [what]", spec.Usage())
+
+	spec = actions.NewCommandSpec("react", "with").
+		WithVariadicArg("emoji", actions.EmojiArg)
+	assert.Equal(t, "react with <emoji>...", spec.Usage())
+
+	spec = actions.NewCommandSpec("sum").
+		WithOptionalVariadicArg("number", actions.IntArg)
+	assert.Equal(t, "sum [number...]", spec.Usage())
+}
+
+func TestCommandSpecMatches(t *testing.T) {
+	spec := actions.NewCommandSpec("karma", "top").WithOptionalArg("count", actions.IntArg)
+
+	assert.True(t, spec.Matches("karma top"))
+	assert.True(t, spec.Matches("Karma TOP 10"))
+	assert.True(t, spec.Matches("karma   top abc"))
+	assert.False(t, spec.Matches("karma"))
+	assert.False(t, spec.Matches("karma worst 10"))
+	assert.False(t, spec.Matches("karmatop 10"))
+}
+
+func TestCommandSpecParse(t *testing.T) {
+	spec := actions.NewCommandSpec("remind").
+		WithArg("who", actions.UserArg).
+		WithArg("where", actions.ChannelArg).
+		WithArg("in", actions.DurationArg).
+		WithArg("count", actions.IntArg).
+		WithArg("emoji", actions.EmojiArg).
+		WithOptionalArg("what", actions.StringArg)
+
+	args, err := spec.Parse("remind <@U012AB3CD> <#C012AB3CD|general> 1h30m 3 :+1::skin-tone-2: “to drink some coffee”")
+	require.NoError(t, err)
+	assert.Equal(t, "U012AB3CD", args.String("who"))
+	assert.Equal(t, "C012AB3CD", args.String("where"))
+	assert.Equal(t, time.Duration(90)*time.Minute, args.Duration("in"))
+	assert.Equal(t, 3, args.Int("count"))
+	assert.Equal(t, "+1", args.String("emoji"))
+	assert.True(t, args.Has("what"))
+	assert.Equal(t, "to drink some coffee", args.String("what"))
+
+	args, err = spec.Parse("remind <@U012AB3CD|alphonse> <#C012AB3CD> 5m 1 :bird:")
+	require.NoError(t, err)
+	assert.Equal(t, "U012AB3CD", args.String("who"))
+	assert.False(t, args.Has("what"))
+	assert.Equal(t, "", args.String("what"))
+	assert.Equal(t, 0, args.Int("unknown"))
+	assert.Equal(t, time.Duration(0), args.Duration("unknown"))
+}
+
+func TestCommandSpecParseVariadicArgs(t *testing.T) {
+	spec := actions.NewCommandSpec("karma", "give").
+		WithVariadicArg("user", actions.UserArg).
+		WithOptionalArg("reason", actions.StringArg)
+
+	args, err := spec.Parse("karma give <@U1> <@U2> \"for the coffee\"")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"U1", "U2"}, args.Strings("user"))
+	assert.Equal(t, "for the coffee", args.String("reason"))
+
+	args, err = spec.Parse("karma give <@U1>")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"U1"}, args.Strings("user"))
+	assert.False(t, args.Has("reason"))
+
+	spec = actions.NewCommandSpec("sum").WithOptionalVariadicArg("number", actions.IntArg)
+	args, err = spec.Parse("sum")
+	require.NoError(t, err)
+	assert.Empty(t, args.Ints("number"))
+
+	args, err = spec.Parse("sum 1 2 3")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, args.Ints("number"))
+
+	spec = actions.NewCommandSpec("wait").WithVariadicArg("delay", actions.DurationArg)
+	args, err = spec.Parse("wait 1s 2m")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Second, time.Duration(2) * time.Minute}, args.Durations("delay"))
+}
+
+func TestCommandSpecParseErrors(t *testing.T) {
+	spec := actions.NewCommandSpec("remind").
+		WithArg("who", actions.UserArg).
+		WithArg("in", actions.DurationArg).
+		WithOptionalArg("count", actions.IntArg)
+
+	tests := map[string]string{
+		"remind":                             "Missing who: expected a user mention (i.e. @someone). Usage: `remind <who> <in> [count]`",
+		"remind alphonse 5m":                 "Invalid who `alphonse`: expected a user mention (i.e. @someone). Usage: `remind <who> <in> [count]`",
+		"remind <@U1>":                       "Missing in: expected a duration (i.e. 1h30m). Usage: `remind <who> <in> [count]`",
+		"remind <@U1> soon":                  "Invalid in `soon`: expected a duration (i.e. 1h30m). Usage: `remind <who> <in> [count]`",
+		"remind <@U1> 5m three":              "Invalid count `three`: expected a number. Usage: `remind <who> <in> [count]`",
+		"remind <@U1> 5m 3 times":            "Unexpected `times`. Usage: `remind <who> <in> [count]`",
+		"remind <@U1> 5m \"3\"":              "Invalid count `3`: expected a number. Usage: `remind <who> <in> [count]`",
+		"forget <@U1> 5m":                    "Expected `remind`. Usage: `remind <who> <in> [count]`",
+		"remind <@U1> 5m 3 \"unterminated":   "Unexpected `\"unterminated`. Usage: `remind <who> <in> [count]`",
+		"remind <@U1> 5m 3 \"an extra one\"": "Unexpected `an extra one`. Usage: `remind <who> <in> [count]`",
+	}
+
+	for text, expectedErr := range tests {
+		t.Run(text, func(t *testing.T) {
+			_, err := spec.Parse(text)
+
+			if assert.Error(t, err) {
+				assert.Equal(t, expectedErr, err.Error())
+				_, ok := err.(*actions.ArgsError)
+				assert.True(t, ok)
+			}
+		})
+	}
+}
+
+func TestNewActionWithSpec(t *testing.T) {
+	action := actions.NewCommand().
+		WithSpec(actions.NewCommandSpec("repeat").WithArg("times", actions.IntArg).WithArg("what", actions.StringArg), func(m *slackscot.IncomingMessage, args *actions.Args) *slackscot.Answer {
+			return &slackscot.Answer{Text: strings.TrimSpace(strings.Repeat(fmt.Sprintf("%s ", args.String("what")), args.Int("times")))}
+		}).
+		Build()
+
+	assert.Equal(t, "repeat <times> <what>", action.Usage)
+	assert.True(t, action.Match(&slackscot.IncomingMessage{NormalizedText: "repeat 3 chirp"}))
+	assert.False(t, action.Match(&slackscot.IncomingMessage{NormalizedText: "chirp 3 times"}))
+	assert.Equal(t, &slackscot.Answer{Text: "chirp chirp chirp"}, action.Answer(&slackscot.IncomingMessage{NormalizedText: "repeat 3 chirp"}))
+	assert.Equal(t, &slackscot.Answer{Text: "Invalid times `thrice`: expected a number. Usage: `repeat <times> <what>`"}, action.Answer(&slackscot.IncomingMessage{NormalizedText: "repeat thrice chirp"}))
+}
@@ -0,0 +1,216 @@
+package slackscot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/marcsantiago/gocron"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// HealthzPath is the path of the liveness endpoint served by the AdminHandler
+	HealthzPath = "/healthz"
+
+	// ReadyzPath is the path of the readiness endpoint served by the AdminHandler
+	ReadyzPath = "/readyz"
+
+	// StatusPath is the path of the status endpoint served by the AdminHandler
+	StatusPath = "/status"
+)
+
+// scheduledJob is a scheduled action registered with the scheduler
+type scheduledJob struct {
+	actionID   string
+	definition ScheduledActionDefinition
+	job        *gocron.Job
+}
+
+// status is the state of a running slackscot as served on StatusPath
+type status struct {
+	Name               string               `json:"name"`
+	Version            string               `json:"version"`
+	Ready              bool                 `json:"ready"`
+	SlackLatencyMillis int64                `json:"slackLatencyMillis"`
+	Workspaces         []workspaceStatus    `json:"workspaces"`
+	QueueDepths        []int                `json:"queueDepths"`
+	Plugins            []pluginStatus       `json:"plugins"`
+	ScheduledJobs      []scheduledJobStatus `json:"scheduledJobs"`
+}
+
+// workspaceStatus is the state of the connection to a workspace. The response cache size is only known
+// for response caches keeping count of their entries (like the default ARCResponseCache)
+type workspaceStatus struct {
+	Name              string `json:"name,omitempty"`
+	TeamID            string `json:"teamId,omitempty"`
+	Ready             bool   `json:"ready"`
+	ConnectionCount   int    `json:"connectionCount"`
+	ResponseCacheSize *int   `json:"responseCacheSize,omitempty"`
+}
+
+// pluginStatus is a registered plugin with its actions
+type pluginStatus struct {
+	Name    string         `json:"name"`
+	Actions []actionStatus `json:"actions"`
+}
+
+// actionStatus is an action of a plugin
+type actionStatus struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Usage       string `json:"usage,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// scheduledJobStatus is a scheduled action with the next time it runs
+type scheduledJobStatus struct {
+	ActionID    string    `json:"actionId"`
+	Schedule    string    `json:"schedule"`
+	Description string    `json:"description,omitempty"`
+	NextRun     time.Time `json:"nextRun"`
+}
+
+// AdminHandler returns an http.Handler serving the liveness (HealthzPath), readiness (ReadyzPath) and status (StatusPath)
+// endpoints. Slackscot is ready once it's connected to all of its workspaces and has loaded its identity in them. The status
+// is a JSON document with the state of the workspace connections, the slack latency, the depth of the message processing
+// queues, the registered plugins with their actions and the scheduled actions with their next run time.
+//
+// Slackscot serves this handler on its own when config.AdminListenAddrKey is set
+func (s *Slackscot) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthzPath, s.serveHealthz)
+	mux.HandleFunc(ReadyzPath, s.serveReadyz)
+	mux.HandleFunc(StatusPath, s.serveStatus)
+
+	return mux
+}
+
+// startAdminServer serves the AdminHandler on the listenAddr and returns a function to stop the server
+func (s *Slackscot) startAdminServer(listenAddr string) (stop func()) {
+	server := &http.Server{Addr: listenAddr, Handler: s.AdminHandler()}
+
+	go func() {
+		s.log.Info("Serving admin endpoints", "addr", listenAddr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.log.Error("Error serving admin endpoints", "addr", listenAddr, "err", err)
+		}
+	}()
+
+	return func() {
+		server.Close()
+	}
+}
+
+// serveHealthz reports that the process is alive
+func (s *Slackscot) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, "ok")
+}
+
+// serveReadyz reports whether slackscot is connected to all of its workspaces
+func (s *Slackscot) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	if !s.isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, "ok")
+}
+
+// serveStatus writes the status as JSON
+func (s *Slackscot) serveStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(s.status()); err != nil {
+		s.log.Error("Error writing status", "err", err)
+	}
+}
+
+// isReady returns true if all workspaces are connected
+func (s *Slackscot) isReady() bool {
+	for _, ws := range s.workspaces.all {
+		if ready, _, _ := ws.connectionStatus(); !ready {
+			return false
+		}
+	}
+
+	return true
+}
+
+// status returns the current status
+func (s *Slackscot) status() (st status) {
+	s.reloadLock.RLock()
+	defer s.reloadLock.RUnlock()
+
+	st.Name = s.name
+	st.Version = VERSION
+	st.Ready = s.isReady()
+	st.SlackLatencyMillis = atomic.LoadInt64(&s.slackLatencyMillis)
+
+	st.Workspaces = make([]workspaceStatus, 0)
+	for _, ws := range s.workspaces.all {
+		ready, connectionCount, teamID := ws.connectionStatus()
+		st.Workspaces = append(st.Workspaces, workspaceStatus{Name: ws.name, TeamID: teamID, Ready: ready, ConnectionCount: connectionCount, ResponseCacheSize: responseCacheSize(ws.responseCache)})
+	}
+
+	st.QueueDepths = make([]int, 0)
+	for _, q := range s.messageQueues {
+		st.QueueDepths = append(st.QueueDepths, len(q))
+	}
+
+	st.Plugins = make([]pluginStatus, 0)
+	for _, p := range s.plugins {
+		st.Plugins = append(st.Plugins, newPluginStatus(p))
+	}
+
+	st.ScheduledJobs = make([]scheduledJobStatus, 0)
+	for _, sj := range s.scheduledJobs {
+		st.ScheduledJobs = append(st.ScheduledJobs, scheduledJobStatus{ActionID: sj.actionID, Schedule: sj.definition.Schedule.String(), Description: sj.definition.Description, NextRun: sj.job.NextScheduledTime()})
+	}
+
+	return st
+}
+
+// newPluginStatus returns the status of a plugin with all of its actions
+func newPluginStatus(p *Plugin) (ps pluginStatus) {
+	ps.Name = p.Name
+	ps.Actions = make([]actionStatus, 0)
+
+	for i, c := range p.Commands {
+		ps.Actions = append(ps.Actions, actionStatus{ID: getActionID(p.Name, CommandActionType, i), Type: CommandActionType, Usage: c.Usage, Description: c.Description})
+	}
+
+	for i, ha := range p.HearActions {
+		ps.Actions = append(ps.Actions, actionStatus{ID: getActionID(p.Name, HearActionType, i), Type: HearActionType, Usage: ha.Usage, Description: ha.Description})
+	}
+
+	for i, ra := range p.ReactionActions {
+		ps.Actions = append(ps.Actions, actionStatus{ID: getActionID(p.Name, ReactionActionType, i), Type: ReactionActionType, Usage: ra.Usage, Description: ra.Description})
+	}
+
+	for i, ia := range p.InteractionActions {
+		ps.Actions = append(ps.Actions, actionStatus{ID: getActionID(p.Name, InteractionActionType, i), Type: InteractionActionType, Description: ia.Description})
+	}
+
+	for i, sa := range p.ScheduledActions {
+		ps.Actions = append(ps.Actions, actionStatus{ID: getActionID(p.Name, ScheduledActionType, i), Type: ScheduledActionType, Usage: sa.Schedule.String(), Description: sa.Description})
+	}
+
+	return ps
+}
+
+// responseCacheSize returns the number of entries of a response cache or nil if the cache doesn't keep count of them
+func responseCacheSize(cache ResponseCache) (size *int) {
+	if c, ok := cache.(*responseCacheWithTelemetry); ok {
+		cache = c.base
+	}
+
+	if sizer, ok := cache.(interface{ Len() int }); ok {
+		l := sizer.Len()
+		return &l
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package slackscot
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/schedule"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// adminResponse is the response of an admin endpoint
+type adminResponse struct {
+	code int
+	body string
+}
+
+func getAdminEndpoint(s *Slackscot, path string) (r adminResponse) {
+	rec := httptest.NewRecorder()
+	s.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return adminResponse{code: rec.Code, body: rec.Body.String()}
+}
+
+// optionCaptureSlackscot captures the slackscot instance it's applied to
+func optionCaptureSlackscot(captured **Slackscot) Option {
+	return func(s *Slackscot) {
+		*captured = s
+	}
+}
+
+// newStatusTestPlugin returns a plugin requesting the status of slackscot when hearing a message
+func newStatusTestPlugin(s **Slackscot, st *status) (p *Plugin) {
+	p = newTestPlugin()
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return m.NormalizedText == "status?"
+		},
+		Usage:       "status?",
+		Description: "Takes a look at the status",
+		Answer: func(m *IncomingMessage) *Answer {
+			json.Unmarshal([]byte(getAdminEndpoint(*s, StatusPath).body), st)
+
+			return nil
+		},
+	}}
+	p.ScheduledActions = []ScheduledActionDefinition{{Schedule: schedule.Definition{Interval: 1, Unit: schedule.Hours}, Description: "Checks in every hour", Action: func() {}}}
+
+	return p
+}
+
+func TestHealthz(t *testing.T) {
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionLog(log.New(&nullWriter{}, "", 0)))
+	require.NoError(t, err)
+
+	r := getAdminEndpoint(s, HealthzPath)
+	assert.Equal(t, http.StatusOK, r.code)
+	assert.Equal(t, "ok", r.body)
+}
+
+func TestNotReadyBeforeConnected(t *testing.T) {
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionLog(log.New(&nullWriter{}, "", 0)))
+	require.NoError(t, err)
+
+	r := getAdminEndpoint(s, ReadyzPath)
+	assert.Equal(t, http.StatusServiceUnavailable, r.code)
+	assert.Equal(t, "not ready\n", r.body)
+}
+
+func TestReadinessFollowsConnection(t *testing.T) {
+	termination := make(chan bool)
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionLog(log.New(&nullWriter{}, "", 0)), OptionTestMode(termination))
+	require.NoError(t, err)
+
+	ec := make(chan slack.RTMEvent)
+	s.workspaces.defaultWorkspace().attach(ec, &runDependencies{chatDriver: &inMemoryChatDriver{}, userInfoFinder: &userInfoFinder{}, emojiReactor: &emojiReactor{}, selfInfoFinder: &selfFinder{}})
+	go s.runInternal(context.Background())
+
+	assert.Equal(t, http.StatusServiceUnavailable, getAdminEndpoint(s, ReadyzPath).code)
+
+	ec <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{ConnectionCount: 2}}
+	assert.Eventually(t, func() bool {
+		return getAdminEndpoint(s, ReadyzPath).code == http.StatusOK
+	}, time.Second, time.Millisecond)
+
+	ready, connectionCount, _ := s.workspaces.defaultWorkspace().connectionStatus()
+	assert.True(t, ready)
+	assert.Equal(t, 2, connectionCount)
+
+	ec <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: true, Cause: slack.ErrRTMGoodbye}}
+	<-termination
+
+	assert.Equal(t, http.StatusServiceUnavailable, getAdminEndpoint(s, ReadyzPath).code)
+}
+
+func TestStatus(t *testing.T) {
+	var s *Slackscot
+	var st status
+
+	runSlackscotWithIncomingEvents(t, nil, newStatusTestPlugin(&s, &st), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "status?", "Alphonse", timestamp1)),
+	}, nil, OptionLog(log.New(&nullWriter{}, "", 0)), optionCaptureSlackscot(&s))
+
+	assert.Equal(t, "chickadee", st.Name)
+	assert.Equal(t, VERSION, st.Version)
+	assert.Equal(t, []int{0}, st.QueueDepths)
+
+	require.Len(t, st.Workspaces, 1)
+	if assert.NotNil(t, st.Workspaces[0].ResponseCacheSize) {
+		assert.Equal(t, 0, *st.Workspaces[0].ResponseCacheSize)
+	}
+
+	require.Len(t, st.Plugins, 2)
+	assert.Equal(t, "noRules", st.Plugins[0].Name)
+	assert.Contains(t, st.Plugins[0].Actions, actionStatus{ID: "noRules.command[0]", Type: CommandActionType, Usage: "make `<something>`", Description: "Have the test bot make something for you"})
+	assert.Contains(t, st.Plugins[0].Actions, actionStatus{ID: "noRules.hearAction[0]", Type: HearActionType, Usage: "status?", Description: "Takes a look at the status"})
+	assert.Contains(t, st.Plugins[0].Actions, actionStatus{ID: "noRules.scheduledAction[0]", Type: ScheduledActionType, Usage: "Every hour", Description: "Checks in every hour"})
+	assert.Equal(t, helpPluginName, st.Plugins[1].Name)
+
+	require.Len(t, st.ScheduledJobs, 1)
+	assert.Equal(t, "noRules.scheduledAction[0]", st.ScheduledJobs[0].ActionID)
+	assert.Equal(t, "Every hour", st.ScheduledJobs[0].Schedule)
+	assert.Equal(t, "Checks in every hour", st.ScheduledJobs[0].Description)
+	assert.False(t, st.ScheduledJobs[0].NextRun.IsZero())
+}
+
+func TestResponseCacheSizeUnknownForStorerResponseCache(t *testing.T) {
+	assert.Nil(t, responseCacheSize(NewStorerResponseCache(nil, time.Hour)))
+}
@@ -13,8 +13,15 @@ const (
 	ThreadTimestamp = "threadTimestamp"
 	// EphemeralAnswerToOpt marks an answer to be sent as an ephemeral message to the provided userID
 	EphemeralAnswerToOpt = "ephemeralMsgToUserID"
+	// UpdateOriginalOpt is the name of the option indicating that an answer to an interaction should update the message holding the interactive component
+	UpdateOriginalOpt = "updateOriginal"
+	// StartDialogOpt is the name of the option indicating the name of the dialog started by an answer
+	StartDialogOpt = "startDialog"
 )
 
+// dialogValueOptPrefix prefixes the names of the options holding the initial values of a started dialog
+const dialogValueOptPrefix = "dialogValue."
+
 // Answer holds data of an Action's Answer: namely, its text and options
 // to use when delivering it
 type Answer struct {
@@ -75,6 +82,30 @@ func AnswerEphemeral(userID string) AnswerOption {
 	}
 }
 
+// AnswerUpdatingOriginal sends the answer as an update of the message holding the interactive component that
+// was interacted with. This only applies to answers of interaction actions
+func AnswerUpdatingOriginal() AnswerOption {
+	return func(sendOpts map[string]string) {
+		sendOpts[UpdateOriginalOpt] = "true"
+	}
+}
+
+// AnswerStartingDialog starts the plugin's dialog with the given name (see DialogDefinition). The next messages of the
+// user in the channel and thread the answer is sent in go to the dialog until it ends. This only applies to answers of commands and hear actions
+func AnswerStartingDialog(name string) AnswerOption {
+	return func(sendOpts map[string]string) {
+		sendOpts[StartDialogOpt] = name
+	}
+}
+
+// AnswerWithDialogValue sets a value of the dialog started by the answer (see AnswerStartingDialog). Values are
+// available to the dialog's steps in DialogTurn.Values
+func AnswerWithDialogValue(key string, value string) AnswerOption {
+	return func(sendOpts map[string]string) {
+		sendOpts[dialogValueOptPrefix+key] = value
+	}
+}
+
 // ApplyAnswerOpts applies answering options to build the send configuration
 func ApplyAnswerOpts(opts ...AnswerOption) (sendOptions map[string]string) {
 	sendOptions = make(map[string]string)
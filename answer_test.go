@@ -19,6 +19,7 @@ func TestApplyAnswerOptions(t *testing.T) {
 		{"noThreading", []slackscot.AnswerOption{slackscot.AnswerWithoutThreading()}, map[string]string{slackscot.ThreadedReplyOpt: "false"}},
 		{"threadReplyOnExistingThread", []slackscot.AnswerOption{slackscot.AnswerInExistingThread("1000")}, map[string]string{slackscot.ThreadedReplyOpt: "true", slackscot.ThreadTimestamp: "1000"}},
 		{"ephemeralAnswer", []slackscot.AnswerOption{slackscot.AnswerEphemeral("U12321")}, map[string]string{slackscot.EphemeralAnswerToOpt: "U12321"}},
+		{"updatingOriginalAnswer", []slackscot.AnswerOption{slackscot.AnswerUpdatingOriginal()}, map[string]string{slackscot.UpdateOriginalOpt: "true"}},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,189 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/spf13/viper"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AdminRole is the role of admins as configured at config.AuthorizationAdminsKey (and, optionally, slack workspace
+// admins and owners). Admins have all roles so actions requiring the AdminRole can only be run by admins
+const AdminRole = "admin"
+
+const (
+	// Slack user group ids start with an S while user ids start with a U or a W
+	userGroupIDPrefix = "S"
+)
+
+// UserGroupMemberFinder is implemented by any value that has the GetUserGroupMembers method. slack.Client
+// implements it
+type UserGroupMemberFinder interface {
+	GetUserGroupMembers(userGroup string) (members []string, err error)
+}
+
+// cachedUserGroupMembers holds the members of a user group and when they should be looked up again
+type cachedUserGroupMembers struct {
+	members   []string
+	expiresAt time.Time
+}
+
+// authorizer decides whether users are authorized to run actions requiring roles. Admins and role members are
+// read from the configuration on every check and can be user ids or user group ids (whose members are cached
+// for config.UserGroupCacheExpirationKey)
+type authorizer struct {
+	config       *viper.Viper
+	groupMembers map[string]cachedUserGroupMembers
+	lock         sync.Mutex
+	now          func() time.Time
+}
+
+// newAuthorizer returns a new authorizer for the configuration
+func newAuthorizer(v *viper.Viper) (az *authorizer) {
+	az = new(authorizer)
+	az.config = v
+	az.groupMembers = make(map[string]cachedUserGroupMembers)
+	az.now = time.Now
+
+	return az
+}
+
+// isAuthorized returns true if the user has all required roles. The UserInfoFinder is used to find whether the
+// user is a slack workspace admin or owner (when config.AuthorizationSlackAdminsKey is enabled) and the
+// UserGroupMemberFinder to find the members of user groups. Either one can be nil in which case the corresponding
+// lookups are skipped
+func (az *authorizer) isAuthorized(userID string, requiredRoles []string, userInfoFinder UserInfoFinder, groupMemberFinder UserGroupMemberFinder) (authorized bool, err error) {
+	if len(requiredRoles) == 0 {
+		return true, nil
+	}
+
+	isAdmin, err := az.isAdmin(userID, userInfoFinder, groupMemberFinder)
+	if err != nil || isAdmin {
+		return isAdmin, err
+	}
+
+	roles := az.config.GetStringMapStringSlice(config.AuthorizationRolesKey)
+	for _, role := range requiredRoles {
+		role = strings.ToLower(role)
+		if role == AdminRole {
+			return false, nil
+		}
+
+		hasRole, err := az.isMember(userID, roles[role], groupMemberFinder)
+		if err != nil || !hasRole {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// isAdmin returns true if the user is an admin
+func (az *authorizer) isAdmin(userID string, userInfoFinder UserInfoFinder, groupMemberFinder UserGroupMemberFinder) (isAdmin bool, err error) {
+	isAdmin, err = az.isMember(userID, az.config.GetStringSlice(config.AuthorizationAdminsKey), groupMemberFinder)
+	if err != nil || isAdmin {
+		return isAdmin, err
+	}
+
+	if !az.config.GetBool(config.AuthorizationSlackAdminsKey) || userInfoFinder == nil {
+		return false, nil
+	}
+
+	user, err := userInfoFinder.GetUserInfo(userID)
+	if err != nil {
+		return false, fmt.Errorf("Error getting user info for user [%s]: %v", userID, err)
+	}
+
+	return user.IsAdmin || user.IsOwner, nil
+}
+
+// isMember returns true if the user is one of the members. Members can be user ids or user group ids
+func (az *authorizer) isMember(userID string, members []string, groupMemberFinder UserGroupMemberFinder) (isMember bool, err error) {
+	for _, m := range members {
+		if m == userID {
+			return true, nil
+		}
+
+		if !strings.HasPrefix(m, userGroupIDPrefix) || groupMemberFinder == nil {
+			continue
+		}
+
+		groupMembers, err := az.getUserGroupMembers(m, groupMemberFinder)
+		if err != nil {
+			return false, err
+		}
+
+		for _, gm := range groupMembers {
+			if gm == userID {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// getUserGroupMembers returns the members of a user group from cache or looks them up if they're not
+// cached or have expired
+func (az *authorizer) getUserGroupMembers(userGroupID string, groupMemberFinder UserGroupMemberFinder) (members []string, err error) {
+	az.lock.Lock()
+	defer az.lock.Unlock()
+
+	if cached, ok := az.groupMembers[userGroupID]; ok && az.now().Before(cached.expiresAt) {
+		return cached.members, nil
+	}
+
+	members, err = groupMemberFinder.GetUserGroupMembers(userGroupID)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting members of user group [%s]: %v", userGroupID, err)
+	}
+
+	az.groupMembers[userGroupID] = cachedUserGroupMembers{members: members, expiresAt: az.now().Add(az.config.GetDuration(config.UserGroupCacheExpirationKey))}
+
+	return members, nil
+}
+
+// isAuthorized returns true if the author of the message is authorized to run an action requiring the roles.
+// Errors looking up users or user groups are logged and deny access
+func (s *Slackscot) isAuthorized(m *IncomingMessage, requiredRoles []string) bool {
+	if len(requiredRoles) == 0 {
+		return true
+	}
+
+	var userInfoFinder UserInfoFinder
+	var groupMemberFinder UserGroupMemberFinder
+	if ws, err := s.workspaces.find(m.TeamID); err == nil && ws.services != nil {
+		userInfoFinder = ws.services.UserInfoFinder
+		groupMemberFinder = ws.deps.groupMemberFinder
+	}
+
+	authorized, err := s.authorizer.isAuthorized(m.User, requiredRoles, userInfoFinder, groupMemberFinder)
+	if err != nil {
+		s.log.Printf("Error checking authorization of user [%s] for roles %v, denying access: %v", m.User, requiredRoles, err)
+	}
+
+	return authorized
+}
+
+// authorizedAction returns the action guarded by an authorization check if it requires roles. The check happens once
+// the action matches and, when the author of the message isn't authorized, the answer is config.UnauthorizedAnswerKey
+// instead of the action's answer
+func (s *Slackscot) authorizedAction(action ActionDefinition) (authorized ActionDefinition) {
+	if len(action.RequiredRoles) == 0 {
+		return action
+	}
+
+	authorized = action
+	authorized.ContextAnswer = func(ctx context.Context, m *IncomingMessage) *Answer {
+		if !s.isAuthorized(m, action.RequiredRoles) {
+			return &Answer{Text: s.config.GetString(config.UnauthorizedAnswerKey)}
+		}
+
+		return action.answer(ctx, m)
+	}
+
+	return authorized
+}
@@ -0,0 +1,163 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type userGroupMemberFinder struct {
+	members map[string][]string
+	calls   int
+}
+
+func (f *userGroupMemberFinder) GetUserGroupMembers(userGroup string) (members []string, err error) {
+	f.calls = f.calls + 1
+
+	members, ok := f.members[userGroup]
+	if !ok {
+		return nil, fmt.Errorf("user group [%s] not found", userGroup)
+	}
+
+	return members, nil
+}
+
+type slackAdminInfoFinder struct {
+	admins map[string]bool
+}
+
+func (f *slackAdminInfoFinder) GetUserInfo(userID string) (user *slack.User, err error) {
+	return &slack.User{ID: userID, IsAdmin: f.admins[userID]}, nil
+}
+
+func newAuthorizationConfig() (v *viper.Viper) {
+	v = config.NewViperWithDefaults()
+	v.Set(config.AuthorizationAdminsKey, []string{"Alphonse", "Sadmins"})
+	v.Set(config.AuthorizationRolesKey, map[string][]string{"moderator": {"Ignatius", "Smoderators"}})
+
+	return v
+}
+
+func TestAuthorizerWithoutRequiredRoles(t *testing.T) {
+	az := newAuthorizer(newAuthorizationConfig())
+
+	authorized, err := az.isAuthorized("Jane", nil, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, authorized)
+}
+
+func TestAuthorizerAdmins(t *testing.T) {
+	az := newAuthorizer(newAuthorizationConfig())
+	groupFinder := &userGroupMemberFinder{members: map[string][]string{"Sadmins": {"Dolores"}, "Smoderators": {"Lana"}}}
+
+	tests := map[string]bool{"Alphonse": true, "Dolores": true, "Ignatius": false, "Lana": false, "Jane": false}
+	for user, expected := range tests {
+		t.Run(user, func(t *testing.T) {
+			authorized, err := az.isAuthorized(user, []string{AdminRole}, nil, groupFinder)
+			require.NoError(t, err)
+			assert.Equal(t, expected, authorized)
+		})
+	}
+}
+
+func TestAuthorizerRoles(t *testing.T) {
+	az := newAuthorizer(newAuthorizationConfig())
+	groupFinder := &userGroupMemberFinder{members: map[string][]string{"Sadmins": {"Dolores"}, "Smoderators": {"Lana"}}}
+
+	// Admins have all roles and role names aren't case sensitive
+	tests := map[string]bool{"Alphonse": true, "Dolores": true, "Ignatius": true, "Lana": true, "Jane": false}
+	for user, expected := range tests {
+		t.Run(user, func(t *testing.T) {
+			authorized, err := az.isAuthorized(user, []string{"Moderator"}, nil, groupFinder)
+			require.NoError(t, err)
+			assert.Equal(t, expected, authorized)
+		})
+	}
+
+	authorized, err := az.isAuthorized("Ignatius", []string{"moderator", "publisher"}, nil, groupFinder)
+	require.NoError(t, err)
+	assert.False(t, authorized)
+}
+
+func TestAuthorizerSlackAdmins(t *testing.T) {
+	v := newAuthorizationConfig()
+	az := newAuthorizer(v)
+	userInfoFinder := &slackAdminInfoFinder{admins: map[string]bool{"Jane": true}}
+
+	authorized, err := az.isAuthorized("Jane", []string{AdminRole}, userInfoFinder, nil)
+	require.NoError(t, err)
+	assert.False(t, authorized)
+
+	v.Set(config.AuthorizationSlackAdminsKey, true)
+	authorized, err = az.isAuthorized("Jane", []string{AdminRole}, userInfoFinder, nil)
+	require.NoError(t, err)
+	assert.True(t, authorized)
+
+	authorized, err = az.isAuthorized("Lana", []string{AdminRole}, userInfoFinder, nil)
+	require.NoError(t, err)
+	assert.False(t, authorized)
+}
+
+func TestAuthorizerCachesUserGroupMembers(t *testing.T) {
+	clock := fakeClock{now: time.Now()}
+	az := newAuthorizer(newAuthorizationConfig())
+	az.now = clock.Now
+	groupFinder := &userGroupMemberFinder{members: map[string][]string{"Sadmins": {"Dolores"}}}
+
+	for i := 0; i < 3; i++ {
+		authorized, err := az.isAuthorized("Dolores", []string{AdminRole}, nil, groupFinder)
+		require.NoError(t, err)
+		assert.True(t, authorized)
+	}
+	assert.Equal(t, 1, groupFinder.calls)
+
+	clock.now = clock.now.Add(time.Duration(6) * time.Minute)
+	groupFinder.members["Sadmins"] = []string{}
+	authorized, err := az.isAuthorized("Dolores", []string{AdminRole}, nil, groupFinder)
+	require.NoError(t, err)
+	assert.False(t, authorized)
+	assert.Equal(t, 2, groupFinder.calls)
+}
+
+func TestAuthorizerUserGroupLookupError(t *testing.T) {
+	az := newAuthorizer(newAuthorizationConfig())
+
+	authorized, err := az.isAuthorized("Dolores", []string{AdminRole}, nil, &userGroupMemberFinder{})
+	assert.Error(t, err)
+	assert.False(t, authorized)
+}
+
+func newRestrictedPlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "restricted"
+	p.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return m.NormalizedText == "wipe everything"
+		},
+		Usage:         "wipe everything",
+		Description:   "Wipe everything",
+		RequiredRoles: []string{AdminRole},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "Everything wiped"}
+		},
+	}}
+
+	return p
+}
+
+func TestUnauthorizedUsersDeniedRestrictedActions(t *testing.T) {
+	v := newAuthorizationConfig()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newRestrictedPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", formattedBotUserID+" wipe everything", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", formattedBotUserID+" wipe everything", "Jane", timestamp2)),
+	}, nil)
+
+	assert.Equal(t, []string{"<@Alphonse>: Everything wiped", "<@Jane>: 🚫 Sorry, you're not authorized to do that"}, sentTexts(sentMsgs))
+}
@@ -72,22 +72,32 @@ func (sb *Builder) WithPluginCloserErr(closer io.Closer, p *Plugin, err error) *
 }
 
 // WithConfigurablePluginErr adds a plugin to the slackscot instance by first checking and
-// getting its configuration
+// getting its configuration. The plugin is re-instantiated when its configuration changes on
+// reload (see Slackscot.ReloadConfig)
 func (sb *Builder) WithConfigurablePluginErr(name string, newInstance PluginInstantiator) *Builder {
-	if pc, exists := sb.loadPluginConfig(name); exists {
-		return sb.WithPluginErr(newInstance(pc))
-	}
-
-	return sb
+	return sb.WithConfigurablePluginCloserErr(name, func(c *config.PluginConfig) (closer io.Closer, p *Plugin, err error) {
+		p, err = newInstance(c)
+		return nil, p, err
+	})
 }
 
 // WithConfigurablePluginCloserErr adds a closer plugin to the slackscot instance by first checking and
-// getting its configuration
+// getting its configuration. The plugin is re-instantiated when its configuration changes on reload (see
+// Slackscot.ReloadConfig) and its previous instance is then closed
 func (sb *Builder) WithConfigurablePluginCloserErr(name string, newInstance CloserPluginInstantiator) *Builder {
-	if pc, exists := sb.loadPluginConfig(name); exists {
-		return sb.WithPluginCloserErr(newInstance(pc))
+	pc, exists := sb.loadPluginConfig(name)
+	if !exists {
+		return sb
+	}
+
+	closer, p, err := newInstance(pc)
+	if err != nil {
+		sb.err = err
+		return sb
 	}
 
+	sb.bot.registerConfigurablePlugin(name, newInstance, pc, closer, p)
+
 	return sb
 }
 
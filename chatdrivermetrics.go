@@ -12,29 +12,50 @@ import (
 	"unicode"
 
 	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/label"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // chatDriverWithTelemetry implements chatDriver interface with all methods wrapped
-// with open telemetry metrics
+// with open telemetry metrics and spans
 type chatDriverWithTelemetry struct {
 	base                     chatDriver
 	methodCounters           map[string]metric.BoundInt64Counter
 	errCounters              map[string]metric.BoundInt64Counter
 	methodTimeValueRecorders map[string]metric.BoundInt64ValueRecorder
+	tracer                   trace.Tracer
+	ctx                      context.Context
 }
 
 // NewchatDriverWithTelemetry returns an instance of the chatDriver decorated with open telemetry timing and count metrics
-func NewchatDriverWithTelemetry(base chatDriver, name string, meter metric.Meter) chatDriverWithTelemetry {
+// and a span for each call
+func NewchatDriverWithTelemetry(base chatDriver, name string, meter metric.Meter, tracer trace.Tracer) chatDriverWithTelemetry {
 	return chatDriverWithTelemetry{
 		base:                     base,
 		methodCounters:           newchatDriverMethodCounters("Calls", name, meter),
 		errCounters:              newchatDriverMethodCounters("Errors", name, meter),
 		methodTimeValueRecorders: newchatDriverMethodTimeValueRecorders(name, meter),
+		tracer:                   tracer,
 	}
 }
 
+// bindContext returns a copy of the chatDriverWithTelemetry starting the spans of its calls as children of the span in ctx
+func (_d chatDriverWithTelemetry) bindContext(ctx context.Context) interface{} {
+	_d.ctx = ctx
+	return _d
+}
+
+// spanParent returns the context holding the parent span of calls
+func (_d chatDriverWithTelemetry) spanParent() context.Context {
+	if _d.ctx == nil {
+		return context.Background()
+	}
+
+	return _d.ctx
+}
+
 func newchatDriverMethodTimeValueRecorders(appName string, meter metric.Meter) (boundTimeValueRecorders map[string]metric.BoundInt64ValueRecorder) {
 	boundTimeValueRecorders = make(map[string]metric.BoundInt64ValueRecorder)
 	mt := metric.Must(meter)
@@ -82,10 +103,14 @@ func newchatDriverMethodCounters(suffix string, appName string, meter metric.Met
 // DeleteMessage implements chatDriver
 func (_d chatDriverWithTelemetry) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
 	_since := time.Now()
+	_, _span := _d.tracer.Start(_d.spanParent(), "chatDriver.DeleteMessage", trace.WithSpanKind(trace.SpanKindClient))
 	defer func() {
 		if err != nil {
 			errCounter := _d.errCounters["DeleteMessage"]
 			errCounter.Add(context.Background(), 1)
+
+			_span.RecordError(err)
+			_span.SetStatus(codes.Error, err.Error())
 		}
 
 		methodCounter := _d.methodCounters["DeleteMessage"]
@@ -93,6 +118,8 @@ func (_d chatDriverWithTelemetry) DeleteMessage(channelID string, timestamp stri
 
 		methodTimeMeasure := _d.methodTimeValueRecorders["DeleteMessage"]
 		methodTimeMeasure.Record(context.Background(), time.Since(_since).Milliseconds())
+
+		_span.End()
 	}()
 	return _d.base.DeleteMessage(channelID, timestamp)
 }
@@ -100,10 +127,14 @@ func (_d chatDriverWithTelemetry) DeleteMessage(channelID string, timestamp stri
 // SendMessage implements chatDriver
 func (_d chatDriverWithTelemetry) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
 	_since := time.Now()
+	_, _span := _d.tracer.Start(_d.spanParent(), "chatDriver.SendMessage", trace.WithSpanKind(trace.SpanKindClient))
 	defer func() {
 		if err != nil {
 			errCounter := _d.errCounters["SendMessage"]
 			errCounter.Add(context.Background(), 1)
+
+			_span.RecordError(err)
+			_span.SetStatus(codes.Error, err.Error())
 		}
 
 		methodCounter := _d.methodCounters["SendMessage"]
@@ -111,6 +142,8 @@ func (_d chatDriverWithTelemetry) SendMessage(channelID string, options ...slack
 
 		methodTimeMeasure := _d.methodTimeValueRecorders["SendMessage"]
 		methodTimeMeasure.Record(context.Background(), time.Since(_since).Milliseconds())
+
+		_span.End()
 	}()
 	return _d.base.SendMessage(channelID, options...)
 }
@@ -118,10 +151,14 @@ func (_d chatDriverWithTelemetry) SendMessage(channelID string, options ...slack
 // UpdateMessage implements chatDriver
 func (_d chatDriverWithTelemetry) UpdateMessage(channelID string, timestamp string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
 	_since := time.Now()
+	_, _span := _d.tracer.Start(_d.spanParent(), "chatDriver.UpdateMessage", trace.WithSpanKind(trace.SpanKindClient))
 	defer func() {
 		if err != nil {
 			errCounter := _d.errCounters["UpdateMessage"]
 			errCounter.Add(context.Background(), 1)
+
+			_span.RecordError(err)
+			_span.SetStatus(codes.Error, err.Error())
 		}
 
 		methodCounter := _d.methodCounters["UpdateMessage"]
@@ -129,6 +166,8 @@ func (_d chatDriverWithTelemetry) UpdateMessage(channelID string, timestamp stri
 
 		methodTimeMeasure := _d.methodTimeValueRecorders["UpdateMessage"]
 		methodTimeMeasure.Record(context.Background(), time.Since(_since).Milliseconds())
+
+		_span.End()
 	}()
 	return _d.base.UpdateMessage(channelID, timestamp, options...)
 }
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
+	"sort"
 	"time"
 )
 
 // Slackscot global configuration keys
 const (
 	TokenKey                    = "token"                                  // Slack token, string
+	AppTokenKey                 = "appToken"                               // Slack app-level token used to open socket mode connections, string
+	SigningSecretKey            = "signingSecret"                          // Slack app signing secret used to verify requests received over http, string
 	DebugKey                    = "debug"                                  // Debug mode, boolean
 	MaxAgeHandledMessages       = "maxAgeHandledMessages"                  // The maximum age of messages before they are ignored (applicable for message updates)
 	ResponseCacheSizeKey        = "responseCacheSize"                      // Response cache size in number of entries, int
@@ -19,6 +22,43 @@ const (
 	BroadcastThreadedRepliesKey = "replyBehavior.broadcastThreadedReplies" // Broadcast threaded replies (slackscot will set broadcast on threaded replies, only applies if threaded replies are enabled), boolean
 	PluginsKey                  = "plugins"                                // Root element of the map of string key/values for plugins string
 	UserInfoCacheSizeKey        = "userInfoCacheSize"                      // The number of entries to keep in the user info cache, int value. Defaults to no caching (value of 0)
+	ActionTimeoutKey            = "actionTimeout"                          // The maximum execution time of plugin actions before their answer is abandoned, duration. Defaults to no timeout (value of 0)
+	ActionPanicAnswerKey        = "actionPanicAnswer"                      // The answer to reply with when a plugin action panics, string. Defaults to no answer (empty value)
+	ActionPanicThresholdKey     = "actionPanicThreshold"                   // The number of consecutive panics after which a plugin action gets disabled, int. A value of 0 means actions never get disabled
+	ShutdownGracePeriodKey      = "shutdownGracePeriod"                    // The maximum time to wait for queued messages and in-flight scheduled actions to be processed on shutdown, duration
+	WorkspacesKey               = "workspaces"                             // Root element of the map of workspace names to their token (and appToken, for socket mode) to connect to several workspaces
+	DeliveryMaxAttemptsKey      = "delivery.maxAttempts"                   // The maximum number of attempts to deliver an outgoing message (new, update or delete) on rate limiting or transient errors, int
+	DeliveryInitialBackoffKey   = "delivery.initialBackoff"                // The delay before the first retry of a failed delivery, doubled (with jitter) on every following retry, duration
+	DeliveryMaxBackoffKey       = "delivery.maxBackoff"                    // The maximum delay between retries of a failed delivery, duration
+	ChannelPostingIntervalKey   = "delivery.channelPostingInterval"        // The minimum time between new messages posted to the same channel, duration. Slack allows roughly one message per second per channel
+	DialogTimeoutKey            = "dialogTimeout"                          // The maximum time to wait for the next reply of a user in a dialog before the dialog is abandoned, duration
+	AuthorizationAdminsKey      = "authorization.admins"                   // The user ids and user group ids (starting with S) of admins, authorized to run all actions, string slice
+	AuthorizationRolesKey       = "authorization.roles"                    // Root element of the map of role names to the user ids and user group ids (starting with S) having the role, map of string slices
+	AuthorizationSlackAdminsKey = "authorization.slackAdmins"              // Whether slack workspace admins and owners are also admins, boolean
+	UnauthorizedAnswerKey       = "authorization.unauthorizedAnswer"       // The answer to reply with when a user isn't authorized to run an action, string
+	UserGroupCacheExpirationKey = "authorization.userGroupCacheExpiration" // The time user group members are cached for before being looked up again, duration
+	RateLimitsKey               = "rateLimits"                             // Root element of the default rate limits of plugin actions, overridden by those under the configuration of a plugin (plugins.<name>.rateLimits)
+	AdminListenAddrKey          = "admin.listenAddr"                       // The address (i.e. ":8081") of the http server exposing the health, readiness and status endpoints, string. Defaults to no server (empty value)
+	ExclusiveRoutingKey         = "exclusiveRouting"                       // Whether only the highest-priority action matching a message answers it (first match wins), boolean. Defaults to all matching actions answering
+)
+
+// Rate limit configuration keys, relative to RateLimitsKey at the root of the configuration or under the configuration of a plugin
+const (
+	UserRateLimitKey           = "user"           // Root element of the limit on answers to each user, with a capacity and refill interval
+	ChannelRateLimitKey        = "channel"        // Root element of the limit on answers in each channel, with a capacity and refill interval
+	ActionRateLimitKey         = "action"         // Root element of the limit on answers of each action, with a capacity and refill interval
+	RateLimitCapacityKey       = "capacity"       // The number of answers allowed in a burst, int. A value of 0 disables the limit
+	RateLimitRefillIntervalKey = "refillInterval" // The time it takes for one more answer to be allowed, duration
+	RateLimitPolicyKey         = "policy"         // What happens to answers over the limit: drop (silently), notify (the user once with an ephemeral notice, then drop) or delay (until allowed), string
+	RateLimitNoticeKey         = "notice"         // The ephemeral notice sent to users over the limit with the notify policy, string
+	RateLimitMaxDelayKey       = "maxDelay"       // The maximum time an answer is delayed with the delay policy before it's dropped, duration. Delays hold up the processing of other messages so keep this short
+)
+
+// Rate limit policies
+const (
+	DropRateLimitPolicy   = "drop"
+	NotifyRateLimitPolicy = "notify"
+	DelayRateLimitPolicy  = "delay"
 )
 
 // Advanced configuration keys, only change if you really know what you're doing and have reviewed the internals
@@ -35,6 +75,23 @@ const (
 	threadedRepliesDefault                   = false
 	broadcastThreadedRepliesDefault          = false
 	maxAgeHandledMessagesDefault             = time.Duration(24) * time.Hour
+	actionTimeoutDefault                     = time.Duration(0)
+	actionPanicAnswerDefault                 = ""
+	actionPanicThresholdDefault              = 3
+	shutdownGracePeriodDefault               = time.Duration(10) * time.Second
+	deliveryMaxAttemptsDefault               = 5
+	deliveryInitialBackoffDefault            = time.Duration(500) * time.Millisecond
+	deliveryMaxBackoffDefault                = time.Duration(30) * time.Second
+	channelPostingIntervalDefault            = time.Duration(1) * time.Second
+	dialogTimeoutDefault                     = time.Duration(10) * time.Minute
+	authorizationSlackAdminsDefault          = false
+	unauthorizedAnswerDefault                = "🚫 Sorry, you're not authorized to do that"
+	userGroupCacheExpirationDefault          = time.Duration(5) * time.Minute
+	rateLimitPolicyDefault                   = DropRateLimitPolicy
+	rateLimitNoticeDefault                   = "🐢 Slow down! I'll get back to answering you in a bit"
+	rateLimitMaxDelayDefault                 = time.Duration(5) * time.Second
+	adminListenAddrDefault                   = ""
+	exclusiveRoutingDefault                  = false
 	msgProcessingPartitionCountDefault       = 16
 	msgProcessingBufferedMessageCountDefault = 10
 )
@@ -45,6 +102,13 @@ type ReplyBehavior struct {
 	Broadcast       bool
 }
 
+// WorkspaceConfig holds the tokens used to connect to a workspace
+type WorkspaceConfig struct {
+	Name     string
+	Token    string
+	AppToken string
+}
+
 // PluginConfig is a sub-viper instance holding the subtree specific to a named plugin
 type PluginConfig = viper.Viper
 
@@ -57,6 +121,23 @@ func NewViperWithDefaults() (v *viper.Viper) {
 	v.SetDefault(ThreadedRepliesKey, threadedRepliesDefault)
 	v.SetDefault(BroadcastThreadedRepliesKey, broadcastThreadedRepliesDefault)
 	v.SetDefault(MaxAgeHandledMessages, maxAgeHandledMessagesDefault)
+	v.SetDefault(ActionTimeoutKey, actionTimeoutDefault)
+	v.SetDefault(ActionPanicAnswerKey, actionPanicAnswerDefault)
+	v.SetDefault(ActionPanicThresholdKey, actionPanicThresholdDefault)
+	v.SetDefault(ShutdownGracePeriodKey, shutdownGracePeriodDefault)
+	v.SetDefault(DeliveryMaxAttemptsKey, deliveryMaxAttemptsDefault)
+	v.SetDefault(DeliveryInitialBackoffKey, deliveryInitialBackoffDefault)
+	v.SetDefault(DeliveryMaxBackoffKey, deliveryMaxBackoffDefault)
+	v.SetDefault(ChannelPostingIntervalKey, channelPostingIntervalDefault)
+	v.SetDefault(DialogTimeoutKey, dialogTimeoutDefault)
+	v.SetDefault(AuthorizationSlackAdminsKey, authorizationSlackAdminsDefault)
+	v.SetDefault(UnauthorizedAnswerKey, unauthorizedAnswerDefault)
+	v.SetDefault(UserGroupCacheExpirationKey, userGroupCacheExpirationDefault)
+	v.SetDefault(fmt.Sprintf("%s.%s", RateLimitsKey, RateLimitPolicyKey), rateLimitPolicyDefault)
+	v.SetDefault(fmt.Sprintf("%s.%s", RateLimitsKey, RateLimitNoticeKey), rateLimitNoticeDefault)
+	v.SetDefault(fmt.Sprintf("%s.%s", RateLimitsKey, RateLimitMaxDelayKey), rateLimitMaxDelayDefault)
+	v.SetDefault(AdminListenAddrKey, adminListenAddrDefault)
+	v.SetDefault(ExclusiveRoutingKey, exclusiveRoutingDefault)
 	v.SetDefault(MessageProcessingPartitionCount, msgProcessingPartitionCountDefault)
 	v.SetDefault(MessageProcessingBufferedMessageCount, msgProcessingBufferedMessageCountDefault)
 
@@ -98,3 +179,31 @@ func GetPluginConfig(v *viper.Viper, name string) (pluginConfig *PluginConfig, e
 	pc := PluginConfig(*subViper)
 	return &pc, nil
 }
+
+// GetWorkspaces returns the configuration of the workspaces to connect to. The workspace defined by the token at TokenKey
+// comes first (with an empty name) followed by the ones under WorkspacesKey, ordered by name. Without any workspace under
+// WorkspacesKey, the workspace defined by TokenKey is always returned (even if the token isn't set). The app-level token of a
+// workspace defaults to the one at AppTokenKey
+func GetWorkspaces(v *viper.Viper) (workspaces []WorkspaceConfig) {
+	names := make([]string, 0)
+	for name := range v.GetStringMap(WorkspacesKey) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	workspaces = make([]WorkspaceConfig, 0)
+	if v.GetString(TokenKey) != "" || len(names) == 0 {
+		workspaces = append(workspaces, WorkspaceConfig{Token: v.GetString(TokenKey), AppToken: v.GetString(AppTokenKey)})
+	}
+
+	for _, name := range names {
+		wc := WorkspaceConfig{Name: name, Token: v.GetString(fmt.Sprintf("%s.%s.%s", WorkspacesKey, name, TokenKey)), AppToken: v.GetString(fmt.Sprintf("%s.%s.%s", WorkspacesKey, name, AppTokenKey))}
+		if wc.AppToken == "" {
+			wc.AppToken = v.GetString(AppTokenKey)
+		}
+
+		workspaces = append(workspaces, wc)
+	}
+
+	return workspaces
+}
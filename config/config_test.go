@@ -17,6 +17,23 @@ func TestNewWithDefault(t *testing.T) {
 	assert.Equal(t, false, v.GetBool(config.ThreadedRepliesKey), "%s should be %t", config.ThreadedRepliesKey, false)
 	assert.Equal(t, false, v.GetBool(config.BroadcastThreadedRepliesKey), "%s should be %t", config.BroadcastThreadedRepliesKey, false)
 	assert.Equal(t, time.Duration(24)*time.Hour, v.GetDuration(config.MaxAgeHandledMessages), "%s should be %t", config.MaxAgeHandledMessages, time.Duration(24)*time.Hour)
+	assert.Equal(t, time.Duration(0), v.GetDuration(config.ActionTimeoutKey), "%s should be %s", config.ActionTimeoutKey, time.Duration(0))
+	assert.Equal(t, "", v.GetString(config.ActionPanicAnswerKey), "%s should be empty", config.ActionPanicAnswerKey)
+	assert.Equal(t, "", v.GetString(config.AdminListenAddrKey), "%s should be empty", config.AdminListenAddrKey)
+	assert.Equal(t, false, v.GetBool(config.ExclusiveRoutingKey), "%s should be %t", config.ExclusiveRoutingKey, false)
+	assert.Equal(t, 3, v.GetInt(config.ActionPanicThresholdKey), "%s should be %d", config.ActionPanicThresholdKey, 3)
+	assert.Equal(t, time.Duration(10)*time.Second, v.GetDuration(config.ShutdownGracePeriodKey), "%s should be %s", config.ShutdownGracePeriodKey, time.Duration(10)*time.Second)
+	assert.Equal(t, 5, v.GetInt(config.DeliveryMaxAttemptsKey), "%s should be %d", config.DeliveryMaxAttemptsKey, 5)
+	assert.Equal(t, time.Duration(500)*time.Millisecond, v.GetDuration(config.DeliveryInitialBackoffKey), "%s should be %s", config.DeliveryInitialBackoffKey, time.Duration(500)*time.Millisecond)
+	assert.Equal(t, time.Duration(30)*time.Second, v.GetDuration(config.DeliveryMaxBackoffKey), "%s should be %s", config.DeliveryMaxBackoffKey, time.Duration(30)*time.Second)
+	assert.Equal(t, time.Duration(1)*time.Second, v.GetDuration(config.ChannelPostingIntervalKey), "%s should be %s", config.ChannelPostingIntervalKey, time.Duration(1)*time.Second)
+	assert.Equal(t, time.Duration(10)*time.Minute, v.GetDuration(config.DialogTimeoutKey), "%s should be %s", config.DialogTimeoutKey, time.Duration(10)*time.Minute)
+	assert.Equal(t, false, v.GetBool(config.AuthorizationSlackAdminsKey), "%s should be %t", config.AuthorizationSlackAdminsKey, false)
+	assert.Equal(t, "🚫 Sorry, you're not authorized to do that", v.GetString(config.UnauthorizedAnswerKey), "%s should be %s", config.UnauthorizedAnswerKey, "🚫 Sorry, you're not authorized to do that")
+	assert.Equal(t, time.Duration(5)*time.Minute, v.GetDuration(config.UserGroupCacheExpirationKey), "%s should be %s", config.UserGroupCacheExpirationKey, time.Duration(5)*time.Minute)
+	assert.Equal(t, "drop", v.GetString("rateLimits.policy"), "%s should be %s", "rateLimits.policy", "drop")
+	assert.Equal(t, "🐢 Slow down! I'll get back to answering you in a bit", v.GetString("rateLimits.notice"), "%s should be %s", "rateLimits.notice", "🐢 Slow down! I'll get back to answering you in a bit")
+	assert.Equal(t, time.Duration(5)*time.Second, v.GetDuration("rateLimits.maxDelay"), "%s should be %s", "rateLimits.maxDelay", time.Duration(5)*time.Second)
 	assert.Equal(t, 16, v.GetInt(config.MessageProcessingPartitionCount), "%s should be %d", config.MessageProcessingPartitionCount, 16)
 	assert.Equal(t, 10, v.GetInt(config.MessageProcessingBufferedMessageCount), "%s should be %d", config.MessageProcessingBufferedMessageCount, 10)
 }
@@ -117,3 +134,32 @@ func TestGetPluginConfigWithMissingConfig(t *testing.T) {
 		assert.Contains(t, err.Error(), "Missing plugin configuration for plugin [pluginName]")
 	}
 }
+
+func TestGetWorkspacesWithSingleToken(t *testing.T) {
+	v := viper.New()
+	v.Set(config.TokenKey, "xoxb-1")
+	v.Set(config.AppTokenKey, "xapp-1")
+
+	assert.Equal(t, []config.WorkspaceConfig{{Token: "xoxb-1", AppToken: "xapp-1"}}, config.GetWorkspaces(v))
+}
+
+func TestGetWorkspacesWithoutAnyToken(t *testing.T) {
+	assert.Equal(t, []config.WorkspaceConfig{{}}, config.GetWorkspaces(viper.New()))
+}
+
+func TestGetWorkspaces(t *testing.T) {
+	v := viper.New()
+	v.Set(config.AppTokenKey, "xapp-shared")
+	v.Set(config.WorkspacesKey, map[string]interface{}{
+		"initech": map[string]interface{}{config.TokenKey: "xoxb-initech", config.AppTokenKey: "xapp-initech"},
+		"acme":    map[string]interface{}{config.TokenKey: "xoxb-acme"},
+	})
+
+	assert.Equal(t, []config.WorkspaceConfig{{Name: "acme", Token: "xoxb-acme", AppToken: "xapp-shared"}, {Name: "initech", Token: "xoxb-initech", AppToken: "xapp-initech"}}, config.GetWorkspaces(v))
+
+	v.Set(config.TokenKey, "xoxb-default")
+	workspaces := config.GetWorkspaces(v)
+	if assert.Len(t, workspaces, 3) {
+		assert.Equal(t, config.WorkspaceConfig{Token: "xoxb-default", AppToken: "xapp-shared"}, workspaces[0])
+	}
+}
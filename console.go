@@ -0,0 +1,384 @@
+package slackscot
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/slack-go/slack"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Identifiers of the console workspace, bot and default conversation
+const (
+	consoleTeamID           = "TCONSOLE"
+	consoleBotUserID        = "UBOT"
+	consoleBotID            = "BBOT"
+	consoleDefaultUserID    = "UCONSOLE"
+	consoleDefaultChannelID = "CCONSOLE"
+)
+
+// consoleHelp lists the console meta-commands
+const consoleHelp = `Type messages to send them to the bot. Mention it with @%s (or talk to it in a DM) for commands. Meta-commands:
+	/as <user id>            send the next messages as another user
+	/in <channel id>         send the next messages in a channel
+	/dm                      send the next messages in a direct message with the bot
+	/thread [timestamp]      send the next messages in the thread of a message (or outside of threads without a timestamp)
+	/edit <timestamp> <text> edit a message sent from the console
+	/delete <timestamp>      delete a message sent from the console
+	/help                    show this help
+`
+
+// consoleIO holds the input and output of the console
+type consoleIO struct {
+	in  io.Reader
+	out io.Writer
+}
+
+// OptionConsole runs slackscot in console mode for local development: instead of connecting to slack, the lines read
+// from in are the messages sent to the bot and everything the bot does (messages sent, updated and deleted, emoji reactions
+// and file uploads) is printed to out. Meta-commands (i.e. /as, /in, /dm, /thread, /edit, /delete) switch the user, channel
+// or thread of the next messages and edit or delete previous ones. Slackscot terminates when in reaches EOF.
+//
+// Typical usage is OptionConsole(os.Stdin, os.Stdout)
+func OptionConsole(in io.Reader, out io.Writer) Option {
+	return func(s *Slackscot) {
+		s.console = &consoleIO{in: in, out: out}
+	}
+}
+
+// consoleSession is an EventSource turning the lines read from the console into slack message events. It also
+// implements all other runtime dependencies by printing what the bot does to the console
+type consoleSession struct {
+	botName string
+	in      *bufio.Scanner
+	out     io.Writer
+	events  chan slack.RTMEvent
+	done    chan bool
+
+	// Current user, channel and thread of the messages sent from the console
+	userID          string
+	channelID       string
+	inDM            bool
+	threadTimestamp string
+
+	// Messages sent from the console by timestamp, to edit or delete them
+	sentMsgs map[string]slack.Msg
+
+	// Timestamps are the creation time of the session with an increasing sequence number to keep them unique
+	startTime time.Time
+	sequence  int
+
+	lock       sync.Mutex
+	disconnect sync.Once
+}
+
+// newConsoleSession returns a new consoleSession for a bot name reading from in and printing to out
+func newConsoleSession(botName string, in io.Reader, out io.Writer) (cs *consoleSession) {
+	cs = new(consoleSession)
+	cs.botName = botName
+	cs.in = bufio.NewScanner(in)
+	cs.out = out
+	cs.events = make(chan slack.RTMEvent)
+	cs.done = make(chan bool)
+	cs.userID = consoleDefaultUserID
+	cs.channelID = consoleDefaultChannelID
+	cs.sentMsgs = make(map[string]slack.Msg)
+	cs.startTime = time.Now()
+
+	return cs
+}
+
+// runDependencies returns the runtime dependencies backed by the console
+func (cs *consoleSession) runDependencies() *runDependencies {
+	return &runDependencies{chatDriver: consoleChatDriver{cs}, userInfoFinder: cs, emojiReactor: cs, fileUploader: NewFileUploader(cs), selfInfoFinder: cs, realTimeMsgSender: cs}
+}
+
+// IncomingEvents returns the channel of the events of the console
+func (cs *consoleSession) IncomingEvents() <-chan slack.RTMEvent {
+	return cs.events
+}
+
+// ManageConnection reads the console input until EOF (or Disconnect is called) and sends the corresponding events. The
+// events channel is closed once the input is exhausted
+func (cs *consoleSession) ManageConnection() {
+	defer close(cs.events)
+
+	if !cs.send(slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{Info: cs.GetInfo()}}) {
+		return
+	}
+
+	cs.printf(consoleHelp, cs.botName)
+
+	for cs.in.Scan() {
+		line := strings.TrimSpace(cs.in.Text())
+		if line == "" {
+			continue
+		}
+
+		var event *slack.MessageEvent
+		if strings.HasPrefix(line, "/") {
+			event = cs.runMetaCommand(line)
+		} else {
+			event = cs.newMessageEvent(line)
+		}
+
+		if event != nil && !cs.send(slack.RTMEvent{Type: "message", Data: event}) {
+			return
+		}
+	}
+}
+
+// send sends an event unless the session is disconnected. It returns false if the session is disconnected
+func (cs *consoleSession) send(e slack.RTMEvent) bool {
+	select {
+	case cs.events <- e:
+		return true
+	case <-cs.done:
+		return false
+	}
+}
+
+// Disconnect stops the sending of events
+func (cs *consoleSession) Disconnect() error {
+	cs.disconnect.Do(func() {
+		close(cs.done)
+	})
+
+	return nil
+}
+
+// GetInfo returns the info of the console bot
+func (cs *consoleSession) GetInfo() *slack.Info {
+	return &slack.Info{User: &slack.UserDetails{ID: consoleBotUserID, Name: cs.botName}, Team: &slack.Team{ID: consoleTeamID, Name: "console"}}
+}
+
+// runMetaCommand runs a console meta-command and returns the message event it generates, if any
+func (cs *consoleSession) runMetaCommand(line string) (event *slack.MessageEvent) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	fields := strings.Fields(line)
+	command, args := fields[0], fields[1:]
+
+	switch {
+	case command == "/as" && len(args) == 1:
+		cs.userID = args[0]
+	case command == "/in" && len(args) == 1:
+		cs.channelID = args[0]
+		cs.inDM = false
+		cs.threadTimestamp = ""
+	case command == "/dm" && len(args) == 0:
+		cs.inDM = true
+		cs.threadTimestamp = ""
+	case command == "/thread" && len(args) <= 1:
+		cs.threadTimestamp = strings.Join(args, "")
+	case command == "/edit" && len(args) >= 2:
+		return cs.newEditEvent(args[0], strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(line, command)), args[0])))
+	case command == "/delete" && len(args) == 1:
+		return cs.newDeleteEvent(args[0])
+	default:
+		fmt.Fprintf(cs.out, consoleHelp, cs.botName)
+		return nil
+	}
+
+	fmt.Fprintf(cs.out, "» Now sending as [%s] in [%s]%s\n", cs.userID, cs.currentChannelID(), cs.threadDescription(cs.threadTimestamp))
+	return nil
+}
+
+// newMessageEvent returns a new message event for a line of text sent by the current user in the current channel and thread.
+// Mentions of the bot by name are replaced by a proper mention
+func (cs *consoleSession) newMessageEvent(text string) (event *slack.MessageEvent) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	event = new(slack.MessageEvent)
+	event.Type = "message"
+	event.Team = consoleTeamID
+	event.User = cs.userID
+	event.Channel = cs.currentChannelID()
+	event.Text = strings.ReplaceAll(text, fmt.Sprintf("@%s", cs.botName), fmt.Sprintf("<@%s>", consoleBotUserID))
+	event.Timestamp = cs.nextTimestamp()
+	event.ThreadTimestamp = cs.threadTimestamp
+
+	cs.sentMsgs[event.Timestamp] = event.Msg
+	fmt.Fprintf(cs.out, "» Sent [%s] as [%s] in [%s]%s\n", event.Timestamp, event.User, event.Channel, cs.threadDescription(event.ThreadTimestamp))
+
+	return event
+}
+
+// newEditEvent returns a new message_changed event for a message previously sent from the console. Must be called with the lock held
+func (cs *consoleSession) newEditEvent(timestamp string, text string) (event *slack.MessageEvent) {
+	original, ok := cs.sentMsgs[timestamp]
+	if !ok {
+		fmt.Fprintf(cs.out, "» Unknown message [%s]\n", timestamp)
+		return nil
+	}
+
+	edited := original
+	edited.Text = strings.ReplaceAll(text, fmt.Sprintf("@%s", cs.botName), fmt.Sprintf("<@%s>", consoleBotUserID))
+	cs.sentMsgs[timestamp] = edited
+
+	event = new(slack.MessageEvent)
+	event.Type = "message"
+	event.SubType = "message_changed"
+	event.Team = consoleTeamID
+	event.Channel = original.Channel
+	event.Timestamp = cs.nextTimestamp()
+	event.SubMessage = &edited
+
+	fmt.Fprintf(cs.out, "» Edited [%s] in [%s]\n", timestamp, original.Channel)
+	return event
+}
+
+// newDeleteEvent returns a new message_deleted event for a message previously sent from the console. Must be called with the lock held
+func (cs *consoleSession) newDeleteEvent(timestamp string) (event *slack.MessageEvent) {
+	original, ok := cs.sentMsgs[timestamp]
+	if !ok {
+		fmt.Fprintf(cs.out, "» Unknown message [%s]\n", timestamp)
+		return nil
+	}
+
+	delete(cs.sentMsgs, timestamp)
+
+	event = new(slack.MessageEvent)
+	event.Type = "message"
+	event.SubType = "message_deleted"
+	event.Team = consoleTeamID
+	event.Channel = original.Channel
+	event.Timestamp = cs.nextTimestamp()
+	event.DeletedTimestamp = timestamp
+
+	fmt.Fprintf(cs.out, "» Deleted [%s] in [%s]\n", timestamp, original.Channel)
+	return event
+}
+
+// currentChannelID returns the channel id of the next messages. Must be called with the lock held
+func (cs *consoleSession) currentChannelID() string {
+	if cs.inDM {
+		return fmt.Sprintf("D%s", cs.userID)
+	}
+
+	return cs.channelID
+}
+
+// nextTimestamp returns a new unique message timestamp. Must be called with the lock held
+func (cs *consoleSession) nextTimestamp() string {
+	cs.sequence = cs.sequence + 1
+
+	return fmt.Sprintf("%d.%06d", cs.startTime.Unix(), cs.sequence)
+}
+
+// threadDescription returns the description of a thread for printing
+func (cs *consoleSession) threadDescription(threadTimestamp string) string {
+	if threadTimestamp == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" (thread [%s])", threadTimestamp)
+}
+
+// printf prints to the console while holding the lock so that outputs don't get interleaved
+func (cs *consoleSession) printf(format string, a ...interface{}) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	fmt.Fprintf(cs.out, format, a...)
+}
+
+// consoleChatDriver is the chatDriver of a consoleSession
+type consoleChatDriver struct {
+	*consoleSession
+}
+
+// SendMessage prints a message sent by the bot
+func (cs consoleChatDriver) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	_, values, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	if userID := values.Get("user"); userID != "" {
+		fmt.Fprintf(cs.out, "« [%s]%s (only visible to [%s]): %s\n", channelID, cs.threadDescription(values.Get("thread_ts")), userID, renderMessageValues(values.Get("text"), values.Get("blocks")))
+		return channelID, "", values.Get("text"), nil
+	}
+
+	rTimestamp = cs.nextTimestamp()
+	fmt.Fprintf(cs.out, "« [%s] [%s]%s: %s\n", rTimestamp, channelID, cs.threadDescription(values.Get("thread_ts")), renderMessageValues(values.Get("text"), values.Get("blocks")))
+
+	return channelID, rTimestamp, values.Get("text"), nil
+}
+
+// UpdateMessage prints an update of a message sent by the bot
+func (cs consoleChatDriver) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	_, values, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	cs.printf("« Updated [%s] [%s]: %s\n", timestamp, channelID, renderMessageValues(values.Get("text"), values.Get("blocks")))
+
+	return channelID, timestamp, values.Get("text"), nil
+}
+
+// DeleteMessage prints the deletion of a message sent by the bot
+func (cs consoleChatDriver) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
+	cs.printf("« Deleted [%s] [%s]\n", timestamp, channelID)
+
+	return channelID, timestamp, nil
+}
+
+// NewOutgoingMessage creates a new real time message to send
+func (cs *consoleSession) NewOutgoingMessage(text string, channelID string, options ...slack.RTMsgOption) *slack.OutgoingMessage {
+	outMsg := &slack.OutgoingMessage{Type: "message", Channel: channelID, Text: text}
+	for _, opt := range options {
+		opt(outMsg)
+	}
+
+	return outMsg
+}
+
+// SendMessage prints a real time message sent by the bot
+func (cs *consoleSession) SendMessage(outMsg *slack.OutgoingMessage) {
+	consoleChatDriver{cs}.SendMessage(outMsg.Channel, slack.MsgOptionText(outMsg.Text, false), slack.MsgOptionTS(outMsg.ThreadTimestamp))
+}
+
+// AddReaction prints an emoji reaction of the bot
+func (cs *consoleSession) AddReaction(name string, item slack.ItemRef) error {
+	cs.printf("« Reacted with :%s: to [%s] [%s]\n", name, item.Timestamp, item.Channel)
+
+	return nil
+}
+
+// UploadFile prints a file uploaded by the bot
+func (cs *consoleSession) UploadFile(params slack.FileUploadParameters) (file *slack.File, err error) {
+	cs.printf("« Uploaded [%s] (%s) to %v%s\n", params.Filename, params.Title, params.Channels, cs.threadDescription(params.ThreadTimestamp))
+
+	return &slack.File{Name: params.Filename, Title: params.Title}, nil
+}
+
+// GetUserInfo returns the info of a user with its id as its name. The bot user is the only one with a bot id
+func (cs *consoleSession) GetUserInfo(userID string) (user *slack.User, err error) {
+	user = &slack.User{ID: userID, Name: userID, RealName: userID}
+	if userID == consoleBotUserID {
+		user.Name = cs.botName
+		user.RealName = cs.botName
+		user.IsBot = true
+		user.Profile.BotID = consoleBotID
+	}
+
+	return user, nil
+}
+
+// renderMessageValues returns the text of a message or its blocks when it doesn't have text
+func renderMessageValues(text string, blocks string) string {
+	if text == "" && blocks != "" {
+		return blocks
+	}
+
+	return text
+}
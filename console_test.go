@@ -0,0 +1,166 @@
+package slackscot
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var consoleSentTimestampRegex = regexp.MustCompile("^» Sent \\[([0-9.]+)\\]")
+
+// consoleTester drives a slackscot instance running in console mode
+type consoleTester struct {
+	t     *testing.T
+	in    *io.PipeWriter
+	lines chan string
+}
+
+func newConsoleTester(t *testing.T) (ct *consoleTester, in io.Reader, out io.Writer) {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+
+	ct = &consoleTester{t: t, in: inWriter, lines: make(chan string, 100)}
+	go func() {
+		scanner := bufio.NewScanner(outReader)
+		for scanner.Scan() {
+			ct.lines <- scanner.Text()
+		}
+	}()
+
+	return ct, inReader, outWriter
+}
+
+// send sends a line to the console
+func (ct *consoleTester) send(line string) {
+	_, err := fmt.Fprintln(ct.in, line)
+	require.NoError(ct.t, err)
+}
+
+// expect reads the console output until a line contains the expected text and returns that line
+func (ct *consoleTester) expect(expected string) (line string) {
+	for {
+		select {
+		case line = <-ct.lines:
+			if strings.Contains(line, expected) {
+				return line
+			}
+		case <-time.After(time.Duration(2) * time.Second):
+			require.FailNowf(ct.t, "Timed out waiting for console output", "Expected a line containing [%s]", expected)
+		}
+	}
+}
+
+// expectSent reads the console output until a message is sent and returns its timestamp
+func (ct *consoleTester) expectSent() (timestamp string) {
+	matches := consoleSentTimestampRegex.FindStringSubmatch(ct.expect("» Sent ["))
+	require.NotNil(ct.t, matches)
+
+	return matches[1]
+}
+
+func newBaristaPlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "barista"
+	p.NamespaceCommands = true
+	p.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "make ")
+		},
+		Usage:       "make <drink>",
+		Description: "Make a drink",
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: fmt.Sprintf("Made %s for %s", strings.TrimPrefix(m.NormalizedText, "make "), m.User)}
+		},
+	}}
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.Contains(m.NormalizedText, "chickadee")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			if err := p.EmojiReactor.AddReaction("bird", slack.NewRefToMessage(m.Channel, m.Timestamp)); err != nil {
+				return nil
+			}
+
+			_, err := p.FileUploader.UploadFile(slack.FileUploadParameters{Filename: "chickadee.png", Title: "A chickadee", Channels: []string{m.Channel}})
+			if err != nil {
+				return nil
+			}
+
+			return &Answer{Text: "chirp", Options: []AnswerOption{AnswerInThread()}}
+		},
+	}}
+
+	return p
+}
+
+func TestConsole(t *testing.T) {
+	ct, in, out := newConsoleTester(t)
+
+	s, err := New("robot", config.NewViperWithDefaults(), OptionConsole(in, out), OptionLog(log.New(&nullWriter{}, "", 0)))
+	require.NoError(t, err)
+	s.RegisterPlugin(newBaristaPlugin())
+
+	done := make(chan error)
+	go func() {
+		done <- s.RunContext(context.Background())
+	}()
+
+	ct.expect("Meta-commands:")
+
+	ct.send("@robot barista make coffee")
+	ts := ct.expectSent()
+	assert.Regexp(t, "^« \\[[0-9.]+\\] \\[CCONSOLE\\]: <@UCONSOLE>: Made coffee for UCONSOLE$", ct.expect("Made coffee"))
+
+	ct.send(fmt.Sprintf("/edit %s @robot barista make tea", ts))
+	ct.expect(fmt.Sprintf("» Edited [%s] in [CCONSOLE]", ts))
+	assert.Regexp(t, "^« Updated \\[[0-9.]+\\] \\[CCONSOLE\\]: <@UCONSOLE>: Made tea for UCONSOLE$", ct.expect("Made tea"))
+
+	ct.send(fmt.Sprintf("/delete %s", ts))
+	ct.expect(fmt.Sprintf("» Deleted [%s] in [CCONSOLE]", ts))
+	ct.expect("« Deleted [")
+
+	ct.send("/as U123")
+	ct.expect("» Now sending as [U123] in [CCONSOLE]")
+	ct.send("/dm")
+	ct.expect("» Now sending as [U123] in [DU123]")
+	ct.send("barista make juice")
+	ct.expectSent()
+	assert.Regexp(t, "^« \\[[0-9.]+\\] \\[DU123\\]: Made juice for U123$", ct.expect("Made juice"))
+
+	ct.send("/in C42")
+	ct.expect("» Now sending as [U123] in [C42]")
+	ct.send("a chickadee!")
+	ts = ct.expectSent()
+	ct.expect(fmt.Sprintf("« Reacted with :bird: to [%s] [C42]", ts))
+	ct.expect("« Uploaded [chickadee.png] (A chickadee) to [C42]")
+	assert.Regexp(t, fmt.Sprintf("^« \\[[0-9.]+\\] \\[C42\\] \\(thread \\[%s\\]\\): chirp$", ts), ct.expect("chirp"))
+
+	ct.send(fmt.Sprintf("/thread %s", ts))
+	ct.expect(fmt.Sprintf("» Now sending as [U123] in [C42] (thread [%s])", ts))
+	ct.send("@robot help")
+	ct.expectSent()
+	ct.expect("I currently support the following commands:")
+	ct.expect("barista make <drink>")
+
+	ct.send("/unknown")
+	ct.expect("Meta-commands:")
+
+	require.NoError(t, ct.in.Close())
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Duration(2) * time.Second):
+		assert.Fail(t, "Timed out waiting for slackscot to terminate after the end of the console input")
+	}
+}
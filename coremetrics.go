@@ -1,15 +1,21 @@
 package slackscot
 
 import (
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/label"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
 	"time"
 )
 
 const (
-	newMsgType    = "new"
-	updateMsgType = "edit"
-	deleteMsgType = "delete"
+	newMsgType          = "new"
+	updateMsgType       = "edit"
+	deleteMsgType       = "delete"
+	reactionMsgType     = "reaction"
+	interactionMsgType  = "interaction"
+	slashCommandMsgType = "slashCommand"
 )
 
 // instrumenter holds data for core instrumentation
@@ -17,7 +23,11 @@ type instrumenter struct {
 	appName       string
 	coreMetrics   coreMetrics
 	pluginMetrics map[string]pluginMetrics
-	meter         metric.Meter
+	// pluginMetricsLock guards pluginMetrics since partition workers create them concurrently
+	pluginMetricsLock sync.Mutex
+	deliveryMetrics
+	meter  metric.Meter
+	tracer trace.Tracer
 }
 
 // coreMetrics holds core slackscot metrics
@@ -33,6 +43,9 @@ type coreMetrics struct {
 type pluginMetrics struct {
 	processingTimeMillis metric.BoundInt64ValueRecorder
 	reactionCount        metric.BoundInt64Counter
+	actionTimeoutCount   metric.BoundInt64Counter
+	actionPanicCount     metric.BoundInt64Counter
+	rateLimitedCount     metric.BoundInt64Counter
 }
 
 // newInstrumenter creates a new core instrumenter
@@ -72,10 +85,16 @@ func newInstrumenter(appName string, meter metric.Meter, latencyCallback metric.
 		msgDispatchLatencyMillis:   dispatchLatency.Bind(defaultLabels...),
 		slackLatencyMillis:         slackLatency}
 
+	ins.deliveryMetrics, err = newDeliveryMetrics(appName, meter)
+	if err != nil {
+		return nil, err
+	}
+
 	ins.appName = appName
 	ins.pluginMetrics = make(map[string]pluginMetrics)
 
 	ins.meter = meter
+	ins.tracer = otel.Tracer(instrumentationName)
 	return ins, nil
 }
 
@@ -91,6 +110,9 @@ func newBoundCounterByMsgType(counterName string, appName string, meter metric.M
 	boundCounter[newMsgType] = c.Bind(label.String("name", appName), label.String("msgType", newMsgType))
 	boundCounter[updateMsgType] = c.Bind(label.String("name", appName), label.String("msgType", updateMsgType))
 	boundCounter[deleteMsgType] = c.Bind(label.String("name", appName), label.String("msgType", deleteMsgType))
+	boundCounter[reactionMsgType] = c.Bind(label.String("name", appName), label.String("msgType", reactionMsgType))
+	boundCounter[interactionMsgType] = c.Bind(label.String("name", appName), label.String("msgType", interactionMsgType))
+	boundCounter[slashCommandMsgType] = c.Bind(label.String("name", appName), label.String("msgType", slashCommandMsgType))
 
 	return boundCounter, nil
 }
@@ -107,12 +129,18 @@ func newBoundValueRecorderByMsgType(ValueRecorderName string, appName string, me
 	boundValueRecorder[newMsgType] = m.Bind(label.String("name", appName), label.String("msgType", newMsgType))
 	boundValueRecorder[updateMsgType] = m.Bind(label.String("name", appName), label.String("msgType", updateMsgType))
 	boundValueRecorder[deleteMsgType] = m.Bind(label.String("name", appName), label.String("msgType", deleteMsgType))
+	boundValueRecorder[reactionMsgType] = m.Bind(label.String("name", appName), label.String("msgType", reactionMsgType))
+	boundValueRecorder[interactionMsgType] = m.Bind(label.String("name", appName), label.String("msgType", interactionMsgType))
+	boundValueRecorder[slashCommandMsgType] = m.Bind(label.String("name", appName), label.String("msgType", slashCommandMsgType))
 
 	return boundValueRecorder, nil
 }
 
 // getOrCreatePluginMetrics returns an existing pluginMetrics for a plugin or creates a new one, if necessary
 func (ins *instrumenter) getOrCreatePluginMetrics(pluginName string) (pm pluginMetrics, err error) {
+	ins.pluginMetricsLock.Lock()
+	defer ins.pluginMetricsLock.Unlock()
+
 	if _, ok := ins.pluginMetrics[pluginName]; !ok {
 		pm, err = newPluginMetrics(ins.appName, pluginName, ins.meter)
 		if err != nil {
@@ -134,9 +162,24 @@ func newPluginMetrics(appName string, pluginName string, meter metric.Meter) (pm
 	if err != nil {
 		return pm, err
 	}
+	tc, err := meter.NewInt64Counter("actionTimeoutCount")
+	if err != nil {
+		return pm, err
+	}
+	pc, err := meter.NewInt64Counter("actionPanicCount")
+	if err != nil {
+		return pm, err
+	}
+	rc, err := meter.NewInt64Counter("rateLimitedCount")
+	if err != nil {
+		return pm, err
+	}
 
 	pm.reactionCount = c.Bind(label.String("name", appName), label.String("plugin", pluginName))
 	pm.processingTimeMillis = m.Bind(label.String("name", appName), label.String("plugin", pluginName))
+	pm.actionTimeoutCount = tc.Bind(label.String("name", appName), label.String("plugin", pluginName))
+	pm.actionPanicCount = pc.Bind(label.String("name", appName), label.String("plugin", pluginName))
+	pm.rateLimitedCount = rc.Bind(label.String("name", appName), label.String("plugin", pluginName))
 
 	return pm, nil
 }
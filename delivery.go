@@ -0,0 +1,322 @@
+package slackscot
+
+import (
+	"context"
+	"errors"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackutilsx"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/metric"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// pacedEndpoints are the endpoints posting new messages to a channel, which count towards the channel's posting limit
+var pacedEndpoints = map[string]bool{"chat.postMessage": true, "chat.meMessage": true}
+
+// deliveryPolicy defines how outgoing messages are retried and paced
+type deliveryPolicy struct {
+	maxAttempts            int
+	initialBackoff         time.Duration
+	maxBackoff             time.Duration
+	channelPostingInterval time.Duration
+}
+
+// newDeliveryPolicy returns the deliveryPolicy defined by the configuration
+func newDeliveryPolicy(v *viper.Viper) (p deliveryPolicy) {
+	p.maxAttempts = v.GetInt(config.DeliveryMaxAttemptsKey)
+	if p.maxAttempts < 1 {
+		p.maxAttempts = 1
+	}
+	p.initialBackoff = v.GetDuration(config.DeliveryInitialBackoffKey)
+	p.maxBackoff = v.GetDuration(config.DeliveryMaxBackoffKey)
+	p.channelPostingInterval = v.GetDuration(config.ChannelPostingIntervalKey)
+
+	return p
+}
+
+// backoff returns the delay before retrying a delivery after a number of failed attempts. The delay doubles with
+// every attempt (up to maxBackoff) and is jittered to a random duration between half of it and all of it
+func (p deliveryPolicy) backoff(failedAttempts int, random func(n int64) int64) (delay time.Duration) {
+	delay = p.initialBackoff
+	for i := 1; i < failedAttempts && delay < p.maxBackoff; i++ {
+		delay = delay * 2
+	}
+
+	if delay > p.maxBackoff {
+		delay = p.maxBackoff
+	}
+
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+
+	return time.Duration(half + random(half))
+}
+
+// deliveryMetrics holds the metrics of the delivery of outgoing messages
+type deliveryMetrics struct {
+	retries    metric.BoundInt64Counter
+	drops      metric.BoundInt64Counter
+	queueDepth metric.BoundInt64UpDownCounter
+}
+
+// newDeliveryMetrics returns new deliveryMetrics
+func newDeliveryMetrics(appName string, meter metric.Meter) (dm deliveryMetrics, err error) {
+	retries, err := meter.NewInt64Counter("deliveryRetries")
+	if err != nil {
+		return dm, err
+	}
+	drops, err := meter.NewInt64Counter("deliveryDrops")
+	if err != nil {
+		return dm, err
+	}
+	queueDepth, err := meter.NewInt64UpDownCounter("deliveryQueueDepth")
+	if err != nil {
+		return dm, err
+	}
+
+	dm.retries = retries.Bind(label.String("name", appName))
+	dm.drops = drops.Bind(label.String("name", appName))
+	dm.queueDepth = queueDepth.Bind(label.String("name", appName))
+
+	return dm, nil
+}
+
+// channelQueue serializes the deliveries to a single channel and keeps track of when the next new message can be posted to it
+type channelQueue struct {
+	lock sync.Mutex
+
+	// paceLock guards nextPost so that new messages reserve their posting time without holding the channel's turn
+	paceLock sync.Mutex
+	nextPost time.Time
+}
+
+// reservePost reserves the next posting time of the channel and returns how long to wait until then
+func (q *channelQueue) reservePost(now time.Time, interval time.Duration) (wait time.Duration) {
+	q.paceLock.Lock()
+	defer q.paceLock.Unlock()
+
+	postAt := q.nextPost
+	if postAt.Before(now) {
+		postAt = now
+	}
+	q.nextPost = postAt.Add(interval)
+
+	return postAt.Sub(now)
+}
+
+// deliveringChatDriver is a chatDriver delivering outgoing messages with retries on rate limiting and transient errors. Deliveries
+// to a channel go through that channel's queue and new messages are paced to respect Slack's per-channel posting limit.
+//
+// Deliveries are synchronous: a call returns once its message is delivered (or dropped) which means that the order in which
+// a partition sends, updates and deletes messages is preserved. This includes waiting for the posting interval so pacing
+// blocks the caller: a partition worker sending an answer of 3 new messages to a channel waits for 2 posting intervals
+// before processing its next event, holding off configuration reloads until then. Ephemeral messages and messages sent
+// to a response url aren't posted to the channel and aren't paced.
+//
+// Waits are cut short once the context is done: on shutdown, deliveries aren't retried anymore and new messages are
+// posted without waiting for their channel's posting interval so that draining queued messages isn't held back
+type deliveringChatDriver struct {
+	ctx     context.Context
+	base    chatDriver
+	policy  deliveryPolicy
+	metrics deliveryMetrics
+	log     *sLogger
+
+	// Shared with copies bound to a context
+	queues     map[string]*channelQueue
+	queuesLock *sync.Mutex
+
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) (err error)
+	random func(n int64) int64
+}
+
+// newDeliveringChatDriver returns a new deliveringChatDriver delivering outgoing messages with the base chatDriver until
+// the context is done
+func newDeliveringChatDriver(ctx context.Context, base chatDriver, policy deliveryPolicy, metrics deliveryMetrics, log *sLogger) (d *deliveringChatDriver) {
+	d = new(deliveringChatDriver)
+	d.ctx = ctx
+	d.base = base
+	d.policy = policy
+	d.metrics = metrics
+	d.log = log
+	d.queues = make(map[string]*channelQueue)
+	d.queuesLock = new(sync.Mutex)
+	d.now = time.Now
+	d.sleep = sleepContext
+	d.random = rand.Int63n
+
+	return d
+}
+
+// SendMessage posts a new message to a channel once the channel's posting interval has elapsed. Ephemeral messages and messages
+// sent to a response url are sent right away
+func (d *deliveringChatDriver) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	q := d.queue(channelID)
+
+	// Wait for the posting time outside of the channel's turn so that updates and deletions to the channel aren't held back
+	if isPacedPost(options...) {
+		if wait := q.reservePost(d.now(), d.policy.channelPostingInterval); wait > 0 {
+			d.sleep(d.ctx, wait)
+		}
+	}
+
+	d.enqueue(q)
+	defer d.dequeue(q)
+
+	err = d.deliver("SendMessage", channelID, func() (err error) {
+		rChannelID, rTimestamp, rText, err = d.base.SendMessage(channelID, options...)
+		return err
+	})
+
+	return rChannelID, rTimestamp, rText, err
+}
+
+// UpdateMessage updates an existing message
+func (d *deliveringChatDriver) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	q := d.queue(channelID)
+	d.enqueue(q)
+	defer d.dequeue(q)
+
+	err = d.deliver("UpdateMessage", channelID, func() (err error) {
+		rChannelID, rTimestamp, rText, err = d.base.UpdateMessage(channelID, timestamp, options...)
+		return err
+	})
+
+	return rChannelID, rTimestamp, rText, err
+}
+
+// DeleteMessage deletes an existing message
+func (d *deliveringChatDriver) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
+	q := d.queue(channelID)
+	d.enqueue(q)
+	defer d.dequeue(q)
+
+	err = d.deliver("DeleteMessage", channelID, func() (err error) {
+		rChannelID, rTimestamp, err = d.base.DeleteMessage(channelID, timestamp)
+		return err
+	})
+
+	return rChannelID, rTimestamp, err
+}
+
+// isPacedPost returns true if the message options post a new message to the channel (as opposed to posting an ephemeral
+// message or sending the message to a response url)
+func isPacedPost(options ...slack.MsgOption) bool {
+	endpoint, _, err := slack.UnsafeApplyMsgOptions("", "", "", options...)
+	if err != nil {
+		return true
+	}
+
+	return pacedEndpoints[endpoint]
+}
+
+// bindContext returns a copy of the deliveringChatDriver, sharing its channel queues, with its base bound to the context
+func (d *deliveringChatDriver) bindContext(ctx context.Context) interface{} {
+	bound := *d
+	bound.base = bindContext(ctx, d.base).(chatDriver)
+
+	return &bound
+}
+
+// queue returns the channel's queue, creating it if necessary
+func (d *deliveringChatDriver) queue(channelID string) (q *channelQueue) {
+	d.queuesLock.Lock()
+	defer d.queuesLock.Unlock()
+
+	q, ok := d.queues[channelID]
+	if !ok {
+		q = new(channelQueue)
+		d.queues[channelID] = q
+	}
+
+	return q
+}
+
+// enqueue waits for the delivery's turn in the channel's queue
+func (d *deliveringChatDriver) enqueue(q *channelQueue) {
+	d.metrics.queueDepth.Add(context.Background(), 1)
+	q.lock.Lock()
+}
+
+// dequeue gives the turn to the next delivery in the channel's queue
+func (d *deliveringChatDriver) dequeue(q *channelQueue) {
+	q.lock.Unlock()
+	d.metrics.queueDepth.Add(context.Background(), -1)
+}
+
+// deliver calls the delivery function until it succeeds, fails with a permanent error, runs out of attempts or the
+// context is done. Rate limited deliveries are retried after the delay requested by Slack and other transient errors with
+// a jittered exponential backoff
+func (d *deliveringChatDriver) deliver(method string, channelID string, delivery func() (err error)) (err error) {
+	for attempt := 1; ; attempt++ {
+		if err = delivery(); err == nil {
+			return nil
+		}
+
+		delay, retryable := d.retryDelay(err, attempt)
+		if retryable && attempt < d.policy.maxAttempts && d.ctx.Err() == nil {
+			d.metrics.retries.Add(context.Background(), 1)
+			d.log.Debug("Retrying message delivery", "method", method, "channel", channelID, "attempt", attempt, "delay", delay, "err", err)
+
+			if d.sleep(d.ctx, delay) == nil {
+				continue
+			}
+		}
+
+		d.metrics.drops.Add(context.Background(), 1)
+		d.log.Warn("Dropping message delivery", "method", method, "channel", channelID, "attempts", attempt, "err", err)
+
+		return err
+	}
+}
+
+// sleepContext waits for the delay to elapse or for the context to be done, whichever comes first. It returns the
+// context's error if the wait was cut short
+func sleepContext(ctx context.Context, delay time.Duration) (err error) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryDelay returns the delay before retrying a failed delivery and whether it should be retried at all
+func (d *deliveringChatDriver) retryDelay(err error, failedAttempts int) (delay time.Duration, retryable bool) {
+	var rateLimitedErr *slack.RateLimitedError
+	if errors.As(err, &rateLimitedErr) && rateLimitedErr.RetryAfter > 0 {
+		return rateLimitedErr.RetryAfter, true
+	}
+
+	if !isTransientError(err) {
+		return 0, false
+	}
+
+	return d.policy.backoff(failedAttempts, d.random), true
+}
+
+// isTransientError returns true if the error is one worth retrying (rate limiting, 5xx responses or network timeouts)
+func isTransientError(err error) bool {
+	var r slackutilsx.Retryable
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+
+	return false
+}
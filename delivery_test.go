@@ -0,0 +1,250 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric"
+	"log"
+	"testing"
+	"time"
+)
+
+// scriptedChatDriver is a chatDriver failing calls with scripted errors before succeeding
+type scriptedChatDriver struct {
+	errs  []error
+	calls []string
+}
+
+func (d *scriptedChatDriver) nextErr() (err error) {
+	if len(d.errs) > 0 {
+		err, d.errs = d.errs[0], d.errs[1:]
+	}
+
+	return err
+}
+
+func (d *scriptedChatDriver) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	d.calls = append(d.calls, "send "+channelID)
+	return channelID, timestamp1, "", d.nextErr()
+}
+
+func (d *scriptedChatDriver) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	d.calls = append(d.calls, "update "+channelID)
+	return channelID, timestamp, "", d.nextErr()
+}
+
+func (d *scriptedChatDriver) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
+	d.calls = append(d.calls, "delete "+channelID)
+	return channelID, timestamp, d.nextErr()
+}
+
+// serverError is a transient error as returned by slack on 5xx responses
+type serverError struct {
+	code int
+}
+
+func (e serverError) Error() string {
+	return fmt.Sprintf("slack server error: %d", e.code)
+}
+
+func (e serverError) Retryable() bool {
+	return e.code >= 500
+}
+
+var testDeliveryPolicy = deliveryPolicy{maxAttempts: 3, initialBackoff: time.Duration(1) * time.Second, maxBackoff: time.Duration(3) * time.Second, channelPostingInterval: time.Duration(1) * time.Second}
+
+func newTestDeliveringChatDriver(t *testing.T, base chatDriver) (d *deliveringChatDriver, sleeps *[]time.Duration) {
+	dm, err := newDeliveryMetrics("test", metric.Meter{})
+	require.NoError(t, err)
+
+	clock := fakeClock{now: time.Now()}
+	sleeps = &[]time.Duration{}
+
+	d = newDeliveringChatDriver(context.Background(), base, testDeliveryPolicy, dm, NewSLogger(log.New(&nullWriter{}, "", 0), false))
+	d.now = clock.Now
+	d.sleep = func(ctx context.Context, duration time.Duration) (err error) {
+		*sleeps = append(*sleeps, duration)
+		clock.now = clock.now.Add(duration)
+		return nil
+	}
+	// Always pick the lowest jittered delay
+	d.random = func(n int64) int64 { return 0 }
+
+	return d, sleeps
+}
+
+func TestDeliveryPolicyBackoff(t *testing.T) {
+	p := deliveryPolicy{initialBackoff: time.Duration(1) * time.Second, maxBackoff: time.Duration(5) * time.Second}
+	lowest := func(n int64) int64 { return 0 }
+	highest := func(n int64) int64 { return n - 1 }
+
+	assert.Equal(t, time.Duration(500)*time.Millisecond, p.backoff(1, lowest))
+	assert.Equal(t, time.Duration(1)*time.Second, p.backoff(2, lowest))
+	assert.Equal(t, time.Duration(2)*time.Second, p.backoff(3, lowest))
+	assert.Equal(t, time.Duration(2500)*time.Millisecond, p.backoff(4, lowest))
+	assert.Equal(t, time.Duration(2500)*time.Millisecond, p.backoff(100, lowest))
+	assert.Equal(t, time.Duration(5)*time.Second-time.Nanosecond, p.backoff(100, highest))
+}
+
+func TestDeliveryRetriesAfterRateLimitingDelay(t *testing.T) {
+	base := &scriptedChatDriver{errs: []error{&slack.RateLimitedError{RetryAfter: time.Duration(7) * time.Second}}}
+	d, sleeps := newTestDeliveringChatDriver(t, base)
+
+	channelID, ts, _, err := d.SendMessage("Cgeneral")
+	require.NoError(t, err)
+	assert.Equal(t, "Cgeneral", channelID)
+	assert.Equal(t, timestamp1, ts)
+	assert.Equal(t, []string{"send Cgeneral", "send Cgeneral"}, base.calls)
+	assert.Equal(t, []time.Duration{time.Duration(7) * time.Second}, *sleeps)
+}
+
+func TestDeliveryRetriesTransientErrorsWithBackoff(t *testing.T) {
+	base := &scriptedChatDriver{errs: []error{serverError{code: 503}, fmt.Errorf("wrapped: %w", serverError{code: 500})}}
+	d, sleeps := newTestDeliveringChatDriver(t, base)
+
+	_, _, _, err := d.UpdateMessage("Cgeneral", timestamp1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"update Cgeneral", "update Cgeneral", "update Cgeneral"}, base.calls)
+	assert.Equal(t, []time.Duration{time.Duration(500) * time.Millisecond, time.Duration(1) * time.Second}, *sleeps)
+}
+
+func TestDeliveryDroppedAfterMaxAttempts(t *testing.T) {
+	base := &scriptedChatDriver{errs: []error{serverError{code: 502}, serverError{code: 502}, serverError{code: 502}, serverError{code: 502}}}
+	d, sleeps := newTestDeliveringChatDriver(t, base)
+
+	_, _, err := d.DeleteMessage("Cgeneral", timestamp1)
+	assert.EqualError(t, err, "slack server error: 502")
+	assert.Equal(t, 3, len(base.calls))
+	assert.Equal(t, 2, len(*sleeps))
+}
+
+func TestDeliveryNotRetriedOnPermanentErrors(t *testing.T) {
+	base := &scriptedChatDriver{errs: []error{fmt.Errorf("channel_not_found"), serverError{code: 404}}}
+	d, sleeps := newTestDeliveringChatDriver(t, base)
+
+	_, _, _, err := d.SendMessage("Cgeneral")
+	assert.EqualError(t, err, "channel_not_found")
+
+	_, _, _, err = d.UpdateMessage("Cgeneral", timestamp1)
+	assert.EqualError(t, err, "slack server error: 404")
+
+	assert.Equal(t, []string{"send Cgeneral", "update Cgeneral"}, base.calls)
+	assert.Empty(t, *sleeps)
+}
+
+func TestDeliveryPacesNewMessagesByChannel(t *testing.T) {
+	base := &scriptedChatDriver{}
+	d, sleeps := newTestDeliveringChatDriver(t, base)
+
+	d.SendMessage("Cgeneral")
+	d.SendMessage("Crandom")
+	d.UpdateMessage("Cgeneral", timestamp1)
+	d.SendMessage("Cgeneral")
+
+	assert.Equal(t, []string{"send Cgeneral", "send Crandom", "update Cgeneral", "send Cgeneral"}, base.calls)
+	// Only the second new message to Cgeneral has to wait for the posting interval
+	assert.Equal(t, []time.Duration{time.Duration(1) * time.Second}, *sleeps)
+}
+
+func TestDeliveryPacingBlocksSender(t *testing.T) {
+	base := &scriptedChatDriver{}
+	d, sleeps := newTestDeliveringChatDriver(t, base)
+
+	// Sending an answer of 3 new messages to a channel returns only once the last one is posted, 2 posting intervals later
+	d.SendMessage("Cgeneral")
+	d.SendMessage("Cgeneral")
+	d.SendMessage("Cgeneral")
+
+	assert.Equal(t, []string{"send Cgeneral", "send Cgeneral", "send Cgeneral"}, base.calls)
+	assert.Equal(t, []time.Duration{time.Duration(1) * time.Second, time.Duration(1) * time.Second}, *sleeps)
+}
+
+func TestDeliveryDoesNotPaceEphemeralOrResponseURLMessages(t *testing.T) {
+	base := &scriptedChatDriver{}
+	d, sleeps := newTestDeliveringChatDriver(t, base)
+
+	d.SendMessage("Cgeneral")
+	d.SendMessage("Cgeneral", slack.MsgOptionPostEphemeral("Alphonse"))
+	d.SendMessage("Cgeneral", slack.MsgOptionResponseURL("https://hooks.slack.com/commands/T1/1/abc", slack.ResponseTypeEphemeral))
+	assert.Empty(t, *sleeps)
+
+	// Ephemeral messages and messages sent to a response url don't take up the channel's posting time either
+	d.SendMessage("Cgeneral")
+	assert.Equal(t, []time.Duration{time.Duration(1) * time.Second}, *sleeps)
+	assert.Equal(t, 4, len(base.calls))
+}
+
+func TestDeliveryPacingDoesNotHoldChannelQueue(t *testing.T) {
+	base := &scriptedChatDriver{}
+	d, _ := newTestDeliveringChatDriver(t, base)
+
+	d.SendMessage("Cgeneral")
+
+	waiting := make(chan bool)
+	release := make(chan bool)
+	d.sleep = func(ctx context.Context, duration time.Duration) (err error) {
+		close(waiting)
+		<-release
+		return nil
+	}
+
+	sent := make(chan bool)
+	go func() {
+		d.SendMessage("Cgeneral")
+		close(sent)
+	}()
+
+	// The update goes through while the second new message waits for the posting interval
+	<-waiting
+	d.UpdateMessage("Cgeneral", timestamp1)
+	close(release)
+	<-sent
+
+	assert.Equal(t, []string{"send Cgeneral", "update Cgeneral", "send Cgeneral"}, base.calls)
+}
+
+func TestDeliveryNotRetriedOnShutdown(t *testing.T) {
+	base := &scriptedChatDriver{errs: []error{&slack.RateLimitedError{RetryAfter: time.Duration(1) * time.Hour}}}
+	d, sleeps := newTestDeliveringChatDriver(t, base)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d.ctx = ctx
+
+	_, _, _, err := d.SendMessage("Cgeneral")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"send Cgeneral"}, base.calls)
+	assert.Empty(t, *sleeps)
+}
+
+func TestDeliveryWaitsCutShortOnShutdown(t *testing.T) {
+	base := &scriptedChatDriver{errs: []error{nil, &slack.RateLimitedError{RetryAfter: time.Duration(1) * time.Hour}}}
+	d, _ := newTestDeliveringChatDriver(t, base)
+	d.policy.channelPostingInterval = time.Duration(1) * time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.ctx = ctx
+	d.sleep = func(ctx context.Context, duration time.Duration) (err error) {
+		cancel()
+		return sleepContext(ctx, duration)
+	}
+
+	_, _, _, err := d.SendMessage("Cgeneral")
+	require.NoError(t, err)
+
+	// The second message is posted without waiting for the posting interval but its delivery isn't retried
+	_, _, _, err = d.SendMessage("Cgeneral")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"send Cgeneral", "send Cgeneral"}, base.calls)
+}
+
+func TestSleepContext(t *testing.T) {
+	assert.NoError(t, sleepContext(context.Background(), time.Duration(1)*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, sleepContext(ctx, time.Duration(1)*time.Hour))
+}
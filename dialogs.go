@@ -0,0 +1,562 @@
+package slackscot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// The message a user sends to cancel the dialog they're in
+	dialogCancelKeyword = "cancel"
+
+	// The answer to a cancelled dialog when its definition doesn't have a CancelAnswer
+	defaultDialogCancelAnswer = "Ok, never mind then"
+
+	// The silo in which StorerDialogStore keeps its entries
+	dialogStoreSilo = "slackscot.dialogs"
+
+	// The minimum time between purges of expired dialogs
+	dialogPurgeInterval = time.Duration(1) * time.Hour
+)
+
+// DialogDefinition defines a multi-turn dialog of a plugin. A dialog is started by a command or hear action answering
+// with the AnswerStartingDialog option. From then on, the next messages of the user in the channel and thread the answer
+// is sent in (i.e. in the thread of a threaded answer) go to the dialog's current step (before any command or hear action)
+// until the dialog ends, is cancelled or times out.
+//
+// For example, an incident dialog could be started by a "create incident" command answering with "What severity?" and
+//
+//   DialogDefinition{
+//       Name:  "incident",
+//       Start: "severity",
+//       Steps: map[string]DialogStep{
+//           "severity": func(t *DialogTurn) *Answer {
+//               t.Values["severity"] = t.Message.NormalizedText
+//               t.GoTo("service")
+//               return &Answer{Text: "Which service?"}
+//           },
+//           "service": func(t *DialogTurn) *Answer {
+//               t.End()
+//               return &Answer{Text: fmt.Sprintf("Created %s incident on %s", t.Values["severity"], t.Message.NormalizedText)}
+//           },
+//       },
+//   }
+//
+// A user cancels the dialog they're in by sending "cancel"
+type DialogDefinition struct {
+	// Name of the dialog, unique to its plugin
+	Name string
+
+	// Name of the step handling the first reply of the user
+	Start string
+
+	// Steps of the dialog by name
+	Steps map[string]DialogStep
+
+	// Maximum time to wait for the next reply of the user before the dialog is abandoned. If zero, the
+	// config.DialogTimeoutKey configuration applies
+	Timeout time.Duration
+
+	// Answer to the user cancelling the dialog. Defaults to "Ok, never mind then"
+	CancelAnswer string
+}
+
+// DialogStep handles a reply of the user in a dialog. A step moves the dialog forward with DialogTurn.GoTo or ends
+// it with DialogTurn.End. Otherwise, the next reply goes to the same step (i.e. to ask again on invalid input). To signal the
+// absence of an answer, a step should return nil
+type DialogStep func(t *DialogTurn) *Answer
+
+// DialogTurn is a reply of the user in a dialog along with the values kept by the dialog's steps
+type DialogTurn struct {
+	// The reply of the user
+	Message *IncomingMessage
+
+	// Values kept between steps of the dialog. Values set when starting the dialog (see AnswerWithDialogValue) are
+	// available to the first step
+	Values map[string]string
+
+	nextStep string
+	ended    bool
+}
+
+// GoTo sets the step handling the next reply of the user
+func (t *DialogTurn) GoTo(step string) {
+	t.nextStep = step
+}
+
+// End ends the dialog
+func (t *DialogTurn) End() {
+	t.ended = true
+}
+
+// DialogState holds the state of a dialog in progress
+type DialogState struct {
+	Plugin    string            `json:"plugin"`
+	Dialog    string            `json:"dialog"`
+	Step      string            `json:"step"`
+	Values    map[string]string `json:"values"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// isExpired returns true if the dialog timed out
+func (ds *DialogState) isExpired(now time.Time) bool {
+	return now.After(ds.ExpiresAt)
+}
+
+// NewDialogState returns the state of the dialog started by an answer of a plugin action or started false if the
+// answer doesn't start a dialog (see AnswerStartingDialog). The state is at the dialog's Start step with the values set
+// on the answer with AnswerWithDialogValue
+func NewDialogState(p *Plugin, answer *Answer) (state *DialogState, started bool) {
+	sendOpts := ApplyAnswerOpts(answer.Options...)
+
+	def, ok := p.findDialog(sendOpts[StartDialogOpt])
+	if !ok {
+		return nil, false
+	}
+
+	state = &DialogState{Plugin: p.Name, Dialog: def.Name, Step: def.Start, Values: make(map[string]string)}
+	for k, v := range sendOpts {
+		if strings.HasPrefix(k, dialogValueOptPrefix) {
+			state.Values[strings.TrimPrefix(k, dialogValueOptPrefix)] = v
+		}
+	}
+
+	return state, true
+}
+
+// TakeDialogTurn runs the current step of a dialog with a reply of the user and moves the state of the dialog forward. A reply
+// of "cancel" cancels the dialog instead. ended is true if the dialog ended (or was cancelled)
+func TakeDialogTurn(def DialogDefinition, state *DialogState, m *IncomingMessage) (answer *Answer, ended bool) {
+	if strings.EqualFold(strings.TrimSpace(m.NormalizedText), dialogCancelKeyword) {
+		if def.CancelAnswer != "" {
+			return &Answer{Text: def.CancelAnswer}, true
+		}
+
+		return &Answer{Text: defaultDialogCancelAnswer}, true
+	}
+
+	step, ok := def.Steps[state.Step]
+	if !ok {
+		return nil, true
+	}
+
+	values := make(map[string]string)
+	for k, v := range state.Values {
+		values[k] = v
+	}
+
+	turn := &DialogTurn{Message: m, Values: values, nextStep: state.Step}
+	answer = step(turn)
+
+	state.Step = turn.nextStep
+	state.Values = turn.Values
+
+	return answer, turn.ended
+}
+
+// findDialog returns the plugin's dialog definition with the given name
+func (p *Plugin) findDialog(name string) (def DialogDefinition, found bool) {
+	if name == "" {
+		return def, false
+	}
+
+	for _, d := range p.Dialogs {
+		if d.Name == name {
+			return d, true
+		}
+	}
+
+	return def, false
+}
+
+// DialogKey identifies the dialog of a user in a channel and thread
+type DialogKey struct {
+	teamID          string
+	channelID       string
+	threadTimestamp string
+	userID          string
+}
+
+// newDialogKey returns the key of the dialog the sender of the message is in
+func newDialogKey(teamID string, m slack.Msg) (key DialogKey) {
+	return DialogKey{teamID: teamID, channelID: m.Channel, threadTimestamp: m.ThreadTimestamp, userID: m.User}
+}
+
+// newStartedDialogKey returns the key of a dialog started by an answer to a message. The dialog continues where the answer is
+// sent: in the thread of the answer when it's threaded and in the thread (or channel) of the message otherwise
+func (s *Slackscot) newStartedDialogKey(m IncomingMessage, answer *Answer) (key DialogKey) {
+	key = newDialogKey(m.TeamID, m.Msg)
+	if threadTS, threaded := s.answerThreadTimestamp(ApplyAnswerOpts(answer.Options...), m.Timestamp); threaded {
+		key.threadTimestamp = threadTS
+	}
+
+	return key
+}
+
+// String returns the string representation of a DialogKey
+func (k DialogKey) String() string {
+	return fmt.Sprintf("%s/%s/%s/%s", k.teamID, k.channelID, k.threadTimestamp, k.userID)
+}
+
+// dialogLocks holds a lock per dialog key so that the replies of a user in a dialog are handled one at a time even when they're
+// processed by different partitions
+type dialogLocks struct {
+	locks map[DialogKey]*dialogLock
+	lock  sync.Mutex
+}
+
+// dialogLock is the lock of a dialog key along with the number of goroutines holding or waiting for it
+type dialogLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newDialogLocks() (dl *dialogLocks) {
+	return &dialogLocks{locks: make(map[DialogKey]*dialogLock)}
+}
+
+// acquire locks the dialog key and returns the function releasing it. Locks are removed once released by everyone
+func (dl *dialogLocks) acquire(key DialogKey) (release func()) {
+	dl.lock.Lock()
+	l, ok := dl.locks[key]
+	if !ok {
+		l = new(dialogLock)
+		dl.locks[key] = l
+	}
+	l.refs++
+	dl.lock.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		dl.lock.Lock()
+		defer dl.lock.Unlock()
+
+		l.refs--
+		if l.refs == 0 {
+			delete(dl.locks, key)
+		}
+	}
+}
+
+// DialogStore keeps the state of dialogs in progress. Implementations don't need to check for expiry
+// of dialogs (slackscot does) but should eventually purge expired dialogs
+type DialogStore interface {
+	// Get returns the state of a dialog. found is false if there's no dialog for that key
+	Get(key DialogKey) (state *DialogState, found bool, err error)
+
+	// Put sets the state of a dialog
+	Put(key DialogKey, state *DialogState) (err error)
+
+	// Delete removes a dialog
+	Delete(key DialogKey) (err error)
+}
+
+// InMemoryDialogStore is a DialogStore keeping dialogs in memory. This is the default DialogStore. Dialogs in
+// progress are lost on restart
+type InMemoryDialogStore struct {
+	dialogs   map[DialogKey]DialogState
+	lock      sync.Mutex
+	now       func() time.Time
+	lastPurge time.Time
+}
+
+// NewInMemoryDialogStore returns a new InMemoryDialogStore
+func NewInMemoryDialogStore() (ds *InMemoryDialogStore) {
+	ds = new(InMemoryDialogStore)
+	ds.dialogs = make(map[DialogKey]DialogState)
+	ds.now = time.Now
+
+	return ds
+}
+
+// Get returns the state of a dialog
+func (ds *InMemoryDialogStore) Get(key DialogKey) (state *DialogState, found bool, err error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	s, found := ds.dialogs[key]
+	if !found {
+		return nil, false, nil
+	}
+
+	return &s, true, nil
+}
+
+// Put sets the state of a dialog. Expired dialogs are purged if they haven't been for a while
+func (ds *InMemoryDialogStore) Put(key DialogKey, state *DialogState) (err error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	now := ds.now()
+	if now.Sub(ds.lastPurge) >= dialogPurgeInterval {
+		for k, s := range ds.dialogs {
+			if s.isExpired(now) {
+				delete(ds.dialogs, k)
+			}
+		}
+		ds.lastPurge = now
+	}
+
+	ds.dialogs[key] = *state
+	return nil
+}
+
+// Delete removes a dialog
+func (ds *InMemoryDialogStore) Delete(key DialogKey) (err error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	delete(ds.dialogs, key)
+	return nil
+}
+
+// StorerDialogStore is a DialogStore persisted with a store.SiloStringStorer so that dialogs in progress survive restarts.
+// Since entries are kept in their own silo, the storer can be shared with plugins but it should not be closed before slackscot
+// is done running
+type StorerDialogStore struct {
+	storer    store.SiloStringStorer
+	now       func() time.Time
+	lastPurge time.Time
+	purgeLock sync.Mutex
+}
+
+// NewStorerDialogStore returns a new StorerDialogStore persisting dialogs with the storer
+func NewStorerDialogStore(storer store.SiloStringStorer) (ds *StorerDialogStore) {
+	ds = new(StorerDialogStore)
+	ds.storer = storer
+	ds.now = time.Now
+
+	return ds
+}
+
+// Get returns the state of a dialog
+func (ds *StorerDialogStore) Get(key DialogKey) (state *DialogState, found bool, err error) {
+	v, err := ds.storer.GetSiloString(dialogStoreSilo, key.String())
+	if err != nil || v == "" {
+		// Storers return an error on missing keys so we consider any error a miss
+		return nil, false, nil
+	}
+
+	state = new(DialogState)
+	if err = json.Unmarshal([]byte(v), state); err != nil {
+		return nil, false, err
+	}
+
+	return state, true, nil
+}
+
+// Put sets the state of a dialog. Expired dialogs are purged if they haven't been for a while
+func (ds *StorerDialogStore) Put(key DialogKey, state *DialogState) (err error) {
+	if err = ds.purgeExpiredIfDue(); err != nil {
+		return err
+	}
+
+	v, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return ds.storer.PutSiloString(dialogStoreSilo, key.String(), string(v))
+}
+
+// Delete removes a dialog
+func (ds *StorerDialogStore) Delete(key DialogKey) (err error) {
+	return ds.storer.DeleteSiloString(dialogStoreSilo, key.String())
+}
+
+// purgeExpiredIfDue deletes all expired dialogs if the last purge is older than the purge interval
+func (ds *StorerDialogStore) purgeExpiredIfDue() (err error) {
+	ds.purgeLock.Lock()
+	defer ds.purgeLock.Unlock()
+
+	now := ds.now()
+	if now.Sub(ds.lastPurge) < dialogPurgeInterval {
+		return nil
+	}
+
+	entries, err := ds.storer.ScanSilo(dialogStoreSilo)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range entries {
+		var state DialogState
+		if err = json.Unmarshal([]byte(v), &state); err != nil || state.isExpired(now) {
+			if err = ds.storer.DeleteSiloString(dialogStoreSilo, k); err != nil {
+				return err
+			}
+		}
+	}
+
+	ds.lastPurge = now
+	return nil
+}
+
+// dialogTimeout returns the maximum time to wait for the next reply of the user in a dialog
+func (s *Slackscot) dialogTimeout(def DialogDefinition) (timeout time.Duration) {
+	if def.Timeout > 0 {
+		return def.Timeout
+	}
+
+	return s.config.GetDuration(config.DialogTimeoutKey)
+}
+
+// getDialogStepID returns the identifier of a step of a plugin's dialog. Each step gets its own identifier so that
+// steps have their own circuit breaker and rate limits
+func getDialogStepID(pluginName string, dialogName string, step string) (stepID string) {
+	return fmt.Sprintf("%s.dialog.%s.%s", pluginName, dialogName, step)
+}
+
+// startDialogIfAny starts the dialog of a plugin if the answer to an incoming message has the AnswerStartingDialog
+// option. Any dialog already in progress for the user in the same channel and thread is replaced
+func (s *Slackscot) startDialogIfAny(p *Plugin, m IncomingMessage, answer *Answer) {
+	state, started := NewDialogState(p, answer)
+	if !started {
+		return
+	}
+
+	def, _ := p.findDialog(state.Dialog)
+	state.ExpiresAt = time.Now().Add(s.dialogTimeout(def))
+
+	key := s.newStartedDialogKey(m, answer)
+	release := s.dialogLocks.acquire(key)
+	defer release()
+
+	s.log.Debugf("Starting dialog [%s] of plugin [%s] for [%s]", state.Dialog, p.Name, key)
+
+	if err := s.dialogStore.Put(key, state); err != nil {
+		s.log.Printf("Error starting dialog [%s] of plugin [%s] for [%s]: %v", state.Dialog, p.Name, key, err)
+	}
+}
+
+// continueDialog routes a message to the current step of the dialog its sender is in, if any. continued is false if the
+// sender isn't in a dialog (or if it expired) in which case the message should be routed as usual. The dialog is locked
+// from reading its state to saving the next one so that quick replies of a user don't both take the same turn
+func (s *Slackscot) continueDialog(ctx context.Context, ws *workspace, m slack.Msg) (responses []OutgoingMessage, continued bool) {
+	key := newDialogKey(ws.teamID, m)
+
+	release := s.dialogLocks.acquire(key)
+	defer release()
+
+	state, found, err := s.dialogStore.Get(key)
+	if err != nil {
+		s.log.Printf("Error getting dialog for [%s]: %v", key, err)
+		return nil, false
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	if state.isExpired(time.Now()) {
+		s.log.Debugf("Dialog [%s] of plugin [%s] for [%s] timed out", state.Dialog, state.Plugin, key)
+		s.deleteDialog(key)
+
+		return nil, false
+	}
+
+	p, def, ok := s.findPluginDialog(state.Plugin, state.Dialog)
+	if !ok {
+		s.log.Printf("Abandoning unknown dialog [%s] of plugin [%s] for [%s]", state.Dialog, state.Plugin, key)
+		s.deleteDialog(key)
+
+		return nil, false
+	}
+
+	// The step runs as an action of the plugin (with its middlewares, timeout and panic isolation) and only a step that
+	// completes moves the dialog forward. A step abandoned on timeout might still take its turn later on but, since its
+	// answer is thrown away, that turn is ignored
+	turns := make(chan *DialogState, 1)
+	step := ActionDefinition{
+		Match: func(m *IncomingMessage) bool {
+			return true
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			next := *state
+			answer, ended := TakeDialogTurn(def, &next, m)
+			if ended {
+				next.Step = ""
+			}
+
+			turns <- &next
+			return answer
+		},
+	}
+
+	rs := send
+	if isDirectMessage(m) {
+		rs = directReply
+	}
+
+	candidate := actionCandidate{p: p, actionType: DialogActionType, actionID: getDialogStepID(p.Name, def.Name, state.Step), action: step, msg: s.newIncomingMsgWithNormalizedText(ws, m)}
+
+	usages := newPluginActionUsages()
+	usage := usages.of(p)
+
+	responses = make([]OutgoingMessage, 0)
+	answer, _, completed := s.tryAction(ctx, candidate, usage)
+	if answer != nil {
+		responses = append(responses, s.processAnswer(candidate, answer, rs, usage))
+	}
+
+	usages.record(s)
+
+	if !completed {
+		s.log.Printf("Step [%s] of dialog [%s] of plugin [%s] didn't complete, the dialog stays at that step", state.Step, state.Dialog, state.Plugin)
+		return responses, true
+	}
+
+	// Once the step completed, its turn (if a middleware didn't skip it) is already taken
+	select {
+	case next := <-turns:
+		s.saveDialogTurn(key, state, def, next)
+	default:
+		s.log.Printf("Step [%s] of dialog [%s] of plugin [%s] was skipped, the dialog stays at that step", state.Step, state.Dialog, state.Plugin)
+	}
+
+	return responses, true
+}
+
+// saveDialogTurn saves the state of a dialog after a turn or deletes the dialog if the turn ended it
+func (s *Slackscot) saveDialogTurn(key DialogKey, state *DialogState, def DialogDefinition, next *DialogState) {
+	if next.Step == "" {
+		s.log.Debugf("Dialog [%s] of plugin [%s] for [%s] ended", state.Dialog, state.Plugin, key)
+		s.deleteDialog(key)
+
+		return
+	}
+
+	next.ExpiresAt = time.Now().Add(s.dialogTimeout(def))
+	if err := s.dialogStore.Put(key, next); err != nil {
+		s.log.Printf("Error saving dialog [%s] of plugin [%s] for [%s]: %v", state.Dialog, state.Plugin, key, err)
+	}
+}
+
+// deleteDialog removes a dialog from the dialog store
+func (s *Slackscot) deleteDialog(key DialogKey) {
+	if err := s.dialogStore.Delete(key); err != nil {
+		s.log.Printf("Error deleting dialog for [%s]: %v", key, err)
+	}
+}
+
+// findPluginDialog returns the plugin with the given name and its dialog definition
+func (s *Slackscot) findPluginDialog(pluginName string, dialogName string) (p *Plugin, def DialogDefinition, found bool) {
+	for _, p := range s.plugins {
+		if p.Name == pluginName {
+			def, found = p.findDialog(dialogName)
+			return p, def, found
+		}
+	}
+
+	return nil, def, false
+}
@@ -0,0 +1,347 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newIncidentDialog() (def DialogDefinition) {
+	return DialogDefinition{
+		Name:  "incident",
+		Start: "severity",
+		Steps: map[string]DialogStep{
+			"severity": func(t *DialogTurn) *Answer {
+				if !strings.HasPrefix(t.Message.NormalizedText, "sev") {
+					return &Answer{Text: "Severity should be one of sev1, sev2 or sev3"}
+				}
+
+				t.Values["severity"] = t.Message.NormalizedText
+				t.GoTo("service")
+				return &Answer{Text: "Which service?"}
+			},
+			"service": func(t *DialogTurn) *Answer {
+				t.End()
+				return &Answer{Text: fmt.Sprintf("Created %s incident on %s for %s", t.Values["severity"], t.Message.NormalizedText, t.Values["reporter"])}
+			},
+		},
+	}
+}
+
+func newIncidentPlugin(dialog DialogDefinition) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "incidents"
+	p.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return m.NormalizedText == "create incident"
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "What severity?", Options: []AnswerOption{AnswerStartingDialog("incident"), AnswerWithDialogValue("reporter", m.User)}}
+		},
+	}}
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.Contains(m.NormalizedText, "blue jays")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "I heard about blue jays"}
+		},
+	}}
+	p.Dialogs = []DialogDefinition{dialog}
+
+	return p
+}
+
+func TestNewDialogState(t *testing.T) {
+	p := newIncidentPlugin(newIncidentDialog())
+
+	state, started := NewDialogState(p, &Answer{Text: "What severity?", Options: []AnswerOption{AnswerStartingDialog("incident"), AnswerWithDialogValue("reporter", "Alphonse"), AnswerInThread()}})
+	require.True(t, started)
+	assert.Equal(t, &DialogState{Plugin: "incidents", Dialog: "incident", Step: "severity", Values: map[string]string{"reporter": "Alphonse"}}, state)
+
+	_, started = NewDialogState(p, &Answer{Text: "What severity?", Options: []AnswerOption{AnswerStartingDialog("unknown")}})
+	assert.False(t, started)
+
+	_, started = NewDialogState(p, &Answer{Text: "What severity?"})
+	assert.False(t, started)
+}
+
+func TestTakeDialogTurn(t *testing.T) {
+	def := newIncidentDialog()
+	state := &DialogState{Plugin: "incidents", Dialog: "incident", Step: "severity", Values: map[string]string{"reporter": "Alphonse"}}
+
+	answer, ended := TakeDialogTurn(def, state, &IncomingMessage{NormalizedText: "critical"})
+	assert.False(t, ended)
+	assert.Equal(t, "Severity should be one of sev1, sev2 or sev3", answer.Text)
+	assert.Equal(t, "severity", state.Step)
+
+	answer, ended = TakeDialogTurn(def, state, &IncomingMessage{NormalizedText: "sev1"})
+	assert.False(t, ended)
+	assert.Equal(t, "Which service?", answer.Text)
+	assert.Equal(t, "service", state.Step)
+	assert.Equal(t, map[string]string{"reporter": "Alphonse", "severity": "sev1"}, state.Values)
+
+	answer, ended = TakeDialogTurn(def, state, &IncomingMessage{NormalizedText: "payments"})
+	assert.True(t, ended)
+	assert.Equal(t, "Created sev1 incident on payments for Alphonse", answer.Text)
+}
+
+func TestTakeDialogTurnCancelled(t *testing.T) {
+	def := newIncidentDialog()
+
+	answer, ended := TakeDialogTurn(def, &DialogState{Step: "severity"}, &IncomingMessage{NormalizedText: " Cancel "})
+	assert.True(t, ended)
+	assert.Equal(t, "Ok, never mind then", answer.Text)
+
+	def.CancelAnswer = "Incident creation cancelled"
+	answer, ended = TakeDialogTurn(def, &DialogState{Step: "severity"}, &IncomingMessage{NormalizedText: "cancel"})
+	assert.True(t, ended)
+	assert.Equal(t, "Incident creation cancelled", answer.Text)
+}
+
+func TestDialogConversation(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newIncidentPlugin(newIncidentDialog()), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", formattedBotUserID+" create incident", "Alphonse", timestamp1)),
+		// Messages of other users or in other threads aren't part of the dialog
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Ignatius", timestamp2)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", "1546833215.036900", optionMessageOnThread(timestamp2))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "critical", "Alphonse", "1546833216.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sev1", "Alphonse", "1546833217.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", "1546833218.036900")),
+		// Once the dialog has ended, messages are routed as usual
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", "1546833219.036900")),
+	}, nil)
+
+	assert.Equal(t, []string{
+		"<@Alphonse>: What severity?",
+		"I heard about blue jays",
+		"I heard about blue jays",
+		"Severity should be one of sev1, sev2 or sev3",
+		"Which service?",
+		"Created sev1 incident on blue jays for Alphonse",
+		"I heard about blue jays",
+	}, sentTexts(sentMsgs))
+}
+
+func TestDialogContinuedInThreadOfThreadedReplies(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.ThreadedRepliesKey, true)
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newIncidentPlugin(newIncidentDialog()), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", formattedBotUserID+" create incident", "Alphonse", timestamp1)),
+		// The dialog continues in the thread the bot answered in, not in the channel
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sev1 blue jays", "Alphonse", timestamp2)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sev1", "Alphonse", "1546833215.036900", optionMessageOnThread(timestamp1))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "payments", "Alphonse", "1546833216.036900", optionMessageOnThread(timestamp1))),
+	}, nil)
+
+	assert.Equal(t, []string{
+		"<@Alphonse>: What severity?",
+		"I heard about blue jays",
+		"Which service?",
+		"Created sev1 incident on payments for Alphonse",
+	}, sentTexts(sentMsgs))
+
+	for _, m := range []sentMessage{sentMsgs[0], sentMsgs[2], sentMsgs[3]} {
+		assert.Equal(t, timestamp1, applySlackOptions(m.msgOptions...).Get("thread_ts"))
+	}
+}
+
+func TestDialogContinuedInThreadOfAnswerInThread(t *testing.T) {
+	p := newIncidentPlugin(newIncidentDialog())
+	p.Commands[0].Answer = func(m *IncomingMessage) *Answer {
+		return &Answer{Text: "What severity?", Options: []AnswerOption{AnswerStartingDialog("incident"), AnswerInThread()}}
+	}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, p, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", formattedBotUserID+" create incident", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sev1", "Alphonse", timestamp2, optionMessageOnThread(timestamp1))),
+	}, nil)
+
+	assert.Equal(t, []string{"<@Alphonse>: What severity?", "Which service?"}, sentTexts(sentMsgs))
+}
+
+func TestDialogStepsHaveTheirOwnActionID(t *testing.T) {
+	actionIDs := make([]string, 0)
+	recordActionID := func(next ActionInvoker) ActionInvoker {
+		return func(ctx context.Context, inv *ActionInvocation) *Answer {
+			if inv.PluginName == "incidents" {
+				actionIDs = append(actionIDs, inv.ActionID)
+			}
+			return next(ctx, inv)
+		}
+	}
+
+	runSlackscotWithIncomingEvents(t, nil, newIncidentPlugin(newIncidentDialog()), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", formattedBotUserID+" create incident", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sev1", "Alphonse", timestamp2)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "payments", "Alphonse", "1546833215.036900")),
+	}, nil, OptionMiddleware(recordActionID))
+
+	assert.Equal(t, []string{"incidents.command[0]", "incidents.dialog.incident.severity", "incidents.dialog.incident.service"}, actionIDs)
+}
+
+func TestDialogNotAdvancedByStepTimingOut(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.ActionTimeoutKey, time.Duration(20)*time.Millisecond)
+
+	// The first dialog step takes its turn but its invocation times out before it returns
+	var stalled int32
+	stallFirstStep := func(next ActionInvoker) ActionInvoker {
+		return func(ctx context.Context, inv *ActionInvocation) *Answer {
+			answer := next(ctx, inv)
+			if inv.ActionType == DialogActionType && atomic.CompareAndSwapInt32(&stalled, 0, 1) {
+				<-ctx.Done()
+			}
+
+			return answer
+		}
+	}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newIncidentPlugin(newIncidentDialog()), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", formattedBotUserID+" create incident", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sev1", "Alphonse", timestamp2)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sev2", "Alphonse", "1546833215.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "payments", "Alphonse", "1546833216.036900")),
+	}, nil, OptionMiddleware(stallFirstStep))
+
+	assert.Equal(t, []string{"<@Alphonse>: What severity?", "Which service?", "Created sev2 incident on payments for Alphonse"}, sentTexts(sentMsgs))
+}
+
+func TestDialogLocks(t *testing.T) {
+	dl := newDialogLocks()
+	key := DialogKey{teamID: "T1", channelID: "Cgeneral", userID: "Alphonse"}
+
+	release := dl.acquire(key)
+
+	acquired := make(chan bool)
+	released := make(chan bool)
+	go func() {
+		release := dl.acquire(key)
+		acquired <- true
+		release()
+		close(released)
+	}()
+
+	select {
+	case <-acquired:
+		assert.Fail(t, "Dialog lock acquired while held")
+	case <-time.After(time.Duration(50) * time.Millisecond):
+	}
+
+	// Other keys aren't locked
+	dl.acquire(DialogKey{teamID: "T1", channelID: "Cgeneral", userID: "Ignatius"})()
+
+	release()
+	<-acquired
+	<-released
+
+	// Locks are removed once released
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+	assert.Empty(t, dl.locks)
+}
+
+func TestDialogCancelled(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newIncidentPlugin(newIncidentDialog()), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", formattedBotUserID+" create incident", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "cancel", "Alphonse", timestamp2)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sev1 blue jays", "Alphonse", "1546833215.036900")),
+	}, nil)
+
+	assert.Equal(t, []string{"<@Alphonse>: What severity?", "Ok, never mind then", "I heard about blue jays"}, sentTexts(sentMsgs))
+}
+
+func TestDialogTimedOut(t *testing.T) {
+	dialog := newIncidentDialog()
+	dialog.Timeout = time.Nanosecond
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newIncidentPlugin(dialog), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", formattedBotUserID+" create incident", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sev1 blue jays", "Alphonse", timestamp2)),
+	}, nil)
+
+	assert.Equal(t, []string{"<@Alphonse>: What severity?", "I heard about blue jays"}, sentTexts(sentMsgs))
+}
+
+func TestDialogNotContinuedByMessageUpdates(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newIncidentPlugin(newIncidentDialog()), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", formattedBotUserID+" create incident", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp2, optionChangedMessage("sev1 blue jays", "Alphonse", "1546833211.036900"))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "sev2", "Alphonse", "1546833215.036900")),
+	}, nil)
+
+	assert.Equal(t, []string{"<@Alphonse>: What severity?", "I heard about blue jays", "Which service?"}, sentTexts(sentMsgs))
+}
+
+func TestInMemoryDialogStore(t *testing.T) {
+	clock := fakeClock{now: time.Now()}
+	ds := NewInMemoryDialogStore()
+	ds.now = clock.Now
+
+	key := DialogKey{teamID: "T1", channelID: "Cgeneral", userID: "Alphonse"}
+	_, found, err := ds.Get(key)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	state := &DialogState{Plugin: "incidents", Dialog: "incident", Step: "severity", ExpiresAt: clock.now.Add(time.Minute)}
+	require.NoError(t, ds.Put(key, state))
+
+	got, found, err := ds.Get(key)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, state, got)
+
+	// Expired dialogs are purged on following puts
+	clock.now = clock.now.Add(time.Duration(2) * time.Hour)
+	otherKey := DialogKey{teamID: "T1", channelID: "Cgeneral", userID: "Ignatius"}
+	require.NoError(t, ds.Put(otherKey, &DialogState{ExpiresAt: clock.now.Add(time.Minute)}))
+	_, found, err = ds.Get(key)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, ds.Delete(otherKey))
+	_, found, err = ds.Get(otherKey)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestStorerDialogStore(t *testing.T) {
+	ldb, cleanup := newTestLevelDB(t)
+	defer cleanup()
+
+	clock := fakeClock{now: time.Now().UTC()}
+	ds := NewStorerDialogStore(ldb)
+	ds.now = clock.Now
+
+	key := DialogKey{teamID: "T1", channelID: "Cgeneral", threadTimestamp: timestamp1, userID: "Alphonse"}
+	state := &DialogState{Plugin: "incidents", Dialog: "incident", Step: "service", Values: map[string]string{"severity": "sev1"}, ExpiresAt: clock.now.Add(time.Minute)}
+	require.NoError(t, ds.Put(key, state))
+
+	got, found, err := ds.Get(key)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, state, got)
+
+	clock.now = clock.now.Add(time.Duration(2) * time.Hour)
+	require.NoError(t, ds.Put(DialogKey{teamID: "T1", channelID: "Cgeneral", userID: "Ignatius"}, &DialogState{ExpiresAt: clock.now.Add(time.Minute)}))
+
+	entries, err := ldb.ScanSilo(dialogStoreSilo)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Contains(t, entries, "T1/Cgeneral//Ignatius")
+
+	require.NoError(t, ds.Delete(key))
+	_, found, err = ds.Get(key)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
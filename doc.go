@@ -13,12 +13,16 @@ and can support integrating with various metrics exporter.
 
 Plugins also have access to services injected on startup by slackscot such as:
  - UserInfoFinder: To query user info
- - SLogger: To log debug/info statements
+ - SLogger: To log debug/info statements. Plugin.Log gives the structured and leveled Logger, tagged with plugin=<name>
  - EmojiReactor: To emoji react to messages
  - FileUploader: To upload files
  - RealTimeMessageSender: To send unmanaged real time messages outside the normal reaction flow (i.e. for sending many messages or sending via a scheduled action)
  - SlackClient: For advanced access to all the slack APIs via https://godoc.org/github.com/slack-go/slack#Client
 
+Plugins that need those services before processing events (to warm caches, open connections, validate channel ids, etc.)
+can set a Lifecycle (see PluginLifecycle and LifecycleHooks) to be initialized once services are injected, started on each
+connection to a workspace and stopped on shutdown.
+
 Example code (from https://github.com/alexandre-normand/youppi):
 
 	package main
@@ -12,29 +12,50 @@ import (
 	"unicode"
 
 	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/label"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // EmojiReactorWithTelemetry implements EmojiReactor interface with all methods wrapped
-// with open telemetry metrics
+// with open telemetry metrics and spans
 type EmojiReactorWithTelemetry struct {
 	base                     EmojiReactor
 	methodCounters           map[string]metric.BoundInt64Counter
 	errCounters              map[string]metric.BoundInt64Counter
 	methodTimeValueRecorders map[string]metric.BoundInt64ValueRecorder
+	tracer                   trace.Tracer
+	ctx                      context.Context
 }
 
 // NewEmojiReactorWithTelemetry returns an instance of the EmojiReactor decorated with open telemetry timing and count metrics
-func NewEmojiReactorWithTelemetry(base EmojiReactor, name string, meter metric.Meter) EmojiReactorWithTelemetry {
+// and a span for each call
+func NewEmojiReactorWithTelemetry(base EmojiReactor, name string, meter metric.Meter, tracer trace.Tracer) EmojiReactorWithTelemetry {
 	return EmojiReactorWithTelemetry{
 		base:                     base,
 		methodCounters:           newEmojiReactorMethodCounters("Calls", name, meter),
 		errCounters:              newEmojiReactorMethodCounters("Errors", name, meter),
 		methodTimeValueRecorders: newEmojiReactorMethodTimeValueRecorders(name, meter),
+		tracer:                   tracer,
 	}
 }
 
+// bindContext returns a copy of the EmojiReactorWithTelemetry starting the spans of its calls as children of the span in ctx
+func (_d EmojiReactorWithTelemetry) bindContext(ctx context.Context) interface{} {
+	_d.ctx = ctx
+	return _d
+}
+
+// spanParent returns the context holding the parent span of calls
+func (_d EmojiReactorWithTelemetry) spanParent() context.Context {
+	if _d.ctx == nil {
+		return context.Background()
+	}
+
+	return _d.ctx
+}
+
 func newEmojiReactorMethodTimeValueRecorders(appName string, meter metric.Meter) (boundTimeValueRecorders map[string]metric.BoundInt64ValueRecorder) {
 	boundTimeValueRecorders = make(map[string]metric.BoundInt64ValueRecorder)
 	mt := metric.Must(meter)
@@ -62,10 +83,14 @@ func newEmojiReactorMethodCounters(suffix string, appName string, meter metric.M
 // AddReaction implements EmojiReactor
 func (_d EmojiReactorWithTelemetry) AddReaction(name string, item slack.ItemRef) (err error) {
 	_since := time.Now()
+	_, _span := _d.tracer.Start(_d.spanParent(), "EmojiReactor.AddReaction", trace.WithSpanKind(trace.SpanKindClient))
 	defer func() {
 		if err != nil {
 			errCounter := _d.errCounters["AddReaction"]
 			errCounter.Add(context.Background(), 1)
+
+			_span.RecordError(err)
+			_span.SetStatus(codes.Error, err.Error())
 		}
 
 		methodCounter := _d.methodCounters["AddReaction"]
@@ -73,6 +98,8 @@ func (_d EmojiReactorWithTelemetry) AddReaction(name string, item slack.ItemRef)
 
 		methodTimeMeasure := _d.methodTimeValueRecorders["AddReaction"]
 		methodTimeMeasure.Record(context.Background(), time.Since(_since).Milliseconds())
+
+		_span.End()
 	}()
 	return _d.base.AddReaction(name, item)
 }
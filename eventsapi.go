@@ -0,0 +1,254 @@
+package slackscot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/golang-lru"
+	"github.com/slack-go/slack"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// EventsAPIPath is the path on which the events api handler is served when running with OptionEventsAPI. This is what
+	// the slack app's event subscriptions Request URL should point to
+	EventsAPIPath = "/slack/events"
+
+	eventsAPIURLVerificationType = "url_verification"
+	eventsAPICallbackType        = "event_callback"
+
+	// Slack expects an acknowledgement within 3 seconds so we make sure to respond before that
+	eventsAPIAckTimeout = time.Duration(2) * time.Second
+
+	// The number of event ids to remember in order to detect replays and retries of events we've already handled
+	eventsAPISeenEventsCacheSize = 1000
+
+	// Maximum size of requests read from slack
+	maxSlackRequestBodyBytes = 1 << 20
+)
+
+// eventsAPIRequest holds the outer data of any events api request
+type eventsAPIRequest struct {
+	eventsAPICallback
+	Challenge string `json:"challenge"`
+}
+
+// EventsAPIHandler is an http.Handler receiving events from the slack Events API. It verifies that requests are
+// signed by slack, answers url_verification challenges and publishes supported events on an events channel to
+// be processed like any other slack event
+type EventsAPIHandler struct {
+	signingSecret string
+	events        chan<- slack.RTMEvent
+	seenEventIDs  *lru.ARCCache
+	seenLock      sync.Mutex
+	ackTimeout    time.Duration
+	log           SLogger
+}
+
+// NewEventsAPIHandler returns a new EventsAPIHandler verifying requests with the given signingSecret and publishing events
+// on the events channel
+func NewEventsAPIHandler(signingSecret string, events chan<- slack.RTMEvent, logger SLogger) (h *EventsAPIHandler, err error) {
+	h = new(EventsAPIHandler)
+	h.signingSecret = signingSecret
+	h.events = events
+	h.ackTimeout = eventsAPIAckTimeout
+	h.log = logger
+
+	h.seenEventIDs, err = lru.NewARC(eventsAPISeenEventsCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// ServeHTTP handles an events api request
+func (h *EventsAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := readVerifiedBody(r, h.signingSecret)
+	if err != nil {
+		h.log.Printf("Rejecting events api request: %v", err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	var req eventsAPIRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		h.log.Printf("Error unmarshalling events api request [%s]: %v", string(body), err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	switch req.Type {
+	case eventsAPIURLVerificationType:
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, req.Challenge)
+
+	case eventsAPICallbackType:
+		h.handleCallback(w, req.eventsAPICallback)
+
+	default:
+		h.log.Debugf("Ignoring events api request of type [%s]", req.Type)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// handleCallback publishes the event of a callback, if supported. Events already seen (on retries, for example)
+// are acknowledged but not published again
+func (h *EventsAPIHandler) handleCallback(w http.ResponseWriter, callback eventsAPICallback) {
+	if h.markSeen(callback.EventID) {
+		h.log.Debugf("Ignoring already handled event [%s]", callback.EventID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	e, supported, err := newRTMEventFromEventsAPI(callback)
+	if err != nil {
+		h.log.Printf("Error mapping events api event [%s]: %v", callback.EventID, err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if supported {
+		select {
+		case h.events <- e:
+		case <-time.After(h.ackTimeout):
+			// Let slack retry later rather than losing the event
+			h.log.Printf("Timed out publishing event [%s], letting slack retry", callback.EventID)
+			h.seenEventIDs.Remove(callback.EventID)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// markSeen records an event id as seen and returns true if it had already been seen before
+func (h *EventsAPIHandler) markSeen(eventID string) (alreadySeen bool) {
+	h.seenLock.Lock()
+	defer h.seenLock.Unlock()
+
+	if h.seenEventIDs.Contains(eventID) {
+		return true
+	}
+
+	h.seenEventIDs.Add(eventID, true)
+	return false
+}
+
+// readVerifiedBody reads the body of a request after verifying its signature with the signing secret. Requests with a timestamp
+// older than 5 minutes are also rejected to protect against replays. See https://api.slack.com/authentication/verifying-requests-from-slack
+func readVerifiedBody(r *http.Request, signingSecret string) (body []byte, err error) {
+	verifier, err := slack.NewSecretsVerifier(r.Header, signingSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err = ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxSlackRequestBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = verifier.Write(body); err != nil {
+		return nil, err
+	}
+
+	if err = verifier.Ensure(); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// eventsAPIEventSource is an EventSource running an http server receiving events from the slack Events API
+type eventsAPIEventSource struct {
+	server     *http.Server
+	authTester authTester
+	events     chan slack.RTMEvent
+	info       *slack.Info
+	disconnect chan bool
+	once       sync.Once
+	log        *sLogger
+
+	webAPIMessageSender
+}
+
+// newEventsAPIEventSource returns a new EventSource serving the events api handler on the listenAddr
+func newEventsAPIEventSource(listenAddr string, signingSecret string, sc *slack.Client, log *sLogger) (es *eventsAPIEventSource, err error) {
+	es = new(eventsAPIEventSource)
+	es.events = make(chan slack.RTMEvent, 50)
+	es.authTester = sc
+	es.disconnect = make(chan bool)
+	es.log = log
+	es.webAPIMessageSender = webAPIMessageSender{sender: sc, log: log}
+
+	h, err := NewEventsAPIHandler(signingSecret, es.events, log)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(EventsAPIPath, h)
+	mux.Handle(InteractionsPath, NewInteractionsHandler(signingSecret, es.events, log))
+	mux.Handle(SlashCommandsPath, NewSlashCommandsHandler(signingSecret, es.events, log))
+	es.server = &http.Server{Addr: listenAddr, Handler: mux}
+
+	return es, nil
+}
+
+// IncomingEvents returns the channel on which events are delivered
+func (es *eventsAPIEventSource) IncomingEvents() <-chan slack.RTMEvent {
+	return es.events
+}
+
+// GetInfo returns the info of the bot
+func (es *eventsAPIEventSource) GetInfo() *slack.Info {
+	return es.info
+}
+
+// ManageConnection loads the bot info, signals the connection and serves requests until Disconnect is called
+func (es *eventsAPIEventSource) ManageConnection() {
+	delay := socketModeMinReconnectDelay
+
+	for es.info == nil {
+		info, err := loadSelfInfo(es.authTester)
+		if err != nil {
+			es.log.Printf("Error loading bot info, retrying in [%s]: %v", delay, err)
+
+			select {
+			case <-es.disconnect:
+				return
+			case <-time.After(delay):
+			}
+
+			delay = nextReconnectDelay(delay)
+			continue
+		}
+
+		es.info = info
+	}
+
+	es.events <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{ConnectionCount: 1, Info: es.info}}
+
+	es.log.Printf("Serving events api requests on [%s%s], interactions on [%s%s] and slash commands on [%s%s]", es.server.Addr, EventsAPIPath, es.server.Addr, InteractionsPath, es.server.Addr, SlashCommandsPath)
+	if err := es.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		es.log.Printf("Error serving events api requests: %v", err)
+		es.events <- slack.RTMEvent{Type: "connection_error", Data: &slack.ConnectionErrorEvent{ErrorObj: err}}
+	}
+}
+
+// Disconnect shuts down the http server
+func (es *eventsAPIEventSource) Disconnect() error {
+	es.once.Do(func() {
+		close(es.disconnect)
+	})
+
+	return es.server.Close()
+}
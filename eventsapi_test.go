@@ -0,0 +1,207 @@
+package slackscot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testSigningSecret = "e6b19c573432dcc6b075501d51b51bb8"
+)
+
+func newSignedSlackRequest(t *testing.T, path string, body string, signingSecret string, ts time.Time) (r *http.Request) {
+	r = httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	h := hmac.New(sha256.New, []byte(signingSecret))
+	_, err := h.Write([]byte(fmt.Sprintf("v0:%s:%s", timestamp, body)))
+	require.NoError(t, err)
+
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(h.Sum(nil)))
+
+	return r
+}
+
+func newTestEventsAPIHandler(t *testing.T, events chan slack.RTMEvent) (h *EventsAPIHandler) {
+	h, err := NewEventsAPIHandler(testSigningSecret, events, NewSLogger(log.New(&nullWriter{}, "", 0), false))
+	require.NoError(t, err)
+
+	return h
+}
+
+func newEventCallbackBody(eventID string, event string) string {
+	return fmt.Sprintf(`{"token": "ignored", "team_id": "T1", "api_app_id": "A1", "type": "event_callback", "event_id": "%s", "event_time": 1546833210, "event": %s}`, eventID, event)
+}
+
+func TestEventsAPIURLVerification(t *testing.T) {
+	h := newTestEventsAPIHandler(t, make(chan slack.RTMEvent, 1))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newSignedSlackRequest(t, EventsAPIPath, `{"token": "ignored", "challenge": "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P", "type": "url_verification"}`, testSigningSecret, time.Now()))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P", w.Body.String())
+}
+
+func TestEventsAPIRequestVerification(t *testing.T) {
+	body := newEventCallbackBody("Ev1", `{"type": "message", "channel": "Cgeneral", "user": "Alphonse", "text": "blue jays", "ts": "1546833210.036900"}`)
+
+	tests := map[string]struct {
+		request      func() *http.Request
+		expectedCode int
+	}{
+		"ValidSignature": {
+			request:      func() *http.Request { return newSignedSlackRequest(t, EventsAPIPath, body, testSigningSecret, time.Now()) },
+			expectedCode: http.StatusOK,
+		},
+		"InvalidSignature": {
+			request:      func() *http.Request { return newSignedSlackRequest(t, EventsAPIPath, body, "not the secret", time.Now()) },
+			expectedCode: http.StatusUnauthorized,
+		},
+		"ReplayedRequest": {
+			request: func() *http.Request {
+				return newSignedSlackRequest(t, EventsAPIPath, body, testSigningSecret, time.Now().Add(time.Duration(-10)*time.Minute))
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		"MissingHeaders": {
+			request:      func() *http.Request { return httptest.NewRequest(http.MethodPost, EventsAPIPath, strings.NewReader(body)) },
+			expectedCode: http.StatusUnauthorized,
+		},
+		"NotAPost": {
+			request:      func() *http.Request { return httptest.NewRequest(http.MethodGet, EventsAPIPath, nil) },
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			events := make(chan slack.RTMEvent, 1)
+			h := newTestEventsAPIHandler(t, events)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, tc.request())
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			if tc.expectedCode == http.StatusOK {
+				assert.Len(t, events, 1)
+			} else {
+				assert.Len(t, events, 0)
+			}
+		})
+	}
+}
+
+func TestEventsAPIEventCallbackMappedToMessageEvent(t *testing.T) {
+	events := make(chan slack.RTMEvent, 2)
+	h := newTestEventsAPIHandler(t, events)
+
+	body := newEventCallbackBody("Ev1", `{"type": "message", "subtype": "message_changed", "channel": "Cgeneral", "ts": "1546833214.036900", "message": {"type": "message", "user": "Alphonse", "text": "blue jays eat acorn", "ts": "1546833210.036900"}}`)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newSignedSlackRequest(t, EventsAPIPath, body, testSigningSecret, time.Now()))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A retry of the same event is acknowledged but not delivered twice
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, newSignedSlackRequest(t, EventsAPIPath, body, testSigningSecret, time.Now()))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	if assert.Len(t, events, 1) {
+		e := <-events
+		if me, ok := e.Data.(*slack.MessageEvent); assert.True(t, ok) {
+			assert.Equal(t, "message_changed", me.SubType)
+			assert.Equal(t, "Cgeneral", me.Channel)
+			assert.Equal(t, "1546833214.036900", me.Timestamp)
+			if assert.NotNil(t, me.SubMessage) {
+				assert.Equal(t, "blue jays eat acorn", me.SubMessage.Text)
+				assert.Equal(t, "1546833210.036900", me.SubMessage.Timestamp)
+			}
+		}
+	}
+}
+
+func TestEventsAPIUnsupportedEventAcknowledged(t *testing.T) {
+	events := make(chan slack.RTMEvent, 1)
+	h := newTestEventsAPIHandler(t, events)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newSignedSlackRequest(t, EventsAPIPath, newEventCallbackBody("Ev1", `{"type": "app_home_opened", "user": "Alphonse"}`), testSigningSecret, time.Now()))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, events, 0)
+}
+
+func TestEventsAPIAcknowledgesBeforeSlackTimeout(t *testing.T) {
+	// Unbuffered and never consumed
+	events := make(chan slack.RTMEvent)
+	h := newTestEventsAPIHandler(t, events)
+	h.ackTimeout = time.Duration(10) * time.Millisecond
+
+	body := newEventCallbackBody("Ev1", `{"type": "message", "channel": "Cgeneral", "user": "Alphonse", "text": "blue jays", "ts": "1546833210.036900"}`)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newSignedSlackRequest(t, EventsAPIPath, body, testSigningSecret, time.Now()))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// Slack's retry of the same event should get processed
+	go func() {
+		<-events
+	}()
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, newSignedSlackRequest(t, EventsAPIPath, body, testSigningSecret, time.Now()))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestEventsAPIMessageTriggeringResponse(t *testing.T) {
+	termination := make(chan bool)
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionLog(log.New(&nullWriter{}, "", 0)), OptionTestMode(termination))
+	require.NoError(t, err)
+	s.RegisterPlugin(newTestPlugin())
+
+	events := make(chan slack.RTMEvent)
+	h := newTestEventsAPIHandler(t, events)
+
+	driver := inMemoryChatDriver{timeCursor: firstReplyTimestamp - replyTimeIncrementInSeconds, sentMsgs: make([]sentMessage, 0)}
+	s.workspaces.defaultWorkspace().attach(events, &runDependencies{chatDriver: &driver, userInfoFinder: &userInfoFinder{}, emojiReactor: &emojiReactor{}, selfInfoFinder: &selfFinder{}})
+	go s.runInternal(context.Background())
+
+	events <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newSignedSlackRequest(t, EventsAPIPath, newEventCallbackBody("Ev1", `{"type": "message", "channel": "Cgeneral", "user": "Alphonse", "text": "blue jays", "ts": "1546833210.036900"}`), testSigningSecret, time.Now()))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	events <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: true, Cause: slack.ErrRTMGoodbye}}
+	<-termination
+
+	if assert.Len(t, driver.sentMsgs, 1) {
+		assert.Equal(t, "Cgeneral", driver.sentMsgs[0].channelID)
+
+		vals := applySlackOptions(driver.sentMsgs[0].msgOptions...)
+		assert.Equal(t, "I heard you say something about blue jays?", vals.Get("text"))
+	}
+}
+
+func TestEventsAPIRequiresSigningSecret(t *testing.T) {
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionEventsAPI(":0"))
+	require.NoError(t, err)
+
+	_, err = s.newEventSource(slack.New("xoxb-token"), s.workspaces.defaultWorkspace())
+	assert.EqualError(t, err, "Events API requires a signing secret set in config [signingSecret]")
+}
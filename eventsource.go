@@ -0,0 +1,147 @@
+package slackscot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/slack-go/slack"
+	"reflect"
+)
+
+// EventSource is implemented by any value that delivers slack events to slackscot. The RTM connection
+// is the default but slack doesn't allow new apps to use it anymore so alternatives (like Socket Mode) can be
+// selected with an Option.
+//
+// Events are delivered as slack.RTMEvent regardless of the transport so that the processing by slackscot
+// is the same for all of them
+type EventSource interface {
+	// IncomingEvents returns the channel on which events are delivered
+	IncomingEvents() <-chan slack.RTMEvent
+
+	// ManageConnection connects to slack and keeps the connection alive (reconnecting when needed) until
+	// Disconnect is called. This is blocking and is meant to run in a goroutine
+	ManageConnection()
+
+	// Disconnect terminates the connection
+	Disconnect() error
+
+	// GetInfo returns the info of the connected bot. This is only expected to be set once connected
+	GetInfo() *slack.Info
+
+	// The RealTimeMessageSender injected into plugins
+	RealTimeMessageSender
+}
+
+// EventSourceFactory creates an EventSource given a slack.Client set up with the slackscot token and options. When
+// connected to several workspaces, it is called once per workspace with a slack.Client set up with the token of that
+// workspace
+type EventSourceFactory func(sc *slack.Client) (es EventSource, err error)
+
+// workspaceEventSourceFactory creates the EventSource of a workspace given a slack.Client set up with its token
+type workspaceEventSourceFactory func(sc *slack.Client, ws *workspace) (es EventSource, err error)
+
+// newWorkspaceEventSourceFactory returns a workspaceEventSourceFactory creating event sources with an EventSourceFactory
+func newWorkspaceEventSourceFactory(newEventSource EventSourceFactory) workspaceEventSourceFactory {
+	return func(sc *slack.Client, ws *workspace) (es EventSource, err error) {
+		return newEventSource(sc)
+	}
+}
+
+// rtmEventSource wraps a slack.RTM to implement EventSource
+type rtmEventSource struct {
+	*slack.RTM
+}
+
+// newRTMEventSource returns a new EventSource backed by a slack.RTM connection
+func newRTMEventSource(sc *slack.Client) (es EventSource, err error) {
+	return &rtmEventSource{RTM: sc.NewRTM()}, nil
+}
+
+// IncomingEvents returns the slack.RTM's channel of incoming events
+func (r *rtmEventSource) IncomingEvents() <-chan slack.RTMEvent {
+	return r.RTM.IncomingEvents
+}
+
+// webAPIMessageSender implements RealTimeMessageSender by sending messages via the web api. This is used for
+// event sources where there is no RTM connection to send messages on
+type webAPIMessageSender struct {
+	sender messageSender
+	log    *sLogger
+}
+
+// NewOutgoingMessage creates a new message to send
+func (w *webAPIMessageSender) NewOutgoingMessage(text string, channelID string, options ...slack.RTMsgOption) *slack.OutgoingMessage {
+	outMsg := &slack.OutgoingMessage{Type: "message", Channel: channelID, Text: text}
+	for _, opt := range options {
+		opt(outMsg)
+	}
+
+	return outMsg
+}
+
+// SendMessage sends the message using the web api. Since RealTimeMessageSender doesn't report errors, they are
+// only logged
+func (w *webAPIMessageSender) SendMessage(outMsg *slack.OutgoingMessage) {
+	options := []slack.MsgOption{slack.MsgOptionText(outMsg.Text, false), slack.MsgOptionAsUser(true)}
+	if outMsg.ThreadTimestamp != "" {
+		options = append(options, slack.MsgOptionTS(outMsg.ThreadTimestamp))
+
+		if outMsg.ThreadBroadcast {
+			options = append(options, slack.MsgOptionBroadcast())
+		}
+	}
+
+	if _, _, _, err := w.sender.SendMessage(outMsg.Channel, options...); err != nil {
+		w.log.Printf("Error sending message on channel [%s]: %v", outMsg.Channel, err)
+	}
+}
+
+// authTester is implemented by any value that has the AuthTest method.
+//
+// slack.Client implements this interface
+type authTester interface {
+	AuthTest() (response *slack.AuthTestResponse, err error)
+}
+
+// loadSelfInfo returns the bot's slack.Info as returned by auth.test. This is what event sources other than
+// the RTM use given that they don't get that info on connection
+func loadSelfInfo(tester authTester) (info *slack.Info, err error) {
+	r, err := tester.AuthTest()
+	if err != nil {
+		return nil, err
+	}
+
+	return &slack.Info{URL: r.URL, User: &slack.UserDetails{ID: r.UserID, Name: r.User}, Team: &slack.Team{ID: r.TeamID, Name: r.Team}}, nil
+}
+
+// eventsAPICallback holds the data of an events api event_callback payload
+type eventsAPICallback struct {
+	Type    string          `json:"type"`
+	TeamID  string          `json:"team_id"`
+	EventID string          `json:"event_id"`
+	Event   json.RawMessage `json:"event"`
+}
+
+// newRTMEventFromEventsAPI maps the inner event of an events api callback to the slack.RTMEvent that the RTM
+// would deliver for that same event. Events API inner events share their format with the RTM so this relies on
+// the slack.EventMapping to unmarshal them. If the event type isn't known, supported is false
+func newRTMEventFromEventsAPI(callback eventsAPICallback) (e slack.RTMEvent, supported bool, err error) {
+	var inner struct {
+		Type string `json:"type"`
+	}
+
+	if err = json.Unmarshal(callback.Event, &inner); err != nil {
+		return e, false, err
+	}
+
+	v, supported := slack.EventMapping[inner.Type]
+	if !supported {
+		return e, false, nil
+	}
+
+	data := reflect.New(reflect.TypeOf(v)).Interface()
+	if err = json.Unmarshal(callback.Event, data); err != nil {
+		return e, true, fmt.Errorf("Error unmarshalling event [%s]: %v", inner.Type, err)
+	}
+
+	return slack.RTMEvent{Type: inner.Type, Data: data}, true, nil
+}
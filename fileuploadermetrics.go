@@ -12,29 +12,50 @@ import (
 	"unicode"
 
 	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/label"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // FileUploaderWithTelemetry implements FileUploader interface with all methods wrapped
-// with open telemetry metrics
+// with open telemetry metrics and spans
 type FileUploaderWithTelemetry struct {
 	base                     FileUploader
 	methodCounters           map[string]metric.BoundInt64Counter
 	errCounters              map[string]metric.BoundInt64Counter
 	methodTimeValueRecorders map[string]metric.BoundInt64ValueRecorder
+	tracer                   trace.Tracer
+	ctx                      context.Context
 }
 
 // NewFileUploaderWithTelemetry returns an instance of the FileUploader decorated with open telemetry timing and count metrics
-func NewFileUploaderWithTelemetry(base FileUploader, name string, meter metric.Meter) FileUploaderWithTelemetry {
+// and a span for each call
+func NewFileUploaderWithTelemetry(base FileUploader, name string, meter metric.Meter, tracer trace.Tracer) FileUploaderWithTelemetry {
 	return FileUploaderWithTelemetry{
 		base:                     base,
 		methodCounters:           newFileUploaderMethodCounters("Calls", name, meter),
 		errCounters:              newFileUploaderMethodCounters("Errors", name, meter),
 		methodTimeValueRecorders: newFileUploaderMethodTimeValueRecorders(name, meter),
+		tracer:                   tracer,
 	}
 }
 
+// bindContext returns a copy of the FileUploaderWithTelemetry starting the spans of its calls as children of the span in ctx
+func (_d FileUploaderWithTelemetry) bindContext(ctx context.Context) interface{} {
+	_d.ctx = ctx
+	return _d
+}
+
+// spanParent returns the context holding the parent span of calls
+func (_d FileUploaderWithTelemetry) spanParent() context.Context {
+	if _d.ctx == nil {
+		return context.Background()
+	}
+
+	return _d.ctx
+}
+
 func newFileUploaderMethodTimeValueRecorders(appName string, meter metric.Meter) (boundTimeValueRecorders map[string]metric.BoundInt64ValueRecorder) {
 	boundTimeValueRecorders = make(map[string]metric.BoundInt64ValueRecorder)
 	mt := metric.Must(meter)
@@ -62,10 +83,14 @@ func newFileUploaderMethodCounters(suffix string, appName string, meter metric.M
 // UploadFile implements FileUploader
 func (_d FileUploaderWithTelemetry) UploadFile(params slack.FileUploadParameters, options ...UploadOption) (file *slack.File, err error) {
 	_since := time.Now()
+	_, _span := _d.tracer.Start(_d.spanParent(), "FileUploader.UploadFile", trace.WithSpanKind(trace.SpanKindClient))
 	defer func() {
 		if err != nil {
 			errCounter := _d.errCounters["UploadFile"]
 			errCounter.Add(context.Background(), 1)
+
+			_span.RecordError(err)
+			_span.SetStatus(codes.Error, err.Error())
 		}
 
 		methodCounter := _d.methodCounters["UploadFile"]
@@ -73,6 +98,8 @@ func (_d FileUploaderWithTelemetry) UploadFile(params slack.FileUploadParameters
 
 		methodTimeMeasure := _d.methodTimeValueRecorders["UploadFile"]
 		methodTimeMeasure.Record(context.Background(), time.Since(_since).Milliseconds())
+
+		_span.End()
 	}()
 	return _d.base.UploadFile(params, options...)
 }
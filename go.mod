@@ -4,6 +4,7 @@ require (
 	cloud.google.com/go v0.38.0
 	github.com/alexandre-normand/figlet4go v1.0.0
 	github.com/fatih/color v1.7.0 // indirect
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/gorilla/websocket v1.4.1
 	github.com/hashicorp/golang-lru v0.5.1
 	github.com/magiconair/properties v1.8.1 // indirect
@@ -26,6 +27,7 @@ require (
 	github.com/syndtr/goleveldb v0.0.0-20190203031304-2f17a3356c66
 	go.opentelemetry.io/otel v0.17.0
 	go.opentelemetry.io/otel/metric v0.17.0
+	go.opentelemetry.io/otel/trace v0.17.0
 	golang.org/x/net v0.0.0-20190923162816-aa69164e4478 // indirect
 	golang.org/x/sys v0.0.0-20191210023423-ac6580df4449 // indirect
 	google.golang.org/api v0.20.0
@@ -15,12 +15,21 @@ type helpPlugin struct {
 	timeLocation           string
 	commands               map[string][]ActionDefinition
 	hearActions            []ActionDefinition
+	reactionActions        []ReactionActionDefinition
 	pluginScheduledActions []pluginScheduledAction
 	cmdPrefix              string
+
+	// Authorization check used to omit actions the requester isn't authorized to run
+	isAuthorized func(m *IncomingMessage, requiredRoles []string) bool
+
+	// Routing mode and explanation of the routing of messages
+	isExclusiveRouting func() bool
+	explainRouting     func(m *IncomingMessage, text string) string
 }
 
 const (
 	helpPluginName = "help"
+	explainCmd     = "explain "
 )
 
 // pluginScheduledAction represents a plugin's scheduled action with the plugin name and the action's definition
@@ -31,6 +40,7 @@ type pluginScheduledAction struct {
 
 func (s *Slackscot) newHelpPlugin(version string) *helpPlugin {
 	commands, hearActions, scheduledActions := findAllActions(s.namespaceCommands, s.plugins)
+	reactionActions := findAllReactionActions(s.plugins)
 
 	helpPlugin := new(helpPlugin)
 	helpPlugin.timeLocation = s.config.GetString(config.TimeLocationKey)
@@ -38,8 +48,12 @@ func (s *Slackscot) newHelpPlugin(version string) *helpPlugin {
 	helpPlugin.slackscotVersion = version
 	helpPlugin.commands = commands
 	helpPlugin.hearActions = hearActions
+	helpPlugin.reactionActions = reactionActions
 	helpPlugin.pluginScheduledActions = scheduledActions
-	helpPlugin.cmdPrefix = s.cmdMatcher.UsagePrefix()
+	helpPlugin.cmdPrefix = s.cmdMatcherFor(s.workspaces.defaultWorkspace()).UsagePrefix()
+	helpPlugin.isAuthorized = s.isAuthorized
+	helpPlugin.isExclusiveRouting = s.isExclusiveRouting
+	helpPlugin.explainRouting = s.explainRouting
 
 	helpPlugin.Plugin = Plugin{Name: helpPluginName, Commands: []ActionDefinition{{
 		Match: func(m *IncomingMessage) bool {
@@ -48,13 +62,22 @@ func (s *Slackscot) newHelpPlugin(version string) *helpPlugin {
 		Usage:       helpPluginName,
 		Description: "Reply with usage instructions",
 		Answer:      helpPlugin.showHelp,
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, explainCmd)
+		},
+		Usage:       "explain `<message>`",
+		Description: "Explain which actions answer a message",
+		Answer:      helpPlugin.explain,
+		Priority:    explainPriority,
 	}}, HearActions: nil}
 
 	return helpPlugin
 }
 
 // showHelp generates a message providing a list of all of the slackscot commands and hear actions.
-// Note that ActionDefinitions with the flag Hidden set to true won't be included in the list
+// Note that ActionDefinitions with the flag Hidden set to true or requiring roles the requester doesn't have
+// won't be included in the list
 func (h *helpPlugin) showHelp(m *IncomingMessage) *Answer {
 	var b strings.Builder
 
@@ -69,18 +92,35 @@ func (h *helpPlugin) showHelp(m *IncomingMessage) *Answer {
 
 	fmt.Fprintf(&b, "I'm `%s` (engine `v%s`) and I listen to the team's chat and provides automated functions :genie:.\n", h.name, h.slackscotVersion)
 
-	if lenCommands(h.commands) > 0 {
+	commands := make(map[string][]ActionDefinition)
+	for n, actions := range h.commands {
+		commands[n] = h.filterAuthorizedActions(m, actions)
+	}
+
+	if lenCommands(commands) > 0 {
 		fmt.Fprintf(&b, "\nI currently support the following commands:\n")
 
-		for n, commands := range h.commands {
+		for n, commands := range commands {
 			appendActions(&b, h.cmdPrefix, n, commands)
 		}
 	}
 
-	if len(h.hearActions) > 0 {
+	hearActions := h.filterAuthorizedActions(m, h.hearActions)
+	if len(hearActions) > 0 {
 		fmt.Fprintf(&b, "\nAnd listen for the following:\n")
 
-		appendActions(&b, "", "", h.hearActions)
+		appendActions(&b, "", "", hearActions)
+	}
+
+	if h.isExclusiveRouting() {
+		fmt.Fprintf(&b, "\nWhen several of those match a message, only the one with the highest priority answers. Try `%s%s<message>` to see which one does\n", h.cmdPrefix, explainCmd)
+	}
+
+	reactionActions := h.filterAuthorizedReactionActions(m, h.reactionActions)
+	if len(reactionActions) > 0 {
+		fmt.Fprintf(&b, "\nAnd react to the following reactions:\n")
+
+		appendReactionActions(&b, reactionActions)
 	}
 
 	if len(h.pluginScheduledActions) > 0 {
@@ -92,6 +132,13 @@ func (h *helpPlugin) showHelp(m *IncomingMessage) *Answer {
 	return &Answer{Text: b.String(), Options: []AnswerOption{AnswerInThread()}}
 }
 
+// explain replies with the explanation of the routing of the message following the explain command
+func (h *helpPlugin) explain(m *IncomingMessage) *Answer {
+	text := strings.TrimSpace(strings.TrimPrefix(m.NormalizedText, explainCmd))
+
+	return &Answer{Text: h.explainRouting(m, text), Options: []AnswerOption{AnswerInThread()}}
+}
+
 // lenCommands returns the length of a map of string to array of values by summing
 // up the length of all array values
 func lenCommands(entries map[string][]ActionDefinition) (length int) {
@@ -107,14 +154,31 @@ func appendActions(w io.Writer, prefix string, pluginNamespace string, actions [
 	for _, value := range actions {
 		if value.Usage != "" && !value.Hidden {
 			if len(pluginNamespace) > 0 {
-				fmt.Fprintf(w, "\t• `%s%s %s` - %s\n", prefix, pluginNamespace, value.Usage, value.Description)
+				fmt.Fprintf(w, "\t• `%s%s %s` - %s%s\n", prefix, pluginNamespace, value.Usage, value.Description, describeNonDefaultRouting(value))
 			} else {
-				fmt.Fprintf(w, "\t• `%s%s` - %s\n", prefix, value.Usage, value.Description)
+				fmt.Fprintf(w, "\t• `%s%s` - %s%s\n", prefix, value.Usage, value.Description, describeNonDefaultRouting(value))
 			}
 		}
 	}
 }
 
+// describeNonDefaultRouting describes the priority and exclusivity of an action, if either isn't the default
+func describeNonDefaultRouting(action ActionDefinition) (description string) {
+	if action.Priority == 0 && !action.Exclusive {
+		return ""
+	}
+
+	return " " + describeRouting(action)
+}
+
+func appendReactionActions(w io.Writer, actions []ReactionActionDefinition) {
+	for _, value := range actions {
+		if value.Usage != "" {
+			fmt.Fprintf(w, "\t• `%s` - %s\n", value.Usage, value.Description)
+		}
+	}
+}
+
 func appendScheduledActions(w io.Writer, timeLocationName string, scheduledActions []pluginScheduledAction) {
 	for _, value := range scheduledActions {
 		if !value.ScheduledActionDefinition.Hidden {
@@ -146,6 +210,30 @@ func findAllActions(namespaceCommands bool, plugins []*Plugin) (commands map[str
 	return commands, hearActions, pluginScheduledActions
 }
 
+// filterAuthorizedActions returns the actions the author of the message is authorized to run
+func (h *helpPlugin) filterAuthorizedActions(m *IncomingMessage, actions []ActionDefinition) (authorizedActions []ActionDefinition) {
+	authorizedActions = make([]ActionDefinition, 0)
+	for _, a := range actions {
+		if h.isAuthorized(m, a.RequiredRoles) {
+			authorizedActions = append(authorizedActions, a)
+		}
+	}
+
+	return authorizedActions
+}
+
+// filterAuthorizedReactionActions returns the reaction actions the author of the message is authorized to run
+func (h *helpPlugin) filterAuthorizedReactionActions(m *IncomingMessage, actions []ReactionActionDefinition) (authorizedActions []ReactionActionDefinition) {
+	authorizedActions = make([]ReactionActionDefinition, 0)
+	for _, a := range actions {
+		if h.isAuthorized(m, a.RequiredRoles) {
+			authorizedActions = append(authorizedActions, a)
+		}
+	}
+
+	return authorizedActions
+}
+
 func filterNonHiddenActions(actions []ActionDefinition) (visibleActions []ActionDefinition) {
 	visibleActions = make([]ActionDefinition, 0)
 	for _, a := range actions {
@@ -168,3 +256,17 @@ func filterNonHiddenScheduledActions(pluginName string, actions []ScheduledActio
 
 	return visibleActions
 }
+
+func findAllReactionActions(plugins []*Plugin) (reactionActions []ReactionActionDefinition) {
+	reactionActions = make([]ReactionActionDefinition, 0)
+
+	for _, p := range plugins {
+		for _, ra := range p.ReactionActions {
+			if !ra.Hidden {
+				reactionActions = append(reactionActions, ra)
+			}
+		}
+	}
+
+	return reactionActions
+}
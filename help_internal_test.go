@@ -218,3 +218,34 @@ func TestHelpWithNamespacingDisabledWithCommandOptionPrefix(t *testing.T) {
 		"\t• `say `chickadee` and hear a chirp` - Chirp when hearing people talk about chickadees\n\nAnd do those things periodically:\n"+
 		"\t• [`thank`] `Every 30 seconds` (`Local`) - Sends a heartbeat every 30 seconds\n", a.Text)
 }
+
+func TestHelpOmitsActionsRequesterIsNotAuthorizedToRun(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.AuthorizationAdminsKey, []string{"Alphonse"})
+
+	s, err := New("robert", v, OptionNoPluginNamespacing())
+	require.NoError(t, err)
+
+	p := newPluginWithActionsOfAllTypes(false)
+	p.Commands[0].RequiredRoles = []string{AdminRole}
+	p.ScheduledActions = nil
+	s.RegisterPlugin(p)
+
+	help := s.newHelpPlugin("1.0.0")
+	help.UserInfoFinder = &userInfoFinder{}
+
+	cmd := help.Commands[0]
+	a := cmd.Answer(&IncomingMessage{NormalizedText: "help", Msg: slack.Msg{User: "Alphonse"}})
+	require.NotNil(t, a)
+
+	assert.Equal(t, "🤝 Hi, `Daniel Quinn`! I'm `robert` (engine `v1.0.0`) and I listen to the team's chat and provides automated functions :genie:.\n\n"+
+		"I currently support the following commands:\n\t• `<someone of something to thank>` - Format a thank you note\n\nAnd listen for the following:\n"+
+		"\t• `say `chickadee` and hear a chirp` - Chirp when hearing people talk about chickadees\n", a.Text)
+
+	a = cmd.Answer(&IncomingMessage{NormalizedText: "help", Msg: slack.Msg{User: "Jane"}})
+	require.NotNil(t, a)
+
+	assert.Equal(t, "🤝 Hi, `Daniel Quinn`! I'm `robert` (engine `v1.0.0`) and I listen to the team's chat and provides automated functions :genie:.\n\n"+
+		"And listen for the following:\n"+
+		"\t• `say `chickadee` and hear a chirp` - Chirp when hearing people talk about chickadees\n", a.Text)
+}
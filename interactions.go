@@ -0,0 +1,275 @@
+package slackscot
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/slack-go/slack"
+	"github.com/spf13/cast"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// InteractionsPath is the path on which the interactions handler is served when running with OptionEventsAPI. This is what
+	// the slack app's interactivity Request URL should point to
+	InteractionsPath = "/slack/interactions"
+
+	interactionPayloadField       = "payload"
+	interactionNamespaceSeparator = "."
+)
+
+// IncomingInteraction holds the data of a user interaction with an interactive Block Kit component (i.e. a button click or
+// an option selection) of a message sent by a plugin
+type IncomingInteraction struct {
+	TeamID           string            // The id of the team (workspace) the interaction happened in
+	ActionID         string            // The action id of the component, stripped of its plugin namespace (see NewInteractionActionID)
+	BlockID          string            // The id of the block holding the component
+	Value            string            // The value of the button clicked or of the option selected, if applicable
+	User             string            // The user who interacted with the component
+	Channel          string            // The channel of the message holding the component
+	MessageTimestamp string            // The timestamp of the message holding the component
+	ThreadTimestamp  string            // The thread timestamp of the message holding the component, if in a thread
+	ResponseURL      string            // The url to which responses to the interaction can be sent for up to 30 minutes
+	TriggerID        string            // The trigger id that can be used to open a modal
+	Action           slack.BlockAction // The block action as received from slack (with its namespaced action id)
+
+	// The name of the plugin the action id is namespaced with
+	namespace string
+}
+
+// InteractionMatcher is the function that determines whether or not an interaction action should be triggered
+// by an IncomingInteraction
+type InteractionMatcher func(i *IncomingInteraction) bool
+
+// InteractionAnswerer is what gets executed when an interaction action is triggered. By default, the answer is sent
+// as a new message on the channel of the message holding the component. Use AnswerUpdatingOriginal to update that message
+// instead or AnswerEphemeral to only show the answer to the user who interacted with it. To signal the absence of an answer,
+// an action should return nil
+type InteractionAnswerer func(i *IncomingInteraction) *Answer
+
+// InteractionContextAnswerer is the context-aware variant of an InteractionAnswerer. The context is done when the action times out
+type InteractionContextAnswerer func(ctx context.Context, i *IncomingInteraction) *Answer
+
+// InteractionActionDefinition represents how an interaction action is triggered and described along with its actual
+// action implementation. Interaction actions are only ever triggered by interactions with components having an action id
+// namespaced with the name of their plugin (see NewInteractionActionID)
+type InteractionActionDefinition struct {
+	// Matcher that will determine whether or not the action should be triggered
+	Match InteractionMatcher
+
+	// Description of the action
+	Description string
+
+	// Function to execute if the Matcher matches
+	Answer InteractionAnswerer
+
+	// Function to execute if the Matcher matches with a context.Context that is done when the action's
+	// execution times out. When set, it is used instead of Answer
+	ContextAnswer InteractionContextAnswerer
+
+	// Maximum execution time of the action after which its answer is abandoned. If zero,
+	// the config.ActionTimeoutKey configuration applies
+	Timeout time.Duration
+
+	// Roles the user who interacted must have to run the action (see ActionDefinition.RequiredRoles)
+	RequiredRoles []string
+}
+
+// answer invokes the ContextAnswer, if set, or the Answer
+func (a InteractionActionDefinition) answer(ctx context.Context, i *IncomingInteraction) *Answer {
+	if a.ContextAnswer != nil {
+		return a.ContextAnswer(ctx, i)
+	}
+
+	return a.Answer(i)
+}
+
+// NewInteractionActionID returns the action id to set on an interactive Block Kit component (i.e. a slack.ButtonBlockElement)
+// so that interactions with it get routed to the interaction actions of the named plugin. Interaction actions see the
+// original actionID as the IncomingInteraction's ActionID
+func NewInteractionActionID(pluginName string, actionID string) (namespacedActionID string) {
+	return pluginName + interactionNamespaceSeparator + actionID
+}
+
+// splitInteractionActionID splits a namespaced action id into its plugin name and action id. ok is false if the
+// action id isn't namespaced
+func splitInteractionActionID(namespacedActionID string) (pluginName string, actionID string, ok bool) {
+	parts := strings.SplitN(namespacedActionID, interactionNamespaceSeparator, 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// newIncomingInteractions returns an IncomingInteraction for each namespaced block action of a block_actions interaction
+// callback. Other types of callbacks aren't supported and yield no interaction. The team id of the callback is used if
+// set and the defaultTeamID otherwise
+func newIncomingInteractions(defaultTeamID string, callback *slack.InteractionCallback) (interactions []IncomingInteraction) {
+	interactions = make([]IncomingInteraction, 0)
+
+	if callback.Type != slack.InteractionTypeBlockActions {
+		return interactions
+	}
+
+	teamID := callback.Team.ID
+	if teamID == "" {
+		teamID = defaultTeamID
+	}
+
+	for _, a := range callback.ActionCallback.BlockActions {
+		pluginName, actionID, ok := splitInteractionActionID(a.ActionID)
+		if !ok {
+			continue
+		}
+
+		value := a.Value
+		if value == "" {
+			value = a.SelectedOption.Value
+		}
+
+		interactions = append(interactions, IncomingInteraction{TeamID: teamID, ActionID: actionID, BlockID: a.BlockID, Value: value, User: callback.User.ID,
+			Channel: callback.Channel.ID, MessageTimestamp: callback.Message.Timestamp, ThreadTimestamp: callback.Message.ThreadTimestamp,
+			ResponseURL: callback.ResponseURL, TriggerID: callback.TriggerID, Action: *a, namespace: pluginName})
+	}
+
+	return interactions
+}
+
+// newRTMEventFromInteraction maps an interaction payload to a slack.RTMEvent holding a *slack.InteractionCallback
+func newRTMEventFromInteraction(payload []byte) (e slack.RTMEvent, err error) {
+	var callback slack.InteractionCallback
+	if err = json.Unmarshal(payload, &callback); err != nil {
+		return e, err
+	}
+
+	return slack.RTMEvent{Type: string(callback.Type), Data: &callback}, nil
+}
+
+// InteractionsHandler is an http.Handler receiving interactions with Block Kit components. It verifies that requests are
+// signed by slack and publishes interactions on an events channel to be processed like any other slack event
+type InteractionsHandler struct {
+	signingSecret string
+	events        chan<- slack.RTMEvent
+	ackTimeout    time.Duration
+	log           SLogger
+}
+
+// NewInteractionsHandler returns a new InteractionsHandler verifying requests with the given signingSecret and publishing
+// interactions on the events channel
+func NewInteractionsHandler(signingSecret string, events chan<- slack.RTMEvent, logger SLogger) (h *InteractionsHandler) {
+	h = new(InteractionsHandler)
+	h.signingSecret = signingSecret
+	h.events = events
+	h.ackTimeout = eventsAPIAckTimeout
+	h.log = logger
+
+	return h
+}
+
+// ServeHTTP handles an interaction request
+func (h *InteractionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := readVerifiedBody(r, h.signingSecret)
+	if err != nil {
+		h.log.Printf("Rejecting interaction request: %v", err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		h.log.Printf("Error parsing interaction request [%s]: %v", string(body), err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	payload := values.Get(interactionPayloadField)
+	e, err := newRTMEventFromInteraction([]byte(payload))
+	if err != nil {
+		h.log.Printf("Error unmarshalling interaction payload [%s]: %v", payload, err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case h.events <- e:
+	case <-time.After(h.ackTimeout):
+		h.log.Printf("Timed out publishing interaction of type [%s]", e.Type)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// processInteraction runs the interaction actions of the plugin the interaction is namespaced with and sends their answers
+func (s *Slackscot) processInteraction(ctx context.Context, driver chatDriver, i IncomingInteraction) {
+	for _, p := range s.plugins {
+		if p.Name != i.namespace {
+			continue
+		}
+
+		for _, o := range s.tryPluginInteractionActions(ctx, p, i) {
+			if err := s.sendInteractionAnswer(driver, i, o); err != nil {
+				s.log.Printf("Unable to send answer to interaction [%s] on [%s/%s]: %v\n", i.Action.ActionID, i.Channel, i.MessageTimestamp, err)
+			}
+		}
+	}
+}
+
+// sendInteractionAnswer sends the answer to an interaction by updating the message holding the component, if the answer
+// is set to do so, or by sending it as a new message otherwise
+func (s *Slackscot) sendInteractionAnswer(driver chatDriver, i IncomingInteraction, o OutgoingMessage) (err error) {
+	sendOpts := ApplyAnswerOpts(o.Options...)
+	if cast.ToBool(sendOpts[UpdateOriginalOpt]) {
+		_, err = s.updateExistingMessage(driver, SlackMessageID{teamID: i.TeamID, channelID: i.Channel, timestamp: i.MessageTimestamp}, o)
+		return err
+	}
+
+	threadTS := i.ThreadTimestamp
+	if threadTS == "" {
+		threadTS = i.MessageTimestamp
+	}
+
+	_, err = s.sendNewMessage(driver, o, threadTS)
+	return err
+}
+
+// tryPluginInteractionActions invokes all interaction actions of a plugin matching the interaction and returns their answers
+func (s *Slackscot) tryPluginInteractionActions(ctx context.Context, p *Plugin, i IncomingInteraction) (outMsgs []OutgoingMessage) {
+	return s.tryEventActions(ctx, newInteractionActionCandidates(p, i))
+}
+
+// newInteractionActionCandidates returns the interaction actions of a plugin as candidates to answer an interaction. The candidates'
+// message stands for the interaction: it's from the user who interacted and on the message holding the component
+func newInteractionActionCandidates(p *Plugin, i IncomingInteraction) (candidates []actionCandidate) {
+	m := IncomingMessage{TeamID: i.TeamID, Msg: slack.Msg{Type: "message", Team: i.TeamID, Channel: i.Channel, User: i.User, Timestamp: i.MessageTimestamp}}
+
+	candidates = make([]actionCandidate, 0, len(p.InteractionActions))
+	for index, action := range p.InteractionActions {
+		candidates = append(candidates, actionCandidate{p: p, actionType: InteractionActionType, actionID: getActionID(p.Name, InteractionActionType, index), action: newInteractionAction(action, i), msg: m})
+	}
+
+	return candidates
+}
+
+// newInteractionAction returns the ActionDefinition running an interaction action with its own copy of the interaction
+func newInteractionAction(action InteractionActionDefinition, i IncomingInteraction) (a ActionDefinition) {
+	return ActionDefinition{
+		Match: func(m *IncomingMessage) bool {
+			return action.Match(&i)
+		},
+		ContextAnswer: func(ctx context.Context, m *IncomingMessage) *Answer {
+			return action.answer(ctx, &i)
+		},
+		Timeout:       action.Timeout,
+		RequiredRoles: action.RequiredRoles,
+	}
+}
@@ -0,0 +1,230 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newBlockActionsPayload(actionID string, value string, user string, channel string, ts string) string {
+	return fmt.Sprintf(`{"type": "block_actions", "trigger_id": "T123", "response_url": "https://hooks.slack.com/actions/T1/1/abc", "user": {"id": "%s"}, "channel": {"id": "%s"}, "message": {"type": "message", "ts": "%s", "text": "Vote"}, "actions": [{"action_id": "%s", "block_id": "vote", "type": "button", "value": "%s", "action_ts": "1546833220.036900"}]}`, user, channel, ts, actionID, value)
+}
+
+func newInteractionEvent(payload string) (e slack.RTMEvent) {
+	e, _ = newRTMEventFromInteraction([]byte(payload))
+
+	return e
+}
+
+func newInteractionPlugin(interactions *[]IncomingInteraction) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "poll"
+	p.InteractionActions = []InteractionActionDefinition{{
+		Match: func(i *IncomingInteraction) bool {
+			return i.ActionID == "vote"
+		},
+		Description: "Record a vote",
+		Answer: func(i *IncomingInteraction) *Answer {
+			*interactions = append(*interactions, *i)
+
+			return &Answer{Text: fmt.Sprintf("<@%s> voted for %s", i.User, i.Value), Options: []AnswerOption{AnswerUpdatingOriginal()}}
+		},
+	}, {
+		Match: func(i *IncomingInteraction) bool {
+			return i.ActionID == "results"
+		},
+		Description: "Show the results",
+		Answer: func(i *IncomingInteraction) *Answer {
+			return &Answer{Text: "No votes yet", Options: []AnswerOption{AnswerEphemeral(i.User)}}
+		},
+	}, {
+		Match: func(i *IncomingInteraction) bool {
+			return i.ActionID == "share"
+		},
+		Description: "Share the poll",
+		Answer: func(i *IncomingInteraction) *Answer {
+			return &Answer{Text: "Come vote!", Options: []AnswerOption{AnswerInThread()}}
+		},
+	}}
+
+	return p
+}
+
+func TestInteractionActions(t *testing.T) {
+	interactions := make([]IncomingInteraction, 0)
+
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, nil, newInteractionPlugin(&interactions), []slack.RTMEvent{
+		newInteractionEvent(newBlockActionsPayload(NewInteractionActionID("poll", "vote"), "chickadees", "Alphonse", "Cgeneral", timestamp1)),
+		newInteractionEvent(newBlockActionsPayload(NewInteractionActionID("poll", "results"), "", "Alphonse", "Cgeneral", timestamp1)),
+		newInteractionEvent(newBlockActionsPayload(NewInteractionActionID("poll", "share"), "", "Alphonse", "Cgeneral", timestamp1)),
+	}, nil)
+
+	require.Len(t, interactions, 1)
+	assert.Equal(t, "vote", interactions[0].ActionID)
+	assert.Equal(t, "vote", interactions[0].BlockID)
+	assert.Equal(t, "chickadees", interactions[0].Value)
+	assert.Equal(t, "Alphonse", interactions[0].User)
+	assert.Equal(t, "Cgeneral", interactions[0].Channel)
+	assert.Equal(t, timestamp1, interactions[0].MessageTimestamp)
+	assert.Equal(t, "T123", interactions[0].TriggerID)
+	assert.Equal(t, "https://hooks.slack.com/actions/T1/1/abc", interactions[0].ResponseURL)
+	assert.Equal(t, "poll.vote", interactions[0].Action.ActionID)
+
+	if assert.Equal(t, 1, len(updatedMsgs)) {
+		assert.Equal(t, "Cgeneral", updatedMsgs[0].channelID)
+		assert.Equal(t, timestamp1, updatedMsgs[0].timestamp)
+		vals := applySlackOptions(updatedMsgs[0].msgOptions...)
+		assert.Equal(t, "<@Alphonse> voted for chickadees", vals.Get("text"))
+	}
+
+	if assert.Equal(t, 2, len(sentMsgs)) {
+		assert.Equal(t, "Cgeneral", sentMsgs[0].channelID)
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "No votes yet", vals.Get("text"))
+		assert.Equal(t, "Alphonse", vals.Get("user"))
+
+		assert.Equal(t, "Cgeneral", sentMsgs[1].channelID)
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "Come vote!", vals.Get("text"))
+		assert.Equal(t, timestamp1, vals.Get("thread_ts"))
+	}
+
+	assert.Equal(t, 0, len(deletedMsgs))
+}
+
+func TestInteractionActionsRunThroughMiddlewaresAndAuthorization(t *testing.T) {
+	v := newAuthorizationConfig()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	invocations := make([]string, 0)
+	recordInvocation := func(next ActionInvoker) ActionInvoker {
+		return func(ctx context.Context, inv *ActionInvocation) *Answer {
+			invocations = append(invocations, fmt.Sprintf("%s by %s on %s", inv.ActionID, inv.Message.User, inv.Message.Channel))
+			return next(ctx, inv)
+		}
+	}
+
+	interactions := make([]IncomingInteraction, 0)
+	p := newInteractionPlugin(&interactions)
+	p.InteractionActions[1].RequiredRoles = []string{AdminRole}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, p, []slack.RTMEvent{
+		newInteractionEvent(newBlockActionsPayload(NewInteractionActionID("poll", "results"), "", "Alphonse", "Cgeneral", timestamp1)),
+		newInteractionEvent(newBlockActionsPayload(NewInteractionActionID("poll", "results"), "", "Jane", "Cgeneral", timestamp1)),
+	}, nil, OptionMiddleware(recordInvocation))
+
+	assert.Equal(t, []string{"poll.interactionAction[0] by Alphonse on Cgeneral", "poll.interactionAction[1] by Alphonse on Cgeneral", "poll.interactionAction[2] by Alphonse on Cgeneral",
+		"poll.interactionAction[0] by Jane on Cgeneral", "poll.interactionAction[1] by Jane on Cgeneral", "poll.interactionAction[2] by Jane on Cgeneral"}, invocations)
+	assert.Equal(t, []string{"No votes yet", "🚫 Sorry, you're not authorized to do that"}, sentTexts(sentMsgs))
+}
+
+func TestInteractionActionTimeout(t *testing.T) {
+	interactions := make([]IncomingInteraction, 0)
+	p := newInteractionPlugin(&interactions)
+	p.InteractionActions[0].Timeout = time.Duration(10) * time.Millisecond
+	p.InteractionActions[0].ContextAnswer = func(ctx context.Context, i *IncomingInteraction) *Answer {
+		<-ctx.Done()
+		return &Answer{Text: "Voted too late"}
+	}
+
+	sentMsgs, updatedMsgs, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, nil, p, []slack.RTMEvent{
+		newInteractionEvent(newBlockActionsPayload(NewInteractionActionID("poll", "vote"), "chickadees", "Alphonse", "Cgeneral", timestamp1)),
+	})
+
+	assert.Empty(t, sentMsgs)
+	assert.Empty(t, updatedMsgs)
+	assert.Contains(t, logs, "Action [poll.interactionAction[0]] didn't complete within [10ms], abandoning its answer: context deadline exceeded")
+}
+
+func TestInteractionRoutedToNamespacedPluginOnly(t *testing.T) {
+	interactions := make([]IncomingInteraction, 0)
+
+	sentMsgs, updatedMsgs, _, _ := runSlackscotWithIncomingEvents(t, nil, newInteractionPlugin(&interactions), []slack.RTMEvent{
+		newInteractionEvent(newBlockActionsPayload(NewInteractionActionID("survey", "vote"), "chickadees", "Alphonse", "Cgeneral", timestamp1)),
+		newInteractionEvent(newBlockActionsPayload("vote", "chickadees", "Alphonse", "Cgeneral", timestamp1)),
+	}, nil)
+
+	assert.Empty(t, interactions)
+	assert.Equal(t, 0, len(sentMsgs))
+	assert.Equal(t, 0, len(updatedMsgs))
+}
+
+func TestNewIncomingInteractionsIgnoresOtherCallbackTypes(t *testing.T) {
+	e := newInteractionEvent(`{"type": "view_submission", "user": {"id": "Alphonse"}}`)
+
+	callback, ok := e.Data.(*slack.InteractionCallback)
+	require.True(t, ok)
+	assert.Empty(t, newIncomingInteractions("", callback))
+}
+
+func TestInteractionsHandler(t *testing.T) {
+	payload := newBlockActionsPayload(NewInteractionActionID("poll", "vote"), "chickadees", "Alphonse", "Cgeneral", timestamp1)
+	body := url.Values{interactionPayloadField: []string{payload}}.Encode()
+
+	tests := map[string]struct {
+		request      func() *http.Request
+		expectedCode int
+		published    bool
+	}{
+		"valid": {func() *http.Request {
+			return newSignedSlackRequest(t, InteractionsPath, body, testSigningSecret, time.Now())
+		}, http.StatusOK, true},
+		"badSignature": {func() *http.Request {
+			return newSignedSlackRequest(t, InteractionsPath, body, "wrong", time.Now())
+		}, http.StatusUnauthorized, false},
+		"expiredTimestamp": {func() *http.Request {
+			return newSignedSlackRequest(t, InteractionsPath, body, testSigningSecret, time.Now().Add(time.Duration(-10)*time.Minute))
+		}, http.StatusUnauthorized, false},
+		"invalidPayload": {func() *http.Request {
+			return newSignedSlackRequest(t, InteractionsPath, url.Values{interactionPayloadField: []string{"{"}}.Encode(), testSigningSecret, time.Now())
+		}, http.StatusBadRequest, false},
+		"get": {func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, InteractionsPath, nil)
+		}, http.StatusMethodNotAllowed, false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			events := make(chan slack.RTMEvent, 1)
+			h := NewInteractionsHandler(testSigningSecret, events, NewSLogger(log.New(&nullWriter{}, "", 0), false))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, tc.request())
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			if tc.published {
+				require.Len(t, events, 1)
+				e := <-events
+				assert.Equal(t, "block_actions", e.Type)
+
+				callback, ok := e.Data.(*slack.InteractionCallback)
+				require.True(t, ok)
+				require.Len(t, callback.ActionCallback.BlockActions, 1)
+				assert.Equal(t, "poll.vote", callback.ActionCallback.BlockActions[0].ActionID)
+			} else {
+				assert.Len(t, events, 0)
+			}
+		})
+	}
+}
+
+func TestInteractionsHandlerTimesOutPublishing(t *testing.T) {
+	h := NewInteractionsHandler(testSigningSecret, make(chan slack.RTMEvent), NewSLogger(log.New(&nullWriter{}, "", 0), false))
+	h.ackTimeout = time.Duration(10) * time.Millisecond
+
+	body := url.Values{interactionPayloadField: []string{newBlockActionsPayload("poll.vote", "chickadees", "Alphonse", "Cgeneral", timestamp1)}}.Encode()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newSignedSlackRequest(t, InteractionsPath, body, testSigningSecret, time.Now()))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
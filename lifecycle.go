@@ -0,0 +1,137 @@
+package slackscot
+
+import (
+	"fmt"
+)
+
+// PluginLifecycle is implemented by plugins that need to do work once slackscot's services are available (warm caches,
+// open connections, validate channel ids, etc.) or on shutdown. Set it as the Lifecycle of a Plugin
+type PluginLifecycle interface {
+	// Init is called once the services are injected in the plugin and before any event is processed. An error fails Run
+	Init() error
+
+	// Start is called once connected to a workspace and its self identity is loaded, on the first connection as well
+	// as on reconnections. It's called from the loop receiving events so it should return promptly. An error fails Run
+	Start(c Connection) error
+
+	// Stop is called on shutdown after the processing of events and scheduled actions is done and before closers
+	// are closed. Plugins are stopped in the reverse order of their initialization
+	Stop() error
+}
+
+// Connection is a connection to a workspace as given to the Start hook of plugins
+type Connection struct {
+	// The name of the workspace in the configuration. Empty for the workspace defined by config.TokenKey
+	WorkspaceName string
+
+	// The team id of the workspace
+	TeamID string
+
+	// True when connected again to the workspace after a disconnection
+	Reconnected bool
+
+	// The services of the workspace
+	Services *WorkspaceServices
+}
+
+// LifecycleHooks is a PluginLifecycle calling its hooks. Hooks left nil do nothing
+type LifecycleHooks struct {
+	OnInit  func() error
+	OnStart func(c Connection) error
+	OnStop  func() error
+}
+
+// Init calls the OnInit hook, if set
+func (lh LifecycleHooks) Init() (err error) {
+	if lh.OnInit == nil {
+		return nil
+	}
+
+	return lh.OnInit()
+}
+
+// Start calls the OnStart hook, if set
+func (lh LifecycleHooks) Start(c Connection) (err error) {
+	if lh.OnStart == nil {
+		return nil
+	}
+
+	return lh.OnStart(c)
+}
+
+// Stop calls the OnStop hook, if set
+func (lh LifecycleHooks) Stop() (err error) {
+	if lh.OnStop == nil {
+		return nil
+	}
+
+	return lh.OnStop()
+}
+
+// initPlugins initializes all plugins having a Lifecycle and keeps them to be started and stopped. It stops at
+// the first plugin failing to initialize
+func (s *Slackscot) initPlugins() (err error) {
+	for _, p := range s.plugins {
+		if p.Lifecycle == nil {
+			continue
+		}
+
+		if err = initPlugin(p); err != nil {
+			return err
+		}
+
+		s.initializedPlugins = append(s.initializedPlugins, p)
+	}
+
+	return nil
+}
+
+// initPlugin initializes a plugin
+func initPlugin(p *Plugin) (err error) {
+	if err = p.Lifecycle.Init(); err != nil {
+		return fmt.Errorf("Error initializing plugin [%s]: %v", p.Name, err)
+	}
+
+	return nil
+}
+
+// startPlugins starts the initialized plugins for a connection to a workspace
+func (s *Slackscot) startPlugins(ws *workspace, reconnected bool) (err error) {
+	s.reloadLock.RLock()
+	defer s.reloadLock.RUnlock()
+
+	for _, p := range s.initializedPlugins {
+		if err = startPlugin(p, ws, reconnected); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startPlugin starts a plugin for a connection to a workspace
+func startPlugin(p *Plugin, ws *workspace, reconnected bool) (err error) {
+	c := Connection{WorkspaceName: ws.name, TeamID: ws.teamID, Reconnected: reconnected, Services: ws.services}
+	if err = p.Lifecycle.Start(c); err != nil {
+		return fmt.Errorf("Error starting plugin [%s] on connection to %s: %v", p.Name, ws, err)
+	}
+
+	return nil
+}
+
+// stopPlugins stops the initialized plugins in the reverse order of their initialization
+func (s *Slackscot) stopPlugins() {
+	s.reloadLock.RLock()
+	defer s.reloadLock.RUnlock()
+
+	for i := len(s.initializedPlugins) - 1; i >= 0; i-- {
+		s.stopPlugin(s.initializedPlugins[i])
+	}
+}
+
+// stopPlugin stops a plugin, logging stop errors
+func (s *Slackscot) stopPlugin(p *Plugin) {
+	if err := p.Lifecycle.Stop(); err != nil {
+		s.log.Warn("Error stopping plugin", "plugin", p.Name, "err", err)
+	}
+}
@@ -0,0 +1,148 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// lifecycleRecorder records the lifecycle hooks called on plugins
+type lifecycleRecorder struct {
+	calls []string
+}
+
+// newLifecyclePlugin returns a plugin recording its lifecycle hooks. The hook named by failOn returns an error
+func (lr *lifecycleRecorder) newLifecyclePlugin(name string, failOn string) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = name
+	p.Lifecycle = LifecycleHooks{
+		OnInit: func() error {
+			lr.calls = append(lr.calls, fmt.Sprintf("%s.init(services=%t)", name, p.UserInfoFinder != nil))
+			if failOn == "init" {
+				return fmt.Errorf("can't warm cache")
+			}
+
+			return nil
+		},
+		OnStart: func(c Connection) error {
+			lr.calls = append(lr.calls, fmt.Sprintf("%s.start(reconnected=%t, services=%t)", name, c.Reconnected, c.Services != nil))
+			if failOn == "start" {
+				return fmt.Errorf("unknown channel")
+			}
+
+			return nil
+		},
+		OnStop: func() error {
+			lr.calls = append(lr.calls, fmt.Sprintf("%s.stop", name))
+			return nil
+		},
+	}
+
+	return p
+}
+
+func waitForRunContext(t *testing.T, done chan error) (err error) {
+	select {
+	case err = <-done:
+		return err
+	case <-time.After(time.Duration(2) * time.Second):
+		require.FailNow(t, "Timed out waiting for RunContext to return")
+	}
+
+	return nil
+}
+
+func TestPluginLifecycle(t *testing.T) {
+	api := newSlackAPIStandIn()
+	defer api.server.Close()
+
+	es := newFakeEventSource()
+	s := newSlackscotWithFakeEventSource(t, api, es)
+
+	lr := new(lifecycleRecorder)
+	s.RegisterPlugin(lr.newLifecyclePlugin("first", ""))
+	s.RegisterPlugin(newTestPlugin())
+	s.RegisterPlugin(lr.newLifecyclePlugin("second", ""))
+
+	closer := new(fakeCloser)
+	s.closers = append(s.closers, closer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.RunContext(ctx)
+	}()
+
+	es.events <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}}
+	es.events <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{}}
+	es.events <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{ConnectionCount: 1}}
+	cancel()
+
+	assert.NoError(t, waitForRunContext(t, done))
+	assert.Equal(t, []string{
+		"first.init(services=true)",
+		"second.init(services=true)",
+		"first.start(reconnected=false, services=true)",
+		"second.start(reconnected=false, services=true)",
+		"first.start(reconnected=true, services=true)",
+		"second.start(reconnected=true, services=true)",
+		"second.stop",
+		"first.stop",
+	}, lr.calls)
+	assert.Equal(t, 1, closer.closeCount)
+}
+
+func TestPluginInitErrorFailsRun(t *testing.T) {
+	api := newSlackAPIStandIn()
+	defer api.server.Close()
+
+	es := newFakeEventSource()
+	s := newSlackscotWithFakeEventSource(t, api, es)
+
+	lr := new(lifecycleRecorder)
+	s.RegisterPlugin(lr.newLifecyclePlugin("first", ""))
+	s.RegisterPlugin(lr.newLifecyclePlugin("second", "init"))
+	s.RegisterPlugin(lr.newLifecyclePlugin("third", ""))
+
+	done := make(chan error)
+	go func() {
+		done <- s.RunContext(context.Background())
+	}()
+
+	assert.EqualError(t, waitForRunContext(t, done), "Error initializing plugin [second]: can't warm cache")
+	assert.Equal(t, []string{"first.init(services=true)", "second.init(services=true)", "first.stop"}, lr.calls)
+	assert.True(t, es.disconnected)
+}
+
+func TestPluginStartErrorFailsRun(t *testing.T) {
+	api := newSlackAPIStandIn()
+	defer api.server.Close()
+
+	es := newFakeEventSource()
+	s := newSlackscotWithFakeEventSource(t, api, es)
+
+	lr := new(lifecycleRecorder)
+	s.RegisterPlugin(lr.newLifecyclePlugin("first", "start"))
+
+	done := make(chan error)
+	go func() {
+		done <- s.RunContext(context.Background())
+	}()
+
+	es.events <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}}
+
+	assert.EqualError(t, waitForRunContext(t, done), "Error starting plugin [first] on connection to Workspace{ }: unknown channel")
+	assert.Equal(t, []string{"first.init(services=true)", "first.start(reconnected=false, services=true)", "first.stop"}, lr.calls)
+}
+
+func TestLifecycleHooksDefaultToNoop(t *testing.T) {
+	lh := LifecycleHooks{}
+
+	assert.NoError(t, lh.Init())
+	assert.NoError(t, lh.Start(Connection{}))
+	assert.NoError(t, lh.Stop())
+}
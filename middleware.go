@@ -0,0 +1,76 @@
+package slackscot
+
+import (
+	"context"
+)
+
+// ActionInvocation holds the data of the invocation of a plugin action as seen by middlewares
+type ActionInvocation struct {
+	PluginName string           // The name of the plugin the action belongs to
+	ActionType string           // The type of action (CommandActionType, HearActionType, DialogActionType, ReactionActionType or InteractionActionType)
+	ActionID   string           // The identifier of the action within the plugin (i.e. "maker.command[0]")
+	Message    *IncomingMessage // The message the action is invoked for
+
+	// Matched is set to true by the innermost invoker once the action's Matcher has matched the message. This
+	// is what middlewares can rely on to tell apart an action that didn't match from one that matched but didn't
+	// answer anything
+	Matched bool
+
+	// onMatch, if set, is called by the innermost invoker once the action's Matcher has matched the message
+	onMatch func()
+}
+
+// ActionInvoker invokes an action. It returns the answer of the action or nil if it didn't match or
+// had nothing to answer
+type ActionInvoker func(ctx context.Context, inv *ActionInvocation) *Answer
+
+// Middleware wraps the invocation of plugin actions (matching and answering) to implement cross-cutting concerns such as
+// channel allowlists, audit logging or rewriting of answers. A Middleware can short-circuit the action by returning without
+// calling next and it can modify or replace the answer returned by next. For example, a middleware restricting
+// actions to a channel could look like:
+//
+//	func generalOnly(next slackscot.ActionInvoker) slackscot.ActionInvoker {
+//		return func(ctx context.Context, inv *slackscot.ActionInvocation) *slackscot.Answer {
+//			if inv.Message.Channel != "Cgeneral" {
+//				return nil
+//			}
+//
+//			return next(ctx, inv)
+//		}
+//	}
+//
+// Global middlewares are registered with OptionMiddleware and apply to all plugins while plugins can register their own in
+// Plugin.Middlewares. Global middlewares wrap plugin middlewares and middlewares run in the order they are registered
+type Middleware func(next ActionInvoker) ActionInvoker
+
+// OptionMiddleware adds a middleware wrapping the invocation of actions of all plugins
+func OptionMiddleware(middleware Middleware) Option {
+	return func(s *Slackscot) {
+		s.middlewares = append(s.middlewares, middleware)
+	}
+}
+
+// newActionInvoker returns the ActionInvoker for an action wrapped by all of the middlewares
+func newActionInvoker(action ActionDefinition, middlewares ...[]Middleware) (invoker ActionInvoker) {
+	invoker = func(ctx context.Context, inv *ActionInvocation) *Answer {
+		if !action.Match(inv.Message) {
+			return nil
+		}
+
+		inv.Matched = true
+		if inv.onMatch != nil {
+			inv.onMatch()
+		}
+
+		return action.answer(ctx, inv.Message)
+	}
+
+	// Wrap from the innermost middleware to the outermost one so that the first registered ends up running first
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		for j := len(middlewares[i]) - 1; j >= 0; j-- {
+			invoker = middlewares[i][j](invoker)
+		}
+	}
+
+	return invoker
+}
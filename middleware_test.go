@@ -0,0 +1,82 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func channelAllowlist(channels ...string) Middleware {
+	return func(next ActionInvoker) ActionInvoker {
+		return func(ctx context.Context, inv *ActionInvocation) *Answer {
+			for _, c := range channels {
+				if inv.Message.Channel == c {
+					return next(ctx, inv)
+				}
+			}
+
+			return nil
+		}
+	}
+}
+
+func suffixer(suffix string) Middleware {
+	return func(next ActionInvoker) ActionInvoker {
+		return func(ctx context.Context, inv *ActionInvocation) *Answer {
+			a := next(ctx, inv)
+			if a != nil {
+				a.Text = a.Text + suffix
+			}
+
+			return a
+		}
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cbirds", "blue jays", "Alphonse", timestamp1)),
+	}, nil, OptionMiddleware(channelAllowlist("Cbirds")))
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		assert.Equal(t, "Cbirds", sentMsgs[0].channelID)
+	}
+}
+
+func TestMiddlewareOrdering(t *testing.T) {
+	tp := newTestPlugin()
+	tp.Middlewares = []Middleware{suffixer(" (plugin 1)"), suffixer(" (plugin 2)")}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, tp, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, OptionMiddleware(suffixer(" (global 1)")), OptionMiddleware(suffixer(" (global 2)")))
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "I heard you say something about blue jays? (plugin 2) (plugin 1) (global 2) (global 1)", vals.Get("text"))
+	}
+}
+
+func TestMiddlewareSeesInvocation(t *testing.T) {
+	invocations := make([]string, 0)
+	audit := func(next ActionInvoker) ActionInvoker {
+		return func(ctx context.Context, inv *ActionInvocation) *Answer {
+			a := next(ctx, inv)
+			if inv.Matched {
+				invocations = append(invocations, fmt.Sprintf("%s|%s|%s|%s|%t", inv.PluginName, inv.ActionType, inv.ActionID, inv.Message.NormalizedText, a != nil))
+			}
+
+			return a
+		}
+	}
+
+	runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s noRules make cake", formattedBotUserID), "Alphonse", timestamp2)),
+	}, nil, OptionMiddleware(audit))
+
+	assert.Equal(t, []string{"noRules|hearAction|noRules.hearAction[0]|blue jays|true", "noRules|command|noRules.command[0]|make cake|true"}, invocations)
+}
@@ -0,0 +1,75 @@
+package slackscot
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// actionPanic is the error returned when an action panics. It holds the recovered value along with
+// the stack trace at the time of the panic
+type actionPanic struct {
+	value interface{}
+	stack []byte
+}
+
+// Error returns the description of the panic
+func (p *actionPanic) Error() string {
+	return fmt.Sprintf("panic: %v", p.value)
+}
+
+// callSafely calls f and recovers from any panic it might raise, returning it as an *actionPanic
+func callSafely(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &actionPanic{value: r, stack: debug.Stack()}
+		}
+	}()
+
+	f()
+	return nil
+}
+
+// circuitBreaker keeps track of consecutive panics by action and disables (opens the circuit of) actions that
+// reach the threshold. Plugin actions run concurrently from many partitions and the scheduler so access is synchronized
+type circuitBreaker struct {
+	threshold         int
+	consecutivePanics map[string]int
+	lock              sync.Mutex
+}
+
+// newCircuitBreaker returns a new circuitBreaker disabling actions after threshold consecutive panics. A
+// threshold of 0 means actions never get disabled
+func newCircuitBreaker(threshold int) (cb *circuitBreaker) {
+	cb = new(circuitBreaker)
+	cb.threshold = threshold
+	cb.consecutivePanics = make(map[string]int)
+
+	return cb
+}
+
+// isOpen returns true if the action has been disabled
+func (cb *circuitBreaker) isOpen(actionID string) bool {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	return cb.threshold > 0 && cb.consecutivePanics[actionID] >= cb.threshold
+}
+
+// recordPanic records a panic for the action and returns true if that panic resulted in the action getting disabled
+func (cb *circuitBreaker) recordPanic(actionID string) (opened bool) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	cb.consecutivePanics[actionID]++
+
+	return cb.threshold > 0 && cb.consecutivePanics[actionID] == cb.threshold
+}
+
+// recordSuccess resets the consecutive panic count of an action
+func (cb *circuitBreaker) recordSuccess(actionID string) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	delete(cb.consecutivePanics, actionID)
+}
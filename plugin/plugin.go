@@ -64,6 +64,8 @@ func New(name string) (pb *PluginBuilder) {
 	pb.plugin.Commands = make([]slackscot.ActionDefinition, 0)
 	pb.plugin.HearActions = make([]slackscot.ActionDefinition, 0)
 	pb.plugin.ScheduledActions = make([]slackscot.ScheduledActionDefinition, 0)
+	pb.plugin.ReactionActions = make([]slackscot.ReactionActionDefinition, 0)
+	pb.plugin.InteractionActions = make([]slackscot.InteractionActionDefinition, 0)
 
 	return pb
 }
@@ -92,6 +94,24 @@ func (pb *PluginBuilder) WithScheduledAction(scheduledAction slackscot.Scheduled
 	return pb
 }
 
+// WithReactionAction adds a reaction action to the plugin
+func (pb *PluginBuilder) WithReactionAction(reactionAction slackscot.ReactionActionDefinition) *PluginBuilder {
+	pb.plugin.ReactionActions = append(pb.plugin.ReactionActions, reactionAction)
+	return pb
+}
+
+// WithInteractionAction adds an interaction action to the plugin
+func (pb *PluginBuilder) WithInteractionAction(interactionAction slackscot.InteractionActionDefinition) *PluginBuilder {
+	pb.plugin.InteractionActions = append(pb.plugin.InteractionActions, interactionAction)
+	return pb
+}
+
+// WithMiddleware adds a middleware wrapping the invocation of the plugin's commands and hear actions
+func (pb *PluginBuilder) WithMiddleware(middleware slackscot.Middleware) *PluginBuilder {
+	pb.plugin.Middlewares = append(pb.plugin.Middlewares, middleware)
+	return pb
+}
+
 // Build returns the created Plugin instance
 func (pb *PluginBuilder) Build() (p *slackscot.Plugin) {
 	return pb.plugin
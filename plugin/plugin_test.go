@@ -1,6 +1,7 @@
 package plugin_test
 
 import (
+	"github.com/alexandre-normand/slackscot"
 	"github.com/alexandre-normand/slackscot/actions"
 	"github.com/alexandre-normand/slackscot/plugin"
 	"github.com/stretchr/testify/assert"
@@ -17,6 +18,8 @@ func TestDefaultNewPlugin(t *testing.T) {
 	assert.Empty(t, p.Commands)
 	assert.Empty(t, p.HearActions)
 	assert.Empty(t, p.ScheduledActions)
+	assert.Empty(t, p.ReactionActions)
+	assert.Empty(t, p.InteractionActions)
 }
 
 func TestPluginWithSingleCommand(t *testing.T) {
@@ -68,6 +71,26 @@ func TestPluginWithScheduledActions(t *testing.T) {
 	assert.Equal(t, "Check service status", p.ScheduledActions[0].Description)
 }
 
+func TestPluginWithReactionActions(t *testing.T) {
+	p := plugin.New("loopy").
+		WithReactionAction(actions.NewReactionAction().WithDescription("Pin messages").Build()).
+		Build()
+
+	require.NotNil(t, p)
+	require.Len(t, p.ReactionActions, 1)
+	assert.Equal(t, "Pin messages", p.ReactionActions[0].Description)
+}
+
+func TestPluginWithInteractionActions(t *testing.T) {
+	p := plugin.New("loopy").
+		WithInteractionAction(actions.NewInteractionAction().WithActionID("confirm").Build()).
+		Build()
+
+	require.NotNil(t, p)
+	require.Len(t, p.InteractionActions, 1)
+	assert.True(t, p.InteractionActions[0].Match(&slackscot.IncomingInteraction{ActionID: "confirm"}))
+}
+
 func TestPluginWithCommandNamespacing(t *testing.T) {
 	p := plugin.New("loopy").
 		WithCommandNamespacing().
@@ -76,3 +99,17 @@ func TestPluginWithCommandNamespacing(t *testing.T) {
 	require.NotNil(t, p)
 	assert.True(t, p.NamespaceCommands)
 }
+
+func TestPluginWithMiddlewares(t *testing.T) {
+	p := plugin.New("loopy").
+		WithMiddleware(func(next slackscot.ActionInvoker) slackscot.ActionInvoker {
+			return next
+		}).
+		WithMiddleware(func(next slackscot.ActionInvoker) slackscot.ActionInvoker {
+			return next
+		}).
+		Build()
+
+	require.NotNil(t, p)
+	assert.Len(t, p.Middlewares, 2)
+}
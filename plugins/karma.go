@@ -181,7 +181,7 @@ func (k *Karma) recordKarma(message *slackscot.IncomingMessage) *slackscot.Answe
 		}
 		karma, err := strconv.Atoi(rawValue)
 		if err != nil {
-			k.Logger.Printf("[%s] Error parsing current karma value [%s], something's wrong and resetting to 0: %v", KarmaPluginName, rawValue, err)
+			k.Log().Warn("Error parsing current karma value, resetting to 0", "thing", thing, "value", rawValue, "err", err)
 			karma = 0
 		}
 
@@ -215,7 +215,7 @@ func (k *Karma) recordKarma(message *slackscot.IncomingMessage) *slackscot.Answe
 		// Store new value
 		err = k.karmaStorer.PutSiloString(message.Channel, thing, strconv.Itoa(karma))
 		if err != nil {
-			k.Logger.Printf("[%s] Error persisting karma: %v", KarmaPluginName, err)
+			k.Log().Error("Error persisting karma", "channel", message.Channel, "thing", thing, "err", err)
 			return nil
 		}
 	}
@@ -0,0 +1,232 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// explainPriority is the priority of the explain command so that it's always reachable with exclusive routing
+	explainPriority = math.MaxInt32
+)
+
+// actionCandidate is an action of a plugin that might answer an incoming message
+type actionCandidate struct {
+	p          *Plugin
+	actionType string
+	actionID   string
+	action     ActionDefinition
+	msg        IncomingMessage
+}
+
+// pluginActionUsage is the usage of the actions of a plugin during the routing of a message, as reported by plugin metrics
+type pluginActionUsage struct {
+	elapsed  time.Duration
+	answers  int
+	timeouts int
+	panics   int
+}
+
+// pluginActionUsages is the usage of the actions of plugins, in the order plugins were first seen
+type pluginActionUsages struct {
+	plugins []string
+	usages  map[string]*pluginActionUsage
+}
+
+// newActionCandidates returns the actions of a plugin as candidates to answer an incoming message
+func newActionCandidates(p *Plugin, actionType string, actions []ActionDefinition, m IncomingMessage) (candidates []actionCandidate) {
+	candidates = make([]actionCandidate, 0, len(actions))
+	for i, action := range actions {
+		candidates = append(candidates, actionCandidate{p: p, actionType: actionType, actionID: getActionID(p.Name, actionType, i), action: action, msg: m})
+	}
+
+	return candidates
+}
+
+// commandCandidates returns the commands of all plugins as candidates to answer a message formatted as a command
+func (s *Slackscot) commandCandidates(ws *workspace, m slack.Msg) (candidates []actionCandidate) {
+	return s.namespacedCommandCandidates(s.newIncomingMsgWithNormalizedText(ws, m))
+}
+
+// namespacedCommandCandidates returns the commands of all plugins whose namespace matches the incoming message (see stripNamespace)
+func (s *Slackscot) namespacedCommandCandidates(inMsg IncomingMessage) (candidates []actionCandidate) {
+	candidates = make([]actionCandidate, 0)
+	for _, p := range s.plugins {
+		if matchedNamespace, stripped := s.stripNamespace(p, inMsg); matchedNamespace {
+			candidates = append(candidates, newActionCandidates(p, CommandActionType, p.Commands, stripped)...)
+		}
+	}
+
+	return candidates
+}
+
+// hearActionCandidates returns the hear actions of all plugins as candidates to answer a message
+func (s *Slackscot) hearActionCandidates(ws *workspace, m slack.Msg) (candidates []actionCandidate) {
+	candidates = make([]actionCandidate, 0)
+	for _, p := range s.plugins {
+		candidates = append(candidates, newActionCandidates(p, HearActionType, p.HearActions, s.newIncomingMsgWithNormalizedText(ws, m))...)
+	}
+
+	return candidates
+}
+
+// sortByPriority sorts candidates from the highest priority to the lowest. Since candidates are created in the registration
+// order of plugins and in the order of the actions within each plugin, the stable sort breaks ties by that order
+func sortByPriority(candidates []actionCandidate) (sorted []actionCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].action.Priority > candidates[j].action.Priority
+	})
+
+	return candidates
+}
+
+// stopsRouting returns true if the actions after an action shouldn't be tried: when the action matched with exclusive
+// routing or when it's an exclusive action that matched
+func stopsRouting(action ActionDefinition, matched bool, exclusiveRouting bool) bool {
+	return matched && (exclusiveRouting || action.Exclusive)
+}
+
+// InvokePluginActions invokes the actions (of the given type) of a plugin with a message the way slackscot does when it routes
+// a message: in order of priority, through the same middlewares, authorization, rate limits, timeouts and panic isolation and up
+// to the first match with exclusive routing (or the first match of an exclusive action). It returns the answers of the actions
+// that answered without sending them. This is meant for driving plugins in tests (see the assertplugin package)
+func (s *Slackscot) InvokePluginActions(ctx context.Context, p *Plugin, actionType string, actions []ActionDefinition, m *IncomingMessage) (answers []*Answer) {
+	answers = make([]*Answer, 0)
+	usages := newPluginActionUsages()
+	exclusiveRouting := s.isExclusiveRouting()
+
+	for _, c := range sortByPriority(newActionCandidates(p, actionType, actions, *m)) {
+		answer, matched, _ := s.tryAction(ctx, c, usages.of(c.p))
+		if answer != nil {
+			answers = append(answers, answer)
+		}
+
+		if stopsRouting(c.action, matched, exclusiveRouting) {
+			break
+		}
+	}
+
+	usages.record(s)
+
+	return answers
+}
+
+// isExclusiveRouting returns true if only the first action matching a message answers it
+func (s *Slackscot) isExclusiveRouting() bool {
+	return s.config.GetBool(config.ExclusiveRoutingKey)
+}
+
+func newPluginActionUsages() (pau *pluginActionUsages) {
+	return &pluginActionUsages{plugins: make([]string, 0), usages: make(map[string]*pluginActionUsage)}
+}
+
+// of returns the usage of the actions of a plugin
+func (pau *pluginActionUsages) of(p *Plugin) (usage *pluginActionUsage) {
+	usage, ok := pau.usages[p.Name]
+	if !ok {
+		usage = new(pluginActionUsage)
+		pau.usages[p.Name] = usage
+		pau.plugins = append(pau.plugins, p.Name)
+	}
+
+	return usage
+}
+
+// record records the usage of the actions of each plugin with the plugin metrics
+func (pau *pluginActionUsages) record(s *Slackscot) {
+	for _, pluginName := range pau.plugins {
+		usage := pau.usages[pluginName]
+
+		pm, err := s.getOrCreatePluginMetrics(pluginName)
+		if err != nil {
+			s.log.Printf("Error creating plugin metrics for plugin [%s], skipping instrumentation measurements: %s", pluginName, err.Error())
+			continue
+		}
+
+		ctx := context.Background()
+
+		pm.processingTimeMillis.Record(ctx, usage.elapsed.Milliseconds())
+		pm.reactionCount.Add(ctx, int64(usage.answers))
+		pm.actionTimeoutCount.Add(ctx, int64(usage.timeouts))
+		pm.actionPanicCount.Add(ctx, int64(usage.panics))
+	}
+}
+
+// explainRouting describes how a message with the given text, sent like the incoming message, would be routed: the actions
+// whose matcher matches it, in the order they're tried, and which ones would answer. Only matchers are called (middlewares,
+// authorization and rate limits aren't) so an action shown as answering could still end up not answering
+func (s *Slackscot) explainRouting(m *IncomingMessage, text string) (explanation string) {
+	ws, err := s.workspaces.find(m.TeamID)
+	if err != nil {
+		return fmt.Sprintf("🤷 I can't explain the routing of messages in this workspace: %v", err)
+	}
+
+	msg := m.Msg
+	msg.Text = text
+	msg.SubType = ""
+
+	var candidates []actionCandidate
+	routedAs := "a message"
+	if s.isCommand(ws, msg) {
+		candidates = s.commandCandidates(ws, msg)
+		routedAs = "a command"
+	} else {
+		candidates = s.hearActionCandidates(ws, msg)
+	}
+
+	exclusiveRouting := s.isExclusiveRouting()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔎 `%s` is routed as %s", text, routedAs)
+	if exclusiveRouting {
+		fmt.Fprintf(&b, " with exclusive routing (the first match wins)")
+	}
+	fmt.Fprintf(&b, ":\n")
+
+	matches := 0
+	won := false
+	for _, c := range sortByPriority(candidates) {
+		inMsg := c.msg
+		matched := false
+		if err := callSafely(func() { matched = c.action.Match(&inMsg) }); err != nil || !matched {
+			continue
+		}
+
+		matches++
+		outcome := "answers"
+		if won {
+			outcome = "skipped"
+		} else if exclusiveRouting || c.action.Exclusive {
+			outcome = "wins"
+			won = true
+		}
+
+		fmt.Fprintf(&b, "\t%d. `%s` %s - %s\n", matches, c.actionID, describeRouting(c.action), outcome)
+	}
+
+	if matches == 0 {
+		fmt.Fprintf(&b, "\tNo action matches it")
+		if routedAs == "a command" {
+			fmt.Fprintf(&b, ", the default answer is sent")
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+
+	return b.String()
+}
+
+// describeRouting describes the priority and exclusivity of an action
+func describeRouting(action ActionDefinition) (description string) {
+	description = fmt.Sprintf("(priority `%d`", action.Priority)
+	if action.Exclusive {
+		description = description + ", exclusive"
+	}
+
+	return description + ")"
+}
@@ -0,0 +1,208 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// newBirdWatcherPlugin returns a plugin hearing about blue jays and with a birds command, both with the given priority and exclusivity
+func newBirdWatcherPlugin(name string, priority int, exclusive bool) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = name
+	p.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return m.NormalizedText == "birds"
+		},
+		Usage:       "birds",
+		Description: "List birds",
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: fmt.Sprintf("%s: blue jays, chickadees", name)}
+		},
+		Priority:  priority,
+		Exclusive: exclusive,
+	}}
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return m.NormalizedText == "blue jays"
+		},
+		Usage:       "blue jays",
+		Description: "Hear about blue jays",
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: fmt.Sprintf("%s heard about blue jays", name)}
+		},
+		Priority:  priority,
+		Exclusive: exclusive,
+	}}
+
+	return p
+}
+
+// optionRegisterPlugins registers plugins ahead of the plugin given to runSlackscotWithIncomingEvents
+func optionRegisterPlugins(plugins ...*Plugin) Option {
+	return func(s *Slackscot) {
+		for _, p := range plugins {
+			s.RegisterPlugin(p)
+		}
+	}
+}
+
+func newExclusiveRoutingConfig() (v *viper.Viper) {
+	v = config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.ExclusiveRoutingKey, true)
+
+	return v
+}
+
+func TestActionsAnswerInOrderOfPriority(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newBirdWatcherPlugin("high", 10, false), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("low", -1, false), newBirdWatcherPlugin("default", 0, false)))
+
+	assert.Equal(t, []string{"high heard about blue jays", "default heard about blue jays", "low heard about blue jays"}, sentTexts(sentMsgs))
+}
+
+func TestExclusiveRoutingHighestPriorityMatchWins(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, newExclusiveRoutingConfig(), newBirdWatcherPlugin("high", 10, false), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s birds", formattedBotUserID), "Alphonse", timestamp2)),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("low", 0, false)))
+
+	assert.Equal(t, []string{"high heard about blue jays", "<@Alphonse>: high: blue jays, chickadees"}, sentTexts(sentMsgs))
+}
+
+func TestExclusiveRoutingTieBreaksByRegistrationOrder(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, newExclusiveRoutingConfig(), newBirdWatcherPlugin("second", 5, false), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("first", 5, false)))
+
+	assert.Equal(t, []string{"first heard about blue jays"}, sentTexts(sentMsgs))
+}
+
+func TestExclusiveRoutingSkipsActionsNotMatching(t *testing.T) {
+	picky := newBirdWatcherPlugin("picky", 10, false)
+	picky.HearActions[0].Match = func(m *IncomingMessage) bool {
+		return false
+	}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, newExclusiveRoutingConfig(), picky, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("low", 0, false)))
+
+	assert.Equal(t, []string{"low heard about blue jays"}, sentTexts(sentMsgs))
+}
+
+func TestExclusiveRoutingMatchWithoutAnswerWins(t *testing.T) {
+	quiet := newBirdWatcherPlugin("quiet", 10, false)
+	quiet.HearActions[0].Answer = func(m *IncomingMessage) *Answer {
+		return nil
+	}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, newExclusiveRoutingConfig(), quiet, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("low", 0, false)))
+
+	assert.Empty(t, sentMsgs)
+}
+
+func TestExclusiveActionSkipsActionsTriedAfterIt(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newBirdWatcherPlugin("low", 0, false), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("exclusive", 5, true), newBirdWatcherPlugin("high", 10, false)))
+
+	assert.Equal(t, []string{"high heard about blue jays", "exclusive heard about blue jays"}, sentTexts(sentMsgs))
+}
+
+// newSlowBirdWatcherPlugin returns a bird watcher plugin whose hear action matches but times out before answering
+func newSlowBirdWatcherPlugin(name string, priority int, exclusive bool) (p *Plugin) {
+	p = newBirdWatcherPlugin(name, priority, exclusive)
+	p.HearActions[0].Timeout = time.Duration(10) * time.Millisecond
+	p.HearActions[0].ContextAnswer = func(ctx context.Context, m *IncomingMessage) *Answer {
+		<-ctx.Done()
+		return &Answer{Text: fmt.Sprintf("%s heard about blue jays too late", name)}
+	}
+
+	return p
+}
+
+func TestExclusiveRoutingMatchTimingOutWins(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, newExclusiveRoutingConfig(), newSlowBirdWatcherPlugin("slow", 10, false), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("low", 0, false)))
+
+	assert.Empty(t, sentMsgs)
+}
+
+func TestExclusiveActionTimingOutSkipsActionsTriedAfterIt(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newBirdWatcherPlugin("low", 0, false), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newSlowBirdWatcherPlugin("exclusive", 5, true), newBirdWatcherPlugin("high", 10, false)))
+
+	assert.Equal(t, []string{"high heard about blue jays"}, sentTexts(sentMsgs))
+}
+
+func TestExclusiveActionTimingOutBeforeMatchingDoesNotWin(t *testing.T) {
+	slow := newBirdWatcherPlugin("slow", 10, true)
+	slow.HearActions[0].Timeout = time.Duration(10) * time.Millisecond
+	slow.HearActions[0].Match = func(m *IncomingMessage) bool {
+		time.Sleep(time.Duration(50) * time.Millisecond)
+		return true
+	}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, slow, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("low", 0, false)))
+
+	assert.Equal(t, []string{"low heard about blue jays"}, sentTexts(sentMsgs))
+}
+
+func TestExplainRouting(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, newExclusiveRoutingConfig(), newBirdWatcherPlugin("high", 10, true), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s explain blue jays", formattedBotUserID), "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s explain %s birds", formattedBotUserID, formattedBotUserID), "Alphonse", timestamp2)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s explain %s owls", formattedBotUserID, formattedBotUserID), "Alphonse", "1546833220.036900")),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("low", 0, false)))
+
+	assert.Equal(t, []string{
+		"<@Alphonse>: 🔎 `blue jays` is routed as a message with exclusive routing (the first match wins):\n" +
+			"\t1. `high.hearAction[0]` (priority `10`, exclusive) - wins\n" +
+			"\t2. `low.hearAction[0]` (priority `0`) - skipped\n",
+		"<@Alphonse>: 🔎 `<@BotUserID> birds` is routed as a command with exclusive routing (the first match wins):\n" +
+			"\t1. `high.command[0]` (priority `10`, exclusive) - wins\n" +
+			"\t2. `low.command[0]` (priority `0`) - skipped\n",
+		"<@Alphonse>: 🔎 `<@BotUserID> owls` is routed as a command with exclusive routing (the first match wins):\n" +
+			"\tNo action matches it, the default answer is sent\n",
+	}, sentTexts(sentMsgs))
+}
+
+func TestExplainRoutingWithoutExclusiveRouting(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newBirdWatcherPlugin("high", 10, false), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s explain blue jays", formattedBotUserID), "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("low", 0, false)))
+
+	assert.Equal(t, []string{
+		"<@Alphonse>: 🔎 `blue jays` is routed as a message:\n" +
+			"\t1. `high.hearAction[0]` (priority `10`) - answers\n" +
+			"\t2. `low.hearAction[0]` (priority `0`) - answers\n",
+	}, sentTexts(sentMsgs))
+}
+
+func TestHelpShowsPriorityAndExclusiveRouting(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, newExclusiveRoutingConfig(), newBirdWatcherPlugin("high", 10, true), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s help", formattedBotUserID), "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newBirdWatcherPlugin("low", 0, false)))
+
+	texts := sentTexts(sentMsgs)
+	if assert.Len(t, texts, 1) {
+		assert.Contains(t, texts[0], "\t• `birds` - List birds (priority `10`, exclusive)\n")
+		assert.Contains(t, texts[0], "\t• `birds` - List birds\n")
+		assert.Contains(t, texts[0], "\t• `blue jays` - Hear about blue jays (priority `10`, exclusive)\n")
+		assert.Contains(t, texts[0], "\nWhen several of those match a message, only the one with the highest priority answers. Try `explain <message>` to see which one does\n")
+	}
+}
@@ -0,0 +1,272 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"sync"
+	"time"
+)
+
+const (
+	// Interval at which buckets back to full capacity are purged. A bucket that's not there is the same as a full one
+	rateLimitBucketPurgeInterval = time.Duration(1) * time.Minute
+)
+
+// rateLimit is the capacity and refill interval of a token bucket
+type rateLimit struct {
+	capacity       int
+	refillInterval time.Duration
+}
+
+// limitedKey is the key of a token bucket along with the limit applying to it
+type limitedKey struct {
+	key   string
+	limit rateLimit
+}
+
+// tokenBucket holds the tokens (answers) available in a bucket
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	fullAt  time.Time
+}
+
+// refill adds the tokens accumulated since the last update of the bucket, up to its capacity
+func (tb *tokenBucket) refill(limit rateLimit, now time.Time) {
+	if limit.refillInterval > 0 {
+		tb.tokens = tb.tokens + float64(now.Sub(tb.updated))/float64(limit.refillInterval)
+	}
+
+	if tb.tokens > float64(limit.capacity) {
+		tb.tokens = float64(limit.capacity)
+	}
+
+	tb.updated = now
+}
+
+// timeUntil returns the time until the bucket has the number of tokens
+func (tb *tokenBucket) timeUntil(tokens float64, limit rateLimit) time.Duration {
+	if tb.tokens >= tokens {
+		return 0
+	}
+
+	return time.Duration((tokens - tb.tokens) * float64(limit.refillInterval))
+}
+
+// rateLimiter holds the token buckets of rate limited keys. Buckets are created full on first use
+type rateLimiter struct {
+	buckets   map[string]*tokenBucket
+	notified  map[string]bool
+	lastPurge time.Time
+	lock      sync.Mutex
+	now       func() time.Time
+}
+
+// newRateLimiter returns a new rateLimiter
+func newRateLimiter() (rl *rateLimiter) {
+	rl = new(rateLimiter)
+	rl.buckets = make(map[string]*tokenBucket)
+	rl.notified = make(map[string]bool)
+	rl.now = time.Now
+	rl.lastPurge = rl.now()
+
+	return rl
+}
+
+// take takes a token from the buckets of all keys if they all have one. When they don't, no token is taken and take returns
+// the time to wait until they do along with the key of the first bucket found empty
+func (rl *rateLimiter) take(keys []limitedKey) (allowed bool, wait time.Duration, limitedBy string) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	now := rl.now()
+	rl.purgeFullBuckets(now)
+
+	for _, k := range keys {
+		b := rl.getOrCreateBucket(k, now)
+
+		if w := b.timeUntil(1, k.limit); w > 0 {
+			if limitedBy == "" {
+				limitedBy = k.key
+			}
+
+			if w > wait {
+				wait = w
+			}
+		}
+	}
+
+	if limitedBy != "" {
+		return false, wait, limitedBy
+	}
+
+	for _, k := range keys {
+		b := rl.buckets[k.key]
+		b.tokens = b.tokens - 1
+		b.fullAt = now.Add(b.timeUntil(float64(k.limit.capacity), k.limit))
+
+		// Users get notified again the next time they go over the limit
+		delete(rl.notified, k.key)
+	}
+
+	return true, 0, ""
+}
+
+// getOrCreateBucket returns the refilled bucket for the key or a new full one if there isn't one
+func (rl *rateLimiter) getOrCreateBucket(k limitedKey, now time.Time) (b *tokenBucket) {
+	b, ok := rl.buckets[k.key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(k.limit.capacity), updated: now, fullAt: now}
+		rl.buckets[k.key] = b
+	}
+
+	b.refill(k.limit, now)
+
+	return b
+}
+
+// notifyOnce returns true if it's the first time it's called for the key since it was last limited
+func (rl *rateLimiter) notifyOnce(key string) bool {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	if rl.notified[key] {
+		return false
+	}
+
+	rl.notified[key] = true
+	return true
+}
+
+// purgeFullBuckets deletes the buckets that are back to full capacity, at most once per rateLimitBucketPurgeInterval
+func (rl *rateLimiter) purgeFullBuckets(now time.Time) {
+	if now.Sub(rl.lastPurge) < rateLimitBucketPurgeInterval {
+		return
+	}
+
+	for k, b := range rl.buckets {
+		if !now.Before(b.fullAt) {
+			delete(rl.buckets, k)
+			delete(rl.notified, k)
+		}
+	}
+
+	rl.lastPurge = now
+}
+
+// rateLimitConfigKey returns the configuration key of a rate limit setting for a plugin. That's the key under the
+// plugin's configuration if it's set or the one at the root of the configuration otherwise
+func (s *Slackscot) rateLimitConfigKey(pluginName string, key string) string {
+	pluginKey := fmt.Sprintf("%s.%s.%s.%s", config.PluginsKey, pluginName, config.RateLimitsKey, key)
+	if s.config.IsSet(pluginKey) {
+		return pluginKey
+	}
+
+	return fmt.Sprintf("%s.%s", config.RateLimitsKey, key)
+}
+
+// getRateLimit returns the rate limit of a scope (config.UserRateLimitKey, config.ChannelRateLimitKey or
+// config.ActionRateLimitKey) for a plugin
+func (s *Slackscot) getRateLimit(pluginName string, scope string) (limit rateLimit) {
+	limit.capacity = s.config.GetInt(s.rateLimitConfigKey(pluginName, fmt.Sprintf("%s.%s", scope, config.RateLimitCapacityKey)))
+	limit.refillInterval = s.config.GetDuration(s.rateLimitConfigKey(pluginName, fmt.Sprintf("%s.%s", scope, config.RateLimitRefillIntervalKey)))
+
+	return limit
+}
+
+// rateLimitedKeys returns the keys of the buckets limiting the answers of a plugin action to a message: one for the user,
+// one for the channel and one for the action, for those with limits enabled (with a capacity and refill interval)
+func (s *Slackscot) rateLimitedKeys(pluginName string, actionID string, m *IncomingMessage) (keys []limitedKey) {
+	scopedKeys := []struct {
+		scope string
+		key   string
+	}{
+		{scope: config.UserRateLimitKey, key: fmt.Sprintf("%s/%s/%s/%s", pluginName, config.UserRateLimitKey, m.TeamID, m.User)},
+		{scope: config.ChannelRateLimitKey, key: fmt.Sprintf("%s/%s/%s/%s", pluginName, config.ChannelRateLimitKey, m.TeamID, m.Channel)},
+		{scope: config.ActionRateLimitKey, key: fmt.Sprintf("%s/%s", config.ActionRateLimitKey, actionID)},
+	}
+
+	keys = make([]limitedKey, 0)
+	for _, sk := range scopedKeys {
+		if limit := s.getRateLimit(pluginName, sk.scope); limit.capacity > 0 && limit.refillInterval > 0 {
+			keys = append(keys, limitedKey{key: sk.key, limit: limit})
+		}
+	}
+
+	return keys
+}
+
+// rateLimitedAction returns the action with its answers rate limited. The limits are checked once the action matches
+// and, when they're reached, the config.RateLimitPolicyKey decides whether the answer is dropped, replaced by a one-time
+// ephemeral notice to the user or delayed until allowed.
+//
+// Delayed answers are waited for while processing the message so they hold up the other messages of its partition (and
+// configuration reloads). The wait is therefore capped to config.RateLimitMaxDelayKey (and the action timeout) after which
+// the answer is dropped.
+//
+// Updated messages aren't rate limited since answers to them update (or delete, if there's none) the answers to the
+// original message
+func (s *Slackscot) rateLimitedAction(pluginName string, actionID string, action ActionDefinition) (limited ActionDefinition) {
+	limited = action
+	limited.ContextAnswer = func(ctx context.Context, m *IncomingMessage) *Answer {
+		if m.SubType == "message_changed" {
+			return action.answer(ctx, m)
+		}
+
+		keys := s.rateLimitedKeys(pluginName, actionID, m)
+		if len(keys) == 0 {
+			return action.answer(ctx, m)
+		}
+
+		var delayed time.Duration
+		for recorded := false; ; recorded = true {
+			allowed, wait, limitedBy := s.rateLimiter.take(keys)
+			if allowed {
+				return action.answer(ctx, m)
+			}
+
+			if !recorded {
+				s.recordRateLimited(pluginName)
+			}
+
+			switch s.config.GetString(s.rateLimitConfigKey(pluginName, config.RateLimitPolicyKey)) {
+			case config.DelayRateLimitPolicy:
+				if delayed = delayed + wait; delayed > s.config.GetDuration(s.rateLimitConfigKey(pluginName, config.RateLimitMaxDelayKey)) {
+					s.log.Debugf("Dropping answer of action [%s] as [%s] is over its rate limit for longer than the maximum delay", actionID, limitedBy)
+					return nil
+				}
+
+				s.log.Debugf("Delaying answer of action [%s] by [%s] as [%s] is over its rate limit", actionID, wait, limitedBy)
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(wait):
+				}
+			case config.NotifyRateLimitPolicy:
+				if s.rateLimiter.notifyOnce(limitedBy) {
+					return &Answer{Text: s.config.GetString(s.rateLimitConfigKey(pluginName, config.RateLimitNoticeKey)), Options: []AnswerOption{AnswerEphemeral(m.User)}}
+				}
+
+				return nil
+			default:
+				s.log.Debugf("Dropping answer of action [%s] as [%s] is over its rate limit", actionID, limitedBy)
+				return nil
+			}
+		}
+	}
+
+	return limited
+}
+
+// recordRateLimited records an answer of a plugin being rate limited
+func (s *Slackscot) recordRateLimited(pluginName string) {
+	pm, err := s.getOrCreatePluginMetrics(pluginName)
+	if err != nil {
+		s.log.Printf("Error creating plugin metrics for plugin [%s], skipping instrumentation measurements: %s", pluginName, err.Error())
+		return
+	}
+
+	pm.rateLimitedCount.Add(context.Background(), 1)
+}
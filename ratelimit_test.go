@@ -0,0 +1,184 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterTake(t *testing.T) {
+	clock := fakeClock{now: time.Now()}
+	rl := newRateLimiter()
+	rl.now = clock.Now
+
+	keys := []limitedKey{{key: "user", limit: rateLimit{capacity: 2, refillInterval: time.Second}}}
+	for i := 0; i < 2; i++ {
+		allowed, _, _ := rl.take(keys)
+		assert.True(t, allowed)
+	}
+
+	allowed, wait, limitedBy := rl.take(keys)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, wait)
+	assert.Equal(t, "user", limitedBy)
+
+	clock.now = clock.now.Add(time.Duration(500) * time.Millisecond)
+	allowed, wait, _ = rl.take(keys)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Duration(500)*time.Millisecond, wait)
+
+	clock.now = clock.now.Add(time.Duration(500) * time.Millisecond)
+	allowed, _, _ = rl.take(keys)
+	assert.True(t, allowed)
+}
+
+func TestRateLimiterTakesFromAllBucketsOrNone(t *testing.T) {
+	clock := fakeClock{now: time.Now()}
+	rl := newRateLimiter()
+	rl.now = clock.Now
+
+	userLimit := limitedKey{key: "user", limit: rateLimit{capacity: 2, refillInterval: time.Second}}
+	channelLimit := limitedKey{key: "channel", limit: rateLimit{capacity: 1, refillInterval: time.Minute}}
+
+	allowed, _, _ := rl.take([]limitedKey{userLimit, channelLimit})
+	assert.True(t, allowed)
+
+	allowed, wait, limitedBy := rl.take([]limitedKey{userLimit, channelLimit})
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, wait)
+	assert.Equal(t, "channel", limitedBy)
+
+	// The user bucket still has a token since none were taken when the channel was limited
+	allowed, _, _ = rl.take([]limitedKey{userLimit})
+	assert.True(t, allowed)
+}
+
+func TestRateLimiterPurgesFullBuckets(t *testing.T) {
+	clock := fakeClock{now: time.Now()}
+	rl := newRateLimiter()
+	rl.now = clock.Now
+
+	rl.take([]limitedKey{{key: "user", limit: rateLimit{capacity: 2, refillInterval: time.Second}}})
+	rl.take([]limitedKey{{key: "channel", limit: rateLimit{capacity: 2, refillInterval: time.Hour}}})
+	assert.Len(t, rl.buckets, 2)
+
+	clock.now = clock.now.Add(time.Duration(2) * time.Minute)
+	rl.take([]limitedKey{})
+	assert.Len(t, rl.buckets, 1)
+	assert.Contains(t, rl.buckets, "channel")
+}
+
+func TestRateLimiterNotifyOnce(t *testing.T) {
+	rl := newRateLimiter()
+	keys := []limitedKey{{key: "user", limit: rateLimit{capacity: 1, refillInterval: time.Nanosecond}}}
+
+	assert.True(t, rl.notifyOnce("user"))
+	assert.False(t, rl.notifyOnce("user"))
+
+	// Taking a token resets the notification so the user gets notified the next time they're limited
+	allowed, _, _ := rl.take(keys)
+	require.True(t, allowed)
+	assert.True(t, rl.notifyOnce("user"))
+}
+
+func newChirpPlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "chirp"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.Contains(m.NormalizedText, "chickadee")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "chirp"}
+		},
+	}}
+
+	return p
+}
+
+func newRateLimitedConfig(policy string) (v *viper.Viper) {
+	v = config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set("plugins.chirp.rateLimits.policy", policy)
+	v.Set("plugins.chirp.rateLimits.user.capacity", 2)
+	v.Set("plugins.chirp.rateLimits.user.refillInterval", time.Hour)
+
+	return v
+}
+
+func newChickadeeEvents() []slack.RTMEvent {
+	return []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "chickadee", "Alphonse", "1546833210.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "chickadee", "Alphonse", "1546833211.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "chickadee", "Alphonse", "1546833212.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "chickadee", "Alphonse", "1546833213.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "chickadee", "Ignatius", "1546833214.036900")),
+	}
+}
+
+func TestRateLimitDropPolicy(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, newRateLimitedConfig(config.DropRateLimitPolicy), newChirpPlugin(), newChickadeeEvents(), nil)
+
+	assert.Equal(t, []string{"chirp", "chirp", "chirp"}, sentTexts(sentMsgs))
+}
+
+func TestRateLimitNotifyPolicy(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, newRateLimitedConfig(config.NotifyRateLimitPolicy), newChirpPlugin(), newChickadeeEvents(), nil)
+
+	require.Equal(t, []string{"chirp", "chirp", "🐢 Slow down! I'll get back to answering you in a bit", "chirp"}, sentTexts(sentMsgs))
+	assert.Equal(t, "Alphonse", applySlackOptions(sentMsgs[2].msgOptions...).Get("user"))
+}
+
+func TestRateLimitDelayPolicy(t *testing.T) {
+	v := newRateLimitedConfig(config.DelayRateLimitPolicy)
+	v.Set("plugins.chirp.rateLimits.user.refillInterval", time.Duration(20)*time.Millisecond)
+
+	before := time.Now()
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newChirpPlugin(), newChickadeeEvents(), nil)
+
+	assert.Equal(t, []string{"chirp", "chirp", "chirp", "chirp", "chirp"}, sentTexts(sentMsgs))
+	assert.True(t, time.Since(before) >= time.Duration(40)*time.Millisecond)
+}
+
+func TestRateLimitDelayPolicyDropsAnswersOverMaxDelay(t *testing.T) {
+	v := newRateLimitedConfig(config.DelayRateLimitPolicy)
+	v.Set("plugins.chirp.rateLimits.maxDelay", time.Duration(10)*time.Millisecond)
+
+	before := time.Now()
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newChirpPlugin(), newChickadeeEvents(), nil)
+
+	assert.Equal(t, []string{"chirp", "chirp", "chirp"}, sentTexts(sentMsgs))
+	assert.True(t, time.Since(before) < time.Hour)
+}
+
+func TestRateLimitNotAppliedToMessageUpdates(t *testing.T) {
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, newRateLimitedConfig(config.DropRateLimitPolicy), newChirpPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "chickadee", "Alphonse", "1546833210.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "chickadee", "Alphonse", "1546833211.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "chickadee", "Alphonse", "1546833212.036900")),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "chickadee", "Ignored", "1546833213.036900", optionChangedMessage("chickadee!", "Alphonse", "1546833210.036900"))),
+	}, nil)
+
+	assert.Equal(t, []string{"chirp", "chirp"}, sentTexts(sentMsgs))
+	assert.Len(t, updatedMsgs, 1)
+	assert.Empty(t, deletedMsgs)
+}
+
+func TestRateLimitDefaultsOverriddenByPluginConfig(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set("rateLimits.channel.capacity", 1)
+	v.Set("rateLimits.channel.refillInterval", time.Hour)
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newChirpPlugin(), newChickadeeEvents(), nil)
+	assert.Equal(t, []string{"chirp"}, sentTexts(sentMsgs))
+
+	v.Set("plugins.chirp.rateLimits.channel.capacity", 4)
+	sentMsgs, _, _, _ = runSlackscotWithIncomingEvents(t, v, newChirpPlugin(), newChickadeeEvents(), nil)
+	assert.Equal(t, []string{"chirp", "chirp", "chirp", "chirp"}, sentTexts(sentMsgs))
+}
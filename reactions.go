@@ -0,0 +1,161 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/slack-go/slack"
+	"time"
+)
+
+const (
+	reactionItemTypeMessage = "message"
+)
+
+// IncomingReaction holds the data of a reaction added to (or removed from) a message
+type IncomingReaction struct {
+	TeamID         string // The id of the team (workspace) the reaction happened in
+	User           string // The user who added or removed the reaction
+	Emoji          string // The name of the emoji without colons (i.e. "+1" or "pushpin")
+	ItemChannel    string // The channel of the message reacted to
+	ItemTimestamp  string // The timestamp of the message reacted to
+	ItemUser       string // The author of the message reacted to
+	EventTimestamp string // The timestamp of the reaction event
+	Removed        bool   // True if the reaction was removed or false if it was added
+}
+
+// ReactionMatcher is the function that determines whether or not a reaction action should be triggered
+// by an IncomingReaction
+type ReactionMatcher func(r *IncomingReaction) bool
+
+// ReactionAnswerer is what gets executed when a reaction action is triggered. The answer is sent on the channel of the
+// message reacted to. To signal the absence of an answer, an action should return nil
+type ReactionAnswerer func(r *IncomingReaction) *Answer
+
+// ReactionContextAnswerer is the context-aware variant of a ReactionAnswerer. The context is done when the action times out
+type ReactionContextAnswerer func(ctx context.Context, r *IncomingReaction) *Answer
+
+// ReactionActionDefinition represents how a reaction action is triggered, published, used and described
+// along with its actual action implementation
+type ReactionActionDefinition struct {
+	// Indicates whether the action should be omitted from the help message
+	Hidden bool
+
+	// Matcher that will determine whether or not the action should be triggered
+	Match ReactionMatcher
+
+	// Usage example (i.e. ":pushpin: a message")
+	Usage string
+
+	// Help description for the action
+	Description string
+
+	// Function to execute if the Matcher matches
+	Answer ReactionAnswerer
+
+	// Function to execute if the Matcher matches with a context.Context that is done when the action's
+	// execution times out. When set, it is used instead of Answer
+	ContextAnswer ReactionContextAnswerer
+
+	// Maximum execution time of the action after which its answer is abandoned. If zero,
+	// the config.ActionTimeoutKey configuration applies
+	Timeout time.Duration
+
+	// Roles the user who reacted must have to run the action (see ActionDefinition.RequiredRoles)
+	RequiredRoles []string
+}
+
+// answer invokes the ContextAnswer, if set, or the Answer
+func (a ReactionActionDefinition) answer(ctx context.Context, r *IncomingReaction) *Answer {
+	if a.ContextAnswer != nil {
+		return a.ContextAnswer(ctx, r)
+	}
+
+	return a.Answer(r)
+}
+
+// newIncomingReaction returns a new IncomingReaction for the data of a reaction event
+func newIncomingReaction(teamID string, user string, emoji string, itemUser string, itemType string, itemChannel string, itemTimestamp string, eventTimestamp string, removed bool) (r IncomingReaction, isOnMessage bool) {
+	r = IncomingReaction{TeamID: teamID, User: user, Emoji: emoji, ItemUser: itemUser, ItemChannel: itemChannel, ItemTimestamp: itemTimestamp, EventTimestamp: eventTimestamp, Removed: removed}
+
+	return r, itemType == reactionItemTypeMessage
+}
+
+// newIncomingReactionFromAdded returns an IncomingReaction for a slack.ReactionAddedEvent of a team and whether or not the
+// reaction is on a message (reactions to files aren't supported)
+func newIncomingReactionFromAdded(teamID string, e *slack.ReactionAddedEvent) (r IncomingReaction, isOnMessage bool) {
+	return newIncomingReaction(teamID, e.User, e.Reaction, e.ItemUser, e.Item.Type, e.Item.Channel, e.Item.Timestamp, e.EventTimestamp, false)
+}
+
+// newIncomingReactionFromRemoved returns an IncomingReaction for a slack.ReactionRemovedEvent of a team and whether or not the
+// reaction is on a message (reactions to files aren't supported)
+func newIncomingReactionFromRemoved(teamID string, e *slack.ReactionRemovedEvent) (r IncomingReaction, isOnMessage bool) {
+	return newIncomingReaction(teamID, e.User, e.Reaction, e.ItemUser, e.Item.Type, e.Item.Channel, e.Item.Timestamp, e.EventTimestamp, true)
+}
+
+// processReaction runs the reaction actions of all plugins and sends their answers. Answers are
+// sent as new messages on the channel of the message reacted to (or in its thread if answering in threads)
+func (s *Slackscot) processReaction(ctx context.Context, ws *workspace, sender messageSender, r IncomingReaction) {
+	if r.User == ws.selfIdentity.id {
+		s.log.Debugf("Ignoring reaction [%s] from ourselves", r.Emoji)
+		return
+	}
+
+	for _, p := range s.plugins {
+		for _, o := range s.tryPluginReactionActions(ctx, p, r) {
+			if _, err := s.sendNewMessage(sender, o, r.ItemTimestamp); err != nil {
+				s.log.Printf("Unable to send new message triggered by reaction [%s] on [%s/%s]: %v\n", r.Emoji, r.ItemChannel, r.ItemTimestamp, err)
+			}
+		}
+	}
+}
+
+// tryPluginReactionActions invokes all reaction actions of a plugin matching the reaction and returns their answers
+func (s *Slackscot) tryPluginReactionActions(ctx context.Context, p *Plugin, r IncomingReaction) (outMsgs []OutgoingMessage) {
+	return s.tryEventActions(ctx, newReactionActionCandidates(p, r))
+}
+
+// newReactionActionCandidates returns the reaction actions of a plugin as candidates to answer a reaction. The candidates' message
+// stands for the reaction: it's from the user who reacted and on the message reacted to
+func newReactionActionCandidates(p *Plugin, r IncomingReaction) (candidates []actionCandidate) {
+	m := IncomingMessage{TeamID: r.TeamID, Msg: slack.Msg{Type: "message", Team: r.TeamID, Channel: r.ItemChannel, User: r.User, Timestamp: r.ItemTimestamp}}
+
+	candidates = make([]actionCandidate, 0, len(p.ReactionActions))
+	for i, action := range p.ReactionActions {
+		candidates = append(candidates, actionCandidate{p: p, actionType: ReactionActionType, actionID: getActionID(p.Name, ReactionActionType, i), action: newReactionAction(action, r), msg: m})
+	}
+
+	return candidates
+}
+
+// newReactionAction returns the ActionDefinition running a reaction action with its own copy of the reaction
+func newReactionAction(action ReactionActionDefinition, r IncomingReaction) (a ActionDefinition) {
+	return ActionDefinition{
+		Match: func(m *IncomingMessage) bool {
+			return action.Match(&r)
+		},
+		ContextAnswer: func(ctx context.Context, m *IncomingMessage) *Answer {
+			return action.answer(ctx, &r)
+		},
+		Timeout:       action.Timeout,
+		RequiredRoles: action.RequiredRoles,
+	}
+}
+
+// tryEventActions invokes the candidate actions triggered by an event other than a message (i.e. a reaction or an interaction) like the actions
+// triggered by messages (see tryAction) and returns their answers, to be sent on the channel of the candidates' message. Unlike
+// commands and hear actions, all matching actions answer, in order
+func (s *Slackscot) tryEventActions(ctx context.Context, candidates []actionCandidate) (outMsgs []OutgoingMessage) {
+	outMsgs = make([]OutgoingMessage, 0)
+	usages := newPluginActionUsages()
+
+	for _, c := range candidates {
+		usage := usages.of(c.p)
+
+		if answer, _, _ := s.tryAction(ctx, c, usage); answer != nil {
+			outMsgs = append(outMsgs, s.processAnswer(c, answer, send, usage))
+		}
+	}
+
+	usages.record(s)
+
+	return outMsgs
+}
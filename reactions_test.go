@@ -0,0 +1,146 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newReactionEvent(eventType string, user string, emoji string, itemUser string, channel string, ts string) (e slack.RTMEvent) {
+	body := fmt.Sprintf(`{"type": "%s", "user": "%s", "reaction": "%s", "item_user": "%s", "item": {"type": "message", "channel": "%s", "ts": "%s"}, "event_ts": "1546833220.036900"}`, eventType, user, emoji, itemUser, channel, ts)
+
+	callback := eventsAPICallback{Type: "event_callback", Event: []byte(body)}
+	e, _, _ = newRTMEventFromEventsAPI(callback)
+
+	return e
+}
+
+func newReactionPlugin(reactions *[]IncomingReaction) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "pinner"
+	p.ReactionActions = []ReactionActionDefinition{{
+		Match: func(r *IncomingReaction) bool {
+			return r.Emoji == "pushpin"
+		},
+		Usage:       ":pushpin: a message",
+		Description: "Pin the message",
+		Answer: func(r *IncomingReaction) *Answer {
+			*reactions = append(*reactions, *r)
+
+			if r.Removed {
+				return &Answer{Text: fmt.Sprintf("Unpinned message from <@%s>", r.ItemUser)}
+			}
+
+			return &Answer{Text: fmt.Sprintf("Pinned message from <@%s>", r.ItemUser), Options: []AnswerOption{AnswerInThread()}}
+		},
+	}}
+
+	return p
+}
+
+func TestReactionActions(t *testing.T) {
+	reactions := make([]IncomingReaction, 0)
+
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, nil, newReactionPlugin(&reactions), []slack.RTMEvent{
+		newReactionEvent("reaction_added", "Alphonse", "pushpin", "Ignace", "Cgeneral", timestamp1),
+		newReactionEvent("reaction_added", "Alphonse", "+1", "Ignace", "Cgeneral", timestamp1),
+		newReactionEvent("reaction_removed", "Alphonse", "pushpin", "Ignace", "Cgeneral", timestamp1),
+	}, nil)
+
+	assert.Equal(t, []IncomingReaction{
+		{User: "Alphonse", Emoji: "pushpin", ItemChannel: "Cgeneral", ItemTimestamp: timestamp1, ItemUser: "Ignace", EventTimestamp: "1546833220.036900", Removed: false},
+		{User: "Alphonse", Emoji: "pushpin", ItemChannel: "Cgeneral", ItemTimestamp: timestamp1, ItemUser: "Ignace", EventTimestamp: "1546833220.036900", Removed: true},
+	}, reactions)
+
+	if assert.Equal(t, 2, len(sentMsgs)) {
+		assert.Equal(t, "Cgeneral", sentMsgs[0].channelID)
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "Pinned message from <@Ignace>", vals.Get("text"))
+		assert.Equal(t, timestamp1, vals.Get("thread_ts"))
+
+		assert.Equal(t, "Cgeneral", sentMsgs[1].channelID)
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "Unpinned message from <@Ignace>", vals.Get("text"))
+		assert.Equal(t, "", vals.Get("thread_ts"))
+	}
+
+	assert.Equal(t, 0, len(updatedMsgs))
+	assert.Equal(t, 0, len(deletedMsgs))
+}
+
+func TestReactionFromOurselfIgnored(t *testing.T) {
+	reactions := make([]IncomingReaction, 0)
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newReactionPlugin(&reactions), []slack.RTMEvent{
+		newReactionEvent("reaction_added", botUserID, "pushpin", "Ignace", "Cgeneral", timestamp1),
+	}, nil)
+
+	assert.Empty(t, reactions)
+	assert.Equal(t, 0, len(sentMsgs))
+}
+
+func TestHelpWithReactionActions(t *testing.T) {
+	s, err := New("robert", config.NewViperWithDefaults())
+	require.NoError(t, err)
+
+	reactions := make([]IncomingReaction, 0)
+	p := newReactionPlugin(&reactions)
+	p.ReactionActions = append(p.ReactionActions, ReactionActionDefinition{Hidden: true, Usage: ":x: a message", Description: "Delete the message"})
+	s.RegisterPlugin(p)
+
+	help := s.newHelpPlugin("1.0.0")
+	help.UserInfoFinder = &userInfoFinder{}
+
+	a := help.Commands[0].Answer(&IncomingMessage{NormalizedText: "help"})
+	require.NotNil(t, a)
+
+	assert.Equal(t, "🤝 Hi, `Daniel Quinn`! I'm `robert` (engine `v1.0.0`) and I listen to the team's chat and provides automated functions :genie:.\n\n"+
+		"And react to the following reactions:\n\t• `:pushpin: a message` - Pin the message\n", a.Text)
+}
+
+func TestReactionActionsRunThroughMiddlewaresAndAuthorization(t *testing.T) {
+	v := newAuthorizationConfig()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	invocations := make([]string, 0)
+	recordInvocation := func(next ActionInvoker) ActionInvoker {
+		return func(ctx context.Context, inv *ActionInvocation) *Answer {
+			invocations = append(invocations, fmt.Sprintf("%s by %s on %s", inv.ActionID, inv.Message.User, inv.Message.Channel))
+			return next(ctx, inv)
+		}
+	}
+
+	reactions := make([]IncomingReaction, 0)
+	p := newReactionPlugin(&reactions)
+	p.ReactionActions[0].RequiredRoles = []string{AdminRole}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, p, []slack.RTMEvent{
+		newReactionEvent("reaction_added", "Alphonse", "pushpin", "Ignace", "Cgeneral", timestamp1),
+		newReactionEvent("reaction_added", "Jane", "pushpin", "Ignace", "Cgeneral", timestamp1),
+	}, nil, OptionMiddleware(recordInvocation))
+
+	assert.Equal(t, []string{"pinner.reactionAction[0] by Alphonse on Cgeneral", "pinner.reactionAction[0] by Jane on Cgeneral"}, invocations)
+	assert.Equal(t, []string{"Pinned message from <@Ignace>", "🚫 Sorry, you're not authorized to do that"}, sentTexts(sentMsgs))
+}
+
+func TestReactionActionTimeout(t *testing.T) {
+	reactions := make([]IncomingReaction, 0)
+	p := newReactionPlugin(&reactions)
+	p.ReactionActions[0].Timeout = time.Duration(10) * time.Millisecond
+	p.ReactionActions[0].ContextAnswer = func(ctx context.Context, r *IncomingReaction) *Answer {
+		<-ctx.Done()
+		return &Answer{Text: "Pinned too late"}
+	}
+
+	sentMsgs, _, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, nil, p, []slack.RTMEvent{
+		newReactionEvent("reaction_added", "Alphonse", "pushpin", "Ignace", "Cgeneral", timestamp1),
+	})
+
+	assert.Empty(t, sentMsgs)
+	assert.Contains(t, logs, "Action [pinner.reactionAction[0]] didn't complete within [10ms], abandoning its answer: context deadline exceeded")
+}
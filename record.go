@@ -0,0 +1,378 @@
+package slackscot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/slack-go/slack"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Kinds of recorded events
+const (
+	connectedEventKind       = "connected"
+	messageEventKind         = "message"
+	reactionAddedEventKind   = "reaction_added"
+	reactionRemovedEventKind = "reaction_removed"
+	interactionEventKind     = "interaction"
+	slashCommandEventKind    = "slash_command"
+)
+
+// Methods of recorded calls
+const (
+	SendMessageCall         = "SendMessage"
+	UpdateMessageCall       = "UpdateMessage"
+	DeleteMessageCall       = "DeleteMessage"
+	AddReactionCall         = "AddReaction"
+	UploadFileCall          = "UploadFile"
+	SendRealTimeMessageCall = "SendRealTimeMessage"
+)
+
+// RecordedEntry is a line of a recording. Each entry holds one of an incoming event, an outgoing call or the info of a user
+// or of the bot itself (as looked up from slack) along with the name of the workspace it's from (empty for the default workspace)
+type RecordedEntry struct {
+	Workspace string         `json:"workspace,omitempty"`
+	Event     *RecordedEvent `json:"event,omitempty"`
+	Call      *RecordedCall  `json:"call,omitempty"`
+	User      *slack.User    `json:"user,omitempty"`
+	Self      *slack.Info    `json:"self,omitempty"`
+}
+
+// RecordedEvent is an incoming event of a recording
+type RecordedEvent struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+// RecordedCall is an outgoing call of a recording. Values hold the parameters of the call as they'd be sent to the slack api
+// and Response is the timestamp of the message returned by slack for sent and updated messages
+type RecordedCall struct {
+	Workspace string              `json:"-"`
+	Method    string              `json:"method"`
+	ChannelID string              `json:"channelId,omitempty"`
+	Timestamp string              `json:"timestamp,omitempty"`
+	Values    map[string][]string `json:"values,omitempty"`
+	Response  string              `json:"response,omitempty"`
+}
+
+// String returns the json representation of a RecordedCall
+func (rc RecordedCall) String() string {
+	rendered, err := json.Marshal(rc)
+	if err != nil {
+		return fmt.Sprintf("%#v", rc)
+	}
+
+	return string(rendered)
+}
+
+// OptionRecorder records every incoming event and every outgoing call (messages sent, updated and deleted, emoji reactions
+// and file uploads) to the writer as JSON Lines of RecordedEntry. Recordings can be replayed with Replay to reproduce issues
+// in tests
+func OptionRecorder(w io.Writer) Option {
+	return func(s *Slackscot) {
+		s.recorder = &recorder{encoder: json.NewEncoder(w), log: s.log}
+	}
+}
+
+// recorder writes RecordedEntry lines
+type recorder struct {
+	encoder *json.Encoder
+	log     *sLogger
+	lock    sync.Mutex
+}
+
+// record writes an entry, logging errors
+func (r *recorder) record(entry RecordedEntry) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.encoder.Encode(entry); err != nil {
+		r.log.Printf("Error recording entry: %v", err)
+	}
+}
+
+// recordEvent records an incoming event of a workspace. Only events processed by slackscot are recorded
+func (r *recorder) recordEvent(workspace string, e slack.RTMEvent) {
+	var kind string
+	switch e.Data.(type) {
+	case *slack.ConnectedEvent:
+		kind = connectedEventKind
+	case *slack.MessageEvent:
+		kind = messageEventKind
+	case *slack.ReactionAddedEvent:
+		kind = reactionAddedEventKind
+	case *slack.ReactionRemovedEvent:
+		kind = reactionRemovedEventKind
+	case *slack.InteractionCallback:
+		kind = interactionEventKind
+	case *slack.SlashCommand:
+		kind = slashCommandEventKind
+	default:
+		return
+	}
+
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		r.log.Printf("Error recording event [%s]: %v", kind, err)
+		return
+	}
+
+	r.record(RecordedEntry{Workspace: workspace, Event: &RecordedEvent{Kind: kind, Data: data}})
+}
+
+// wrap returns runtime dependencies recording their calls
+func (r *recorder) wrap(workspace string, deps *runDependencies) *runDependencies {
+	wrapped := *deps
+	wrapped.chatDriver = &recordingChatDriver{chatDriver: deps.chatDriver, recorder: r, workspace: workspace}
+	wrapped.userInfoFinder = &recordingUserInfoFinder{userInfoFinder: deps.userInfoFinder, recorder: r, workspace: workspace}
+	wrapped.selfInfoFinder = &recordingSelfInfoFinder{selfInfoFinder: deps.selfInfoFinder, recorder: r, workspace: workspace}
+
+	if deps.emojiReactor != nil {
+		wrapped.emojiReactor = &recordingEmojiReactor{emojiReactor: deps.emojiReactor, recorder: r, workspace: workspace}
+	}
+
+	if deps.fileUploader != nil {
+		wrapped.fileUploader = &recordingFileUploader{fileUploader: deps.fileUploader, recorder: r, workspace: workspace}
+	}
+
+	if deps.realTimeMsgSender != nil {
+		wrapped.realTimeMsgSender = &recordingRealTimeMessageSender{RealTimeMessageSender: deps.realTimeMsgSender, recorder: r, workspace: workspace}
+	}
+
+	return &wrapped
+}
+
+// msgOptionValues returns the values of message options as they'd be sent to the slack api
+func msgOptionValues(channelID string, options ...slack.MsgOption) (values map[string][]string) {
+	_, vals, _ := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	vals.Del("token")
+	vals.Del("channel")
+
+	return vals
+}
+
+// recordingChatDriver records the calls of a chatDriver
+type recordingChatDriver struct {
+	chatDriver chatDriver
+	recorder   *recorder
+	workspace  string
+}
+
+// bindContext returns a copy of the recordingChatDriver with its chatDriver bound to the context
+func (d *recordingChatDriver) bindContext(ctx context.Context) interface{} {
+	bound := *d
+	bound.chatDriver = bindContext(ctx, d.chatDriver).(chatDriver)
+
+	return &bound
+}
+
+// SendMessage sends a message and records the call
+func (d *recordingChatDriver) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	rChannelID, rTimestamp, rText, err = d.chatDriver.SendMessage(channelID, options...)
+	d.recorder.record(RecordedEntry{Workspace: d.workspace, Call: &RecordedCall{Method: SendMessageCall, ChannelID: channelID, Values: msgOptionValues(channelID, options...), Response: rTimestamp}})
+
+	return rChannelID, rTimestamp, rText, err
+}
+
+// UpdateMessage updates a message and records the call
+func (d *recordingChatDriver) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	rChannelID, rTimestamp, rText, err = d.chatDriver.UpdateMessage(channelID, timestamp, options...)
+	d.recorder.record(RecordedEntry{Workspace: d.workspace, Call: &RecordedCall{Method: UpdateMessageCall, ChannelID: channelID, Timestamp: timestamp, Values: msgOptionValues(channelID, options...), Response: rTimestamp}})
+
+	return rChannelID, rTimestamp, rText, err
+}
+
+// DeleteMessage deletes a message and records the call
+func (d *recordingChatDriver) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
+	rChannelID, rTimestamp, err = d.chatDriver.DeleteMessage(channelID, timestamp)
+	d.recorder.record(RecordedEntry{Workspace: d.workspace, Call: &RecordedCall{Method: DeleteMessageCall, ChannelID: channelID, Timestamp: timestamp}})
+
+	return rChannelID, rTimestamp, err
+}
+
+// recordingEmojiReactor records the reactions of an EmojiReactor
+type recordingEmojiReactor struct {
+	emojiReactor EmojiReactor
+	recorder     *recorder
+	workspace    string
+}
+
+// bindContext returns a copy of the recordingEmojiReactor with its EmojiReactor bound to the context
+func (er *recordingEmojiReactor) bindContext(ctx context.Context) interface{} {
+	bound := *er
+	bound.emojiReactor = bindContext(ctx, er.emojiReactor).(EmojiReactor)
+
+	return &bound
+}
+
+// AddReaction adds a reaction and records the call
+func (er *recordingEmojiReactor) AddReaction(name string, item slack.ItemRef) error {
+	err := er.emojiReactor.AddReaction(name, item)
+	er.recorder.record(RecordedEntry{Workspace: er.workspace, Call: newAddReactionCall(name, item)})
+
+	return err
+}
+
+// newAddReactionCall returns the RecordedCall of an emoji reaction
+func newAddReactionCall(name string, item slack.ItemRef) *RecordedCall {
+	return &RecordedCall{Method: AddReactionCall, ChannelID: item.Channel, Timestamp: item.Timestamp, Values: map[string][]string{"name": {name}}}
+}
+
+// recordingFileUploader records the uploads of a FileUploader
+type recordingFileUploader struct {
+	fileUploader FileUploader
+	recorder     *recorder
+	workspace    string
+}
+
+// bindContext returns a copy of the recordingFileUploader with its FileUploader bound to the context
+func (fu *recordingFileUploader) bindContext(ctx context.Context) interface{} {
+	bound := *fu
+	bound.fileUploader = bindContext(ctx, fu.fileUploader).(FileUploader)
+
+	return &bound
+}
+
+// UploadFile uploads a file and records the call
+func (fu *recordingFileUploader) UploadFile(params slack.FileUploadParameters, options ...UploadOption) (file *slack.File, err error) {
+	file, err = fu.fileUploader.UploadFile(params, options...)
+
+	for _, opt := range options {
+		opt(&params)
+	}
+	fu.recorder.record(RecordedEntry{Workspace: fu.workspace, Call: newUploadFileCall(params)})
+
+	return file, err
+}
+
+// newUploadFileCall returns the RecordedCall of a file upload. The content of files read from a reader isn't recorded
+func newUploadFileCall(params slack.FileUploadParameters) *RecordedCall {
+	values := url.Values{}
+	for k, v := range map[string]string{"filename": params.Filename, "title": params.Title, "filetype": params.Filetype, "content": params.Content, "initial_comment": params.InitialComment, "thread_ts": params.ThreadTimestamp, "channels": strings.Join(params.Channels, ",")} {
+		if v != "" {
+			values.Set(k, v)
+		}
+	}
+
+	return &RecordedCall{Method: UploadFileCall, Values: values}
+}
+
+// recordingRealTimeMessageSender records the messages sent by a RealTimeMessageSender
+type recordingRealTimeMessageSender struct {
+	RealTimeMessageSender
+	recorder  *recorder
+	workspace string
+}
+
+// SendMessage sends a real time message and records the call
+func (rs *recordingRealTimeMessageSender) SendMessage(outMsg *slack.OutgoingMessage) {
+	rs.RealTimeMessageSender.SendMessage(outMsg)
+	rs.recorder.record(RecordedEntry{Workspace: rs.workspace, Call: newSendRealTimeMessageCall(outMsg)})
+}
+
+// newSendRealTimeMessageCall returns the RecordedCall of a real time message
+func newSendRealTimeMessageCall(outMsg *slack.OutgoingMessage) *RecordedCall {
+	values := url.Values{}
+	values.Set("text", outMsg.Text)
+	if outMsg.ThreadTimestamp != "" {
+		values.Set("thread_ts", outMsg.ThreadTimestamp)
+	}
+
+	return &RecordedCall{Method: SendRealTimeMessageCall, ChannelID: outMsg.Channel, Values: values}
+}
+
+// recordingUserInfoFinder records the users found by a UserInfoFinder
+type recordingUserInfoFinder struct {
+	userInfoFinder UserInfoFinder
+	recorder       *recorder
+	workspace      string
+}
+
+// bindContext returns a copy of the recordingUserInfoFinder with its UserInfoFinder bound to the context
+func (uf *recordingUserInfoFinder) bindContext(ctx context.Context) interface{} {
+	bound := *uf
+	bound.userInfoFinder = bindContext(ctx, uf.userInfoFinder).(UserInfoFinder)
+
+	return &bound
+}
+
+// GetUserInfo finds a user and records it
+func (uf *recordingUserInfoFinder) GetUserInfo(userID string) (user *slack.User, err error) {
+	user, err = uf.userInfoFinder.GetUserInfo(userID)
+	if err == nil && user != nil {
+		uf.recorder.record(RecordedEntry{Workspace: uf.workspace, User: user})
+	}
+
+	return user, err
+}
+
+// recordingSelfInfoFinder records the info of the bot found by a selfInfoFinder
+type recordingSelfInfoFinder struct {
+	selfInfoFinder selfInfoFinder
+	recorder       *recorder
+	workspace      string
+}
+
+// GetInfo returns the info of the bot and records it
+func (sf *recordingSelfInfoFinder) GetInfo() (info *slack.Info) {
+	info = sf.selfInfoFinder.GetInfo()
+	if info != nil {
+		sf.recorder.record(RecordedEntry{Workspace: sf.workspace, Self: info})
+	}
+
+	return info
+}
+
+// ReadRecording reads the entries of a recording
+func ReadRecording(r io.Reader) (entries []RecordedEntry, err error) {
+	entries = make([]RecordedEntry, 0)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var entry RecordedEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("Error reading recording entry on line [%d]: %v", line, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// newRTMEvent returns the slack.RTMEvent of a RecordedEvent
+func (re *RecordedEvent) newRTMEvent() (e slack.RTMEvent, err error) {
+	var data interface{}
+	switch re.Kind {
+	case connectedEventKind:
+		data = new(slack.ConnectedEvent)
+	case messageEventKind:
+		data = new(slack.MessageEvent)
+	case reactionAddedEventKind:
+		data = new(slack.ReactionAddedEvent)
+	case reactionRemovedEventKind:
+		data = new(slack.ReactionRemovedEvent)
+	case interactionEventKind:
+		data = new(slack.InteractionCallback)
+	case slashCommandEventKind:
+		data = new(slack.SlashCommand)
+	default:
+		return e, fmt.Errorf("Unknown recorded event kind [%s]", re.Kind)
+	}
+
+	if err := json.Unmarshal(re.Data, data); err != nil {
+		return e, fmt.Errorf("Error reading recorded event [%s]: %v", re.Kind, err)
+	}
+
+	return slack.RTMEvent{Type: re.Kind, Data: data}, nil
+}
@@ -0,0 +1,157 @@
+package slackscot
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"strings"
+	"testing"
+)
+
+func recordTestEvents(t *testing.T, events []slack.RTMEvent) (recording *bytes.Buffer) {
+	recording = new(bytes.Buffer)
+	runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), events, nil, OptionLog(log.New(&nullWriter{}, "", 0)), OptionRecorder(recording))
+
+	return recording
+}
+
+func newEditAndDeleteTestEvents() []slack.RTMEvent {
+	return []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s noRules block hello you", formattedBotUserID), "Alphonse", timestamp2)),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Ignored", "1546833210.036911", optionChangedMessage("blue jays eat acorn", "Alphonse", timestamp1))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s noRules block hello you", formattedBotUserID), "Ignored", "1546833210.036912", optionChangedMessage(fmt.Sprintf("%s noRules block hello everyone", formattedBotUserID), "Alphonse", timestamp2))),
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "", "Ignored", "1546833210.036913", optionDeletedMessage("Cgeneral", timestamp1))),
+	}
+}
+
+func TestRecordEventsAndCalls(t *testing.T) {
+	recording := recordTestEvents(t, newEditAndDeleteTestEvents())
+
+	entries, err := ReadRecording(recording)
+	require.NoError(t, err)
+
+	kinds := make([]string, 0)
+	calls := make([]string, 0)
+	for _, entry := range entries {
+		if entry.Event != nil {
+			kinds = append(kinds, entry.Event.Kind)
+		}
+
+		if entry.Call != nil {
+			calls = append(calls, entry.Call.Method)
+		}
+	}
+
+	assert.Equal(t, []string{"connected", "message", "message", "message", "message", "message"}, kinds)
+	assert.Equal(t, []string{SendMessageCall, SendMessageCall, UpdateMessageCall, UpdateMessageCall, DeleteMessageCall}, calls)
+}
+
+func TestRecordedEventRoundTrip(t *testing.T) {
+	recording := recordTestEvents(t, newEditAndDeleteTestEvents())
+
+	entries, err := ReadRecording(recording)
+	require.NoError(t, err)
+
+	events := make([]*slack.MessageEvent, 0)
+	for _, entry := range entries {
+		if entry.Event != nil && entry.Event.Kind == messageEventKind {
+			e, err := entry.Event.newRTMEvent()
+			require.NoError(t, err)
+
+			events = append(events, e.Data.(*slack.MessageEvent))
+		}
+	}
+
+	if assert.Equal(t, 5, len(events)) {
+		assert.Equal(t, "blue jays", events[0].Text)
+		assert.Equal(t, timestamp1, events[0].Timestamp)
+		assert.Equal(t, "message_changed", events[2].SubType)
+		assert.Equal(t, "blue jays eat acorn", events[2].SubMessage.Text)
+		assert.Equal(t, "message_deleted", events[4].SubType)
+		assert.Equal(t, timestamp1, events[4].DeletedTimestamp)
+	}
+}
+
+func TestReplayRecording(t *testing.T) {
+	recording := recordTestEvents(t, newEditAndDeleteTestEvents())
+
+	result, err := Replay("chickadee", config.NewViperWithDefaults(), []*Plugin{newTestPlugin()}, recording, OptionLog(log.New(&nullWriter{}, "", 0)))
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, len(result.Recorded))
+	assert.Equal(t, result.Recorded, result.Replayed, result.Diff())
+	assert.Empty(t, result.Diff())
+}
+
+func TestReplayRecordingWithChangedPlugin(t *testing.T) {
+	recording := recordTestEvents(t, newEditAndDeleteTestEvents())
+
+	p := newTestPlugin()
+	p.HearActions = nil
+
+	result, err := Replay("chickadee", config.NewViperWithDefaults(), []*Plugin{p}, recording, OptionLog(log.New(&nullWriter{}, "", 0)))
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, len(result.Recorded))
+	assert.Equal(t, 2, len(result.Replayed))
+	assert.Contains(t, result.Diff(), "I heard you say something about blue jays?")
+}
+
+func TestReplayLeavesConfigUntouched(t *testing.T) {
+	recording := recordTestEvents(t, newEditAndDeleteTestEvents())
+
+	v := config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 4)
+
+	_, err := Replay("chickadee", v, []*Plugin{newTestPlugin()}, recording, OptionLog(log.New(&nullWriter{}, "", 0)))
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, v.GetInt(config.MessageProcessingPartitionCount))
+}
+
+func TestCopyConfig(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set("plugins.karma.format", "<@%s>")
+
+	c := copyConfig(v)
+	c.Set(config.MessageProcessingPartitionCount, 1)
+
+	assert.Equal(t, "<@%s>", c.Sub("plugins.karma").GetString("format"))
+	assert.Equal(t, v.GetDuration(config.ShutdownGracePeriodKey), c.GetDuration(config.ShutdownGracePeriodKey))
+	assert.NotEqual(t, 1, v.GetInt(config.MessageProcessingPartitionCount))
+}
+
+func TestReadRecordingWithInvalidEntry(t *testing.T) {
+	_, err := ReadRecording(strings.NewReader("{\"event\":{\"kind\":\"message\",\"data\":{}}}\nnot json\n"))
+
+	assert.EqualError(t, err, "Error reading recording entry on line [2]: invalid character 'o' in literal null (expecting 'u')")
+}
+
+func TestReplayRecordingWithUnknownEventKind(t *testing.T) {
+	_, err := Replay("chickadee", config.NewViperWithDefaults(), []*Plugin{newTestPlugin()}, strings.NewReader("{\"event\":{\"kind\":\"goodbye\",\"data\":{}}}\n"), OptionLog(log.New(&nullWriter{}, "", 0)))
+
+	assert.EqualError(t, err, "Unknown recorded event kind [goodbye]")
+}
+
+// failingWriter is an io.Writer failing all writes
+type failingWriter struct {
+}
+
+func (w *failingWriter) Write(p []byte) (n int, err error) {
+	return 0, fmt.Errorf("disk full")
+}
+
+func TestRecorderLogsErrorsWithoutRunning(t *testing.T) {
+	var logs strings.Builder
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionRecorder(&failingWriter{}), OptionLog(log.New(&logs, "", 0)))
+	require.NoError(t, err)
+
+	s.recorder.recordEvent("default", newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)))
+
+	assert.Equal(t, "Error recording entry: disk full\n", logs.String())
+}
@@ -0,0 +1,416 @@
+package slackscot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+)
+
+// configurablePlugin is a plugin instantiated from its configuration (at plugins.<name>) and re-instantiated when
+// that configuration changes on reload
+type configurablePlugin struct {
+	name        string
+	newInstance CloserPluginInstantiator
+	settings    map[string]interface{}
+	plugin      *Plugin
+	closer      io.Closer
+}
+
+// pluginInstance is a new instance of a configurable plugin created on reload
+type pluginInstance struct {
+	cp          *configurablePlugin
+	settings    map[string]interface{}
+	plugin      *Plugin
+	closer      io.Closer
+	initialized bool
+}
+
+// Close closes the current instance of the plugin, if it's a closer
+func (cp *configurablePlugin) Close() (err error) {
+	if cp.closer == nil {
+		return nil
+	}
+
+	return cp.closer.Close()
+}
+
+// OptionWatchConfig sets slackscot to reload its configuration (see ReloadConfig) whenever its configuration file changes
+func OptionWatchConfig() Option {
+	return func(s *Slackscot) {
+		s.watchConfig = true
+	}
+}
+
+// OptionReloadConfigOnSIGHUP sets slackscot to reload its configuration (see ReloadConfig) when the process receives a SIGHUP
+func OptionReloadConfigOnSIGHUP() Option {
+	return func(s *Slackscot) {
+		s.reloadOnSIGHUP = true
+	}
+}
+
+// registerConfigurablePlugin registers a plugin instantiated from its configuration so that it can be re-instantiated
+// on reload. Its closer, if any, is closed along with the other closers
+func (s *Slackscot) registerConfigurablePlugin(name string, newInstance CloserPluginInstantiator, pc *config.PluginConfig, closer io.Closer, p *Plugin) {
+	cp := &configurablePlugin{name: name, newInstance: newInstance, settings: pc.AllSettings(), plugin: p, closer: closer}
+
+	s.configurablePlugins = append(s.configurablePlugins, cp)
+	s.closers = append(s.closers, cp)
+	s.RegisterPlugin(p)
+}
+
+// ReloadConfig reads the configuration file again and applies it while slackscot is running. Plugins added with
+// Builder.WithConfigurablePluginErr or Builder.WithConfigurablePluginCloserErr whose configuration (at plugins.<name>)
+// changed are re-instantiated and swapped in between the processing of events after which their old instances are
+// stopped (see PluginLifecycle) and closed. The help plugin and the scheduler of scheduled actions are then rebuilt with
+// the new instances.
+//
+// The content of the configuration file replaces the current one so that settings removed from the file fall back to their
+// defaults. Values set programmatically (with viper's Set) are kept.
+//
+// If the new configuration can't be read or if a plugin fails to instantiate (or to initialize or start) with its new
+// configuration, the new configuration is rejected and the current configuration and plugin instances are kept. Since new
+// instances are created before the old ones are closed, instantiators shouldn't acquire resources that can only be held
+// once (like a leveldb storer opened on a path).
+//
+// Reloads wait for the events and scheduled actions being processed to complete so ReloadConfig shouldn't be called
+// from a plugin action
+func (s *Slackscot) ReloadConfig() (err error) {
+	configFile := s.config.ConfigFileUsed()
+	if configFile == "" {
+		return fmt.Errorf("Can't reload configuration that wasn't read from a configuration file")
+	}
+
+	// The file is read once so that the configuration validated is the one applied even if the file changes in between
+	content, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("Error reading configuration file [%s]: %v", configFile, err)
+	}
+
+	next := viper.New()
+	next.SetConfigFile(configFile)
+	if err = next.ReadConfig(bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("Error reading configuration file [%s]: %v", configFile, err)
+	}
+
+	return s.applyConfig(config.LayerConfigWithDefaults(next), content)
+}
+
+// applyConfig validates the new configuration by re-instantiating the plugins whose configuration changed and, if
+// they all instantiate successfully, replaces the configuration with the content of the configuration file, swaps the
+// new instances in and rebuilds the help plugin and the scheduler
+func (s *Slackscot) applyConfig(next *viper.Viper, content []byte) (err error) {
+	timeLoc, err := config.GetTimeLocation(next)
+	if err != nil {
+		return err
+	}
+
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	if s.stopScheduler == nil {
+		return fmt.Errorf("Configuration can only be reloaded while running")
+	}
+
+	services := s.workspaces.defaultWorkspace().services
+	if services == nil {
+		services = &WorkspaceServices{}
+	}
+
+	instances, err := s.newPluginInstances(next, services)
+	if err != nil {
+		return err
+	}
+
+	timeLocChanged := next.GetString(config.TimeLocationKey) != s.config.GetString(config.TimeLocationKey)
+	if err = s.config.ReadConfig(bytes.NewReader(content)); err != nil {
+		s.closePluginInstances(instances)
+		return err
+	}
+
+	reloaded := make([]string, 0)
+	for _, instance := range instances {
+		s.replacePlugin(instance.cp.plugin, instance.plugin)
+		s.replaceInitializedPlugin(instance.cp.plugin, instance)
+
+		if err := instance.cp.Close(); err != nil {
+			s.log.Warn("Error closing plugin replaced on reload", "plugin", instance.cp.name, "err", err)
+		}
+
+		instance.cp.settings = instance.settings
+		instance.cp.plugin = instance.plugin
+		instance.cp.closer = instance.closer
+		reloaded = append(reloaded, instance.cp.name)
+	}
+
+	if len(reloaded) > 0 || timeLocChanged {
+		s.rebuildHelpPlugin(services)
+
+		s.stopScheduler <- true
+		s.stopScheduler = s.startActionScheduler(timeLoc)
+	}
+
+	s.log.Info("Reloaded configuration", "plugins", reloaded)
+
+	return nil
+}
+
+// newPluginInstances instantiates the configurable plugins whose configuration changed, injects their services and,
+// for those with a Lifecycle, initializes and starts them on the connected workspaces. If any of them fails, the instances
+// already created are stopped and closed and the error is returned
+func (s *Slackscot) newPluginInstances(next *viper.Viper, services *WorkspaceServices) (instances []pluginInstance, err error) {
+	instances = make([]pluginInstance, 0)
+
+	for _, cp := range s.configurablePlugins {
+		pc, err := config.GetPluginConfig(next, cp.name)
+		if err != nil {
+			s.closePluginInstances(instances)
+			return nil, err
+		}
+
+		settings := pc.AllSettings()
+		if reflect.DeepEqual(settings, cp.settings) {
+			continue
+		}
+
+		closer, p, err := cp.newInstance(pc)
+		if err != nil {
+			s.closePluginInstances(instances)
+			return nil, fmt.Errorf("Rejecting new configuration of plugin [%s]: %v", cp.name, err)
+		}
+
+		instance := pluginInstance{cp: cp, settings: settings, plugin: p, closer: closer}
+		s.injectPluginServices(p, s.log, services)
+
+		if err = s.initAndStartPluginInstance(&instance); err != nil {
+			s.closePluginInstances(append(instances, instance))
+			return nil, fmt.Errorf("Rejecting new configuration of plugin [%s]: %v", cp.name, err)
+		}
+
+		instances = append(instances, instance)
+	}
+
+	return instances, nil
+}
+
+// initAndStartPluginInstance initializes a new plugin instance with a Lifecycle and starts it on the connected workspaces
+func (s *Slackscot) initAndStartPluginInstance(instance *pluginInstance) (err error) {
+	if instance.plugin.Lifecycle == nil {
+		return nil
+	}
+
+	if err = initPlugin(instance.plugin); err != nil {
+		return err
+	}
+	instance.initialized = true
+
+	for _, ws := range s.workspaces.all {
+		if ready, _, _ := ws.connectionStatus(); ready {
+			if err = startPlugin(instance.plugin, ws, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// closePluginInstances stops and closes the plugin instances of a rejected configuration
+func (s *Slackscot) closePluginInstances(instances []pluginInstance) {
+	for _, instance := range instances {
+		if instance.initialized {
+			s.stopPlugin(instance.plugin)
+		}
+
+		if instance.closer != nil {
+			instance.closer.Close()
+		}
+	}
+}
+
+// replaceInitializedPlugin stops the replaced instance of a plugin, if it was initialized, and keeps the new instance,
+// if initialized, in its place to be started and stopped
+func (s *Slackscot) replaceInitializedPlugin(old *Plugin, instance pluginInstance) {
+	for i, p := range s.initializedPlugins {
+		if p == old {
+			s.stopPlugin(old)
+
+			if instance.initialized {
+				s.initializedPlugins[i] = instance.plugin
+			} else {
+				s.initializedPlugins = append(s.initializedPlugins[:i], s.initializedPlugins[i+1:]...)
+			}
+
+			return
+		}
+	}
+
+	if instance.initialized {
+		s.initializedPlugins = append(s.initializedPlugins, instance.plugin)
+	}
+}
+
+// replacePlugin replaces a registered plugin with a new one, keeping its place
+func (s *Slackscot) replacePlugin(old *Plugin, new *Plugin) {
+	for i, p := range s.plugins {
+		if p == old {
+			s.plugins[i] = new
+			return
+		}
+	}
+}
+
+// rebuildHelpPlugin replaces the help plugin with a new one listing the actions of the current plugins
+func (s *Slackscot) rebuildHelpPlugin(services *WorkspaceServices) {
+	if s.helpPlugin == nil {
+		return
+	}
+
+	plugins := make([]*Plugin, 0, len(s.plugins))
+	for _, p := range s.plugins {
+		if p != s.helpPlugin {
+			plugins = append(plugins, p)
+		}
+	}
+	s.plugins = plugins
+
+	helpPlugin := s.newHelpPlugin(VERSION)
+	s.helpPlugin = &helpPlugin.Plugin
+	s.injectPluginServices(s.helpPlugin, s.log, services)
+	s.RegisterPlugin(s.helpPlugin)
+}
+
+// newReloadableScheduledAction wraps a scheduled action so that it runs in between reloads and only if the scheduler
+// that triggered it is still the current one (a scheduler stopped on reload can still trigger its actions one last time)
+func (s *Slackscot) newReloadableScheduledAction(schedulerGeneration int, action ScheduledAction) ScheduledAction {
+	return func() {
+		s.reloadLock.RLock()
+		defer s.reloadLock.RUnlock()
+
+		if schedulerGeneration != s.schedulerGeneration {
+			return
+		}
+
+		action()
+	}
+}
+
+// startConfigReloading starts the configuration reload triggers enabled with OptionWatchConfig and OptionReloadConfigOnSIGHUP
+// and returns a function stopping them
+func (s *Slackscot) startConfigReloading(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	var triggers sync.WaitGroup
+
+	if s.watchConfig {
+		watcher, err := s.newConfigWatcher()
+		if err != nil {
+			s.log.Error("Error watching configuration file, configuration changes won't be reloaded", "err", err)
+		} else {
+			triggers.Add(1)
+			go func() {
+				defer triggers.Done()
+				s.reloadConfigOnChange(ctx, watcher)
+			}()
+		}
+	}
+
+	if s.reloadOnSIGHUP {
+		triggers.Add(1)
+		go func() {
+			defer triggers.Done()
+			s.reloadConfigOnSIGHUP(ctx)
+		}()
+	}
+
+	return func() {
+		cancel()
+		triggers.Wait()
+	}
+}
+
+// newConfigWatcher returns a watcher of the directory of the configuration file. The directory is watched (rather than the
+// file) in order to see files replaced by editors or by kubernetes when updating a mounted ConfigMap
+func (s *Slackscot) newConfigWatcher() (watcher *fsnotify.Watcher, err error) {
+	configFile := s.config.ConfigFileUsed()
+	if configFile == "" {
+		return nil, fmt.Errorf("Configuration wasn't read from a configuration file")
+	}
+
+	watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = watcher.Add(filepath.Dir(configFile)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	return watcher, nil
+}
+
+// reloadConfigOnChange reloads the configuration whenever the configuration file is written or replaced until the context is done
+func (s *Slackscot) reloadConfigOnChange(ctx context.Context, watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+
+	configFile := filepath.Clean(s.config.ConfigFileUsed())
+	realConfigFile, _ := filepath.EvalSymlinks(configFile)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case e, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			currentConfigFile, _ := filepath.EvalSymlinks(configFile)
+			if (filepath.Clean(e.Name) == configFile && e.Op&(fsnotify.Write|fsnotify.Create) != 0) || (currentConfigFile != "" && currentConfigFile != realConfigFile) {
+				realConfigFile = currentConfigFile
+				s.reloadConfig("fileChange")
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			s.log.Warn("Error watching configuration file", "file", configFile, "err", err)
+		}
+	}
+}
+
+// reloadConfigOnSIGHUP reloads the configuration whenever the process receives a SIGHUP until the context is done
+func (s *Slackscot) reloadConfigOnSIGHUP(ctx context.Context) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			s.reloadConfig("SIGHUP")
+		}
+	}
+}
+
+// reloadConfig reloads the configuration and logs the rejection of invalid configurations
+func (s *Slackscot) reloadConfig(trigger string) {
+	if err := s.ReloadConfig(); err != nil {
+		s.log.Error("Rejected new configuration, keeping the current one", "trigger", trigger, "err", err)
+	}
+}
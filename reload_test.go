@@ -0,0 +1,328 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// greeter is a configurable closer plugin greeting with the greeting of its configuration. It refuses to start when
+// its greeting is "mute"
+type greeter struct {
+	Plugin
+	greeting string
+	started  bool
+	stopped  bool
+	closed   bool
+}
+
+// greeterFactory creates greeters and keeps all of them
+type greeterFactory struct {
+	lock     sync.Mutex
+	greeters []*greeter
+	heard    chan string
+}
+
+func (g *greeter) Close() error {
+	g.closed = true
+	return nil
+}
+
+func (gf *greeterFactory) newGreeter(c *config.PluginConfig) (closer io.Closer, p *Plugin, err error) {
+	greeting := c.GetString("greeting")
+	if greeting == "" {
+		return nil, nil, fmt.Errorf("Missing greeting")
+	}
+
+	g := &greeter{greeting: greeting}
+	g.Plugin = Plugin{Name: "greeter", HearActions: []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return m.NormalizedText == "hi"
+		},
+		Usage:       "hi",
+		Description: fmt.Sprintf("Greets with %s", greeting),
+		Answer: func(m *IncomingMessage) *Answer {
+			gf.heard <- g.greeting
+			return &Answer{Text: g.greeting}
+		},
+	}}}
+	g.Lifecycle = LifecycleHooks{
+		OnStart: func(c Connection) error {
+			if g.greeting == "mute" {
+				return fmt.Errorf("Muted greeter")
+			}
+
+			g.started = true
+			return nil
+		},
+		OnStop: func() error {
+			g.stopped = true
+			return nil
+		},
+	}
+
+	gf.lock.Lock()
+	defer gf.lock.Unlock()
+	gf.greeters = append(gf.greeters, g)
+
+	return g, &g.Plugin, nil
+}
+
+func (gf *greeterFactory) instances() (greeters []*greeter) {
+	gf.lock.Lock()
+	defer gf.lock.Unlock()
+
+	return append([]*greeter{}, gf.greeters...)
+}
+
+func writeConfigFile(t *testing.T, path string, content string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+// newReloadableTestBot returns a slackscot with a greeter plugin configured by a yaml configuration file and a function
+// deleting that file
+func newReloadableTestBot(t *testing.T, content string, options ...Option) (s *Slackscot, gf *greeterFactory, configFile string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "reload")
+	require.NoError(t, err)
+	cleanup = func() {
+		os.RemoveAll(dir)
+	}
+
+	configFile = filepath.Join(dir, "slackscot.yml")
+	writeConfigFile(t, configFile, content)
+
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	require.NoError(t, v.ReadInConfig())
+	v.Set(config.MessageProcessingPartitionCount, 1)
+
+	gf = &greeterFactory{heard: make(chan string, 1)}
+	s, err = NewBot("chickadee", v, append(options, OptionLog(log.New(&nullWriter{}, "", 0)))...).
+		WithConfigurablePluginCloserErr("greeter", gf.newGreeter).
+		Build()
+	require.NoError(t, err)
+
+	return s, gf, configFile, cleanup
+}
+
+// runReloadableTestBot runs a slackscot with test dependencies and returns the channel to send it events and a
+// function to terminate it
+func runReloadableTestBot(t *testing.T, s *Slackscot) (events chan<- slack.RTMEvent, terminate func()) {
+	termination := make(chan bool)
+	OptionTestMode(termination)(s)
+
+	s.stopScheduler = s.startActionScheduler(time.Local)
+
+	ec := make(chan slack.RTMEvent)
+	s.workspaces.defaultWorkspace().attach(ec, &runDependencies{chatDriver: &inMemoryChatDriver{}, userInfoFinder: &userInfoFinder{}, emojiReactor: &emojiReactor{}, selfInfoFinder: &selfFinder{}})
+	go s.runInternal(context.Background())
+
+	ec <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}}
+
+	return ec, func() {
+		ec <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: true, Cause: slack.ErrRTMGoodbye}}
+		<-termination
+	}
+}
+
+func TestReloadReplacesPluginWithChangedConfig(t *testing.T) {
+	s, gf, configFile, cleanup := newReloadableTestBot(t, "plugins:\n  greeter:\n    greeting: hi there\n")
+	defer cleanup()
+	events, terminate := runReloadableTestBot(t, s)
+	defer terminate()
+
+	events <- newRTMMessageEvent(newMessageEvent("Cgeneral", "hi", "Alphonse", timestamp1))
+	assert.Equal(t, "hi there", <-gf.heard)
+	oldHelpPlugin := s.helpPlugin
+
+	writeConfigFile(t, configFile, "plugins:\n  greeter:\n    greeting: hello you\n")
+	require.NoError(t, s.ReloadConfig())
+
+	greeters := gf.instances()
+	require.Len(t, greeters, 2)
+	assert.True(t, greeters[0].closed)
+	assert.False(t, greeters[1].closed)
+	assert.Equal(t, []*Plugin{&greeters[1].Plugin, s.helpPlugin}, s.plugins)
+	assert.NotEqual(t, oldHelpPlugin, s.helpPlugin)
+	assert.NotNil(t, greeters[1].Logger)
+	assert.Equal(t, "hello you", s.config.GetString("plugins.greeter.greeting"))
+
+	events <- newRTMMessageEvent(newMessageEvent("Cgeneral", "hi", "Alphonse", timestamp2))
+	assert.Equal(t, "hello you", <-gf.heard)
+}
+
+func TestReloadKeepsPluginWithUnchangedConfig(t *testing.T) {
+	s, gf, configFile, cleanup := newReloadableTestBot(t, "plugins:\n  greeter:\n    greeting: hi there\n")
+	defer cleanup()
+	_, terminate := runReloadableTestBot(t, s)
+	defer terminate()
+
+	writeConfigFile(t, configFile, "debug: true\nplugins:\n  greeter:\n    greeting: hi there\n")
+	require.NoError(t, s.ReloadConfig())
+
+	greeters := gf.instances()
+	require.Len(t, greeters, 1)
+	assert.False(t, greeters[0].closed)
+	assert.True(t, s.config.GetBool(config.DebugKey))
+}
+
+func TestReloadRemovedSettingsFallBackToDefaults(t *testing.T) {
+	s, _, configFile, cleanup := newReloadableTestBot(t, "exclusiveRouting: true\nrateLimits:\n  user:\n    capacity: 2\nplugins:\n  greeter:\n    greeting: hi there\n")
+	defer cleanup()
+	_, terminate := runReloadableTestBot(t, s)
+	defer terminate()
+
+	require.True(t, s.config.GetBool(config.ExclusiveRoutingKey))
+	require.True(t, s.config.IsSet("rateLimits.user.capacity"))
+
+	writeConfigFile(t, configFile, "plugins:\n  greeter:\n    greeting: hi there\n")
+	require.NoError(t, s.ReloadConfig())
+
+	assert.False(t, s.config.GetBool(config.ExclusiveRoutingKey))
+	assert.False(t, s.config.IsSet("rateLimits.user.capacity"))
+	assert.Equal(t, 1, s.config.GetInt(config.MessageProcessingPartitionCount))
+	assert.Equal(t, "hi there", s.config.GetString("plugins.greeter.greeting"))
+}
+
+func TestReloadRejectsConfigFailingPluginInstantiation(t *testing.T) {
+	s, gf, configFile, cleanup := newReloadableTestBot(t, "plugins:\n  greeter:\n    greeting: hi there\n")
+	defer cleanup()
+	_, terminate := runReloadableTestBot(t, s)
+	defer terminate()
+
+	writeConfigFile(t, configFile, "debug: true\nplugins:\n  greeter:\n    greeting: \"\"\n")
+	err := s.ReloadConfig()
+	assert.EqualError(t, err, "Rejecting new configuration of plugin [greeter]: Missing greeting")
+
+	greeters := gf.instances()
+	require.Len(t, greeters, 1)
+	assert.False(t, greeters[0].closed)
+	assert.Equal(t, &greeters[0].Plugin, s.plugins[0])
+	assert.Equal(t, "hi there", s.config.GetString("plugins.greeter.greeting"))
+	assert.False(t, s.config.GetBool(config.DebugKey))
+}
+
+func TestReloadRejectsConfigMissingPluginConfig(t *testing.T) {
+	s, gf, configFile, cleanup := newReloadableTestBot(t, "plugins:\n  greeter:\n    greeting: hi there\n")
+	defer cleanup()
+	_, terminate := runReloadableTestBot(t, s)
+	defer terminate()
+
+	writeConfigFile(t, configFile, "plugins:\n  other:\n    greeting: hello\n")
+	err := s.ReloadConfig()
+	assert.EqualError(t, err, "Missing plugin configuration for plugin [greeter] at [plugins.greeter]")
+
+	assert.Len(t, gf.instances(), 1)
+}
+
+func TestReloadRejectsUnreadableConfig(t *testing.T) {
+	s, gf, configFile, cleanup := newReloadableTestBot(t, "plugins:\n  greeter:\n    greeting: hi there\n")
+	defer cleanup()
+	_, terminate := runReloadableTestBot(t, s)
+	defer terminate()
+
+	writeConfigFile(t, configFile, "plugins: [\n")
+	assert.Error(t, s.ReloadConfig())
+
+	assert.Len(t, gf.instances(), 1)
+}
+
+func TestReloadOnlyWhileRunning(t *testing.T) {
+	s, _, _, cleanup := newReloadableTestBot(t, "plugins:\n  greeter:\n    greeting: hi there\n")
+	defer cleanup()
+
+	assert.EqualError(t, s.ReloadConfig(), "Configuration can only be reloaded while running")
+}
+
+func TestReloadWithoutConfigFile(t *testing.T) {
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionLog(log.New(&nullWriter{}, "", 0)))
+	require.NoError(t, err)
+
+	assert.EqualError(t, s.ReloadConfig(), "Can't reload configuration that wasn't read from a configuration file")
+}
+
+func TestReloadOnConfigFileChange(t *testing.T) {
+	s, gf, configFile, cleanup := newReloadableTestBot(t, "plugins:\n  greeter:\n    greeting: hi there\n", OptionWatchConfig())
+	defer cleanup()
+	_, terminate := runReloadableTestBot(t, s)
+	defer terminate()
+
+	stopReloading := s.startConfigReloading(context.Background())
+	defer stopReloading()
+
+	writeConfigFile(t, configFile, "plugins:\n  greeter:\n    greeting: hello you\n")
+
+	assert.Eventually(t, func() bool {
+		return len(gf.instances()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestClosingConfigurablePluginClosesCurrentInstance(t *testing.T) {
+	s, gf, configFile, cleanup := newReloadableTestBot(t, "plugins:\n  greeter:\n    greeting: hi there\n")
+	defer cleanup()
+	_, terminate := runReloadableTestBot(t, s)
+
+	writeConfigFile(t, configFile, "plugins:\n  greeter:\n    greeting: hello you\n")
+	require.NoError(t, s.ReloadConfig())
+	terminate()
+
+	require.NoError(t, s.Close())
+
+	greeters := gf.instances()
+	require.Len(t, greeters, 2)
+	assert.True(t, greeters[1].closed)
+}
+
+func TestReloadStartsNewAndStopsOldPluginInstance(t *testing.T) {
+	s, gf, configFile, cleanup := newReloadableTestBot(t, "plugins:\n  greeter:\n    greeting: hi there\n")
+	defer cleanup()
+	events, terminate := runReloadableTestBot(t, s)
+	defer terminate()
+
+	// Wait for the connection to be processed
+	events <- newRTMMessageEvent(newMessageEvent("Cgeneral", "hi", "Alphonse", timestamp1))
+	<-gf.heard
+
+	writeConfigFile(t, configFile, "plugins:\n  greeter:\n    greeting: hello you\n")
+	require.NoError(t, s.ReloadConfig())
+
+	greeters := gf.instances()
+	require.Len(t, greeters, 2)
+	assert.True(t, greeters[0].stopped)
+	assert.True(t, greeters[1].started)
+	assert.False(t, greeters[1].stopped)
+	assert.Equal(t, []*Plugin{&greeters[1].Plugin}, s.initializedPlugins)
+}
+
+func TestReloadRejectsConfigFailingPluginStart(t *testing.T) {
+	s, gf, configFile, cleanup := newReloadableTestBot(t, "plugins:\n  greeter:\n    greeting: hi there\n")
+	defer cleanup()
+	events, terminate := runReloadableTestBot(t, s)
+	defer terminate()
+
+	// Wait for the connection to be processed
+	events <- newRTMMessageEvent(newMessageEvent("Cgeneral", "hi", "Alphonse", timestamp1))
+	<-gf.heard
+
+	writeConfigFile(t, configFile, "plugins:\n  greeter:\n    greeting: mute\n")
+	assert.EqualError(t, s.ReloadConfig(), "Rejecting new configuration of plugin [greeter]: Error starting plugin [greeter] on connection to Workspace{ }: Muted greeter")
+
+	greeters := gf.instances()
+	require.Len(t, greeters, 2)
+	assert.False(t, greeters[0].stopped)
+	assert.True(t, greeters[1].stopped)
+	assert.True(t, greeters[1].closed)
+	assert.Equal(t, []*Plugin{&greeters[0].Plugin}, s.initializedPlugins)
+}
@@ -0,0 +1,320 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ReplayResult holds the outgoing calls of a recording and the ones made when replaying it. Calls are ordered by workspace
+// and channel since messages of different channels are processed concurrently (and their calls can be recorded in any order)
+type ReplayResult struct {
+	Recorded []RecordedCall
+	Replayed []RecordedCall
+}
+
+// Diff returns a description of the differences between the recorded and replayed calls or an empty string if they're the same
+func (rr *ReplayResult) Diff() string {
+	var b strings.Builder
+
+	count := len(rr.Recorded)
+	if len(rr.Replayed) > count {
+		count = len(rr.Replayed)
+	}
+
+	for i := 0; i < count; i++ {
+		recorded, replayed := "<none>", "<none>"
+		if i < len(rr.Recorded) {
+			recorded = rr.Recorded[i].String()
+		}
+
+		if i < len(rr.Replayed) {
+			replayed = rr.Replayed[i].String()
+		}
+
+		if recorded != replayed {
+			fmt.Fprintf(&b, "Call [%d]:\n- recorded: %s\n+ replayed: %s\n", i, recorded, replayed)
+		}
+	}
+
+	return b.String()
+}
+
+// Replay replays a recording (see OptionRecorder) with a new slackscot instance running the plugins and returns the recorded and
+// replayed outgoing calls. Recorded events are processed in order, one at a time, with fake slack dependencies serving the recorded
+// users, bot info and message timestamps so that replays are deterministic and can run in tests. For example:
+//
+//	result, err := slackscot.Replay("youppi", config.NewViperWithDefaults(), []*slackscot.Plugin{karma}, recording)
+//	require.NoError(t, err)
+//	assert.Equal(t, result.Recorded, result.Replayed, result.Diff())
+//
+// The replay runs with a copy of the configuration with its message processing partition count set to 1, leaving v untouched
+func Replay(name string, v *viper.Viper, plugins []*Plugin, recording io.Reader, options ...Option) (result *ReplayResult, err error) {
+	entries, err := ReadRecording(recording)
+	if err != nil {
+		return nil, err
+	}
+
+	replayConfig := copyConfig(v)
+	replayConfig.Set(config.MessageProcessingPartitionCount, 1)
+	s, err := New(name, replayConfig, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range plugins {
+		s.RegisterPlugin(p)
+	}
+
+	replayed := new(replayedCalls)
+	workspaces := make(map[string]*replayWorkspace)
+	for _, ws := range s.workspaces.all {
+		rw := newReplayWorkspace(ws.name, replayed)
+		workspaces[ws.name] = rw
+
+		ws.attach(rw.events, rw.runDependencies())
+	}
+
+	result = &ReplayResult{Recorded: make([]RecordedCall, 0)}
+	events := make([]workspaceEvent, 0)
+	for _, entry := range entries {
+		rw, ok := workspaces[entry.Workspace]
+		if !ok {
+			rw = workspaces[s.workspaces.defaultWorkspace().name]
+		}
+
+		switch {
+		case entry.Event != nil:
+			e, err := entry.Event.newRTMEvent()
+			if err != nil {
+				return nil, err
+			}
+
+			events = append(events, workspaceEvent{ws: &workspace{name: rw.name}, RTMEvent: e})
+		case entry.Call != nil:
+			call := *entry.Call
+			call.Workspace = rw.name
+			result.Recorded = append(result.Recorded, call)
+
+			if call.Method == SendMessageCall || call.Method == UpdateMessageCall {
+				key := responseKey(call.Method, call.ChannelID)
+				rw.responses[key] = append(rw.responses[key], call.Response)
+			}
+		case entry.User != nil:
+			rw.users[entry.User.ID] = entry.User
+		case entry.Self != nil:
+			if rw.self == nil {
+				rw.self = entry.Self
+			}
+		}
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- s.runInternal(context.Background())
+	}()
+
+	for _, e := range events {
+		workspaces[e.ws.name].events <- e.RTMEvent
+	}
+
+	for _, rw := range workspaces {
+		close(rw.events)
+	}
+
+	err = <-done
+	if cerr := s.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+
+	result.Replayed = replayed.calls
+	sortCalls(result.Recorded)
+	sortCalls(result.Replayed)
+
+	return result, err
+}
+
+// sortCalls sorts calls by workspace and channel, keeping the order of calls of the same channel
+func sortCalls(calls []RecordedCall) {
+	sort.SliceStable(calls, func(i, j int) bool {
+		if calls[i].Workspace != calls[j].Workspace {
+			return calls[i].Workspace < calls[j].Workspace
+		}
+
+		return calls[i].ChannelID < calls[j].ChannelID
+	})
+}
+
+// replayedCalls holds the calls made during a replay
+type replayedCalls struct {
+	calls []RecordedCall
+	lock  sync.Mutex
+}
+
+// add adds a call made by a workspace
+func (rc *replayedCalls) add(workspace string, call *RecordedCall) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	call.Workspace = workspace
+	rc.calls = append(rc.calls, *call)
+}
+
+// responseKey returns the key of the recorded responses of a call method to a channel
+func responseKey(method string, channelID string) string {
+	return fmt.Sprintf("%s/%s", method, channelID)
+}
+
+// replayWorkspace implements the runtime dependencies of a workspace during a replay. Sent and updated messages get the
+// timestamps of the recorded ones, in order, for each channel
+type replayWorkspace struct {
+	name      string
+	events    chan slack.RTMEvent
+	self      *slack.Info
+	users     map[string]*slack.User
+	responses map[string][]string
+	replayed  *replayedCalls
+	sequence  int
+	lock      sync.Mutex
+}
+
+// newReplayWorkspace returns a new replayWorkspace adding its calls to the replayed calls
+func newReplayWorkspace(name string, replayed *replayedCalls) (rw *replayWorkspace) {
+	rw = new(replayWorkspace)
+	rw.name = name
+	rw.events = make(chan slack.RTMEvent)
+	rw.users = make(map[string]*slack.User)
+	rw.responses = make(map[string][]string)
+	rw.replayed = replayed
+
+	return rw
+}
+
+// runDependencies returns the runtime dependencies of the replayed workspace
+func (rw *replayWorkspace) runDependencies() *runDependencies {
+	return &runDependencies{chatDriver: rw, userInfoFinder: rw, emojiReactor: rw, fileUploader: NewFileUploader(rw), selfInfoFinder: rw, realTimeMsgSender: replayRealTimeMessageSender{rw}}
+}
+
+// nextResponse returns the timestamp of the next recorded response to a call method on a channel or false if there isn't any
+func (rw *replayWorkspace) nextResponse(method string, channelID string) (timestamp string, ok bool) {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	key := responseKey(method, channelID)
+	if responses := rw.responses[key]; len(responses) > 0 {
+		rw.responses[key] = responses[1:]
+		return responses[0], true
+	}
+
+	return "", false
+}
+
+// nextTimestamp returns a new generated timestamp for messages sent during the replay that weren't in the recording
+func (rw *replayWorkspace) nextTimestamp() string {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	rw.sequence = rw.sequence + 1
+	return fmt.Sprintf("1000000000.%06d", rw.sequence)
+}
+
+// SendMessage records a sent message and returns the timestamp of the recorded one
+func (rw *replayWorkspace) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	values := msgOptionValues(channelID, options...)
+	rTimestamp, ok := rw.nextResponse(SendMessageCall, channelID)
+	if !ok {
+		rTimestamp = rw.nextTimestamp()
+	}
+
+	rw.replayed.add(rw.name, &RecordedCall{Method: SendMessageCall, ChannelID: channelID, Values: values, Response: rTimestamp})
+
+	return channelID, rTimestamp, strings.Join(values["text"], ""), nil
+}
+
+// UpdateMessage records an updated message and returns the timestamp of the recorded one
+func (rw *replayWorkspace) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	values := msgOptionValues(channelID, options...)
+	rTimestamp, ok := rw.nextResponse(UpdateMessageCall, channelID)
+	if !ok {
+		rTimestamp = timestamp
+	}
+
+	rw.replayed.add(rw.name, &RecordedCall{Method: UpdateMessageCall, ChannelID: channelID, Timestamp: timestamp, Values: values, Response: rTimestamp})
+
+	return channelID, rTimestamp, strings.Join(values["text"], ""), nil
+}
+
+// DeleteMessage records a deleted message
+func (rw *replayWorkspace) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
+	rw.replayed.add(rw.name, &RecordedCall{Method: DeleteMessageCall, ChannelID: channelID, Timestamp: timestamp})
+
+	return channelID, timestamp, nil
+}
+
+// AddReaction records an emoji reaction
+func (rw *replayWorkspace) AddReaction(name string, item slack.ItemRef) error {
+	rw.replayed.add(rw.name, newAddReactionCall(name, item))
+
+	return nil
+}
+
+// UploadFile records a file upload
+func (rw *replayWorkspace) UploadFile(params slack.FileUploadParameters) (file *slack.File, err error) {
+	rw.replayed.add(rw.name, newUploadFileCall(params))
+
+	return &slack.File{Name: params.Filename, Title: params.Title}, nil
+}
+
+// GetUserInfo returns the recorded user or a user with only its id if it wasn't recorded
+func (rw *replayWorkspace) GetUserInfo(userID string) (user *slack.User, err error) {
+	if user, ok := rw.users[userID]; ok {
+		return user, nil
+	}
+
+	return &slack.User{ID: userID, Name: userID}, nil
+}
+
+// GetInfo returns the recorded info of the bot
+func (rw *replayWorkspace) GetInfo() (info *slack.Info) {
+	if rw.self != nil {
+		return rw.self
+	}
+
+	return &slack.Info{User: &slack.UserDetails{}}
+}
+
+// replayRealTimeMessageSender is the RealTimeMessageSender of a replayWorkspace
+type replayRealTimeMessageSender struct {
+	*replayWorkspace
+}
+
+// NewOutgoingMessage creates a new real time message to send
+func (rs replayRealTimeMessageSender) NewOutgoingMessage(text string, channelID string, options ...slack.RTMsgOption) *slack.OutgoingMessage {
+	outMsg := &slack.OutgoingMessage{Type: "message", Channel: channelID, Text: text}
+	for _, opt := range options {
+		opt(outMsg)
+	}
+
+	return outMsg
+}
+
+// SendMessage records a real time message
+func (rs replayRealTimeMessageSender) SendMessage(outMsg *slack.OutgoingMessage) {
+	rs.replayed.add(rs.name, newSendRealTimeMessageCall(outMsg))
+}
+
+// copyConfig returns a new viper with all the settings of v
+func copyConfig(v *viper.Viper) (c *viper.Viper) {
+	c = viper.New()
+	for _, key := range v.AllKeys() {
+		c.Set(key, v.Get(key))
+	}
+
+	return c
+}
@@ -0,0 +1,325 @@
+package slackscot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/hashicorp/golang-lru"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/metric"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// The silo in which StorerResponseCache keeps its entries
+	responseCacheSilo = "slackscot.responses"
+
+	// The minimum time between purges of expired entries of a StorerResponseCache
+	responseCachePurgeInterval = time.Duration(1) * time.Hour
+)
+
+// ResponseCache keeps track of the responses sent to triggering messages so that they can be updated or deleted when
+// the triggering message is updated or deleted. Responses of a triggering message are keyed by plugin action identifier
+type ResponseCache interface {
+	// Get returns the responses to a triggering message. found is false if there are no (unexpired) responses
+	// for that message
+	Get(triggeringMsgID SlackMessageID) (responses map[string]SlackMessageID, found bool, err error)
+
+	// Add sets the responses to a triggering message, replacing any existing ones
+	Add(triggeringMsgID SlackMessageID, responses map[string]SlackMessageID) (err error)
+
+	// Remove removes the responses to a triggering message
+	Remove(triggeringMsgID SlackMessageID) (err error)
+}
+
+// evictionNotifier is implemented by response caches able to report the entries they evict (either because of
+// their capacity or because they expired)
+type evictionNotifier interface {
+	notifyEvictions(onEvict func(count int))
+}
+
+// cachedResponses holds the responses to a triggering message along with the time they were added
+type cachedResponses struct {
+	AddedAt   time.Time         `json:"addedAt"`
+	Responses map[string]string `json:"responses"`
+}
+
+// newCachedResponses returns a new cachedResponses with responses serialized as their string representation
+func newCachedResponses(responses map[string]SlackMessageID, addedAt time.Time) (cr cachedResponses) {
+	cr = cachedResponses{AddedAt: addedAt, Responses: make(map[string]string)}
+	for actionID, r := range responses {
+		cr.Responses[actionID] = r.String()
+	}
+
+	return cr
+}
+
+// responseIDs returns the responses as SlackMessageIDs
+func (cr cachedResponses) responseIDs() (responses map[string]SlackMessageID, err error) {
+	responses = make(map[string]SlackMessageID)
+	for actionID, r := range cr.Responses {
+		responses[actionID], err = parseSlackMessageID(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return responses, nil
+}
+
+// isExpired returns true if the responses are older than maxAge
+func (cr cachedResponses) isExpired(now time.Time, maxAge time.Duration) bool {
+	return now.Sub(cr.AddedAt) > maxAge
+}
+
+// parseSlackMessageID parses the string representation of a SlackMessageID (with or without a team id)
+func parseSlackMessageID(s string) (id SlackMessageID, err error) {
+	parts := strings.Split(s, "/")
+	switch len(parts) {
+	case 2:
+		return SlackMessageID{channelID: parts[0], timestamp: parts[1]}, nil
+	case 3:
+		return SlackMessageID{teamID: parts[0], channelID: parts[1], timestamp: parts[2]}, nil
+	default:
+		return id, fmt.Errorf("Invalid slack message id [%s]", s)
+	}
+}
+
+// ARCResponseCache is an in-memory ResponseCache backed by an adaptive replacement cache of a fixed size. This is the
+// default ResponseCache. Responses are lost on restart
+type ARCResponseCache struct {
+	cache   *lru.ARCCache
+	size    int
+	maxAge  time.Duration
+	now     func() time.Time
+	onEvict func(count int)
+}
+
+// NewARCResponseCache returns a new ARCResponseCache holding responses for up to size triggering messages. Entries expire
+// after maxAge
+func NewARCResponseCache(size int, maxAge time.Duration) (c *ARCResponseCache, err error) {
+	c = new(ARCResponseCache)
+	c.size = size
+	c.maxAge = maxAge
+	c.now = time.Now
+	c.onEvict = func(count int) {}
+
+	c.cache, err = lru.NewARC(size)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Get returns the responses to a triggering message
+func (c *ARCResponseCache) Get(triggeringMsgID SlackMessageID) (responses map[string]SlackMessageID, found bool, err error) {
+	v, found := c.cache.Get(triggeringMsgID)
+	if !found {
+		return nil, false, nil
+	}
+
+	cr := v.(cachedResponses)
+	if cr.isExpired(c.now(), c.maxAge) {
+		c.cache.Remove(triggeringMsgID)
+		c.onEvict(1)
+
+		return nil, false, nil
+	}
+
+	responses, err = cr.responseIDs()
+	return responses, err == nil, err
+}
+
+// Add sets the responses to a triggering message
+func (c *ARCResponseCache) Add(triggeringMsgID SlackMessageID, responses map[string]SlackMessageID) (err error) {
+	if !c.cache.Contains(triggeringMsgID) && c.cache.Len() >= c.size {
+		c.onEvict(1)
+	}
+
+	c.cache.Add(triggeringMsgID, newCachedResponses(responses, c.now()))
+	return nil
+}
+
+// Remove removes the responses to a triggering message
+func (c *ARCResponseCache) Remove(triggeringMsgID SlackMessageID) (err error) {
+	c.cache.Remove(triggeringMsgID)
+	return nil
+}
+
+// Len returns the number of triggering messages with cached responses, including expired ones not evicted yet
+func (c *ARCResponseCache) Len() int {
+	return c.cache.Len()
+}
+
+func (c *ARCResponseCache) notifyEvictions(onEvict func(count int)) {
+	c.onEvict = onEvict
+}
+
+// StorerResponseCache is a ResponseCache persisted with a store.GlobalSiloStringStorer so that responses survive restarts.
+// Expired entries are purged periodically
+type StorerResponseCache struct {
+	storer    store.GlobalSiloStringStorer
+	maxAge    time.Duration
+	now       func() time.Time
+	onEvict   func(count int)
+	lastPurge time.Time
+	purgeLock sync.Mutex
+}
+
+// NewStorerResponseCache returns a new StorerResponseCache persisting responses with the storer. Entries expire after maxAge
+// which should normally be the value of config.MaxAgeHandledMessages. Since entries are kept in their own silo, the storer
+// can be shared with plugins but it should not be closed before slackscot is done running
+func NewStorerResponseCache(storer store.GlobalSiloStringStorer, maxAge time.Duration) (c *StorerResponseCache) {
+	c = new(StorerResponseCache)
+	c.storer = storer
+	c.maxAge = maxAge
+	c.now = time.Now
+	c.onEvict = func(count int) {}
+
+	return c
+}
+
+// Get returns the responses to a triggering message
+func (c *StorerResponseCache) Get(triggeringMsgID SlackMessageID) (responses map[string]SlackMessageID, found bool, err error) {
+	v, err := c.storer.GetSiloString(responseCacheSilo, triggeringMsgID.String())
+	if err != nil || v == "" {
+		// Storers return an error on missing keys so we consider any error a miss
+		return nil, false, nil
+	}
+
+	var cr cachedResponses
+	if err = json.Unmarshal([]byte(v), &cr); err != nil {
+		return nil, false, err
+	}
+
+	if cr.isExpired(c.now(), c.maxAge) {
+		c.onEvict(1)
+
+		return nil, false, c.storer.DeleteSiloString(responseCacheSilo, triggeringMsgID.String())
+	}
+
+	responses, err = cr.responseIDs()
+	return responses, err == nil, err
+}
+
+// Add sets the responses to a triggering message. Expired entries are purged if they haven't been for a while
+func (c *StorerResponseCache) Add(triggeringMsgID SlackMessageID, responses map[string]SlackMessageID) (err error) {
+	if err = c.purgeExpiredIfDue(); err != nil {
+		return err
+	}
+
+	v, err := json.Marshal(newCachedResponses(responses, c.now()))
+	if err != nil {
+		return err
+	}
+
+	return c.storer.PutSiloString(responseCacheSilo, triggeringMsgID.String(), string(v))
+}
+
+// Remove removes the responses to a triggering message
+func (c *StorerResponseCache) Remove(triggeringMsgID SlackMessageID) (err error) {
+	return c.storer.DeleteSiloString(responseCacheSilo, triggeringMsgID.String())
+}
+
+func (c *StorerResponseCache) notifyEvictions(onEvict func(count int)) {
+	c.onEvict = onEvict
+}
+
+// purgeExpiredIfDue deletes all expired entries if the last purge is older than the purge interval
+func (c *StorerResponseCache) purgeExpiredIfDue() (err error) {
+	c.purgeLock.Lock()
+	defer c.purgeLock.Unlock()
+
+	now := c.now()
+	if now.Sub(c.lastPurge) < responseCachePurgeInterval {
+		return nil
+	}
+
+	entries, err := c.storer.ScanSilo(responseCacheSilo)
+	if err != nil {
+		return err
+	}
+
+	evicted := 0
+	for k, v := range entries {
+		var cr cachedResponses
+		if err = json.Unmarshal([]byte(v), &cr); err != nil || cr.isExpired(now, c.maxAge) {
+			if err = c.storer.DeleteSiloString(responseCacheSilo, k); err != nil {
+				return err
+			}
+			evicted++
+		}
+	}
+
+	c.lastPurge = now
+	if evicted > 0 {
+		c.onEvict(evicted)
+	}
+
+	return nil
+}
+
+// responseCacheWithTelemetry decorates a ResponseCache with hit, miss and eviction counters
+type responseCacheWithTelemetry struct {
+	base      ResponseCache
+	hits      metric.BoundInt64Counter
+	misses    metric.BoundInt64Counter
+	evictions metric.BoundInt64Counter
+}
+
+// newResponseCacheWithTelemetry returns the ResponseCache decorated with hit, miss and eviction counters. Evictions are
+// only counted for response caches that report them (which is the case for ARCResponseCache and StorerResponseCache)
+func newResponseCacheWithTelemetry(base ResponseCache, appName string, meter metric.Meter) (c *responseCacheWithTelemetry, err error) {
+	hits, err := meter.NewInt64Counter("responseCacheHits")
+	if err != nil {
+		return nil, err
+	}
+	misses, err := meter.NewInt64Counter("responseCacheMisses")
+	if err != nil {
+		return nil, err
+	}
+	evictions, err := meter.NewInt64Counter("responseCacheEvictions")
+	if err != nil {
+		return nil, err
+	}
+
+	c = new(responseCacheWithTelemetry)
+	c.base = base
+	c.hits = hits.Bind(label.String("name", appName))
+	c.misses = misses.Bind(label.String("name", appName))
+	c.evictions = evictions.Bind(label.String("name", appName))
+
+	if en, ok := base.(evictionNotifier); ok {
+		en.notifyEvictions(func(count int) {
+			c.evictions.Add(context.Background(), int64(count))
+		})
+	}
+
+	return c, nil
+}
+
+// Get returns the responses to a triggering message and counts the hit or miss
+func (c *responseCacheWithTelemetry) Get(triggeringMsgID SlackMessageID) (responses map[string]SlackMessageID, found bool, err error) {
+	responses, found, err = c.base.Get(triggeringMsgID)
+	if found {
+		c.hits.Add(context.Background(), 1)
+	} else {
+		c.misses.Add(context.Background(), 1)
+	}
+
+	return responses, found, err
+}
+
+// Add sets the responses to a triggering message
+func (c *responseCacheWithTelemetry) Add(triggeringMsgID SlackMessageID, responses map[string]SlackMessageID) (err error) {
+	return c.base.Add(triggeringMsgID, responses)
+}
+
+// Remove removes the responses to a triggering message
+func (c *responseCacheWithTelemetry) Remove(triggeringMsgID SlackMessageID) (err error) {
+	return c.base.Remove(triggeringMsgID)
+}
@@ -0,0 +1,176 @@
+package slackscot
+
+import (
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+var (
+	testTriggeringMsgID = SlackMessageID{channelID: "Cgeneral", timestamp: timestamp1}
+	testResponses       = map[string]SlackMessageID{"noRules.hear[0]": {channelID: "Cgeneral", timestamp: formatTimestamp(firstReplyTimestamp)}}
+)
+
+// fakeClock is a controllable clock for response caches
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestLevelDB(t *testing.T) (ldb *store.LevelDB, cleanup func()) {
+	dir, err := ioutil.TempDir("", "responsecache")
+	require.NoError(t, err)
+
+	ldb, err = store.NewLevelDB("responses", dir)
+	require.NoError(t, err)
+
+	return ldb, func() {
+		ldb.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestParseSlackMessageID(t *testing.T) {
+	id, err := parseSlackMessageID(testTriggeringMsgID.String())
+	require.NoError(t, err)
+	assert.Equal(t, testTriggeringMsgID, id)
+
+	teamMsgID := SlackMessageID{teamID: "T1", channelID: "Cgeneral", timestamp: timestamp1}
+	id, err = parseSlackMessageID(teamMsgID.String())
+	require.NoError(t, err)
+	assert.Equal(t, teamMsgID, id)
+
+	_, err = parseSlackMessageID("Cgeneral")
+	assert.Error(t, err)
+}
+
+func TestNewARCResponseCacheWithInvalidSize(t *testing.T) {
+	_, err := NewARCResponseCache(0, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestARCResponseCache(t *testing.T) {
+	clock := fakeClock{now: time.Now()}
+	evictions := 0
+
+	c, err := NewARCResponseCache(1, time.Hour)
+	require.NoError(t, err)
+	c.now = clock.Now
+	c.notifyEvictions(func(count int) { evictions += count })
+
+	_, found, err := c.Get(testTriggeringMsgID)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, c.Add(testTriggeringMsgID, testResponses))
+	cached, found, err := c.Get(testTriggeringMsgID)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, testResponses, cached)
+
+	// Replacing the responses of a cached message doesn't evict anything
+	require.NoError(t, c.Add(testTriggeringMsgID, testResponses))
+	assert.Equal(t, 0, evictions)
+
+	// Adding another message goes over capacity
+	require.NoError(t, c.Add(SlackMessageID{channelID: "Cgeneral", timestamp: timestamp2}, testResponses))
+	assert.Equal(t, 1, evictions)
+
+	clock.now = clock.now.Add(time.Duration(2) * time.Hour)
+	_, found, err = c.Get(SlackMessageID{channelID: "Cgeneral", timestamp: timestamp2})
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, 2, evictions)
+}
+
+func TestStorerResponseCache(t *testing.T) {
+	ldb, cleanup := newTestLevelDB(t)
+	defer cleanup()
+
+	clock := fakeClock{now: time.Now()}
+	evictions := 0
+
+	c := NewStorerResponseCache(ldb, time.Hour)
+	c.now = clock.Now
+	c.notifyEvictions(func(count int) { evictions += count })
+
+	_, found, err := c.Get(testTriggeringMsgID)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, c.Add(testTriggeringMsgID, testResponses))
+	cached, found, err := c.Get(testTriggeringMsgID)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, testResponses, cached)
+
+	require.NoError(t, c.Remove(testTriggeringMsgID))
+	_, found, err = c.Get(testTriggeringMsgID)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, c.Add(testTriggeringMsgID, testResponses))
+	clock.now = clock.now.Add(time.Duration(2) * time.Hour)
+	_, found, err = c.Get(testTriggeringMsgID)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, 1, evictions)
+
+	entries, err := ldb.ScanSilo(responseCacheSilo)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestStorerResponseCachePurgesExpiredEntries(t *testing.T) {
+	ldb, cleanup := newTestLevelDB(t)
+	defer cleanup()
+
+	clock := fakeClock{now: time.Now()}
+	evictions := 0
+
+	c := NewStorerResponseCache(ldb, time.Hour)
+	c.now = clock.Now
+	c.notifyEvictions(func(count int) { evictions += count })
+
+	require.NoError(t, c.Add(testTriggeringMsgID, testResponses))
+
+	clock.now = clock.now.Add(time.Duration(2) * time.Hour)
+	require.NoError(t, c.Add(SlackMessageID{channelID: "Cgeneral", timestamp: timestamp2}, testResponses))
+	assert.Equal(t, 1, evictions)
+
+	entries, err := ldb.ScanSilo(responseCacheSilo)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Contains(t, entries, SlackMessageID{channelID: "Cgeneral", timestamp: timestamp2}.String())
+}
+
+func TestResponsesUpdatedAfterRestartWithStorerResponseCache(t *testing.T) {
+	ldb, cleanup := newTestLevelDB(t)
+	defer cleanup()
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, OptionResponseCache(NewStorerResponseCache(ldb, time.Duration(24)*time.Hour)))
+
+	require.Equal(t, 1, len(sentMsgs))
+
+	// Run a new instance with the same storer as if slackscot had restarted
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Ignored", timestamp2, optionChangedMessage("blue jays eat acorn", "Alphonse", timestamp1))),
+	}, nil, OptionResponseCache(NewStorerResponseCache(ldb, time.Duration(24)*time.Hour)))
+
+	assert.Equal(t, 0, len(sentMsgs))
+	if assert.Equal(t, 1, len(updatedMsgs)) {
+		assert.Equal(t, "Cgeneral", updatedMsgs[0].channelID)
+		assert.Equal(t, formatTimestamp(firstReplyTimestamp), updatedMsgs[0].timestamp)
+	}
+	assert.Equal(t, 0, len(deletedMsgs))
+}
@@ -4,11 +4,27 @@ import (
 	"context"
 	"fmt"
 	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/trace"
 	"hash"
 	"hash/crc32"
 	"math"
 )
 
+// queuedEvent is an event of a workspace queued for processing by a partition. Only one of message, reaction, interaction or slashCommand is set
+type queuedEvent struct {
+	ws *workspace
+
+	message      *slack.MessageEvent
+	reaction     *IncomingReaction
+	interaction  *IncomingInteraction
+	slashCommand *slack.SlashCommand
+
+	// The context holding the span of the event and the span of its wait in the queue
+	ctx       context.Context
+	queueWait trace.Span
+}
+
 type partitionRouter struct {
 	// Logger
 	log *sLogger
@@ -17,7 +33,7 @@ type partitionRouter struct {
 	// so that processing of messages (new, updates and deletes) are handled by
 	// the same work queue therefore ensuring correct ordered processing
 	// of those events
-	messageQueues []chan slack.MessageEvent
+	messageQueues []chan queuedEvent
 
 	// workerTerminationSignals are channels receiving a termination signal for each
 	// workerQueue
@@ -36,9 +52,9 @@ func newPartitionRouter(partitionCount int, queueBufferSize int, log *sLogger, i
 	}
 
 	pr = new(partitionRouter)
-	pr.messageQueues = make([]chan slack.MessageEvent, partitionCount)
+	pr.messageQueues = make([]chan queuedEvent, partitionCount)
 	for i := range pr.messageQueues {
-		pr.messageQueues[i] = make(chan slack.MessageEvent, queueBufferSize)
+		pr.messageQueues[i] = make(chan queuedEvent, queueBufferSize)
 	}
 	pr.workerTerminationSignals = make([]chan bool, partitionCount)
 	for i := range pr.workerTerminationSignals {
@@ -54,14 +70,51 @@ func newPartitionRouter(partitionCount int, queueBufferSize int, log *sLogger, i
 
 // routeMessageEvent routes the message processing to the correct partition based on its original message id to ensure
 // that all message and its updates are processed in order
-func (pr *partitionRouter) routeMessageEvent(msgEvent slack.MessageEvent) {
-	msgID := getOriginalMessageID(msgEvent)
+func (pr *partitionRouter) routeMessageEvent(ws *workspace, msgEvent slack.MessageEvent) {
+	msgID := getOriginalMessageID(ws.teamID, msgEvent)
+	ctx, queueWait := pr.startMessageSpan(ws.teamID, msgEvent)
+
+	pr.dispatch(msgID, queuedEvent{ws: ws, message: &msgEvent, ctx: ctx, queueWait: queueWait})
+}
+
+// routeReaction routes the reaction processing to the partition of the message reacted to so that reactions are processed
+// in order with that message and its updates
+func (pr *partitionRouter) routeReaction(ws *workspace, r IncomingReaction) {
+	msgID := SlackMessageID{teamID: r.TeamID, channelID: r.ItemChannel, timestamp: r.ItemTimestamp}
+	ctx, queueWait := pr.startEventSpan(reactionSpanName, label.String("team", r.TeamID), label.String("channel", r.ItemChannel), label.String("ts", r.ItemTimestamp), label.String("emoji", r.Emoji))
+
+	pr.dispatch(msgID, queuedEvent{ws: ws, reaction: &r, ctx: ctx, queueWait: queueWait})
+}
+
+// routeInteraction sends an interaction to the partition of the message holding the interactive component
+// so that it is processed in order with the other events related to that message
+func (pr *partitionRouter) routeInteraction(ws *workspace, i IncomingInteraction) {
+	msgID := SlackMessageID{teamID: i.TeamID, channelID: i.Channel, timestamp: i.MessageTimestamp}
+	ctx, queueWait := pr.startEventSpan(interactionSpanName, label.String("team", i.TeamID), label.String("channel", i.Channel), label.String("ts", i.MessageTimestamp), label.String("actionID", i.Action.ActionID))
+
+	pr.dispatch(msgID, queuedEvent{ws: ws, interaction: &i, ctx: ctx, queueWait: queueWait})
+}
 
+// routeSlashCommand sends a slash command to a partition picked by its unique trigger id. Slash commands aren't messages
+// and don't have to be processed in order with other events so they're spread across partitions, even when issued on the
+// same channel
+func (pr *partitionRouter) routeSlashCommand(ws *workspace, cmd slack.SlashCommand) {
+	msgID := SlackMessageID{teamID: cmd.TeamID, channelID: cmd.ChannelID, timestamp: cmd.TriggerID}
+	ctx, queueWait := pr.startEventSpan(slashCommandSpanName, label.String("team", cmd.TeamID), label.String("channel", cmd.ChannelID), label.String("command", cmd.Command))
+
+	pr.dispatch(msgID, queuedEvent{ws: ws, slashCommand: &cmd, ctx: ctx, queueWait: queueWait})
+}
+
+// dispatch queues an event on the partition for the message id
+func (pr *partitionRouter) dispatch(msgID SlackMessageID, e queuedEvent) {
 	partition := pr.partitionForMsgID(msgID)
+	if e.ctx != nil {
+		trace.SpanFromContext(e.ctx).SetAttributes(label.Int("partition", partition))
+	}
 
-	pr.log.Debugf("Dispatching message [%s] to partition [%d]", msgID, partition)
+	pr.log.Debug("Dispatching event", "team", msgID.teamID, "channel", msgID.channelID, "ts", msgID.timestamp, "partition", partition)
 	d := measure(func() {
-		pr.messageQueues[partition] <- msgEvent
+		pr.messageQueues[partition] <- e
 	})
 
 	pr.coreMetrics.msgDispatchLatencyMillis.Record(context.Background(), d.Milliseconds())
@@ -70,11 +123,12 @@ func (pr *partitionRouter) routeMessageEvent(msgEvent slack.MessageEvent) {
 // partitionForMsgID returns the partition index for a given message ID
 func (pr *partitionRouter) partitionForMsgID(msgID SlackMessageID) (partition int) {
 	pr.hasher.Reset()
+	pr.hasher.Write([]byte(msgID.teamID))
 	pr.hasher.Write([]byte(msgID.channelID))
 	pr.hasher.Write([]byte(msgID.timestamp))
 	res := pr.hasher.Sum32()
 
-	pr.log.Debugf("Hash [%d] calculated for [%s]", res, msgID)
+	pr.log.Debug("Calculated partition hash", "team", msgID.teamID, "channel", msgID.channelID, "ts", msgID.timestamp, "hash", res)
 
 	// Keep only the rightmost bits so we have a max equal to the partition count
 	return int(res) & pr.hashMask
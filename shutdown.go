@@ -0,0 +1,64 @@
+package slackscot
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInvalidAuth is returned by Run when slack rejects the credentials
+var ErrInvalidAuth = errors.New("Invalid credentials")
+
+// scheduledActionTracker keeps track of scheduled actions in flight so that shutdown can wait for them to complete. Once
+// stopped, no new scheduled action is allowed to start
+type scheduledActionTracker struct {
+	lock     sync.Mutex
+	stopped  bool
+	inFlight sync.WaitGroup
+}
+
+// begin records the start of a scheduled action and returns false if the tracker is stopped in which case the action
+// shouldn't run
+func (t *scheduledActionTracker) begin() (ok bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.stopped {
+		return false
+	}
+
+	t.inFlight.Add(1)
+	return true
+}
+
+// end records the completion of a scheduled action
+func (t *scheduledActionTracker) end() {
+	t.inFlight.Done()
+}
+
+// stopAndWait stops the tracker and waits for scheduled actions in flight to complete for up to the timeout. It returns
+// false if they didn't all complete in time
+func (t *scheduledActionTracker) stopAndWait(timeout time.Duration) (completed bool) {
+	t.lock.Lock()
+	t.stopped = true
+	t.lock.Unlock()
+
+	return waitWithTimeout(t.inFlight.Wait, timeout)
+}
+
+// waitWithTimeout runs f in a goroutine and waits for it to complete for up to the timeout. It returns false if f
+// didn't complete in time
+func waitWithTimeout(f func(), timeout time.Duration) (completed bool) {
+	done := make(chan bool)
+	go func() {
+		f()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
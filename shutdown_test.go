@@ -0,0 +1,186 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/alexandre-normand/slackscot/test/capture"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEventSource is an EventSource delivering events sent by a test on its events channel
+type fakeEventSource struct {
+	events       chan slack.RTMEvent
+	disconnected bool
+
+	*capture.RealTimeSenderCaptor
+}
+
+func newFakeEventSource() (es *fakeEventSource) {
+	return &fakeEventSource{events: make(chan slack.RTMEvent), RealTimeSenderCaptor: capture.NewRealTimeSender()}
+}
+
+func (es *fakeEventSource) IncomingEvents() <-chan slack.RTMEvent {
+	return es.events
+}
+
+func (es *fakeEventSource) ManageConnection() {
+}
+
+func (es *fakeEventSource) Disconnect() error {
+	es.disconnected = true
+	return nil
+}
+
+func (es *fakeEventSource) GetInfo() *slack.Info {
+	return &slack.Info{User: &slack.UserDetails{ID: botUserID, Name: "chickadee"}}
+}
+
+// slackAPIStandIn is a minimal stand-in for the slack web api recording the text of posted messages
+type slackAPIStandIn struct {
+	server   *httptest.Server
+	lock     sync.Mutex
+	posted   []string
+	postedTs int
+}
+
+func newSlackAPIStandIn() (api *slackAPIStandIn) {
+	api = new(slackAPIStandIn)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users.info", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"ok": true, "user": {"id": "%s", "profile": {"bot_id": "B1"}}}`, botUserID)
+	})
+	mux.HandleFunc("/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		api.lock.Lock()
+		defer api.lock.Unlock()
+
+		api.posted = append(api.posted, r.FormValue("text"))
+		api.postedTs++
+		fmt.Fprintf(w, `{"ok": true, "channel": "%s", "ts": "%d.000000"}`, r.FormValue("channel"), api.postedTs)
+	})
+	api.server = httptest.NewServer(mux)
+
+	return api
+}
+
+func (api *slackAPIStandIn) postedMessages() []string {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	return append([]string{}, api.posted...)
+}
+
+type fakeCloser struct {
+	closeCount int
+}
+
+func (c *fakeCloser) Close() error {
+	c.closeCount++
+	return nil
+}
+
+func newSlackscotWithFakeEventSource(t *testing.T, api *slackAPIStandIn, es *fakeEventSource) (s *Slackscot) {
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionLog(log.New(&nullWriter{}, "", 0)), OptionWithSlackOption(slack.OptionAPIURL(api.server.URL+"/")), OptionEventSource(func(sc *slack.Client) (EventSource, error) {
+		return es, nil
+	}))
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestRunContextGracefulShutdown(t *testing.T) {
+	api := newSlackAPIStandIn()
+	defer api.server.Close()
+
+	es := newFakeEventSource()
+	s := newSlackscotWithFakeEventSource(t, api, es)
+
+	tp := new(Plugin)
+	tp.Name = "slow"
+	tp.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "blue jays")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			time.Sleep(time.Duration(50) * time.Millisecond)
+			return &Answer{Text: "Finally heard about blue jays"}
+		},
+	}}
+	s.RegisterPlugin(tp)
+
+	closer := new(fakeCloser)
+	s.closers = append(s.closers, closer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.RunContext(ctx)
+	}()
+
+	es.events <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}}
+	es.events <- newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1))
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Duration(2) * time.Second):
+		require.Fail(t, "Timed out waiting for RunContext to return")
+	}
+
+	// The queued message should have been processed before terminating
+	assert.Equal(t, []string{"Finally heard about blue jays"}, api.postedMessages())
+	assert.True(t, es.disconnected)
+	assert.Equal(t, 1, closer.closeCount)
+
+	// Closing again after Run is a no-op
+	assert.NoError(t, s.Close())
+	assert.Equal(t, 1, closer.closeCount)
+}
+
+func TestRunContextReturnsErrInvalidAuth(t *testing.T) {
+	api := newSlackAPIStandIn()
+	defer api.server.Close()
+
+	es := newFakeEventSource()
+	s := newSlackscotWithFakeEventSource(t, api, es)
+
+	done := make(chan error)
+	go func() {
+		done <- s.RunContext(context.Background())
+	}()
+
+	es.events <- slack.RTMEvent{Type: "invalid_auth", Data: &slack.InvalidAuthEvent{}}
+
+	select {
+	case err := <-done:
+		assert.Equal(t, ErrInvalidAuth, err)
+	case <-time.After(time.Duration(2) * time.Second):
+		require.Fail(t, "Timed out waiting for RunContext to return")
+	}
+
+	assert.True(t, es.disconnected)
+}
+
+func TestScheduledActionTracker(t *testing.T) {
+	var tracker scheduledActionTracker
+
+	require.True(t, tracker.begin())
+	assert.False(t, tracker.stopAndWait(time.Duration(10)*time.Millisecond))
+
+	// No new scheduled action can start once stopped
+	assert.False(t, tracker.begin())
+
+	tracker.end()
+	assert.True(t, tracker.stopAndWait(time.Duration(10)*time.Millisecond))
+}
@@ -5,19 +5,22 @@ import (
 	"fmt"
 	"github.com/alexandre-normand/slackscot/config"
 	"github.com/alexandre-normand/slackscot/schedule"
-	"github.com/hashicorp/golang-lru"
 	"github.com/marcsantiago/gocron"
 	"github.com/slack-go/slack"
 	"github.com/spf13/cast"
 	"github.com/spf13/viper"
+	oteltrace "go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/metric"
 	otel "go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"log"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -27,19 +30,41 @@ const (
 	defaultLogFlag   = log.Lshortfile | log.LstdFlags
 )
 
-// Action types
+// Action types, as used in action identifiers and seen by middlewares in ActionInvocation
 const (
-	commandType    = "command"
-	hearActionType = "hearAction"
+	CommandActionType     = "command"
+	HearActionType        = "hearAction"
+	ScheduledActionType   = "scheduledAction"
+	ReactionActionType    = "reactionAction"
+	InteractionActionType = "interactionAction"
+	DialogActionType      = "dialogStep"
 )
 
 // Slackscot represents what defines a Slack Mascot (mostly, a name and its plugins)
 type Slackscot struct {
-	name                    string
-	config                  *viper.Viper
-	defaultAction           Answerer
-	plugins                 []*Plugin
-	triggeringMsgToResponse *lru.ARCCache
+	name          string
+	config        *viper.Viper
+	defaultAction Answerer
+	plugins       []*Plugin
+
+	// Workspaces to connect to (each with its own token, self identity and response cache)
+	workspaces *workspaceRegistry
+
+	// Response cache shared by all workspaces, if set with OptionResponseCache
+	responseCache ResponseCache
+
+	// State of the dialogs in progress and the locks serializing the turns taken in each of them
+	dialogStore DialogStore
+	dialogLocks *dialogLocks
+
+	// Authorization of actions requiring roles
+	authorizer *authorizer
+
+	// Rate limiting of action answers
+	rateLimiter *rateLimiter
+
+	// Circuit breaker disabling actions that keep panicking
+	actionBreaker *circuitBreaker
 
 	// Runtime configuration options
 	namespaceCommands bool
@@ -47,20 +72,53 @@ type Slackscot struct {
 	// Slack options to apply on Run()
 	slackOpts []slack.Option
 
-	// Command identification
-	cmdMatcher CommandMatcher
+	// Factory creating the source of slack events of each workspace on Run()
+	newEventSource workspaceEventSourceFactory
+
+	// Console to run with instead of connecting to slack, if set with OptionConsole
+	console *consoleIO
+
+	// Recorder of incoming events and outgoing calls, if set with OptionRecorder
+	recorder *recorder
+
+	// Scheduled actions registered with the scheduler, reported by the admin status endpoint
+	scheduledJobs []scheduledJob
 
-	// Bot matching for messages from us
-	botMatcher SelfMatcher
+	// Scheduler of scheduled actions along with its generation, incremented every time it's rebuilt on reload
+	stopScheduler       chan bool
+	schedulerGeneration int
 
-	// Default for cmdMatcher and botMatcher
-	selfIdentity selfIdentity
+	// Plugins instantiated from their configuration and the help plugin, replaced on configuration reloads
+	configurablePlugins []*configurablePlugin
+	helpPlugin          *Plugin
+
+	// Configuration reload triggers, if set with OptionWatchConfig or OptionReloadConfigOnSIGHUP
+	watchConfig    bool
+	reloadOnSIGHUP bool
+
+	// Plugins with a Lifecycle, in the order they were initialized
+	initializedPlugins []*Plugin
+
+	// Lock held while processing events and running scheduled actions so that configuration reloads happen in between
+	reloadLock sync.RWMutex
+
+	// Middlewares wrapping the invocation of all plugin actions
+	middlewares []Middleware
+
+	// Command identification. When not set, commands are identified by a mention of the self identity of the
+	// workspace they're sent in
+	cmdMatcher CommandMatcher
 
 	// Logger
 	log *sLogger
 
 	// Resources to close on shutdown
-	closers []io.Closer
+	closers   []io.Closer
+	closeOnce sync.Once
+	closeErr  error
+
+	// Scheduled actions in flight to wait for on shutdown
+	scheduledActions scheduledActionTracker
 
 	// Test mode which defines whether or not the bot reacts to terminationEvents
 	testMode bool
@@ -69,6 +127,7 @@ type Slackscot struct {
 	terminationCh chan bool
 
 	meter              metric.Meter
+	tracerProvider     trace.TracerProvider
 	slackLatencyMillis int64
 
 	*partitionRouter
@@ -109,9 +168,26 @@ type Plugin struct {
 	Commands         []ActionDefinition
 	HearActions      []ActionDefinition
 	ScheduledActions []ScheduledActionDefinition
+	ReactionActions  []ReactionActionDefinition
+
+	// Interaction actions triggered by interactions with Block Kit components having an action id
+	// namespaced with the plugin name. See NewInteractionActionID
+	InteractionActions []InteractionActionDefinition
+
+	// Dialogs started by the plugin's commands and hear actions. See DialogDefinition
+	Dialogs []DialogDefinition
+
+	// Middlewares wrapping the invocation of this plugin's commands and hear actions. See Middleware
+	Middlewares []Middleware
+
+	// Lifecycle hooks called once services are injected, on connections to workspaces and on shutdown. See PluginLifecycle
+	Lifecycle PluginLifecycle
 
 	// Those slackscot services are injected post-creation when slackscot is called.
-	// A plugin shouldn't rely on those being available during creation
+	// A plugin shouldn't rely on those being available during creation (see Lifecycle). When connected to
+	// several workspaces, those are the services of the default (first) workspace and Workspaces
+	// gives access to the services of each workspace
+	Workspaces        WorkspaceServicesFinder
 	UserInfoFinder    UserInfoFinder
 	Logger            SLogger
 	EmojiReactor      EmojiReactor
@@ -124,6 +200,12 @@ type Plugin struct {
 	SlackClient *slack.Client
 }
 
+// Log returns the plugin's structured Logger. The Logger injected by slackscot tags all entries with the plugin
+// name (plugin=<name>)
+func (p *Plugin) Log() Logger {
+	return AsLogger(p.Logger)
+}
+
 // ActionDefinition represents how an action is triggered, published, used and described
 // along with defining the function defining its behavior
 type ActionDefinition struct {
@@ -141,6 +223,26 @@ type ActionDefinition struct {
 
 	// Function to execute if the Matcher matches
 	Answer Answerer
+
+	// Function to execute if the Matcher matches with a context.Context that is done when the action's
+	// execution times out. When set, it is used instead of Answer
+	ContextAnswer ContextAnswerer
+
+	// Maximum execution time of the action after which its answer is abandoned. If zero,
+	// the config.ActionTimeoutKey configuration applies
+	Timeout time.Duration
+
+	// Roles a user must have to run the action. Users without them get the config.UnauthorizedAnswerKey answer
+	// and the action is omitted from the help message they get. Admins have all roles (see AdminRole)
+	RequiredRoles []string
+
+	// Priority of the action when several actions match a message. Actions with a higher priority are tried first. Ties
+	// are broken by the registration order of plugins and then by the order of the actions within their plugin
+	Priority int
+
+	// Indicates whether the action, when it matches a message, is the last one to answer it: the actions tried after it
+	// are skipped. config.ExclusiveRoutingKey makes all actions exclusive so that the first match wins
+	Exclusive bool
 }
 
 // Matcher is the function that determines whether or not an action should be triggered based on a IncomingMessage (which
@@ -152,6 +254,20 @@ type Matcher func(m *IncomingMessage) bool
 // should return nil
 type Answerer func(m *IncomingMessage) *Answer
 
+// ContextAnswerer is the context-aware variant of an Answerer. The context is done when the action
+// times out so that slow answerers (i.e. calling external services) can abort early. To signal the absence of an
+// answer, an action should return nil
+type ContextAnswerer func(ctx context.Context, m *IncomingMessage) *Answer
+
+// answer invokes the ContextAnswer, if set, or the Answer
+func (a ActionDefinition) answer(ctx context.Context, m *IncomingMessage) *Answer {
+	if a.ContextAnswer != nil {
+		return a.ContextAnswer(ctx, m)
+	}
+
+	return a.Answer(m)
+}
+
 // ActionDefinitionWithID holds an action definition along with its identifier string
 type ActionDefinitionWithID struct {
 	ActionDefinition
@@ -179,10 +295,10 @@ type ScheduledActionDefinition struct {
 // so the function has access to the injected services
 type ScheduledAction func()
 
-// SlackMessageID holds the elements that form a unique message identifier for slack. Technically, slack also uses
-// the workspace id as the first part of that unique identifier but since an instance of slackscot only lives within
-// a single workspace, that part is left out
+// SlackMessageID holds the elements that form a unique message identifier for slack: the workspace (team) id,
+// the channel id and the message timestamp. The team id is empty when unknown (i.e. before being connected)
 type SlackMessageID struct {
+	teamID    string
 	channelID string
 	timestamp string
 }
@@ -196,6 +312,10 @@ func (sid SlackMessageID) IsMsgModifiable() bool {
 
 // String returns the string representation of a SlackMessageID
 func (sid SlackMessageID) String() string {
+	if sid.teamID != "" {
+		return fmt.Sprintf("%s/%s/%s", sid.teamID, sid.channelID, sid.timestamp)
+	}
+
 	return fmt.Sprintf("%s/%s", sid.channelID, sid.timestamp)
 }
 
@@ -210,6 +330,10 @@ type responseStrategy func(m IncomingMessage, answer *Answer) slack.OutgoingMess
 type IncomingMessage struct {
 	// The original slack.Msg text stripped from the "<@Mention>" cmdPrefix, if applicable
 	NormalizedText string
+
+	// The id of the team (workspace) the message was received from
+	TeamID string
+
 	slack.Msg
 }
 
@@ -233,6 +357,7 @@ type runDependencies struct {
 	fileUploader      FileUploader
 	selfInfoFinder    selfInfoFinder
 	realTimeMsgSender RealTimeMessageSender
+	groupMemberFinder UserGroupMemberFinder
 	slackClient       *slack.Client
 }
 
@@ -288,13 +413,23 @@ func (s *selfIdentity) String() string {
 // Option defines an option for a Slackscot
 type Option func(*Slackscot)
 
-// OptionLog sets a logger for Slackscot
+// OptionLog sets a logger for Slackscot. Entries are written to it as text lines unless a LogHandler is set with
+// OptionLogHandler, in which case the handler writes them regardless of the order in which the options are applied
 func OptionLog(logger *log.Logger) Option {
 	return func(s *Slackscot) {
 		s.log.logger = logger
 	}
 }
 
+// OptionLogHandler sets a LogHandler writing the structured log entries of Slackscot and its plugins. Use
+// NewJSONLogHandler to log JSON lines. The handler takes precedence over the logger set with OptionLog or OptionLogfile,
+// regardless of the order in which the options are applied. That logger is still used by the slack client
+func OptionLogHandler(handler LogHandler) Option {
+	return func(s *Slackscot) {
+		s.log.handler = handler
+	}
+}
+
 // OptionWithSlackOption adds a slack.Option to apply on the slack client
 func OptionWithSlackOption(opt slack.Option) Option {
 	return func(s *Slackscot) {
@@ -359,6 +494,67 @@ func OptionTestMode(terminationCh chan bool) Option {
 	}
 }
 
+// OptionSocketMode sets slackscot to receive events over a Socket Mode connection instead of the RTM. This requires
+// an app-level token (with the connections:write scope) set in the configuration at config.AppTokenKey. The bot token
+// at config.TokenKey is still used for all other slack api calls. When connected to several workspaces, each one
+// opens its own connection with the appToken set in its configuration (which defaults to the one at config.AppTokenKey)
+func OptionSocketMode() Option {
+	return func(s *Slackscot) {
+		s.newEventSource = func(sc *slack.Client, ws *workspace) (es EventSource, err error) {
+			if ws.appToken == "" {
+				return nil, fmt.Errorf("Socket mode requires an app-level token set in config [%s]", config.AppTokenKey)
+			}
+
+			return newSocketModeEventSource(ws.appToken, slack.APIURL, sc, s.log), nil
+		}
+	}
+}
+
+// OptionEventsAPI sets slackscot to receive events from the Events API over http instead of the RTM. Slackscot
+// serves requests on the listenAddr (i.e. ":8080") at EventsAPIPath and verifies them with the signing secret
+// set in the configuration at config.SigningSecretKey. The Events API is only supported when connected to a single workspace
+func OptionEventsAPI(listenAddr string) Option {
+	return func(s *Slackscot) {
+		s.newEventSource = func(sc *slack.Client, ws *workspace) (es EventSource, err error) {
+			if len(s.workspaces.all) > 1 {
+				return nil, fmt.Errorf("Events API is only supported with a single workspace but [%d] are configured", len(s.workspaces.all))
+			}
+
+			signingSecret := s.config.GetString(config.SigningSecretKey)
+			if signingSecret == "" {
+				return nil, fmt.Errorf("Events API requires a signing secret set in config [%s]", config.SigningSecretKey)
+			}
+
+			return newEventsAPIEventSource(listenAddr, signingSecret, sc, s.log)
+		}
+	}
+}
+
+// OptionEventSource sets a custom factory for the source of slack events
+func OptionEventSource(newEventSource EventSourceFactory) Option {
+	return func(s *Slackscot) {
+		s.newEventSource = newWorkspaceEventSourceFactory(newEventSource)
+	}
+}
+
+// OptionResponseCache sets the cache keeping track of the responses to triggering messages. Use a StorerResponseCache
+// for responses to still get updated/deleted along with their triggering message after a restart. Defaults to an
+// ARCResponseCache of config.ResponseCacheSizeKey entries for each workspace. When set, the cache is shared by all
+// workspaces (entries are keyed by team id so they don't collide)
+func OptionResponseCache(cache ResponseCache) Option {
+	return func(s *Slackscot) {
+		s.responseCache = cache
+	}
+}
+
+// OptionDialogStore sets the store keeping the state of dialogs in progress. Use a StorerDialogStore for dialogs to
+// survive restarts. Defaults to an InMemoryDialogStore
+func OptionDialogStore(dialogStore DialogStore) Option {
+	return func(s *Slackscot) {
+		s.dialogStore = dialogStore
+	}
+}
+
 //OptionCommandPrefix sets a cmdPrefix to all commands that is used instead of at-mentioning the bot
 func OptionCommandPrefix(cmdPrefix string) Option {
 	return func(s *Slackscot) {
@@ -399,18 +595,19 @@ func NewSlackscot(name string, v *viper.Viper, options ...Option) (s *Slackscot,
 func New(name string, v *viper.Viper, options ...Option) (s *Slackscot, err error) {
 	s = new(Slackscot)
 
-	s.triggeringMsgToResponse, err = lru.NewARC(v.GetInt(config.ResponseCacheSizeKey))
-	if err != nil {
-		return nil, err
-	}
-
 	v = config.LayerConfigWithDefaults(v)
 	s.name = name
 	s.config = v
 	s.namespaceCommands = true
 	s.testMode = false
 	s.closers = make([]io.Closer, 0)
-	s.defaultAction = defaultAction
+	s.defaultAction = s.suggestCommands
+	s.actionBreaker = newCircuitBreaker(v.GetInt(config.ActionPanicThresholdKey))
+	s.newEventSource = newWorkspaceEventSourceFactory(newRTMEventSource)
+	s.dialogStore = NewInMemoryDialogStore()
+	s.dialogLocks = newDialogLocks()
+	s.authorizer = newAuthorizer(v)
+	s.rateLimiter = newRateLimiter()
 	s.log = NewSLogger(log.New(os.Stdout, defaultLogPrefix, defaultLogFlag), v.GetBool(config.DebugKey))
 
 	partitionCount := s.config.GetInt(config.MessageProcessingPartitionCount)
@@ -422,10 +619,8 @@ func New(name string, v *viper.Viper, options ...Option) (s *Slackscot, err erro
 	s.slackOpts = append(s.slackOpts, slack.OptionDebug(s.config.GetBool(config.DebugKey)))
 	s.slackOpts = append(s.slackOpts, slack.OptionLog(log.New(s.log.logger.Writer(), "slack: ", defaultLogFlag)))
 
-	s.botMatcher = &s.selfIdentity
-	s.cmdMatcher = &s.selfIdentity
-
-	s.meter = otel.GetMeterProvider().Meter("github.com/alexandre-normand/slackscot")
+	s.meter = otel.GetMeterProvider().Meter(instrumentationName)
+	s.tracerProvider = oteltrace.GetTracerProvider()
 
 	for _, opt := range options {
 		opt(s)
@@ -435,6 +630,26 @@ func New(name string, v *viper.Viper, options ...Option) (s *Slackscot, err erro
 	if err != nil {
 		return nil, err
 	}
+	s.instrumenter.tracer = s.tracerProvider.Tracer(instrumentationName)
+
+	workspaces := make([]*workspace, 0)
+	for _, wc := range config.GetWorkspaces(v) {
+		cache := s.responseCache
+		if cache == nil {
+			cache, err = NewARCResponseCache(v.GetInt(config.ResponseCacheSizeKey), v.GetDuration(config.MaxAgeHandledMessages))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		cache, err = newResponseCacheWithTelemetry(cache, name, s.meter)
+		if err != nil {
+			return nil, err
+		}
+
+		workspaces = append(workspaces, newWorkspace(wc, cache))
+	}
+	s.workspaces = newWorkspaceRegistry(workspaces)
 
 	s.partitionRouter, err = newPartitionRouter(partitionCount, s.config.GetInt(config.MessageProcessingBufferedMessageCount), s.log, s.instrumenter)
 	if err != nil {
@@ -450,22 +665,25 @@ func defaultAction(m *IncomingMessage) *Answer {
 }
 
 func (s *Slackscot) reportLatency(ctx context.Context, result metric.Int64ObserverResult) {
-	result.Observe(s.slackLatencyMillis)
+	result.Observe(atomic.LoadInt64(&s.slackLatencyMillis))
 }
 
 // Close closes all closers of this slackscot. The first error that occurs
 // during a Close is returned but regardless, all closers are attempted
-// to be closed
+// to be closed. Closers are only closed once so calling Close after Run returned
+// is safe
 func (s *Slackscot) Close() (err error) {
-	for _, c := range s.closers {
-		if err == nil {
-			err = c.Close()
-		} else {
-			c.Close()
+	s.closeOnce.Do(func() {
+		for _, c := range s.closers {
+			if s.closeErr == nil {
+				s.closeErr = c.Close()
+			} else {
+				c.Close()
+			}
 		}
-	}
+	})
 
-	return err
+	return s.closeErr
 }
 
 // RegisterPlugin registers a plugin with the Slackscot engine. This should be invoked
@@ -474,89 +692,221 @@ func (s *Slackscot) RegisterPlugin(p *Plugin) {
 	s.plugins = append(s.plugins, p)
 }
 
-// Run starts the Slackscot and loops until the process is interrupted
+// Run starts the Slackscot and loops until the process is interrupted (by a SIGINT or SIGTERM) or a fatal
+// error occurs (i.e. ErrInvalidAuth). See RunContext for details on the shutdown
 func (s *Slackscot) Run() (err error) {
-	sc := slack.New(
-		s.config.GetString(config.TokenKey),
-		s.slackOpts...,
-	)
-
-	// This will initiate the connection to the slack RTM and start the reception of messages
-	rtm := sc.NewRTM()
-	go rtm.ManageConnection()
-
-	// Load time zone location for the scheduler, we just log the error here since we fail to start
-	// but we're in a go routine. Hopefully, this should be sufficient for users to figure out the bad
-	// configuration
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go s.watchForTerminationSignalToAbort(ctx, cancel)
+
+	return s.RunContext(ctx)
+}
+
+// RunContext starts the Slackscot and loops until the context is done or a fatal error occurs (i.e. ErrInvalidAuth).
+// On termination, slackscot shuts down gracefully: it stops accepting events and stops the scheduler, processes the
+// messages already queued and waits for scheduled actions in flight (each for up to the config.ShutdownGracePeriodKey),
+// stops plugins with a Lifecycle and, finally, closes all registered closers.
+//
+// When several workspaces are configured (see config.WorkspacesKey), slackscot connects to all of them and a fatal
+// error on any of them terminates the Slackscot
+func (s *Slackscot) RunContext(ctx context.Context) (err error) {
 	timeLoc, err := config.GetTimeLocation(s.config)
 	if err != nil {
 		return err
 	}
 
+	sources := make([]EventSource, 0)
+	if s.console != nil {
+		cs := newConsoleSession(s.name, s.console.in, s.console.out)
+		go cs.ManageConnection()
+		sources = append(sources, cs)
+
+		s.workspaces.defaultWorkspace().attach(cs.IncomingEvents(), cs.runDependencies())
+	}
+
+	for _, ws := range s.workspaces.all {
+		if s.console != nil {
+			break
+		}
+
+		sc := slack.New(
+			ws.token,
+			s.slackOpts...,
+		)
+
+		// This will initiate the connection to slack and start the reception of events
+		es, err := s.newEventSource(sc, ws)
+		if err != nil {
+			s.disconnect(sources)
+			return err
+		}
+		go es.ManageConnection()
+		sources = append(sources, es)
+
+		chatDriver := newDeliveringChatDriver(ctx, NewchatDriverWithTelemetry(sc, s.name, s.instrumenter.meter, s.tracer), newDeliveryPolicy(s.config), s.deliveryMetrics, s.log)
+		ws.attach(es.IncomingEvents(), &runDependencies{chatDriver: chatDriver, userInfoFinder: NewUserInfoFinderWithTelemetry(sc, s.name, s.instrumenter.meter, s.tracer), emojiReactor: NewEmojiReactorWithTelemetry(sc, s.name, s.instrumenter.meter, s.tracer), fileUploader: NewFileUploaderWithTelemetry(NewFileUploader(sc), s.name, s.instrumenter.meter, s.tracer), selfInfoFinder: es, realTimeMsgSender: es, groupMemberFinder: sc, slackClient: sc})
+	}
+
 	// Start scheduling of all plugins' scheduled actions
-	go s.startActionScheduler(timeLoc)
+	s.stopScheduler = s.startActionScheduler(timeLoc)
 
-	// runInternal is blocking call so it's running in a goroutine. The way slackscot would usually terminate
-	// in a production scenario is by its process getting killed which would result in a last message sent on the termination channel
-	if s.terminationCh != nil {
-		// Start the main processing and send the termination to the externally defined termination channel (so a test can block and wait for processing after sending all of its test messages)
-		go s.runInternal(rtm.IncomingEvents, &runDependencies{chatDriver: NewchatDriverWithTelemetry(sc, s.name, s.instrumenter.meter), userInfoFinder: NewUserInfoFinderWithTelemetry(sc, s.name, s.instrumenter.meter), emojiReactor: NewEmojiReactorWithTelemetry(sc, s.name, s.instrumenter.meter), fileUploader: NewFileUploaderWithTelemetry(NewFileUploader(sc), s.name, s.instrumenter.meter), selfInfoFinder: rtm, realTimeMsgSender: rtm, slackClient: sc})
-	} else {
-		// This is production and the lifecycle is managed here so we create the termination channel and wait for the termination signal
-		s.terminationCh = make(chan bool)
+	// Reload the configuration on changes or on SIGHUP, if enabled
+	stopReloading := s.startConfigReloading(ctx)
+
+	// runInternal blocks until the context is done or a fatal error occurs. When it returns, all messages
+	// it queued for processing have been processed (or the grace period expired)
+	err = s.runInternal(ctx)
 
-		go s.runInternal(rtm.IncomingEvents, &runDependencies{chatDriver: NewchatDriverWithTelemetry(sc, s.name, s.instrumenter.meter), userInfoFinder: NewUserInfoFinderWithTelemetry(sc, s.name, s.instrumenter.meter), emojiReactor: NewEmojiReactorWithTelemetry(sc, s.name, s.instrumenter.meter), fileUploader: NewFileUploaderWithTelemetry(NewFileUploader(sc), s.name, s.instrumenter.meter), selfInfoFinder: rtm, realTimeMsgSender: rtm, slackClient: sc})
+	stopReloading()
+	s.shutdown(sources, s.stopScheduler)
+	s.stopPlugins()
 
-		// Wait for termination
-		<-s.terminationCh
+	if cerr := s.Close(); cerr != nil && err == nil {
+		err = cerr
 	}
 
-	return nil
+	return err
+}
+
+// shutdown disconnects from slack, stops the scheduler and waits for scheduled actions in flight to complete
+func (s *Slackscot) shutdown(sources []EventSource, stopScheduler chan bool) {
+	gracePeriod := s.config.GetDuration(config.ShutdownGracePeriodKey)
+
+	s.disconnect(sources)
+
+	stopScheduler <- true
+
+	if !s.scheduledActions.stopAndWait(gracePeriod) {
+		s.log.Printf("Timed out waiting for scheduled actions to complete after [%s]\n", gracePeriod)
+	}
 }
 
-// runInternal handles all incoming events and acts as the main loop. It will essentially
-// always process events as long as the process isn't interrupted. Normally, this happens
-// by a kill signal being sent and slackscot gets notified and closes the events channel which
-// terminates this loop and shuts down gracefully
-func (s *Slackscot) runInternal(events <-chan slack.RTMEvent, deps *runDependencies) {
-	// Ensure we send a termination signal on the channel to unblock the main thread and exit
+// disconnect disconnects all event sources from slack, waiting for up to the grace period
+func (s *Slackscot) disconnect(sources []EventSource) {
+	gracePeriod := s.config.GetDuration(config.ShutdownGracePeriodKey)
+
+	if !waitWithTimeout(func() {
+		for _, es := range sources {
+			if err := es.Disconnect(); err != nil {
+				s.log.Printf("Error disconnecting from slack: %v\n", err)
+			}
+		}
+	}, gracePeriod) {
+		s.log.Printf("Timed out disconnecting from slack after [%s]\n", gracePeriod)
+	}
+}
+
+// runInternal handles all incoming events of the workspaces (attached to their events and dependencies) and acts as
+// the main loop. It will essentially always process events until the context is done (normally, on a kill signal),
+// the events channels are closed or a fatal error occurs. In all cases, the messages already queued are processed
+// before returning
+func (s *Slackscot) runInternal(ctx context.Context) (err error) {
+	// In test mode, send a termination signal on the channel to let tests know that processing is done
 	defer func() {
-		s.terminationCh <- true
+		if s.terminationCh != nil {
+			s.terminationCh <- true
+		}
 	}()
 
-	// Register to receive a notification for a termination signal which will, in turn, send a termination message to the
-	// termination channel
-	go s.watchForTerminationSignalToAbort()
-
 	// Start by adding the help command now that we know all plugins have been registered
 	helpPlugin := s.newHelpPlugin(VERSION)
-	s.RegisterPlugin(&helpPlugin.Plugin)
+	s.helpPlugin = &helpPlugin.Plugin
+	s.RegisterPlugin(s.helpPlugin)
+
+	// Record the calls of the workspace dependencies when recording
+	if s.recorder != nil {
+		for _, ws := range s.workspaces.all {
+			if ws.deps != nil {
+				ws.deps = s.recorder.wrap(ws.name, ws.deps)
+			}
+		}
+	}
 
-	// Inject services into plugins before starting to process events
-	s.injectServicesToPlugins(deps.userInfoFinder, s.log, deps.emojiReactor, deps.fileUploader, deps.realTimeMsgSender, deps.slackClient)
+	// Inject services into plugins and initialize them before starting to process events
+	s.injectServicesToPlugins(s.log)
+	if err = s.initPlugins(); err != nil {
+		s.log.Error("Error initializing plugins", "err", err)
+		return err
+	}
+
+	// Serve the health, readiness and status endpoints, if enabled
+	if listenAddr := s.config.GetString(config.AdminListenAddrKey); listenAddr != "" {
+		stopAdminServer := s.startAdminServer(listenAddr)
+		defer stopAdminServer()
+	}
 
 	// start all worker go routines
 	for i := range s.messageQueues {
-		go s.processMessages(deps.chatDriver, s.messageQueues[i], s.workerTerminationSignals[i])
+		go s.processMessages(s.messageQueues[i], s.workerTerminationSignals[i])
 	}
 
-	for msg := range events {
+	// Make sure all queued messages get processed before returning
+	defer s.drainMessageQueues()
+
+	events := newWorkspaceEventReceiver(ctx, s.workspaces.all)
+
+	for {
+		msg, done, closed := events.receive()
+		if done {
+			s.log.Printf("Terminating: %v\n", ctx.Err())
+			return nil
+		}
+
+		if closed {
+			s.log.Printf("Incoming events channel closed, terminating\n")
+			return nil
+		}
+
+		ws := msg.ws
+
+		if s.recorder != nil {
+			s.recorder.recordEvent(ws.name, msg.RTMEvent)
+		}
+
 		switch e := msg.Data.(type) {
 		case *slack.ConnectedEvent:
 			s.log.Printf("Infos: %v\n", e.Info)
 			s.log.Printf("Connection counter: %d\n", e.ConnectionCount)
-			err := s.cacheSelfIdentity(deps.selfInfoFinder, deps.userInfoFinder)
+			err := s.cacheSelfIdentity(ws)
 			if err != nil {
 				s.log.Printf("Error getting self identity: %s", err.Error())
-				return
+				return err
+			}
+			reconnected := ws.markConnected(e.ConnectionCount)
+
+			if err := s.startPlugins(ws, reconnected); err != nil {
+				s.log.Error("Error starting plugins", "workspace", ws, "err", err)
+				return err
 			}
 
 		case *slack.MessageEvent:
 			s.coreMetrics.msgsSeen.Add(context.Background(), 1)
-			s.routeMessageEvent(*e)
+			s.routeMessageEvent(ws, *e)
+
+		case *slack.ReactionAddedEvent:
+			if r, isOnMessage := newIncomingReactionFromAdded(ws.teamID, e); isOnMessage {
+				s.routeReaction(ws, r)
+			}
+
+		case *slack.ReactionRemovedEvent:
+			if r, isOnMessage := newIncomingReactionFromRemoved(ws.teamID, e); isOnMessage {
+				s.routeReaction(ws, r)
+			}
+
+		case *slack.InteractionCallback:
+			for _, i := range newIncomingInteractions(ws.teamID, e) {
+				s.routeInteraction(ws, i)
+			}
+
+		case *slack.SlashCommand:
+			if e.TeamID == "" {
+				e.TeamID = ws.teamID
+			}
+			s.routeSlashCommand(ws, *e)
 
 		case *slack.LatencyReport:
-			s.slackLatencyMillis = e.Value.Milliseconds()
+			atomic.StoreInt64(&s.slackLatencyMillis, e.Value.Milliseconds())
 			s.log.Printf("Current latency: %v\n", e.Value)
 
 		case *slack.RTMError:
@@ -564,22 +914,13 @@ func (s *Slackscot) runInternal(events <-chan slack.RTMEvent, deps *runDependenc
 
 		case *slack.InvalidAuthEvent:
 			s.log.Printf("Invalid credentials\n")
-			return
+			return ErrInvalidAuth
 
 		case *slack.DisconnectedEvent:
+			ws.markDisconnected()
 			if s.testMode && e.Cause != nil && e.Cause == slack.ErrRTMGoodbye {
 				s.log.Printf("Received termination event in test mode, terminating\n")
-				// Close all processing queues and wait for the terminations
-				for _, wq := range s.messageQueues {
-					close(wq)
-				}
-
-				// Wait for all workers to terminate processing
-				for _, tc := range s.workerTerminationSignals {
-					<-tc
-				}
-
-				return
+				return nil
 			}
 		default:
 			// Ignoring other messages
@@ -587,35 +928,76 @@ func (s *Slackscot) runInternal(events <-chan slack.RTMEvent, deps *runDependenc
 	}
 }
 
-// injectServicesToPlugins assembles/creates the services and injects them in all plugins
-func (s *Slackscot) injectServicesToPlugins(loadingUserInfoFinder UserInfoFinder, logger SLogger, emojiReactor EmojiReactor, fileUploader FileUploader, msgSender RealTimeMessageSender, slackClient *slack.Client) (err error) {
-	userInfoFinder, err := NewCachingUserInfoFinder(s.config, loadingUserInfoFinder, logger)
-	if err != nil {
-		return err
+// drainMessageQueues closes all processing queues and waits for the workers to process the queued messages for up to
+// the grace period
+func (s *Slackscot) drainMessageQueues() {
+	for _, wq := range s.messageQueues {
+		close(wq)
+	}
+
+	gracePeriod := s.config.GetDuration(config.ShutdownGracePeriodKey)
+	if !waitWithTimeout(func() {
+		for _, tc := range s.workerTerminationSignals {
+			<-tc
+		}
+	}, gracePeriod) {
+		s.log.Printf("Timed out processing queued messages after [%s]\n", gracePeriod)
+	}
+}
+
+// injectServicesToPlugins assembles/creates the services of each workspace and injects them in all plugins. The services
+// set directly on plugins are those of the default workspace
+func (s *Slackscot) injectServicesToPlugins(logger Logger) (err error) {
+	for _, ws := range s.workspaces.all {
+		if ws.deps == nil {
+			continue
+		}
+
+		userInfoFinder, err := NewCachingUserInfoFinder(s.config, ws.deps.userInfoFinder, logger)
+		if err != nil {
+			return err
+		}
+
+		ws.services = &WorkspaceServices{UserInfoFinder: userInfoFinder, EmojiReactor: ws.deps.emojiReactor, FileUploader: ws.deps.fileUploader, RealTimeMsgSender: ws.deps.realTimeMsgSender, SlackClient: ws.deps.slackClient}
+	}
+
+	services := s.workspaces.defaultWorkspace().services
+	if services == nil {
+		services = &WorkspaceServices{}
 	}
 
 	for _, p := range s.plugins {
-		p.Logger = logger
-		p.UserInfoFinder = userInfoFinder
-		p.EmojiReactor = emojiReactor
-		p.FileUploader = fileUploader
-		p.RealTimeMsgSender = msgSender
-		p.SlackClient = slackClient
+		s.injectPluginServices(p, logger, services)
 	}
 
 	return nil
 }
 
-// watchForTerminationSignalToAbort waits for a SIGTERM or SIGINT and sends a termination signal on the termination channel to finish
-// the main Run() loop and terminate cleanly. Note that this is meant to run in a go routine given that this is blocking
-func (s *Slackscot) watchForTerminationSignalToAbort() {
+// injectPluginServices injects the logger, workspaces and services of the default workspace in a plugin
+func (s *Slackscot) injectPluginServices(p *Plugin, logger Logger, services *WorkspaceServices) {
+	p.Logger = logger.With("plugin", p.Name)
+	p.Workspaces = s.workspaces
+	p.UserInfoFinder = services.UserInfoFinder
+	p.EmojiReactor = services.EmojiReactor
+	p.FileUploader = services.FileUploader
+	p.RealTimeMsgSender = services.RealTimeMsgSender
+	p.SlackClient = services.SlackClient
+}
+
+// watchForTerminationSignalToAbort waits for a SIGTERM or SIGINT and cancels the context to finish the main Run() loop
+// and terminate cleanly. Note that this is meant to run in a go routine given that this is blocking
+func (s *Slackscot) watchForTerminationSignalToAbort(ctx context.Context, cancel context.CancelFunc) {
 	tSignals := make(chan os.Signal, 1)
 	// Register to be notified of termination signals so we can abort
 	signal.Notify(tSignals, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-tSignals
+	defer signal.Stop(tSignals)
 
-	s.log.Debugf("Received termination signal [%s], closing RTM's incoming events channel to terminate processing\n", sig)
-	s.terminationCh <- true
+	select {
+	case sig := <-tSignals:
+		s.log.Debugf("Received termination signal [%s], terminating processing\n", sig)
+		cancel()
+	case <-ctx.Done():
+	}
 }
 
 // getActionID returns a formatted identifier for an action. It includes the plugin name,
@@ -629,35 +1011,92 @@ func getActionID(pluginName string, actionType string, index int) (actionID stri
 	return fmt.Sprintf("%s.%s[%d]", pluginName, actionType, index)
 }
 
-// cacheSelfIdentity gets "our" identity and keeps the id.id and id.name to avoid having to look it up every time
-func (s *Slackscot) cacheSelfIdentity(selfInfoFinder selfInfoFinder, userInfoFinder UserInfoFinder) (err error) {
-	s.selfIdentity.id = selfInfoFinder.GetInfo().User.ID
-	s.selfIdentity.name = selfInfoFinder.GetInfo().User.Name
+// cacheSelfIdentity gets "our" identity in a workspace and keeps the id.id and id.name to avoid having to look it up every time.
+// The workspace's team id is also kept for the workspace to be found by it
+func (s *Slackscot) cacheSelfIdentity(ws *workspace) (err error) {
+	info := ws.deps.selfInfoFinder.GetInfo()
+	ws.selfIdentity.id = info.User.ID
+	ws.selfIdentity.name = info.User.Name
+
+	if info.Team != nil {
+		ws.teamID = info.Team.ID
+	}
+	s.workspaces.register(ws)
 
-	user, err := userInfoFinder.GetUserInfo(s.selfIdentity.id)
+	user, err := ws.deps.userInfoFinder.GetUserInfo(ws.selfIdentity.id)
 	if err != nil {
 		return err
 	}
-	s.selfIdentity.botID = user.Profile.BotID
-	s.selfIdentity.userPrefix = fmt.Sprintf("<@%s> ", s.selfIdentity.id)
+	ws.selfIdentity.botID = user.Profile.BotID
+	ws.selfIdentity.userPrefix = fmt.Sprintf("<@%s> ", ws.selfIdentity.id)
 
-	s.log.Debugf("Caching self id [%s], self name [%s], self bot ID [%s] and self cmdPrefix [%s]\n", s.selfIdentity.id, s.selfIdentity.name, s.selfIdentity.botID, s.selfIdentity.userPrefix)
+	s.log.Debugf("Caching self id [%s], self name [%s], self bot ID [%s] and self cmdPrefix [%s] for team [%s]\n", ws.selfIdentity.id, ws.selfIdentity.name, ws.selfIdentity.botID, ws.selfIdentity.userPrefix, ws.teamID)
 	return nil
 }
 
+// cmdMatcherFor returns the CommandMatcher for messages of a workspace. Unless overridden with OptionCommandPrefix, this
+// is the self identity of the workspace
+func (s *Slackscot) cmdMatcherFor(ws *workspace) (cmdMatcher CommandMatcher) {
+	if s.cmdMatcher != nil {
+		return s.cmdMatcher
+	}
+
+	return &ws.selfIdentity
+}
+
+// newSafeScheduledAction wraps a scheduled action to recover from its panics. Recovered panics are logged, counted
+// and recorded with the circuit breaker and the action is skipped once disabled. Executions are also tracked so that
+// shutdown can wait for them
+func (s *Slackscot) newSafeScheduledAction(pluginName string, actionID string, action ScheduledAction) ScheduledAction {
+	return func() {
+		if !s.scheduledActions.begin() {
+			s.log.Debugf("Skipping action [%s] since we're shutting down", actionID)
+			return
+		}
+		defer s.scheduledActions.end()
+
+		if s.actionBreaker.isOpen(actionID) {
+			s.log.Debugf("Skipping action [%s] disabled after too many consecutive panics", actionID)
+			return
+		}
+
+		err := callSafely(func() {
+			action()
+		})
+
+		if p, ok := err.(*actionPanic); ok {
+			s.handleActionPanic(actionID, p)
+
+			if pm, err := s.getOrCreatePluginMetrics(pluginName); err == nil {
+				pm.actionPanicCount.Add(context.Background(), 1)
+			}
+
+			return
+		}
+
+		s.actionBreaker.recordSuccess(actionID)
+	}
+}
+
 // startActionScheduler creates all ScheduledActionDefinition from all plugins and registers them with the scheduler
-// Very importantly, it also starts the scheduler
-func (s *Slackscot) startActionScheduler(timeLoc *time.Location) {
+// Very importantly, it also starts the scheduler and returns the channel to send a value to in order to stop it
+func (s *Slackscot) startActionScheduler(timeLoc *time.Location) (stop chan bool) {
 	gocron.ChangeLoc(timeLoc)
 	sc := gocron.NewScheduler()
+	s.schedulerGeneration++
+	s.scheduledJobs = make([]scheduledJob, 0)
 
 	for _, p := range s.plugins {
 		if p.ScheduledActions != nil {
-			for _, sa := range p.ScheduledActions {
+			for i, sa := range p.ScheduledActions {
 				j, err := schedule.NewJob(sc, sa.Schedule)
 				if err == nil {
 					s.log.Debugf("Adding job [%v] to scheduler\n", j)
-					err = j.Do(sa.Action)
+					actionID := getActionID(p.Name, ScheduledActionType, i)
+					err = j.Do(s.newReloadableScheduledAction(s.schedulerGeneration, s.newSafeScheduledAction(p.Name, actionID, sa.Action)))
+					if err == nil {
+						s.scheduledJobs = append(s.scheduledJobs, scheduledJob{actionID: actionID, definition: sa, job: j})
+					}
 				}
 
 				if err != nil {
@@ -670,67 +1109,143 @@ func (s *Slackscot) startActionScheduler(timeLoc *time.Location) {
 	_, t := sc.NextRun()
 	s.log.Debugf("Starting scheduler with first job scheduled at [%s]\n", t)
 
-	// TODO: consider keeping track of the scheduler to stop it if it starts to appear necessary
-	<-sc.Start()
+	return sc.Start()
 }
 
 // processMessages processes messages from a queue and sends a termination signal on terminationChan when done
-func (s *Slackscot) processMessages(driver chatDriver, queue chan slack.MessageEvent, terminationChan chan bool) {
-	for msg := range queue {
-		// reply_to is an field set to 1 sent by slack when a sent message has been acknowledged and should be considered
-		// officially sent to others. Therefore, we ignore all of those since it's mostly for clients/UI to show status
-		isReply := msg.ReplyTo > 0
+func (s *Slackscot) processMessages(queue chan queuedEvent, terminationChan chan bool) {
+	for e := range queue {
+		// Configuration reloads wait for the event to be processed
+		s.reloadLock.RLock()
+		s.processQueuedEvent(e)
+		s.reloadLock.RUnlock()
+	}
+
+	terminationChan <- true
+}
+
+// processQueuedEvent processes an event taken from a partition queue
+func (s *Slackscot) processQueuedEvent(e queuedEvent) {
+	ws := e.ws
+	driver := ws.deps.chatDriver
 
-		s.log.Debugf("Processing event: %v", msg)
+	if e.reaction != nil {
+		// Calls made while processing the reaction nest under its span, ended once processing is done
+		ctx := e.ctx
+		e.queueWait.End()
+		defer trace.SpanFromContext(ctx).End()
 
-		if !isReply && msg.Type == "message" {
-			if msg.SubType == "message_deleted" {
+		d := measure(func() {
+			s.processReaction(ctx, ws, bindContext(ctx, driver).(chatDriver), *e.reaction)
+		})
+
+		c := s.coreMetrics.msgsProcessed[reactionMsgType]
+		c.Add(context.Background(), 1)
+
+		m := s.coreMetrics.msgProcessingLatencyMillis[reactionMsgType]
+		m.Record(context.Background(), d.Milliseconds())
+
+		return
+	}
+
+	if e.interaction != nil {
+		// Calls made while processing the interaction nest under its span, ended once processing is done
+		ctx := e.ctx
+		e.queueWait.End()
+		defer trace.SpanFromContext(ctx).End()
+
+		d := measure(func() {
+			s.processInteraction(ctx, bindContext(ctx, driver).(chatDriver), *e.interaction)
+		})
+
+		c := s.coreMetrics.msgsProcessed[interactionMsgType]
+		c.Add(context.Background(), 1)
+
+		m := s.coreMetrics.msgProcessingLatencyMillis[interactionMsgType]
+		m.Record(context.Background(), d.Milliseconds())
+
+		return
+	}
+
+	if e.slashCommand != nil {
+		// Calls made while processing the slash command nest under its span, ended once processing is done
+		ctx := e.ctx
+		e.queueWait.End()
+		defer trace.SpanFromContext(ctx).End()
+
+		d := measure(func() {
+			s.processSlashCommand(ctx, bindContext(ctx, driver).(chatDriver), *e.slashCommand)
+		})
+
+		c := s.coreMetrics.msgsProcessed[slashCommandMsgType]
+		c.Add(context.Background(), 1)
+
+		m := s.coreMetrics.msgProcessingLatencyMillis[slashCommandMsgType]
+		m.Record(context.Background(), d.Milliseconds())
+
+		return
+	}
+
+	msg := *e.message
+
+	// Calls made while processing the message nest under its span, ended once processing is done
+	ctx := e.ctx
+	e.queueWait.End()
+	driver = bindContext(ctx, driver).(chatDriver)
+
+	// reply_to is an field set to 1 sent by slack when a sent message has been acknowledged and should be considered
+	// officially sent to others. Therefore, we ignore all of those since it's mostly for clients/UI to show status
+	isReply := msg.ReplyTo > 0
+
+	s.log.Debug("Processing message event", "channel", msg.Channel, "ts", msg.Timestamp, "subtype", msg.SubType)
+
+	if !isReply && msg.Type == "message" {
+		if msg.SubType == "message_deleted" {
+			d := measure(func() {
+				s.processDeletedMessage(ws, driver, msg)
+			})
+
+			c := s.coreMetrics.msgsProcessed[deleteMsgType]
+			c.Add(context.Background(), 1)
+
+			m := s.coreMetrics.msgProcessingLatencyMillis[deleteMsgType]
+			m.Record(context.Background(), d.Milliseconds())
+		} else {
+			if msg.SubType == "message_changed" {
 				d := measure(func() {
-					s.processDeletedMessage(driver, msg)
+					s.processUpdatedMessage(ctx, ws, driver, msg)
 				})
 
-				c := s.coreMetrics.msgsProcessed[deleteMsgType]
+				c := s.coreMetrics.msgsProcessed[updateMsgType]
 				c.Add(context.Background(), 1)
 
-				m := s.coreMetrics.msgProcessingLatencyMillis[deleteMsgType]
+				m := s.coreMetrics.msgProcessingLatencyMillis[updateMsgType]
+				m.Record(context.Background(), d.Milliseconds())
+			} else if msg.SubType != "message_replied" {
+				d := measure(func() {
+					s.processNewMessage(ctx, ws, driver, msg)
+				})
+
+				c := s.coreMetrics.msgsProcessed[newMsgType]
+				c.Add(context.Background(), 1)
+
+				m := s.coreMetrics.msgProcessingLatencyMillis[newMsgType]
 				m.Record(context.Background(), d.Milliseconds())
-			} else {
-				if msg.SubType == "message_changed" {
-					d := measure(func() {
-						s.processUpdatedMessage(driver, msg)
-					})
-
-					c := s.coreMetrics.msgsProcessed[updateMsgType]
-					c.Add(context.Background(), 1)
-
-					m := s.coreMetrics.msgProcessingLatencyMillis[updateMsgType]
-					m.Record(context.Background(), d.Milliseconds())
-				} else if msg.SubType != "message_replied" {
-					d := measure(func() {
-						s.processNewMessage(driver, msg)
-					})
-
-					c := s.coreMetrics.msgsProcessed[newMsgType]
-					c.Add(context.Background(), 1)
-
-					m := s.coreMetrics.msgProcessingLatencyMillis[newMsgType]
-					m.Record(context.Background(), d.Milliseconds())
-				}
 			}
 		}
 	}
 
-	terminationChan <- true
+	trace.SpanFromContext(ctx).End()
 }
 
 // getOriginalMessageID returns the message ID of the original message if it's linked
 // to a previous one or the self message id otherwise
-func getOriginalMessageID(m slack.MessageEvent) (originalID SlackMessageID) {
+func getOriginalMessageID(teamID string, m slack.MessageEvent) (originalID SlackMessageID) {
 	if m.SubMessage != nil {
-		return SlackMessageID{channelID: m.Channel, timestamp: m.SubMessage.Timestamp}
+		return SlackMessageID{teamID: teamID, channelID: m.Channel, timestamp: m.SubMessage.Timestamp}
 	}
 
-	return SlackMessageID{channelID: m.Channel, timestamp: m.Timestamp}
+	return SlackMessageID{teamID: teamID, channelID: m.Channel, timestamp: m.Timestamp}
 }
 
 // getAgeOriginalMsg returns the age of an updated message as defined by the time elapsed between the message
@@ -757,49 +1272,54 @@ func getAgeOriginalMsg(m slack.MessageEvent) (age time.Duration, err error) {
 // 3. If the message is present in cache, we had pre-existing responses so we handle this by updating responses on a plugin action basis. A plugin action that isn't triggering anymore gets its previous
 //    response deleted while a still triggering response will result in a message update. Newly triggered actions will be sent out as new messages.
 // 4. The new state of responses replaces the previous one for the triggering message in the cache
-func (s *Slackscot) processUpdatedMessage(driver chatDriver, m slack.MessageEvent) {
-	incomingMessageID := SlackMessageID{channelID: m.Channel, timestamp: m.Timestamp}
-	editedMsgID := getOriginalMessageID(m)
+func (s *Slackscot) processUpdatedMessage(ctx context.Context, ws *workspace, driver chatDriver, m slack.MessageEvent) {
+	incomingMessageID := SlackMessageID{teamID: ws.teamID, channelID: m.Channel, timestamp: m.Timestamp}
+	editedMsgID := getOriginalMessageID(ws.teamID, m)
 
 	maxAgeThreshold := s.config.GetDuration(config.MaxAgeHandledMessages)
 	msgAge, err := getAgeOriginalMsg(m)
 	if err != nil {
-		s.log.Printf("Unable to determine max age for message [%v]: %s", m, err.Error())
+		s.log.Warn("Unable to determine max age of updated message", "channel", m.Channel, "ts", m.Timestamp, "err", err)
 		return
 	}
 
 	if msgAge > maxAgeThreshold {
-		s.log.Debugf("Updated message: [%s] has an age of [%s] but the max age for handled messages is [%s]. Skipping...", editedMsgID, msgAge, maxAgeThreshold)
+		s.log.Debug("Skipping updated message older than the max age for handled messages", "channel", editedMsgID.channelID, "ts", editedMsgID.timestamp, "age", msgAge, "maxAge", maxAgeThreshold)
 		return
 	}
 
-	s.log.Debugf("Updated message: [%s], does cache contain it => [%t]", editedMsgID, s.triggeringMsgToResponse.Contains(editedMsgID))
+	cachedResponses, exists, err := ws.responseCache.Get(editedMsgID)
+	if err != nil {
+		s.log.Error("Error getting cached responses to updated message", "channel", editedMsgID.channelID, "ts", editedMsgID.timestamp, "err", err)
+	}
+
+	s.log.Debug("Looked up cached responses to updated message", "channel", editedMsgID.channelID, "ts", editedMsgID.timestamp, "cached", exists)
 
-	if cachedResponses, exists := s.triggeringMsgToResponse.Get(editedMsgID); exists {
-		s.processUpdatedMessageWithCachedResponses(driver, m, editedMsgID, cachedResponses.(map[string]SlackMessageID))
+	if exists {
+		s.processUpdatedMessageWithCachedResponses(ctx, ws, driver, m, editedMsgID, cachedResponses)
 	} else {
-		outMsgs := s.routeMessage(m)
+		outMsgs := s.routeMessage(ctx, ws, m)
 
-		s.sendOutgoingMessages(driver, incomingMessageID, outMsgs)
+		s.sendOutgoingMessages(ws, driver, incomingMessageID, outMsgs)
 	}
 }
 
 // processUpdatedMessageWithCachedResponses handles a message update for which we still have cached responses in cache. This is where we take care of deleting responses that are no longer
 // triggering the action they're coming from, updating the reactions for still triggering plugin actions as well as sending new reactions for plugin actions that are now triggering
-func (s *Slackscot) processUpdatedMessageWithCachedResponses(driver chatDriver, m slack.MessageEvent, editedMsgID SlackMessageID, cachedResponses map[string]SlackMessageID) {
+func (s *Slackscot) processUpdatedMessageWithCachedResponses(ctx context.Context, ws *workspace, driver chatDriver, m slack.MessageEvent, editedMsgID SlackMessageID, cachedResponses map[string]SlackMessageID) {
 	newResponseByActionID := make(map[string]SlackMessageID)
 
-	outMsgs := s.routeMessage(m)
-	s.log.Debugf("Detected %d existing responses to message [%s]\n", len(cachedResponses), editedMsgID)
+	outMsgs := s.routeMessage(ctx, ws, m)
+	s.log.Debug("Detected existing responses to updated message", "channel", editedMsgID.channelID, "ts", editedMsgID.timestamp, "responses", len(cachedResponses))
 
 	for _, o := range outMsgs {
 		// We had a previous response for that same plugin action so edit it instead of posting a new message
 		if r, ok := cachedResponses[o.pluginActionID]; ok {
-			s.log.Debugf("Trying to update response at [%s] with message [%s]\n", r, o.OutgoingMessage.Text)
+			s.log.Debug("Updating response to updated message", "channel", r.channelID, "ts", r.timestamp, "actionID", o.pluginActionID, "text", o.OutgoingMessage.Text)
 
 			rID, err := s.updateExistingMessage(driver, r, o)
 			if err != nil {
-				s.log.Printf("Unable to update message [%s] to triggering message [%s]: %v\n", r, editedMsgID, err)
+				s.log.Error("Unable to update response to updated message", "channel", r.channelID, "ts", r.timestamp, "triggerTs", editedMsgID.timestamp, "actionID", o.pluginActionID, "err", err)
 			} else {
 				// Add the new updated message to the new responses
 				newResponseByActionID[o.pluginActionID] = rID
@@ -809,12 +1329,12 @@ func (s *Slackscot) processUpdatedMessageWithCachedResponses(driver chatDriver,
 				delete(cachedResponses, o.pluginActionID)
 			}
 		} else {
-			s.log.Debugf("New response triggered to updated message [%s] [%s]: [%s]\n", o.OutgoingMessage.Text, r, o.OutgoingMessage.Text)
+			s.log.Debug("New response triggered by updated message", "channel", editedMsgID.channelID, "ts", editedMsgID.timestamp, "actionID", o.pluginActionID, "text", o.OutgoingMessage.Text)
 
 			// It's a new message for that action so post it as a new message
 			rID, err := s.sendNewMessage(driver, o, editedMsgID.timestamp)
 			if err != nil {
-				s.log.Printf("Unable to send new message to updated message [%s]: %v\n", r, err)
+				s.log.Error("Unable to send new response to updated message", "channel", editedMsgID.channelID, "ts", editedMsgID.timestamp, "actionID", o.pluginActionID, "err", err)
 			} else if rID.IsMsgModifiable() {
 				// Add the new updated message to the new responses if it can be modified later
 				newResponseByActionID[o.pluginActionID] = rID
@@ -824,59 +1344,68 @@ func (s *Slackscot) processUpdatedMessageWithCachedResponses(driver chatDriver,
 
 	// Delete any previous triggered responses that aren't triggering anymore
 	for pa, r := range cachedResponses {
-		s.log.Debugf("Deleting previous response [%s] on a now non-triggered plugin action [%s]\n", r, pa)
+		s.log.Debug("Deleting response of action no longer triggered by updated message", "channel", r.channelID, "ts", r.timestamp, "actionID", pa)
 		driver.DeleteMessage(r.channelID, r.timestamp)
 	}
 
 	// Since the updated message now has new responses, update the entry with those or remove if no actions are triggered
 	if len(newResponseByActionID) > 0 {
-		s.log.Debugf("Updating responses to edited message [%s]\n", editedMsgID)
-		s.triggeringMsgToResponse.Add(editedMsgID, newResponseByActionID)
+		s.log.Debug("Caching responses to updated message", "channel", editedMsgID.channelID, "ts", editedMsgID.timestamp, "responses", len(newResponseByActionID))
+		if err := ws.responseCache.Add(editedMsgID, newResponseByActionID); err != nil {
+			s.log.Error("Error caching responses to updated message", "channel", editedMsgID.channelID, "ts", editedMsgID.timestamp, "err", err)
+		}
 	} else {
-		s.log.Debugf("Deleting entry for edited message [%s] since no more triggered response\n", editedMsgID)
-		s.triggeringMsgToResponse.Remove(editedMsgID)
+		s.log.Debug("Removing cached responses to updated message no longer triggering actions", "channel", editedMsgID.channelID, "ts", editedMsgID.timestamp)
+		if err := ws.responseCache.Remove(editedMsgID); err != nil {
+			s.log.Error("Error removing cached responses to updated message", "channel", editedMsgID.channelID, "ts", editedMsgID.timestamp, "err", err)
+		}
 	}
 }
 
 // processDeletedMessage handles a deleted message. Slackscot cares about those in order to
 // delete any previous responses triggered by that now inexistant message
-func (s *Slackscot) processDeletedMessage(deleter messageDeleter, msgEvent slack.MessageEvent) {
-	deletedMessageID := SlackMessageID{channelID: msgEvent.Channel, timestamp: msgEvent.DeletedTimestamp}
+func (s *Slackscot) processDeletedMessage(ws *workspace, deleter messageDeleter, msgEvent slack.MessageEvent) {
+	deletedMessageID := SlackMessageID{teamID: ws.teamID, channelID: msgEvent.Channel, timestamp: msgEvent.DeletedTimestamp}
 
-	s.log.Debugf("Message deleted: [%s] and cache contains: [%s]", deletedMessageID, s.triggeringMsgToResponse.Keys())
+	existingResponses, exists, err := ws.responseCache.Get(deletedMessageID)
+	if err != nil {
+		s.log.Error("Error getting cached responses to deleted message", "channel", deletedMessageID.channelID, "ts", deletedMessageID.timestamp, "err", err)
+	}
 
-	if existingResponses, exists := s.triggeringMsgToResponse.Get(deletedMessageID); exists {
-		byAction := existingResponses.(map[string]SlackMessageID)
+	s.log.Debug("Looked up cached responses to deleted message", "channel", deletedMessageID.channelID, "ts", deletedMessageID.timestamp, "cached", exists)
 
-		for _, v := range byAction {
+	if exists {
+		for _, v := range existingResponses {
 			// Delete existing response since the triggering message was deleted
 			_, _, err := deleter.DeleteMessage(v.channelID, v.timestamp)
 			if err != nil {
-				s.log.Printf("Error deleting existing response to triggering message [%s]: %s: %v", deletedMessageID, v, err)
+				s.log.Error("Error deleting response to deleted message", "channel", v.channelID, "ts", v.timestamp, "triggerTs", deletedMessageID.timestamp, "err", err)
 			}
 		}
 
-		s.triggeringMsgToResponse.Remove(deletedMessageID)
+		if err := ws.responseCache.Remove(deletedMessageID); err != nil {
+			s.log.Error("Error removing cached responses to deleted message", "channel", deletedMessageID.channelID, "ts", deletedMessageID.timestamp, "err", err)
+		}
 	}
 }
 
 // processNewMessage handles a regular new message and sends any triggered response
-func (s *Slackscot) processNewMessage(msgSender messageSender, m slack.MessageEvent) {
-	incomingMessageID := SlackMessageID{channelID: m.Channel, timestamp: m.Timestamp}
-	outMsgs := s.routeMessage(m)
+func (s *Slackscot) processNewMessage(ctx context.Context, ws *workspace, msgSender messageSender, m slack.MessageEvent) {
+	incomingMessageID := SlackMessageID{teamID: ws.teamID, channelID: m.Channel, timestamp: m.Timestamp}
+	outMsgs := s.routeMessage(ctx, ws, m)
 
-	s.sendOutgoingMessages(msgSender, incomingMessageID, outMsgs)
+	s.sendOutgoingMessages(ws, msgSender, incomingMessageID, outMsgs)
 }
 
-// sendOutgoingMessages sends out any triggered plugin responses and keeps track of those in the internal cache
-func (s *Slackscot) sendOutgoingMessages(sender messageSender, incomingMessageID SlackMessageID, outMsgs []OutgoingMessage) {
+// sendOutgoingMessages sends out any triggered plugin responses and keeps track of those in the workspace's response cache
+func (s *Slackscot) sendOutgoingMessages(ws *workspace, sender messageSender, incomingMessageID SlackMessageID, outMsgs []OutgoingMessage) {
 	newResponseByActionID := make(map[string]SlackMessageID)
 
 	for _, o := range outMsgs {
 		// Send the message and keep track of our response in cache to be able to update it as needed later
 		rID, err := s.sendNewMessage(sender, o, incomingMessageID.timestamp)
 		if err != nil {
-			s.log.Printf("Unable to send new message triggered by [%s]: %v\n", incomingMessageID, err)
+			s.log.Error("Unable to send response", "channel", incomingMessageID.channelID, "ts", incomingMessageID.timestamp, "actionID", o.pluginActionID, "err", err)
 		} else if rID.IsMsgModifiable() {
 			// Add the new updated message to the new responses if it's one that can be modified later
 			newResponseByActionID[o.pluginActionID] = rID
@@ -884,24 +1413,22 @@ func (s *Slackscot) sendOutgoingMessages(sender messageSender, incomingMessageID
 	}
 
 	if len(newResponseByActionID) > 0 {
-		s.log.Debugf("Adding responses to triggering message [%s]: %s", incomingMessageID, newResponseByActionID)
+		s.log.Debug("Caching responses to message", "channel", incomingMessageID.channelID, "ts", incomingMessageID.timestamp, "responses", len(newResponseByActionID))
 
 		// Add current responses for that triggering message
-		s.triggeringMsgToResponse.Add(incomingMessageID, newResponseByActionID)
+		if err := ws.responseCache.Add(incomingMessageID, newResponseByActionID); err != nil {
+			s.log.Error("Error caching responses to message", "channel", incomingMessageID.channelID, "ts", incomingMessageID.timestamp, "err", err)
+		}
 	}
 }
 
 // sendNewMessage sends a new outgoingMsg and waits for the response to return that message's identifier
 func (s *Slackscot) sendNewMessage(sender messageSender, o OutgoingMessage, defaultThreadTS string) (rID SlackMessageID, err error) {
-	s.log.Printf("Sending new message: %s", o.OutgoingMessage.Text)
+	s.log.Info("Sending new message", "channel", o.OutgoingMessage.Channel, "actionID", o.pluginActionID, "text", o.OutgoingMessage.Text)
 	sendOpts := ApplyAnswerOpts(o.Options...)
 	options := []slack.MsgOption{slack.MsgOptionText(o.OutgoingMessage.Text, false), slack.MsgOptionAsUser(true)}
-	if s.config.GetBool(config.ThreadedRepliesKey) || cast.ToBool(sendOpts[ThreadedReplyOpt]) {
-		if threadTS := cast.ToString(sendOpts[ThreadTimestamp]); threadTS != "" {
-			options = append(options, slack.MsgOptionTS(threadTS))
-		} else {
-			options = append(options, slack.MsgOptionTS(defaultThreadTS))
-		}
+	if threadTS, threaded := s.answerThreadTimestamp(sendOpts, defaultThreadTS); threaded {
+		options = append(options, slack.MsgOptionTS(threadTS))
 
 		if s.config.GetBool(config.BroadcastThreadedRepliesKey) || cast.ToBool(sendOpts[BroadcastOpt]) {
 			options = append(options, slack.MsgOptionBroadcast())
@@ -924,6 +1451,21 @@ func (s *Slackscot) sendNewMessage(sender messageSender, o OutgoingMessage, defa
 	return rID, err
 }
 
+// answerThreadTimestamp returns the timestamp of the thread an answer with the given send options is sent in. Unless the
+// options specify an existing thread, threaded answers start a thread on the message answered to (defaultThreadTS). threaded is
+// false if the answer is sent on the channel
+func (s *Slackscot) answerThreadTimestamp(sendOpts map[string]string, defaultThreadTS string) (threadTS string, threaded bool) {
+	if !s.config.GetBool(config.ThreadedRepliesKey) && !cast.ToBool(sendOpts[ThreadedReplyOpt]) {
+		return "", false
+	}
+
+	if threadTS = cast.ToString(sendOpts[ThreadTimestamp]); threadTS != "" {
+		return threadTS, true
+	}
+
+	return defaultThreadTS, true
+}
+
 // updateExistingMessage updates an existing message with the content of a newly triggered OutgoingMessage
 func (s *Slackscot) updateExistingMessage(updater messageUpdater, r SlackMessageID, o OutgoingMessage) (rID SlackMessageID, err error) {
 	options := []slack.MsgOption{slack.MsgOptionText(o.OutgoingMessage.Text, false), slack.MsgOptionAsUser(true)}
@@ -976,45 +1518,44 @@ func resolveThreadTimestamp(m slack.Msg) (threadTs string, isThreadedMessage boo
 // 	1. If the message is on a channel with a direct mention to us (@name), we route to commands
 // 	2. If the message is a direct message to us, we route to commands
 // 	3. If the message is on a channel without mention (regular conversation), we route to hear actions
-func (s *Slackscot) routeMessage(me slack.MessageEvent) (responses []OutgoingMessage) {
+func (s *Slackscot) routeMessage(ctx context.Context, ws *workspace, me slack.MessageEvent) (responses []OutgoingMessage) {
+	ctx, span := s.tracer.Start(ctx, routeMessageSpanName)
+	defer span.End()
+
 	m := normalizeIncomingMessage(me)
 
 	responses = make([]OutgoingMessage, 0)
 
 	// Ignore messages_replied and messages send by "us"
-	if s.botMatcher.IsBot(m) {
-		s.log.Debugf("Ignoring message from user [%s] / bot ID [%s] because that's \"us\" [%s]", m.User, m.BotID, s.botMatcher)
+	if ws.selfIdentity.IsBot(m) {
+		s.log.Debug("Ignoring message from ourselves", "channel", m.Channel, "ts", m.Timestamp, "user", m.User, "botID", m.BotID)
 
 		return responses
 	}
 
+	// Replies of a user in a dialog go to the dialog before any command or hear action. Only new messages
+	// continue a dialog (message updates don't)
+	if me.SubType != "message_changed" {
+		if dialogResponses, continued := s.continueDialog(ctx, ws, m); continued {
+			return dialogResponses
+		}
+	}
+
 	// Try commands or hear actions depending on the format of the message
-	if s.isCommand(m) {
+	if s.isCommand(ws, m) {
 		replyStrategy := reply
 		if isDirectMessage(m) {
 			replyStrategy = directReply
 		}
 
-		for _, p := range s.plugins {
-			matchedNamespace, inMsg := s.newCmdInMsgWithNormalizedText(p, m)
-
-			if matchedNamespace {
-				outMsgs := s.tryPluginActions(p.Name, commandType, p.Commands, inMsg, replyStrategy)
-				responses = append(responses, outMsgs...)
-			}
-		}
+		responses = append(responses, s.tryActions(ctx, s.commandCandidates(ws, m), replyStrategy)...)
 
 		// Use default answer if this was a message formatted as a command for which we didn't have any answer to
 		if len(responses) == 0 {
-			responses = append(responses, defaultAnswer(s.defaultAction, s.newIncomingMsgWithNormalizedText(m), replyStrategy))
+			responses = append(responses, defaultAnswer(s.defaultAction, s.newIncomingMsgWithNormalizedText(ws, m), replyStrategy))
 		}
 	} else {
-		for _, p := range s.plugins {
-			inMsg := s.newIncomingMsgWithNormalizedText(m)
-
-			outMsgs := s.tryPluginActions(p.Name, hearActionType, p.HearActions, inMsg, send)
-			responses = append(responses, outMsgs...)
-		}
+		responses = append(responses, s.tryActions(ctx, s.hearActionCandidates(ws, m), send)...)
 	}
 
 	return responses
@@ -1030,32 +1571,34 @@ func defaultAnswer(answerDefault Answerer, inMsg IncomingMessage, rs responseStr
 	return newOutMessageForAnswer(slackOutMsg, "default", *answer)
 }
 
-// newCmdInMsgWithNormalizedText creates a new IncomingMessage for a command and generates the normalized text for plugins
-// to have a normalized view of the message regardless of context. For commands part of a Plugin with NamespaceCommands,
-// the normalized text removes the namespace if the proper namespace is found. If not, matchedNamespace is false
-// and the normalized text is the same as what newIncomingMsgWithNormalizedText would return
-func (s *Slackscot) newCmdInMsgWithNormalizedText(p *Plugin, m slack.Msg) (matchedNamespace bool, inMsg IncomingMessage) {
-	inMsg = s.newIncomingMsgWithNormalizedText(m)
+// stripNamespace removes the namespace from the normalized text of a command for a Plugin with NamespaceCommands.
+// If the normalized text doesn't start with the plugin's namespace, matchedNamespace is false and the IncomingMessage
+// is returned unchanged
+func (s *Slackscot) stripNamespace(p *Plugin, inMsg IncomingMessage) (matchedNamespace bool, stripped IncomingMessage) {
+	stripped = inMsg
 	matchedNamespace = true
 
 	if p != nil && s.namespaceCommands && p.NamespaceCommands {
 		namespacePrefix := fmt.Sprintf("%s ", p.Name)
-		if matchedNamespace = strings.HasPrefix(inMsg.NormalizedText, namespacePrefix); matchedNamespace {
-			inMsg.NormalizedText = strings.TrimPrefix(inMsg.NormalizedText, namespacePrefix)
+		if matchedNamespace = strings.HasPrefix(stripped.NormalizedText, namespacePrefix); matchedNamespace {
+			stripped.NormalizedText = strings.TrimPrefix(stripped.NormalizedText, namespacePrefix)
 		}
 	}
 
-	return matchedNamespace, inMsg
+	return matchedNamespace, stripped
 }
 
 // newIncomingMsgWithNormalizedText creates a new IncomingMessage and generates the normalized text for plugins
 // to have a normalized view of the message regardless of context. This includes having the text stripped of the "<@user>"
 // for commands sent via a directed message on a channel
-func (s *Slackscot) newIncomingMsgWithNormalizedText(m slack.Msg) (inMsg IncomingMessage) {
+func (s *Slackscot) newIncomingMsgWithNormalizedText(ws *workspace, m slack.Msg) (inMsg IncomingMessage) {
+	cmdMatcher := s.cmdMatcherFor(ws)
+
 	inMsg.NormalizedText = m.Text
+	inMsg.TeamID = ws.teamID
 	inMsg.Msg = m
-	if isCmd, isDirectMsg := s.cmdMatcher.IsCmd(m), isDirectMessage(m); isCmd && !isDirectMsg {
-		inMsg.NormalizedText = s.cmdMatcher.TrimPrefix(m.Text)
+	if isCmd, isDirectMsg := cmdMatcher.IsCmd(m), isDirectMessage(m); isCmd && !isDirectMsg {
+		inMsg.NormalizedText = cmdMatcher.TrimPrefix(m.Text)
 	}
 
 	return inMsg
@@ -1063,8 +1606,8 @@ func (s *Slackscot) newIncomingMsgWithNormalizedText(m slack.Msg) (inMsg Incomin
 
 // isCommand returns true if the slack message is to be interpreted as a command rather than a normal message
 // subject to be handled by hear actions
-func (s *Slackscot) isCommand(m slack.Msg) (isCommand bool) {
-	return s.cmdMatcher.IsCmd(m) || isDirectMessage(m)
+func (s *Slackscot) isCommand(ws *workspace, m slack.Msg) (isCommand bool) {
+	return s.cmdMatcherFor(ws).IsCmd(m) || isDirectMessage(m)
 }
 
 // isDirectMessage returns true if the slack message is to be interpreted as a command rather than a normal message
@@ -1083,40 +1626,179 @@ func (a *Answer) useExistingThreadIfAny(m *IncomingMessage) {
 	}
 }
 
-// tryPluginActions loops over all action definitions and invokes its action if the incoming message matches it's regular expression
-// Note that more than one action can be triggered during the processing of a single message
-func (s *Slackscot) tryPluginActions(pluginName string, actionType string, actions []ActionDefinition, m IncomingMessage, rs responseStrategy) (outMsgs []OutgoingMessage) {
-	before := time.Now()
+// tryPluginActions tries all actions of a plugin of the given type (see tryActions)
+func (s *Slackscot) tryPluginActions(ctx context.Context, p *Plugin, actionType string, actions []ActionDefinition, m IncomingMessage, rs responseStrategy) (outMsgs []OutgoingMessage) {
+	return s.tryActions(ctx, newActionCandidates(p, actionType, actions, m), rs)
+}
 
+// tryActions loops over the candidate actions in order of priority (see sortByPriority) and invokes them if the incoming message
+// matches. Note that more than one action can be triggered during the processing of a single message unless exclusive routing
+// is enabled (see config.ExclusiveRoutingKey) or an exclusive action matches, in which case the actions after the first exclusive
+// match are skipped
+func (s *Slackscot) tryActions(ctx context.Context, candidates []actionCandidate, rs responseStrategy) (outMsgs []OutgoingMessage) {
 	outMsgs = make([]OutgoingMessage, 0)
+	usages := newPluginActionUsages()
+	exclusiveRouting := s.isExclusiveRouting()
 
-	for i, action := range actions {
-		matches := action.Match(&m)
-
-		if matches {
-			answer := action.Answer(&m)
+	for i, c := range sortByPriority(candidates) {
+		usage := usages.of(c.p)
 
-			if answer != nil {
-				answer.useExistingThreadIfAny(&m)
-				slackOutMsg := rs(m, answer)
+		answer, matched, _ := s.tryAction(ctx, c, usage)
+		if answer != nil {
+			outMsgs = append(outMsgs, s.processAnswer(c, answer, rs, usage))
+		}
 
-				outMsg := newOutMessageForAnswer(slackOutMsg, getActionID(pluginName, actionType, i), *answer)
-				outMsgs = append(outMsgs, outMsg)
+		if stopsRouting(c.action, matched, exclusiveRouting) {
+			if skipped := len(candidates) - i - 1; skipped > 0 {
+				s.log.Debug("Exclusive action won, skipping the other actions", "actionID", c.actionID, "skipped", skipped, "channel", c.msg.Channel, "ts", c.msg.Timestamp)
 			}
+
+			break
 		}
 	}
 
-	pm, err := s.getOrCreatePluginMetrics(pluginName)
+	usages.record(s)
+
+	return outMsgs
+}
+
+// tryAction invokes a candidate action (its matcher and, if it matches, its answerer) wrapped by tracing, rate limiting, authorization
+// and the middlewares, unless the action is disabled after too many consecutive panics. Panics and timeouts are isolated and recorded
+// in the usage of the action's plugin. completed is true only if the action ran to completion, in which case the answer is the one
+// it returned. Otherwise, the answer is the panic answer (if the action panicked) or nil
+func (s *Slackscot) tryAction(ctx context.Context, c actionCandidate, usage *pluginActionUsage) (answer *Answer, matched bool, completed bool) {
+	if s.actionBreaker.isOpen(c.actionID) {
+		s.log.Debug("Skipping action disabled after too many consecutive panics", "actionID", c.actionID, "channel", c.msg.Channel, "ts", c.msg.Timestamp)
+		return nil, false, false
+	}
+
+	before := time.Now()
+
+	// Each action gets its own copy of the message
+	msg := c.msg
+	invoker := newActionInvoker(s.tracedAction(c.p.Name, c.actionType, c.actionID, s.rateLimitedAction(c.p.Name, c.actionID, s.authorizedAction(c.action))), s.middlewares, c.p.Middlewares)
+	inv := &ActionInvocation{PluginName: c.p.Name, ActionType: c.actionType, ActionID: c.actionID, Message: &msg}
+	answer, err := s.invokeAction(ctx, invoker, inv, s.actionTimeout(c.action))
+	usage.elapsed += time.Since(before)
+
+	if ap, ok := err.(*actionPanic); ok {
+		s.handleActionPanic(c.actionID, ap)
+		usage.panics++
+
+		return s.newPanicAnswer(), inv.Matched, false
+	}
+
 	if err != nil {
-		s.log.Printf("Error creating plugin metrics for plugin [%s], skipping instrumentation measurements: %s", pluginName, err.Error())
-	} else {
-		ctx := context.Background()
+		s.log.Printf("Action [%s] didn't complete within [%s], abandoning its answer: %v", c.actionID, s.actionTimeout(c.action), err)
+		usage.timeouts++
 
-		pm.processingTimeMillis.Record(ctx, time.Since(before).Milliseconds())
-		pm.reactionCount.Add(ctx, int64(len(outMsgs)))
+		return nil, inv.Matched, false
 	}
 
-	return outMsgs
+	s.actionBreaker.recordSuccess(c.actionID)
+
+	return answer, inv.Matched, true
+}
+
+// processAnswer returns the outgoing message of the answer of a candidate action, sent with the response strategy, and starts
+// the dialog the answer of a command or hear action starts, if any
+func (s *Slackscot) processAnswer(c actionCandidate, answer *Answer, rs responseStrategy, usage *pluginActionUsage) (outMsg OutgoingMessage) {
+	m := c.msg
+	s.log.Debug("Action answered", "actionID", c.actionID, "channel", m.Channel, "ts", m.Timestamp)
+
+	answer.useExistingThreadIfAny(&m)
+	slackOutMsg := rs(m, answer)
+
+	if c.actionType == CommandActionType || c.actionType == HearActionType {
+		s.startDialogIfAny(c.p, m, answer)
+	}
+
+	usage.answers++
+
+	return newOutMessageForAnswer(slackOutMsg, c.actionID, *answer)
+}
+
+// handleActionPanic logs a recovered action panic and records it with the circuit breaker
+func (s *Slackscot) handleActionPanic(actionID string, p *actionPanic) {
+	s.log.Printf("Recovered from panic in action [%s]: %v\n%s", actionID, p.value, p.stack)
+
+	if s.actionBreaker.recordPanic(actionID) {
+		s.log.Printf("Disabling action [%s] after [%d] consecutive panics", actionID, s.actionBreaker.threshold)
+	}
+}
+
+// newPanicAnswer returns the configured answer to a message that made an action panic or nil if
+// none is configured
+func (s *Slackscot) newPanicAnswer() (answer *Answer) {
+	text := s.config.GetString(config.ActionPanicAnswerKey)
+	if text == "" {
+		return nil
+	}
+
+	return &Answer{Text: text}
+}
+
+// actionTimeout returns the maximum execution time of an action. A value of zero means that the action
+// doesn't time out
+func (s *Slackscot) actionTimeout(action ActionDefinition) (timeout time.Duration) {
+	if action.Timeout > 0 {
+		return action.Timeout
+	}
+
+	return s.config.GetDuration(config.ActionTimeoutKey)
+}
+
+// invokeAction invokes an action (its matcher and, if it matches, its answerer) via its invoker. If the action panics, the panic is
+// recovered and returned as an *actionPanic error. If the action has a timeout, it runs in its own goroutine with a context that is
+// done on timeout in which case the context's error is returned and the eventual answer is abandoned. The invocation is still
+// marked as matched if the action timed out after its matcher matched
+func (s *Slackscot) invokeAction(ctx context.Context, invoker ActionInvoker, inv *ActionInvocation, timeout time.Duration) (answer *Answer, err error) {
+	if timeout <= 0 {
+		return safeInvoke(ctx, invoker, inv)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		answer  *Answer
+		matched bool
+		err     error
+	}
+
+	// Give the invocation its own copy of the message since we might abandon it
+	m := *inv.Message
+	invCopy := *inv
+	invCopy.Message = &m
+
+	var matched int32
+	invCopy.onMatch = func() {
+		atomic.StoreInt32(&matched, 1)
+	}
+
+	results := make(chan result, 1)
+	go func() {
+		answer, err := safeInvoke(ctx, invoker, &invCopy)
+		results <- result{answer: answer, matched: invCopy.Matched, err: err}
+	}()
+
+	select {
+	case r := <-results:
+		inv.Matched = r.matched
+		return r.answer, r.err
+	case <-ctx.Done():
+		inv.Matched = atomic.LoadInt32(&matched) == 1
+		return nil, ctx.Err()
+	}
+}
+
+// safeInvoke calls the invoker, recovering from any panic
+func safeInvoke(ctx context.Context, invoker ActionInvoker, inv *ActionInvocation) (answer *Answer, err error) {
+	err = callSafely(func() {
+		answer = invoker(ctx, inv)
+	})
+
+	return answer, err
 }
 
 // newOutMessageForAnswer creates a new internal OutgoingMessage for the given Answer
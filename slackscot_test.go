@@ -1,6 +1,7 @@
 package slackscot
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/alexandre-normand/slackscot/config"
@@ -18,6 +19,8 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -61,6 +64,8 @@ func (nw *nullWriter) Write(p []byte) (n int, err error) {
 }
 
 type inMemoryChatDriver struct {
+	// lock serializes calls from concurrent partition workers
+	lock        sync.Mutex
 	timeCursor  uint64
 	sentMsgs    []sentMessage
 	updatedMsgs []updatedMessage
@@ -68,6 +73,9 @@ type inMemoryChatDriver struct {
 }
 
 func (c *inMemoryChatDriver) SendMessage(channelID string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	c.sentMsgs = append(c.sentMsgs, sentMessage{channelID: channelID, msgOptions: options})
 
 	endpoint, _, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
@@ -84,11 +92,17 @@ func (c *inMemoryChatDriver) SendMessage(channelID string, options ...slack.MsgO
 }
 
 func (c *inMemoryChatDriver) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) (rChannelID string, rTimestamp string, rText string, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	c.updatedMsgs = append(c.updatedMsgs, updatedMessage{channelID: channelID, timestamp: timestamp, msgOptions: options})
 	return channelID, c.nextTimestamp(), fmt.Sprintf("Message updated on %s", channelID), nil
 }
 
 func (c *inMemoryChatDriver) DeleteMessage(channelID string, timestamp string) (rChannelID string, rTimestamp string, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	c.deletedMsgs = append(c.deletedMsgs, deletedMessage{channelID: channelID, timestamp: timestamp})
 	return channelID, c.nextTimestamp(), nil
 }
@@ -966,7 +980,7 @@ func TestOptionWithSlackOptionApplied(t *testing.T) {
 	require.NotNil(t, tp.SlackClient)
 
 	// Should be auto set
-	assert.NotNil(t, s.botMatcher)
+	assert.NotNil(t, tp.Workspaces)
 	// Should be around before run - although it's set above
 	assert.NotNil(t, s.cmdMatcher)
 
@@ -1189,8 +1203,241 @@ func TestConcurrentProcessingOfNonRelatedMessages(t *testing.T) {
 	}
 }
 
+func newSlowAndFastPlugin(slowActionTimeout time.Duration) (tp *Plugin) {
+	tp = new(Plugin)
+	tp.Name = "maker"
+	tp.NamespaceCommands = false
+	tp.Commands = []ActionDefinition{
+		{
+			Match: func(m *IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "make")
+			},
+			Usage:       "make <something>",
+			Description: "Simulation of a command waiting on external IO that takes too long",
+			Timeout:     slowActionTimeout,
+			ContextAnswer: func(ctx context.Context, m *IncomingMessage) *Answer {
+				<-ctx.Done()
+				return &Answer{Text: "Made it too late"}
+			},
+		},
+		{
+			Match: func(m *IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "make")
+			},
+			Usage:       "make <something>",
+			Description: "Make something quickly",
+			ContextAnswer: func(ctx context.Context, m *IncomingMessage) *Answer {
+				return &Answer{Text: fmt.Sprintf("Made %s", strings.TrimPrefix(m.NormalizedText, "make "))}
+			},
+		},
+	}
+
+	return tp
+}
+
+func TestActionTimeoutFromConfig(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ActionTimeoutKey, time.Duration(50)*time.Millisecond)
+
+	sentMsgs, updatedMsgs, deletedMsgs, rtmSender, logs := runSlackscotWithIncomingEventsWithLogs(t, v, newSlowAndFastPlugin(0), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "make juice", "Alphonse", timestamp1)),
+	})
+
+	assert.Contains(t, logs, "Action [maker.command[0]] didn't complete within [50ms], abandoning its answer: context deadline exceeded")
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "Made juice", vals.Get("text"))
+	}
+
+	assert.Equal(t, 0, len(updatedMsgs))
+	assert.Equal(t, 0, len(deletedMsgs))
+	assert.Equal(t, 0, len(rtmSender.SentMessages))
+}
+
+func TestActionTimeoutOverridesConfig(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ActionTimeoutKey, time.Duration(1)*time.Hour)
+
+	sentMsgs, _, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, v, newSlowAndFastPlugin(time.Duration(10)*time.Millisecond), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "make juice", "Alphonse", timestamp1)),
+	})
+
+	assert.Contains(t, logs, "Action [maker.command[0]] didn't complete within [10ms], abandoning its answer: context deadline exceeded")
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "Made juice", vals.Get("text"))
+	}
+}
+
+func newLoggingPlugin() (tp *Plugin) {
+	tp = new(Plugin)
+	tp.Name = "chatty"
+	tp.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "log")
+		},
+		Usage:       "log",
+		Description: "Log something",
+		Answer: func(m *IncomingMessage) *Answer {
+			tp.Log().Info("Heard log request", "channel", m.Channel)
+			return nil
+		},
+	}}
+
+	return tp
+}
+
+func TestPluginLoggerTaggedWithPluginName(t *testing.T) {
+	_, _, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, nil, newLoggingPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "log this", "Alphonse", timestamp1)),
+	})
+
+	assert.Contains(t, logs, "level=INFO Heard log request plugin=chatty channel=Cgeneral")
+}
+
+func TestStructuredJSONLogs(t *testing.T) {
+	var b strings.Builder
+	runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, OptionLogHandler(NewJSONLogHandler(&b)))
+
+	assert.Contains(t, b.String(), "\"level\":\"INFO\",\"msg\":\"Sending new message\",\"channel\":\"Cgeneral\",\"actionID\":\"noRules.hearAction[0]\",\"text\":\"I heard you say something about blue jays?\"}\n")
+}
+
+func TestLogHandlerTakesPrecedenceOverLoggerRegardlessOfOrder(t *testing.T) {
+	var handlerFirst, loggerAfterHandler strings.Builder
+	runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, OptionLogHandler(NewJSONLogHandler(&handlerFirst)), OptionLog(log.New(&loggerAfterHandler, "", 0)))
+
+	assert.Contains(t, handlerFirst.String(), "\"msg\":\"Sending new message\"")
+	assert.NotContains(t, loggerAfterHandler.String(), "Sending new message")
+}
+
+func newPanickingPlugin(answerCount *int32) (tp *Plugin) {
+	tp = new(Plugin)
+	tp.Name = "maker"
+	tp.NamespaceCommands = false
+	tp.Commands = []ActionDefinition{
+		{
+			Match: func(m *IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "make")
+			},
+			Usage:       "make <something>",
+			Description: "Make something but fail miserably",
+			Answer: func(m *IncomingMessage) *Answer {
+				atomic.AddInt32(answerCount, 1)
+				panic("out of ingredients")
+			},
+		},
+		{
+			Match: func(m *IncomingMessage) bool {
+				return strings.HasPrefix(m.NormalizedText, "make")
+			},
+			Usage:       "make <something>",
+			Description: "Make something",
+			Answer: func(m *IncomingMessage) *Answer {
+				return &Answer{Text: fmt.Sprintf("Made %s", strings.TrimPrefix(m.NormalizedText, "make "))}
+			},
+		},
+	}
+
+	return tp
+}
+
+func TestActionPanicRecovered(t *testing.T) {
+	var answerCount int32
+	sentMsgs, updatedMsgs, deletedMsgs, rtmSender, logs := runSlackscotWithIncomingEventsWithLogs(t, nil, newPanickingPlugin(&answerCount), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "make juice", "Alphonse", timestamp1)),
+	})
+
+	assert.Contains(t, logs, "Recovered from panic in action [maker.command[0]]: out of ingredients")
+	assert.Equal(t, int32(1), answerCount)
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "Made juice", vals.Get("text"))
+	}
+
+	assert.Equal(t, 0, len(updatedMsgs))
+	assert.Equal(t, 0, len(deletedMsgs))
+	assert.Equal(t, 0, len(rtmSender.SentMessages))
+}
+
+func TestActionPanicRecoveredWithTimeout(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ActionTimeoutKey, time.Duration(1)*time.Second)
+
+	var answerCount int32
+	sentMsgs, _, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, v, newPanickingPlugin(&answerCount), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "make juice", "Alphonse", timestamp1)),
+	})
+
+	assert.Contains(t, logs, "Recovered from panic in action [maker.command[0]]: out of ingredients")
+	assert.Equal(t, 1, len(sentMsgs))
+}
+
+func TestActionPanicAnswer(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ActionPanicAnswerKey, "Something went wrong :scream:")
+
+	var answerCount int32
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, v, newPanickingPlugin(&answerCount), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "make juice", "Alphonse", timestamp1)),
+	}, nil)
+
+	if assert.Equal(t, 2, len(sentMsgs)) {
+		vals := applySlackOptions(sentMsgs[0].msgOptions...)
+		assert.Equal(t, "Something went wrong :scream:", vals.Get("text"))
+
+		vals = applySlackOptions(sentMsgs[1].msgOptions...)
+		assert.Equal(t, "Made juice", vals.Get("text"))
+	}
+}
+
+func TestActionDisabledAfterConsecutivePanics(t *testing.T) {
+	v := config.NewViperWithDefaults()
+	v.Set(config.ActionPanicThresholdKey, 2)
+
+	var answerCount int32
+	sentMsgs, _, _, _, logs := runSlackscotWithIncomingEventsWithLogs(t, v, newPanickingPlugin(&answerCount), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "make juice", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "make cake", "Alphonse", timestamp2)),
+		newRTMMessageEvent(newMessageEvent("DFromAlphonse", "make soup", "Alphonse", "1546833217.036900")),
+	})
+
+	assert.Contains(t, logs, "Disabling action [maker.command[0]] after [2] consecutive panics")
+	assert.Equal(t, int32(2), answerCount)
+	assert.Equal(t, 3, len(sentMsgs))
+}
+
+func TestScheduledActionPanicRecovered(t *testing.T) {
+	var logBuilder strings.Builder
+	v := config.NewViperWithDefaults()
+	v.Set(config.ActionPanicThresholdKey, 1)
+
+	s, err := New("chickadee", v, OptionLog(log.New(&logBuilder, "", 0)))
+	require.NoError(t, err)
+
+	runCount := 0
+	action := s.newSafeScheduledAction("beat", getActionID("beat", ScheduledActionType, 0), func() {
+		runCount++
+		panic("skipped a beat")
+	})
+
+	assert.NotPanics(t, assert.PanicTestFunc(action))
+	assert.NotPanics(t, assert.PanicTestFunc(action))
+
+	assert.Equal(t, 1, runCount)
+	assert.Contains(t, logBuilder.String(), "Recovered from panic in action [beat.scheduledAction[0]]: skipped a beat")
+	assert.Contains(t, logBuilder.String(), "Disabling action [beat.scheduledAction[0]] after [1] consecutive panics")
+}
+
 func TestSlackMessageIDStringer(t *testing.T) {
-	assert.Equal(t, "channel/2324", SlackMessageID{"channel", "2324"}.String())
+	assert.Equal(t, "channel/2324", SlackMessageID{channelID: "channel", timestamp: "2324"}.String())
+	assert.Equal(t, "T1/channel/2324", SlackMessageID{teamID: "T1", channelID: "channel", timestamp: "2324"}.String())
 }
 
 func newRTMMessageEvent(msgEvent *slack.MessageEvent) (e slack.RTMEvent) {
@@ -1257,7 +1504,7 @@ func runSlackscotWithIncomingEvents(t *testing.T, v *viper.Viper, plugin *Plugin
 	assert.Nil(t, err)
 
 	// Start the scheduler, it is up to the test to wait enough time to make sure scheduled actions run
-	go s.startActionScheduler(timeLoc)
+	s.startActionScheduler(timeLoc)
 
 	ec := make(chan slack.RTMEvent)
 
@@ -1267,7 +1514,8 @@ func runSlackscotWithIncomingEvents(t *testing.T, v *viper.Viper, plugin *Plugin
 		require.NotNil(t, sc)
 	}
 
-	go s.runInternal(ec, &runDependencies{chatDriver: &inMemoryChatDriver, userInfoFinder: &userInfoFinder, emojiReactor: &emojiReactor, selfInfoFinder: &selfFinder, realTimeMsgSender: rtmSenderCaptor, slackClient: sc})
+	s.workspaces.defaultWorkspace().attach(ec, &runDependencies{chatDriver: &inMemoryChatDriver, userInfoFinder: &userInfoFinder, emojiReactor: &emojiReactor, selfInfoFinder: &selfFinder, realTimeMsgSender: rtmSenderCaptor, slackClient: sc})
+	go s.runInternal(context.Background())
 
 	go sendTestEventsForProcessing(ec, events)
 
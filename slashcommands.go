@@ -0,0 +1,140 @@
+package slackscot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/slack-go/slack"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// SlashCommandsPath is the path on which the slash commands handler is served when running with OptionEventsAPI. This is
+	// what the Request URL of the slack app's slash commands should point to
+	SlashCommandsPath = "/slack/commands"
+
+	slashCommandEventType = "slash_command"
+)
+
+// SlashCommandsHandler is an http.Handler receiving slash commands. It verifies that requests are signed by slack and
+// publishes slash commands on an events channel to be processed like any other slack event. The request is acknowledged
+// right away and answers are delivered later via the slash command's response_url
+type SlashCommandsHandler struct {
+	signingSecret string
+	events        chan<- slack.RTMEvent
+	ackTimeout    time.Duration
+	log           SLogger
+}
+
+// NewSlashCommandsHandler returns a new SlashCommandsHandler verifying requests with the given signingSecret and publishing
+// slash commands on the events channel
+func NewSlashCommandsHandler(signingSecret string, events chan<- slack.RTMEvent, logger SLogger) (h *SlashCommandsHandler) {
+	h = new(SlashCommandsHandler)
+	h.signingSecret = signingSecret
+	h.events = events
+	h.ackTimeout = eventsAPIAckTimeout
+	h.log = logger
+
+	return h
+}
+
+// ServeHTTP handles a slash command request
+func (h *SlashCommandsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := readVerifiedBody(r, h.signingSecret)
+	if err != nil {
+		h.log.Printf("Rejecting slash command request: %v", err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	// Put back the verified body for it to be parsed as a form
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	cmd, err := slack.SlashCommandParse(r)
+	if err != nil || cmd.Command == "" {
+		h.log.Printf("Error parsing slash command request [%s]: %v", string(body), err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case h.events <- slack.RTMEvent{Type: slashCommandEventType, Data: &cmd}:
+	case <-time.After(h.ackTimeout):
+		h.log.Printf("Timed out publishing slash command [%s]", cmd.Command)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// newRTMEventFromSlashCommand maps a slash command json payload (as received in socket mode) to a slack.RTMEvent
+// holding a *slack.SlashCommand
+func newRTMEventFromSlashCommand(payload []byte) (e slack.RTMEvent, err error) {
+	var cmd slack.SlashCommand
+	if err = json.Unmarshal(payload, &cmd); err != nil {
+		return e, err
+	}
+
+	return slack.RTMEvent{Type: slashCommandEventType, Data: &cmd}, nil
+}
+
+// newSlashCommandText returns the text of the command a slash command maps to. A slash command named after the
+// bot (i.e. /chickadee karma top) maps to its text (karma top) while other slash commands (i.e. /karma top) map
+// to their name followed by their text (karma top). This makes /karma top behave like the karma top command
+// directed at the bot and lets commands of namespaced plugins be triggered with a slash command named after their plugin
+func (s *Slackscot) newSlashCommandText(cmd slack.SlashCommand) (text string) {
+	name := strings.TrimPrefix(cmd.Command, "/")
+	if name == s.name {
+		return cmd.Text
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("%s %s", name, cmd.Text))
+}
+
+// processSlashCommand runs a slash command through the commands of all plugins and delivers the answers via the
+// slash command's response_url
+func (s *Slackscot) processSlashCommand(ctx context.Context, sender messageSender, cmd slack.SlashCommand) {
+	m := slack.Msg{Type: "message", Team: cmd.TeamID, Channel: cmd.ChannelID, User: cmd.UserID, Text: s.newSlashCommandText(cmd)}
+	responses := s.tryActions(ctx, s.namespacedCommandCandidates(IncomingMessage{NormalizedText: m.Text, TeamID: cmd.TeamID, Msg: m}), send)
+
+	// Use default answer if this was a slash command for which we didn't have any answer to
+	if len(responses) == 0 {
+		responses = append(responses, defaultAnswer(s.defaultAction, IncomingMessage{NormalizedText: m.Text, TeamID: cmd.TeamID, Msg: m}, send))
+	}
+
+	for _, o := range responses {
+		if err := s.sendSlashCommandAnswer(sender, cmd, o); err != nil {
+			s.log.Printf("Unable to send answer to slash command [%s %s]: %v\n", cmd.Command, cmd.Text, err)
+		}
+	}
+}
+
+// sendSlashCommandAnswer sends an answer to a slash command via its response_url. The answer is only visible to the user
+// who issued the slash command if it's set to be ephemeral and visible to all members of the channel otherwise
+func (s *Slackscot) sendSlashCommandAnswer(sender messageSender, cmd slack.SlashCommand, o OutgoingMessage) (err error) {
+	s.log.Debugf("Sending slash command answer: %s", o.OutgoingMessage.Text)
+
+	responseType := slack.ResponseTypeInChannel
+	if _, ok := ApplyAnswerOpts(o.Options...)[EphemeralAnswerToOpt]; ok {
+		responseType = slack.ResponseTypeEphemeral
+	}
+
+	options := []slack.MsgOption{slack.MsgOptionText(o.OutgoingMessage.Text, false), slack.MsgOptionResponseURL(cmd.ResponseURL, responseType)}
+
+	// Add any block kit content blocks, if any
+	if len(o.ContentBlocks) > 0 {
+		options = append(options, slack.MsgOptionBlocks(o.ContentBlocks...))
+	}
+
+	_, _, _, err = sender.SendMessage(cmd.ChannelID, options...)
+	return err
+}
@@ -0,0 +1,192 @@
+package slackscot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testResponseURL = "https://hooks.slack.com/commands/T1/1/abc"
+)
+
+func newSlashCommandEvent(command string, text string) (e slack.RTMEvent) {
+	return slack.RTMEvent{Type: slashCommandEventType, Data: &slack.SlashCommand{Command: command, Text: text, ChannelID: "Cgeneral", UserID: "Alphonse", ResponseURL: testResponseURL, TriggerID: "T123"}}
+}
+
+func newKarmaLikePlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "karma"
+	p.NamespaceCommands = true
+	p.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "top")
+		},
+		Usage:       "top <count>",
+		Description: "Show the top karma",
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: fmt.Sprintf("%s for <@%s> on %s", m.NormalizedText, m.User, m.Channel)}
+		},
+	}, {
+		Match: func(m *IncomingMessage) bool {
+			return strings.HasPrefix(m.NormalizedText, "mine")
+		},
+		Usage:       "mine",
+		Description: "Show your own karma",
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: "You have 10 karma points", Options: []AnswerOption{AnswerEphemeral(m.User)}}
+		},
+	}}
+
+	return p
+}
+
+// sentSlashCommandAnswer returns the endpoint and the text of a slash command answer sent to the chat driver
+func sentSlashCommandAnswer(msg sentMessage) (endpoint string, text string) {
+	endpoint, vals, _ := slack.UnsafeApplyMsgOptions("token", msg.channelID, "url", msg.msgOptions...)
+
+	return endpoint, vals.Get("text")
+}
+
+func TestSlashCommandsMappedToPluginCommands(t *testing.T) {
+	sentMsgs, updatedMsgs, deletedMsgs, _ := runSlackscotWithIncomingEvents(t, nil, newKarmaLikePlugin(), []slack.RTMEvent{
+		newSlashCommandEvent("/karma", "top 10"),
+		newSlashCommandEvent("/chickadee", "karma top 3"),
+		newSlashCommandEvent("/karma", "mine"),
+		newSlashCommandEvent("/karma", "bottom 10"),
+	}, nil)
+
+	if assert.Equal(t, 4, len(sentMsgs)) {
+		endpoint, text := sentSlashCommandAnswer(sentMsgs[0])
+		assert.Equal(t, testResponseURL, endpoint)
+		assert.Equal(t, "top 10 for <@Alphonse> on Cgeneral", text)
+
+		endpoint, text = sentSlashCommandAnswer(sentMsgs[1])
+		assert.Equal(t, testResponseURL, endpoint)
+		assert.Equal(t, "top 3 for <@Alphonse> on Cgeneral", text)
+
+		endpoint, text = sentSlashCommandAnswer(sentMsgs[2])
+		assert.Equal(t, testResponseURL, endpoint)
+		assert.Equal(t, "You have 10 karma points", text)
+
+		endpoint, text = sentSlashCommandAnswer(sentMsgs[3])
+		assert.Equal(t, testResponseURL, endpoint)
+		assert.Equal(t, "I don't understand.\n\nHere's what `karma` does:\n\t• `karma top <count>` - Show the top karma\n\t• `karma mine` - Show your own karma\n\nAsk me for \"help\" to get a list of things I do", text)
+	}
+
+	assert.Equal(t, 0, len(updatedMsgs))
+	assert.Equal(t, 0, len(deletedMsgs))
+}
+
+func TestSlashCommandsWithNamespacingDisabled(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newKarmaLikePlugin(), []slack.RTMEvent{
+		newSlashCommandEvent("/chickadee", "top 10"),
+	}, nil, OptionNoPluginNamespacing())
+
+	if assert.Equal(t, 1, len(sentMsgs)) {
+		_, text := sentSlashCommandAnswer(sentMsgs[0])
+		assert.Equal(t, "top 10 for <@Alphonse> on Cgeneral", text)
+	}
+}
+
+func TestSlashCommandAnswerResponseType(t *testing.T) {
+	tests := map[string]struct {
+		answer               Answer
+		expectedResponseType string
+	}{
+		"inChannel": {Answer{Text: "Top 10"}, slack.ResponseTypeInChannel},
+		"ephemeral": {Answer{Text: "You have 10 karma points", Options: []AnswerOption{AnswerEphemeral("Alphonse")}}, slack.ResponseTypeEphemeral},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			responses := make(chan slack.Msg, 1)
+			responseServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var msg slack.Msg
+				err := json.NewDecoder(r.Body).Decode(&msg)
+				require.NoError(t, err)
+
+				responses <- msg
+				fmt.Fprint(w, "ok")
+			}))
+			defer responseServer.Close()
+
+			s, err := New("chickadee", config.NewViperWithDefaults(), OptionLog(log.New(&nullWriter{}, "", 0)))
+			require.NoError(t, err)
+
+			cmd := slack.SlashCommand{Command: "/karma", Text: "top 10", ChannelID: "Cgeneral", UserID: "Alphonse", ResponseURL: responseServer.URL}
+			err = s.sendSlashCommandAnswer(slack.New("token"), cmd, newOutMessageForAnswer(newSlackOutgoingMessage("Cgeneral", tc.answer.Text), "karma.command[0]", tc.answer))
+			require.NoError(t, err)
+
+			msg := <-responses
+			assert.Equal(t, tc.answer.Text, msg.Text)
+			assert.Equal(t, tc.expectedResponseType, msg.ResponseType)
+		})
+	}
+}
+
+func TestSlashCommandsHandler(t *testing.T) {
+	body := url.Values{"command": []string{"/karma"}, "text": []string{"top 10"}, "channel_id": []string{"Cgeneral"}, "user_id": []string{"Alphonse"}, "response_url": []string{testResponseURL}}.Encode()
+
+	tests := map[string]struct {
+		request      func() *http.Request
+		expectedCode int
+		published    bool
+	}{
+		"valid": {func() *http.Request {
+			return newSignedSlackRequest(t, SlashCommandsPath, body, testSigningSecret, time.Now())
+		}, http.StatusOK, true},
+		"badSignature": {func() *http.Request {
+			return newSignedSlackRequest(t, SlashCommandsPath, body, "wrong", time.Now())
+		}, http.StatusUnauthorized, false},
+		"expiredTimestamp": {func() *http.Request {
+			return newSignedSlackRequest(t, SlashCommandsPath, body, testSigningSecret, time.Now().Add(time.Duration(-10)*time.Minute))
+		}, http.StatusUnauthorized, false},
+		"missingCommand": {func() *http.Request {
+			return newSignedSlackRequest(t, SlashCommandsPath, "text=top", testSigningSecret, time.Now())
+		}, http.StatusBadRequest, false},
+		"get": {func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, SlashCommandsPath, nil)
+		}, http.StatusMethodNotAllowed, false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			events := make(chan slack.RTMEvent, 1)
+			h := NewSlashCommandsHandler(testSigningSecret, events, NewSLogger(log.New(&nullWriter{}, "", 0), false))
+
+			r := tc.request()
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			if tc.published {
+				require.Len(t, events, 1)
+				e := <-events
+
+				cmd, ok := e.Data.(*slack.SlashCommand)
+				require.True(t, ok)
+				assert.Equal(t, slack.SlashCommand{Command: "/karma", Text: "top 10", ChannelID: "Cgeneral", UserID: "Alphonse", ResponseURL: testResponseURL}, *cmd)
+
+				b, err := ioutil.ReadAll(w.Body)
+				require.NoError(t, err)
+				assert.Empty(t, b)
+			} else {
+				assert.Len(t, events, 0)
+			}
+		})
+	}
+}
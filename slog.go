@@ -1,10 +1,49 @@
 package slackscot
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// Level is the severity of a structured log entry
+type Level int
+
+// Levels of structured log entries. Debug entries are only logged in debug mode
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+const (
+	// Key of the field holding the value of a field without a string key (like with log/slog)
+	badFieldKey = "!BADKEY"
+)
+
+// String returns the name of a level as log/slog does
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case WarnLevel:
+		return "WARN"
+	case ErrorLevel:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
 // SLogger is the slackscot internal logging interface. The standard library logger implements this interface
 type SLogger interface {
 	Printf(format string, v ...interface{})
@@ -12,12 +51,36 @@ type SLogger interface {
 	Debugf(format string, v ...interface{})
 }
 
+// Logger is the structured and leveled logging interface of slackscot. Fields are given as alternating keys and
+// values (like with log/slog) and loggers created With fields add them to all their entries. A Logger is also an
+// SLogger where Printf logs at the info level and Debugf at the debug level
+type Logger interface {
+	SLogger
+
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+
+	With(keysAndValues ...interface{}) Logger
+}
+
+// LogHandler writes structured log entries. Fields are alternating keys and values where keys are strings.
+// Implementations can adapt slackscot logging to any logging library
+type LogHandler interface {
+	Handle(level Level, msg string, fields []interface{})
+}
+
 type sLogger struct {
-	logger *log.Logger
-	debug  bool
+	logger  *log.Logger
+	debug   bool
+	handler LogHandler
+	fields  []interface{}
 }
 
-// NewSLogger creates a new Slackscot logger provided with an interface logger and a debug flag
+// NewSLogger creates a new Slackscot logger provided with an interface logger and a debug flag. Structured entries
+// are logged as text lines of the message followed by the fields as key=value pairs. Info, warning and error entries
+// start with their level (i.e. level=WARN) while Printf and Debugf lines are logged as they are
 func NewSLogger(log *log.Logger, debug bool) (l *sLogger) {
 	sl := new(sLogger)
 	sl.debug = debug
@@ -25,14 +88,202 @@ func NewSLogger(log *log.Logger, debug bool) (l *sLogger) {
 	return sl
 }
 
+// NewLogger creates a new Slackscot logger writing its entries with a LogHandler. Debug entries are only
+// handled if debug is true
+func NewLogger(handler LogHandler, debug bool) (l Logger) {
+	sl := new(sLogger)
+	sl.debug = debug
+	sl.handler = handler
+	return sl
+}
+
 // Debugf logs a debug line after checking if the configuration is in debug mode
 func (sl *sLogger) Debugf(format string, v ...interface{}) {
-	if sl.debug {
-		sl.Printf(fmt.Sprintf(format, v...))
-	}
+	sl.log(DebugLevel, fmt.Sprintf(format, v...), nil, false)
 }
 
 // Printf logs a line by delegating the call to Output
 func (sl *sLogger) Printf(format string, v ...interface{}) {
-	sl.logger.Output(2, fmt.Sprintf(format, v...))
+	sl.log(InfoLevel, fmt.Sprintf(format, v...), nil, false)
+}
+
+// Debug logs a debug entry with fields after checking if the configuration is in debug mode
+func (sl *sLogger) Debug(msg string, keysAndValues ...interface{}) {
+	sl.log(DebugLevel, msg, keysAndValues, false)
+}
+
+// Info logs an info entry with fields
+func (sl *sLogger) Info(msg string, keysAndValues ...interface{}) {
+	sl.log(InfoLevel, msg, keysAndValues, true)
+}
+
+// Warn logs a warning entry with fields
+func (sl *sLogger) Warn(msg string, keysAndValues ...interface{}) {
+	sl.log(WarnLevel, msg, keysAndValues, true)
+}
+
+// Error logs an error entry with fields
+func (sl *sLogger) Error(msg string, keysAndValues ...interface{}) {
+	sl.log(ErrorLevel, msg, keysAndValues, true)
+}
+
+// With returns a logger adding the fields to all its entries
+func (sl *sLogger) With(keysAndValues ...interface{}) Logger {
+	wl := *sl
+	wl.fields = append(append(make([]interface{}, 0, len(sl.fields)+len(keysAndValues)), sl.fields...), normalizeFields(keysAndValues)...)
+
+	return &wl
+}
+
+// log logs an entry with the logger fields followed by the entry's fields. When written as a text line, the entry
+// starts with its level if leveled is true
+func (sl *sLogger) log(level Level, msg string, keysAndValues []interface{}, leveled bool) {
+	if level == DebugLevel && !sl.debug {
+		return
+	}
+
+	fields := sl.fields
+	if len(keysAndValues) > 0 {
+		fields = append(append(make([]interface{}, 0, len(sl.fields)+len(keysAndValues)), sl.fields...), normalizeFields(keysAndValues)...)
+	}
+
+	if sl.handler != nil {
+		sl.handler.Handle(level, msg, fields)
+		return
+	}
+
+	line := formatTextEntry(msg, fields)
+	if leveled {
+		line = fmt.Sprintf("level=%s %s", level, line)
+	}
+
+	// Output is called from 3 frames down (Printf/Debugf/... -> log -> Output) so we want the file and line of our caller's caller
+	sl.logger.Output(3, line)
+}
+
+// normalizeFields returns fields as pairs of string keys and values. Values without a string key get the badFieldKey
+func normalizeFields(keysAndValues []interface{}) (fields []interface{}) {
+	fields = make([]interface{}, 0, len(keysAndValues)+len(keysAndValues)%2)
+
+	for i := 0; i < len(keysAndValues); i++ {
+		key, ok := keysAndValues[i].(string)
+		if !ok || i+1 == len(keysAndValues) {
+			fields = append(fields, badFieldKey, keysAndValues[i])
+			continue
+		}
+
+		fields = append(fields, key, keysAndValues[i+1])
+		i++
+	}
+
+	return fields
+}
+
+// formatTextEntry returns the text line of an entry: the message followed by the fields as key=value pairs
+func formatTextEntry(msg string, fields []interface{}) string {
+	if len(fields) == 0 {
+		return msg
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(msg, "\n"))
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		fmt.Fprintf(&b, " %s=%s", fields[i], formatTextValue(fields[i+1]))
+	}
+
+	return b.String()
+}
+
+// formatTextValue formats a field value, quoting it when it's empty or has spaces, quotes or equal signs
+func formatTextValue(value interface{}) string {
+	formatted := fmt.Sprint(value)
+	if formatted == "" || strings.ContainsAny(formatted, " \t\n\"=") {
+		return strconv.Quote(formatted)
+	}
+
+	return formatted
+}
+
+// jsonLogHandler writes entries as JSON lines in the format of the log/slog JSON handler
+type jsonLogHandler struct {
+	w    io.Writer
+	lock sync.Mutex
+	now  func() time.Time
+}
+
+// NewJSONLogHandler returns a LogHandler writing entries as JSON lines with the same format as the log/slog JSON
+// handler: the time, level and message followed by the fields. For example:
+//
+//	{"time":"2021-02-13T10:12:15.123456789-08:00","level":"INFO","msg":"Sending new message","channel":"C1234","ts":"1612970000.000100"}
+func NewJSONLogHandler(w io.Writer) (h LogHandler) {
+	return &jsonLogHandler{w: w, now: time.Now}
+}
+
+// Handle writes an entry as a JSON line
+func (jh *jsonLogHandler) Handle(level Level, msg string, fields []interface{}) {
+	var b bytes.Buffer
+
+	b.WriteString("{")
+	writeJSONField(&b, "time", jh.now().Format(time.RFC3339Nano))
+	b.WriteString(",")
+	writeJSONField(&b, "level", level.String())
+	b.WriteString(",")
+	writeJSONField(&b, "msg", strings.TrimSuffix(msg, "\n"))
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		b.WriteString(",")
+		writeJSONField(&b, fmt.Sprint(fields[i]), fields[i+1])
+	}
+
+	b.WriteString("}\n")
+
+	jh.lock.Lock()
+	defer jh.lock.Unlock()
+
+	jh.w.Write(b.Bytes())
+}
+
+// writeJSONField writes a "key":value JSON field. Errors are written as their message and values that can't be
+// marshalled are written as their default format
+func writeJSONField(b *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	b.Write(k)
+	b.WriteString(":")
+
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+
+	b.Write(v)
+}
+
+// sLoggerHandler is a LogHandler writing entries as text lines to an SLogger
+type sLoggerHandler struct {
+	logger SLogger
+}
+
+// Handle writes a debug entry with Debugf and the others with Printf
+func (sh sLoggerHandler) Handle(level Level, msg string, fields []interface{}) {
+	if level == DebugLevel {
+		sh.logger.Debugf("%s", formatTextEntry(msg, fields))
+		return
+	}
+
+	sh.logger.Printf("%s", formatTextEntry(msg, fields))
+}
+
+// AsLogger returns an SLogger as a Logger. SLoggers that aren't Loggers have their structured entries written as text
+// lines of the message followed by the fields as key=value pairs
+func AsLogger(l SLogger) Logger {
+	if logger, ok := l.(Logger); ok {
+		return logger
+	}
+
+	return NewLogger(sLoggerHandler{logger: l}, true)
 }
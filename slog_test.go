@@ -1,8 +1,12 @@
 package slackscot_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/alexandre-normand/slackscot"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"log"
 	"strings"
 	"testing"
@@ -52,3 +56,89 @@ func TestPrintfLogsWhenDebugEnabled(t *testing.T) {
 
 	assert.Equal(t, "Writing a log statement for my little red bird\n", o)
 }
+
+func TestStructuredLogWithFields(t *testing.T) {
+	var b strings.Builder
+	l := slackscot.NewSLogger(log.New(&b, "", 0), false)
+
+	l.Info("Sending new message", "channel", "Cgeneral", "text", "hello you", "attempt", 1)
+	l.Warn("Dropping message", "err", errors.New("rate_limited"), "empty", "")
+
+	assert.Equal(t, "level=INFO Sending new message channel=Cgeneral text=\"hello you\" attempt=1\nlevel=WARN Dropping message err=rate_limited empty=\"\"\n", b.String())
+}
+
+func TestStructuredDebugLogWhenDebugDisabled(t *testing.T) {
+	var b strings.Builder
+	l := slackscot.NewSLogger(log.New(&b, "", 0), false)
+
+	l.Debug("Dispatching event", "partition", 1)
+
+	assert.Equal(t, "", b.String())
+}
+
+func TestLoggerWithFields(t *testing.T) {
+	var b strings.Builder
+	l := slackscot.NewSLogger(log.New(&b, "", 0), true).With("plugin", "karma")
+
+	l.Debug("Adding karma", "thing", "birds")
+	l.Printf("Writing a log statement for my little %s\n", "red bird")
+	l.With("channel", "Cgeneral").Error("Error persisting karma")
+
+	assert.Equal(t, "Adding karma plugin=karma thing=birds\nWriting a log statement for my little red bird plugin=karma\nlevel=ERROR Error persisting karma plugin=karma channel=Cgeneral\n", b.String())
+}
+
+func TestLogFieldsWithoutKeys(t *testing.T) {
+	var b strings.Builder
+	l := slackscot.NewSLogger(log.New(&b, "", 0), false)
+
+	l.Info("Odd fields", "channel", "Cgeneral", 42, "orphan")
+
+	assert.Equal(t, "level=INFO Odd fields channel=Cgeneral !BADKEY=42 !BADKEY=orphan\n", b.String())
+}
+
+func TestJSONLogHandler(t *testing.T) {
+	var b strings.Builder
+	l := slackscot.NewLogger(slackscot.NewJSONLogHandler(&b), false).With("plugin", "karma")
+
+	l.Debug("Not logged")
+	l.Error("Error persisting karma", "channel", "Cgeneral", "attempt", 2, "err", errors.New("boom"))
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	require.Equal(t, 1, len(lines))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.NotEmpty(t, entry["time"])
+	delete(entry, "time")
+
+	assert.Equal(t, map[string]interface{}{"level": "ERROR", "msg": "Error persisting karma", "plugin": "karma", "channel": "Cgeneral", "attempt": float64(2), "err": "boom"}, entry)
+	assert.Regexp(t, "^\\{\"time\":\"[^\"]+\",\"level\":\"ERROR\",\"msg\":\"Error persisting karma\",\"plugin\":\"karma\",\"channel\":\"Cgeneral\",\"attempt\":2,\"err\":\"boom\"\\}$", lines[0])
+}
+
+type printfLogger struct {
+	lines []string
+}
+
+func (pl *printfLogger) Printf(format string, v ...interface{}) {
+	pl.lines = append(pl.lines, "printf: "+fmt.Sprintf(format, v...))
+}
+
+func (pl *printfLogger) Debugf(format string, v ...interface{}) {
+	pl.lines = append(pl.lines, "debugf: "+fmt.Sprintf(format, v...))
+}
+
+func TestAsLoggerWithSLogger(t *testing.T) {
+	pl := printfLogger{}
+	l := slackscot.AsLogger(&pl).With("plugin", "karma")
+
+	l.Debug("Adding karma", "thing", "birds")
+	l.Error("Error persisting karma", "err", errors.New("boom"))
+
+	assert.Equal(t, []string{"debugf: Adding karma plugin=karma thing=birds", "printf: Error persisting karma plugin=karma err=boom"}, pl.lines)
+}
+
+func TestAsLoggerWithLogger(t *testing.T) {
+	l := slackscot.NewSLogger(log.New(&strings.Builder{}, "", 0), false)
+
+	assert.Equal(t, l, slackscot.AsLogger(l))
+}
@@ -0,0 +1,317 @@
+package slackscot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Socket mode envelope types and constants
+const (
+	socketModeConnectionsOpenMethod = "apps.connections.open"
+	socketModeHelloType             = "hello"
+	socketModeDisconnectType        = "disconnect"
+	socketModeEventsAPIType         = "events_api"
+	socketModeInteractiveType       = "interactive"
+	socketModeSlashCommandsType     = "slash_commands"
+	socketModeMinReconnectDelay     = time.Duration(100) * time.Millisecond
+	socketModeMaxReconnectDelay     = time.Duration(30) * time.Second
+)
+
+// socketModeEnvelope holds the data of a message received on a socket mode connection
+type socketModeEnvelope struct {
+	Type           string          `json:"type"`
+	EnvelopeID     string          `json:"envelope_id"`
+	Payload        json.RawMessage `json:"payload"`
+	Reason         string          `json:"reason"`
+	NumConnections int             `json:"num_connections"`
+}
+
+// socketModeAck is the acknowledgement sent back to slack for every envelope that has an envelope id
+type socketModeAck struct {
+	EnvelopeID string `json:"envelope_id"`
+}
+
+// socketModeOpenResponse is the response to an apps.connections.open call
+type socketModeOpenResponse struct {
+	slack.SlackResponse
+	URL string `json:"url"`
+}
+
+// socketModeEventSource is an EventSource receiving events over a Socket Mode websocket. See
+// https://api.slack.com/apis/connections/socket for more details
+type socketModeEventSource struct {
+	appToken   string
+	apiURL     string
+	httpClient *http.Client
+	dialer     *websocket.Dialer
+	authTester authTester
+
+	events          chan slack.RTMEvent
+	info            *slack.Info
+	connectionCount int
+
+	disconnect     chan bool
+	disconnectOnce sync.Once
+	connLock       sync.Mutex
+	conn           *websocket.Conn
+
+	log *sLogger
+
+	webAPIMessageSender
+}
+
+// newSocketModeEventSource returns a new socket mode EventSource. The appToken is an app-level token used to open connections
+// while the slack.Client is used for everything else (identifying the bot and sending real time messages)
+func newSocketModeEventSource(appToken string, apiURL string, sc *slack.Client, log *sLogger) (sm *socketModeEventSource) {
+	sm = new(socketModeEventSource)
+	sm.appToken = appToken
+	sm.apiURL = apiURL
+	sm.httpClient = http.DefaultClient
+	sm.dialer = websocket.DefaultDialer
+	sm.authTester = sc
+	sm.events = make(chan slack.RTMEvent, 50)
+	sm.disconnect = make(chan bool)
+	sm.log = log
+	sm.webAPIMessageSender = webAPIMessageSender{sender: sc, log: log}
+
+	return sm
+}
+
+// IncomingEvents returns the channel on which events are delivered
+func (sm *socketModeEventSource) IncomingEvents() <-chan slack.RTMEvent {
+	return sm.events
+}
+
+// GetInfo returns the info of the connected bot
+func (sm *socketModeEventSource) GetInfo() *slack.Info {
+	return sm.info
+}
+
+// Disconnect closes the current connection and stops any further reconnection
+func (sm *socketModeEventSource) Disconnect() error {
+	sm.disconnectOnce.Do(func() {
+		close(sm.disconnect)
+	})
+
+	sm.connLock.Lock()
+	defer sm.connLock.Unlock()
+
+	if sm.conn != nil {
+		return sm.conn.Close()
+	}
+
+	return nil
+}
+
+// ManageConnection connects and handles events until Disconnect is called. Connections closed by slack (with a disconnect
+// message) are reopened immediately while failures are retried with an exponential delay
+func (sm *socketModeEventSource) ManageConnection() {
+	delay := socketModeMinReconnectDelay
+
+	for !sm.isDisconnected() {
+		conn, err := sm.connect()
+		if err != nil {
+			sm.log.Printf("Error connecting in socket mode, retrying in [%s]: %v", delay, err)
+			sm.publish(slack.RTMEvent{Type: "connection_error", Data: &slack.ConnectionErrorEvent{Backoff: delay, ErrorObj: err}})
+
+			select {
+			case <-sm.disconnect:
+				return
+			case <-time.After(delay):
+			}
+
+			delay = nextReconnectDelay(delay)
+			continue
+		}
+
+		err = sm.handleEnvelopes(conn)
+		conn.Close()
+
+		if err != nil && !sm.isDisconnected() {
+			sm.log.Printf("Socket mode connection lost, reconnecting in [%s]: %v", delay, err)
+
+			select {
+			case <-sm.disconnect:
+				return
+			case <-time.After(delay):
+			}
+
+			delay = nextReconnectDelay(delay)
+		} else {
+			delay = socketModeMinReconnectDelay
+		}
+	}
+}
+
+// nextReconnectDelay returns the delay to wait for after a failure given the current one
+func nextReconnectDelay(current time.Duration) (next time.Duration) {
+	next = current * 2
+	if next > socketModeMaxReconnectDelay {
+		return socketModeMaxReconnectDelay
+	}
+
+	return next
+}
+
+// isDisconnected returns true if Disconnect has been called
+func (sm *socketModeEventSource) isDisconnected() bool {
+	select {
+	case <-sm.disconnect:
+		return true
+	default:
+		return false
+	}
+}
+
+// publish sends an event on the events channel unless we get disconnected while waiting for it to be consumed
+func (sm *socketModeEventSource) publish(e slack.RTMEvent) {
+	select {
+	case sm.events <- e:
+	case <-sm.disconnect:
+	}
+}
+
+// connect opens a new socket mode connection. This also loads the bot info on the first connection
+func (sm *socketModeEventSource) connect() (conn *websocket.Conn, err error) {
+	if sm.info == nil {
+		sm.info, err = loadSelfInfo(sm.authTester)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	url, err := sm.openConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, _, err = sm.dialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sm.connLock.Lock()
+	defer sm.connLock.Unlock()
+	sm.conn = conn
+
+	return conn, nil
+}
+
+// openConnection calls apps.connections.open to get the websocket url to connect to
+func (sm *socketModeEventSource) openConnection() (url string, err error) {
+	req, err := http.NewRequest(http.MethodPost, sm.apiURL+socketModeConnectionsOpenMethod, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", sm.appToken))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := sm.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s failed with status [%d]", socketModeConnectionsOpenMethod, resp.StatusCode)
+	}
+
+	var r socketModeOpenResponse
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", err
+	}
+
+	if err = r.Err(); err != nil {
+		return "", err
+	}
+
+	return r.URL, nil
+}
+
+// handleEnvelopes reads and handles envelopes until the connection is closed. A nil error is returned
+// when slack asks us to disconnect (which means we should reconnect right away)
+func (sm *socketModeEventSource) handleEnvelopes(conn *websocket.Conn) (err error) {
+	for {
+		var envelope socketModeEnvelope
+		if err = conn.ReadJSON(&envelope); err != nil {
+			sm.publish(slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: sm.isDisconnected(), Cause: err}})
+			return err
+		}
+
+		// Acknowledge right away, as required by slack, before processing the envelope
+		if envelope.EnvelopeID != "" {
+			if err = conn.WriteJSON(socketModeAck{EnvelopeID: envelope.EnvelopeID}); err != nil {
+				return err
+			}
+		}
+
+		switch envelope.Type {
+		case socketModeHelloType:
+			sm.connectionCount++
+			sm.publish(slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{ConnectionCount: sm.connectionCount, Info: sm.info}})
+
+		case socketModeDisconnectType:
+			sm.log.Debugf("Socket mode disconnect requested by slack with reason [%s]", envelope.Reason)
+			sm.publish(slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: false, Cause: fmt.Errorf("Disconnect requested by slack: %s", envelope.Reason)}})
+			return nil
+
+		case socketModeEventsAPIType:
+			sm.handleEventsAPIPayload(envelope.Payload)
+
+		case socketModeInteractiveType:
+			sm.handleInteractivePayload(envelope.Payload)
+
+		case socketModeSlashCommandsType:
+			sm.handleSlashCommandPayload(envelope.Payload)
+
+		default:
+			sm.log.Debugf("Ignoring socket mode envelope of type [%s]", envelope.Type)
+		}
+	}
+}
+
+// handleEventsAPIPayload maps an events api payload to the matching slack.RTMEvent and publishes it
+func (sm *socketModeEventSource) handleEventsAPIPayload(payload json.RawMessage) {
+	var callback eventsAPICallback
+	if err := json.Unmarshal(payload, &callback); err != nil {
+		sm.log.Printf("Error unmarshalling socket mode events api payload [%s]: %v", string(payload), err)
+		return
+	}
+
+	e, supported, err := newRTMEventFromEventsAPI(callback)
+	if err != nil {
+		sm.log.Printf("Error mapping events api event [%s]: %v", callback.EventID, err)
+		return
+	}
+
+	if supported {
+		sm.publish(e)
+	}
+}
+
+// handleInteractivePayload maps an interaction payload to a slack.RTMEvent and publishes it
+func (sm *socketModeEventSource) handleInteractivePayload(payload json.RawMessage) {
+	e, err := newRTMEventFromInteraction(payload)
+	if err != nil {
+		sm.log.Printf("Error unmarshalling socket mode interactive payload [%s]: %v", string(payload), err)
+		return
+	}
+
+	sm.publish(e)
+}
+
+// handleSlashCommandPayload maps a slash command payload to a slack.RTMEvent and publishes it
+func (sm *socketModeEventSource) handleSlashCommandPayload(payload json.RawMessage) {
+	e, err := newRTMEventFromSlashCommand(payload)
+	if err != nil {
+		sm.log.Printf("Error unmarshalling socket mode slash command payload [%s]: %v", string(payload), err)
+		return
+	}
+
+	sm.publish(e)
+}
@@ -0,0 +1,258 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeAuthTester struct {
+}
+
+func (f *fakeAuthTester) AuthTest() (response *slack.AuthTestResponse, err error) {
+	return &slack.AuthTestResponse{UserID: botUserID, User: "Daniel Quinn", TeamID: "T1", Team: "Expos"}, nil
+}
+
+// socketModeStandIn is a local stand-in for slack's socket mode servers. Each websocket connection
+// is handed to the next script in line
+type socketModeStandIn struct {
+	server      *httptest.Server
+	scripts     chan func(conn *websocket.Conn)
+	openedCount int
+}
+
+func newSocketModeStandIn(t *testing.T) (sm *socketModeStandIn) {
+	sm = new(socketModeStandIn)
+	sm.scripts = make(chan func(conn *websocket.Conn), 10)
+
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer xapp-token", r.Header.Get("Authorization"))
+		sm.openedCount++
+
+		fmt.Fprintf(w, "{\"ok\": true, \"url\": \"ws%s/link\"}", strings.TrimPrefix(sm.server.URL, "http"))
+	})
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		script := <-sm.scripts
+		script(conn)
+	})
+	sm.server = httptest.NewServer(mux)
+
+	return sm
+}
+
+func newTestSocketModeEventSource(sm *socketModeStandIn) (es *socketModeEventSource) {
+	es = newSocketModeEventSource("xapp-token", sm.server.URL+"/", slack.New("xoxb-token", slack.OptionAPIURL(sm.server.URL+"/")), NewSLogger(log.New(&nullWriter{}, "", 0), false))
+	es.authTester = &fakeAuthTester{}
+
+	return es
+}
+
+func nextEvent(t *testing.T, es EventSource) (e slack.RTMEvent) {
+	select {
+	case e = <-es.IncomingEvents():
+		return e
+	case <-time.After(time.Duration(2) * time.Second):
+		require.Fail(t, "Timed out waiting for event")
+	}
+
+	return e
+}
+
+func TestSocketModeEventsAPIEnvelopeAcknowledgedAndDelivered(t *testing.T) {
+	standIn := newSocketModeStandIn(t)
+	defer standIn.server.Close()
+
+	acks := make(chan socketModeAck, 1)
+	standIn.scripts <- func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "hello", "num_connections": 1}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"envelope_id": "e1", "type": "events_api", "accepts_response_payload": false, "payload": {"type": "event_callback", "team_id": "T1", "event_id": "Ev1", "event": {"type": "message", "channel": "Cgeneral", "user": "Alphonse", "text": "blue jays", "ts": "1546833210.036900"}}}`))
+
+		var ack socketModeAck
+		conn.ReadJSON(&ack)
+		acks <- ack
+
+		// Wait for the client to close the connection
+		conn.ReadMessage()
+	}
+
+	es := newTestSocketModeEventSource(standIn)
+	go es.ManageConnection()
+	defer es.Disconnect()
+
+	e := nextEvent(t, es)
+	if ce, ok := e.Data.(*slack.ConnectedEvent); assert.True(t, ok) {
+		assert.Equal(t, 1, ce.ConnectionCount)
+		assert.Equal(t, botUserID, ce.Info.User.ID)
+	}
+	assert.Equal(t, botUserID, es.GetInfo().User.ID)
+
+	e = nextEvent(t, es)
+	if me, ok := e.Data.(*slack.MessageEvent); assert.True(t, ok) {
+		assert.Equal(t, "message", e.Type)
+		assert.Equal(t, "Cgeneral", me.Channel)
+		assert.Equal(t, "Alphonse", me.User)
+		assert.Equal(t, "blue jays", me.Text)
+		assert.Equal(t, "1546833210.036900", me.Timestamp)
+	}
+
+	assert.Equal(t, "e1", (<-acks).EnvelopeID)
+}
+
+func TestSocketModeInteractiveEnvelopeDelivered(t *testing.T) {
+	standIn := newSocketModeStandIn(t)
+	defer standIn.server.Close()
+
+	acks := make(chan socketModeAck, 1)
+	standIn.scripts <- func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "hello", "num_connections": 1}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"envelope_id": "e2", "type": "interactive", "accepts_response_payload": false, "payload": %s}`, newBlockActionsPayload("poll.vote", "chickadees", "Alphonse", "Cgeneral", "1546833210.036900"))))
+
+		var ack socketModeAck
+		conn.ReadJSON(&ack)
+		acks <- ack
+
+		// Wait for the client to close the connection
+		conn.ReadMessage()
+	}
+
+	es := newTestSocketModeEventSource(standIn)
+	go es.ManageConnection()
+	defer es.Disconnect()
+
+	e := nextEvent(t, es)
+	_, ok := e.Data.(*slack.ConnectedEvent)
+	assert.True(t, ok)
+
+	e = nextEvent(t, es)
+	if callback, ok := e.Data.(*slack.InteractionCallback); assert.True(t, ok) {
+		assert.Equal(t, "block_actions", e.Type)
+		assert.Equal(t, "Alphonse", callback.User.ID)
+		if assert.Len(t, callback.ActionCallback.BlockActions, 1) {
+			assert.Equal(t, "poll.vote", callback.ActionCallback.BlockActions[0].ActionID)
+		}
+	}
+
+	assert.Equal(t, "e2", (<-acks).EnvelopeID)
+}
+
+func TestSocketModeSlashCommandsEnvelopeDelivered(t *testing.T) {
+	standIn := newSocketModeStandIn(t)
+	defer standIn.server.Close()
+
+	standIn.scripts <- func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "hello", "num_connections": 1}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"envelope_id": "e3", "type": "slash_commands", "accepts_response_payload": true, "payload": {"command": "/karma", "text": "top 10", "channel_id": "Cgeneral", "user_id": "Alphonse", "response_url": "https://hooks.slack.com/commands/T1/1/abc", "trigger_id": "T123"}}`))
+
+		// Wait for the client to close the connection
+		conn.ReadMessage()
+	}
+
+	es := newTestSocketModeEventSource(standIn)
+	go es.ManageConnection()
+	defer es.Disconnect()
+
+	e := nextEvent(t, es)
+	_, ok := e.Data.(*slack.ConnectedEvent)
+	assert.True(t, ok)
+
+	e = nextEvent(t, es)
+	if cmd, ok := e.Data.(*slack.SlashCommand); assert.True(t, ok) {
+		assert.Equal(t, "/karma", cmd.Command)
+		assert.Equal(t, "top 10", cmd.Text)
+		assert.Equal(t, "Cgeneral", cmd.ChannelID)
+		assert.Equal(t, "https://hooks.slack.com/commands/T1/1/abc", cmd.ResponseURL)
+	}
+}
+
+func TestSocketModeReconnectsOnDisconnect(t *testing.T) {
+	standIn := newSocketModeStandIn(t)
+	defer standIn.server.Close()
+
+	standIn.scripts <- func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "hello", "num_connections": 1}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "disconnect", "reason": "refresh_requested"}`))
+		conn.ReadMessage()
+	}
+	standIn.scripts <- func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "hello", "num_connections": 1}`))
+		conn.ReadMessage()
+	}
+
+	es := newTestSocketModeEventSource(standIn)
+	go es.ManageConnection()
+	defer es.Disconnect()
+
+	e := nextEvent(t, es)
+	assert.IsType(t, &slack.ConnectedEvent{}, e.Data)
+
+	e = nextEvent(t, es)
+	if de, ok := e.Data.(*slack.DisconnectedEvent); assert.True(t, ok) {
+		assert.False(t, de.Intentional)
+		assert.EqualError(t, de.Cause, "Disconnect requested by slack: refresh_requested")
+	}
+
+	e = nextEvent(t, es)
+	if ce, ok := e.Data.(*slack.ConnectedEvent); assert.True(t, ok) {
+		assert.Equal(t, 2, ce.ConnectionCount)
+	}
+
+	assert.Equal(t, 2, standIn.openedCount)
+}
+
+func TestSocketModeConnectionErrorRetried(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "{\"ok\": false, \"error\": \"invalid_auth\"}")
+	}))
+	defer server.Close()
+
+	es := newSocketModeEventSource("xapp-token", server.URL+"/", slack.New("xoxb-token"), NewSLogger(log.New(&nullWriter{}, "", 0), false))
+	es.authTester = &fakeAuthTester{}
+	go es.ManageConnection()
+	defer es.Disconnect()
+
+	for i := 0; i < 2; i++ {
+		e := nextEvent(t, es)
+		if ce, ok := e.Data.(*slack.ConnectionErrorEvent); assert.True(t, ok) {
+			assert.EqualError(t, ce.ErrorObj, "invalid_auth")
+		}
+	}
+}
+
+func TestSocketModeRequiresAppToken(t *testing.T) {
+	s, err := New("chickadee", config.NewViperWithDefaults(), OptionSocketMode())
+	require.NoError(t, err)
+
+	_, err = s.newEventSource(slack.New("xoxb-token"), s.workspaces.defaultWorkspace())
+	assert.EqualError(t, err, "Socket mode requires an app-level token set in config [appToken]")
+}
+
+func TestWebAPIMessageSender(t *testing.T) {
+	driver := inMemoryChatDriver{sentMsgs: make([]sentMessage, 0)}
+	sender := webAPIMessageSender{sender: &driver}
+
+	sender.SendMessage(sender.NewOutgoingMessage("beat", "Cstatus", slack.RTMsgOptionTS("1234"), slack.RTMsgOptionBroadcast()))
+
+	if assert.Len(t, driver.sentMsgs, 1) {
+		assert.Equal(t, "Cstatus", driver.sentMsgs[0].channelID)
+
+		vals := applySlackOptions(driver.sentMsgs[0].msgOptions...)
+		assert.Equal(t, "beat", vals.Get("text"))
+		assert.Equal(t, "1234", vals.Get("thread_ts"))
+		assert.Equal(t, "true", vals.Get("reply_broadcast"))
+	}
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"strings"
+)
+
+const (
+	teamSiloDelimiter = "/"
+)
+
+// TeamScopedStorer is a GlobalSiloStringStorer (and StringStorer) keeping the data of a single team (workspace) in a
+// wrapped GlobalSiloStringStorer. Plugins running in several workspaces can use it to keep the data of each workspace
+// separate by scoping their storer with the TeamID of the incoming message. Silos of a team are stored in the wrapped
+// storer as silos prefixed with the team id
+type TeamScopedStorer struct {
+	storer GlobalSiloStringStorer
+	teamID string
+}
+
+// NewTeamScopedStorer returns a new TeamScopedStorer keeping the data of the team with id teamID in the storer.
+// Since the storer is normally shared by all teams, closing the TeamScopedStorer doesn't close it
+func NewTeamScopedStorer(storer GlobalSiloStringStorer, teamID string) (tss *TeamScopedStorer) {
+	return &TeamScopedStorer{storer: storer, teamID: teamID}
+}
+
+// scopedSilo returns the name of the silo of the wrapped storer for a team silo
+func (tss *TeamScopedStorer) scopedSilo(silo string) (scopedSilo string) {
+	return tss.teamID + teamSiloDelimiter + silo
+}
+
+// Close is a no-op since the wrapped storer is shared by all teams and should be closed directly
+func (tss *TeamScopedStorer) Close() (err error) {
+	return nil
+}
+
+// GetSiloString retrieves a value associated to the key in the given silo of the team
+func (tss *TeamScopedStorer) GetSiloString(silo string, key string) (value string, err error) {
+	return tss.storer.GetSiloString(tss.scopedSilo(silo), key)
+}
+
+// GetString retrieves a value associated to the key for the team
+func (tss *TeamScopedStorer) GetString(key string) (value string, err error) {
+	return tss.GetSiloString("", key)
+}
+
+// PutSiloString adds or updates a value associated to the key in the given silo of the team
+func (tss *TeamScopedStorer) PutSiloString(silo string, key string, value string) (err error) {
+	return tss.storer.PutSiloString(tss.scopedSilo(silo), key, value)
+}
+
+// PutString adds or updates a value associated to the key for the team
+func (tss *TeamScopedStorer) PutString(key string, value string) (err error) {
+	return tss.PutSiloString("", key, value)
+}
+
+// DeleteSiloString deletes an entry for a given key string in the given silo of the team
+func (tss *TeamScopedStorer) DeleteSiloString(silo string, key string) (err error) {
+	return tss.storer.DeleteSiloString(tss.scopedSilo(silo), key)
+}
+
+// DeleteString deletes an entry for a given key string for the team
+func (tss *TeamScopedStorer) DeleteString(key string) (err error) {
+	return tss.DeleteSiloString("", key)
+}
+
+// ScanSilo returns the complete set of key/values in the given silo of the team
+func (tss *TeamScopedStorer) ScanSilo(silo string) (entries map[string]string, err error) {
+	return tss.storer.ScanSilo(tss.scopedSilo(silo))
+}
+
+// Scan returns the complete set of key/values for the team
+func (tss *TeamScopedStorer) Scan() (entries map[string]string, err error) {
+	return tss.ScanSilo("")
+}
+
+// GlobalScan returns the complete set of key/values of all silos of the team, keyed by silo name
+func (tss *TeamScopedStorer) GlobalScan() (entries map[string]map[string]string, err error) {
+	all, err := tss.storer.GlobalScan()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := tss.scopedSilo("")
+	entries = make(map[string]map[string]string)
+	for silo, siloEntries := range all {
+		if strings.HasPrefix(silo, prefix) {
+			entries[strings.TrimPrefix(silo, prefix)] = siloEntries
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,64 @@
+package store_test
+
+import (
+	"github.com/alexandre-normand/slackscot/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestTeamScopedStorer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tmpTest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ldb, err := store.NewLevelDB("test", dir)
+	require.NoError(t, err)
+	defer ldb.Close()
+
+	acme := store.NewTeamScopedStorer(ldb, "T1")
+	initech := store.NewTeamScopedStorer(ldb, "T2")
+
+	require.NoError(t, acme.PutSiloString("karma", "alphonse", "10"))
+	require.NoError(t, initech.PutSiloString("karma", "alphonse", "3"))
+	require.NoError(t, acme.PutString("motd", "hello"))
+
+	v, err := acme.GetSiloString("karma", "alphonse")
+	require.NoError(t, err)
+	assert.Equal(t, "10", v)
+
+	v, err = initech.GetSiloString("karma", "alphonse")
+	require.NoError(t, err)
+	assert.Equal(t, "3", v)
+
+	v, err = acme.GetString("motd")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", v)
+
+	_, err = initech.GetString("motd")
+	assert.Error(t, err)
+
+	entries, err := initech.ScanSilo("karma")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"alphonse": "3"}, entries)
+
+	all, err := acme.GlobalScan()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"karma": {"alphonse": "10"}, "": {"motd": "hello"}}, all)
+
+	require.NoError(t, acme.DeleteSiloString("karma", "alphonse"))
+	_, err = acme.GetSiloString("karma", "alphonse")
+	assert.Error(t, err)
+
+	v, err = initech.GetSiloString("karma", "alphonse")
+	require.NoError(t, err)
+	assert.Equal(t, "3", v)
+
+	// Closing a team scoped storer doesn't close the shared storer
+	require.NoError(t, acme.Close())
+	v, err = ldb.GetSiloString("T2/karma", "alphonse")
+	require.NoError(t, err)
+	assert.Equal(t, "3", v)
+}
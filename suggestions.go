@@ -0,0 +1,204 @@
+package slackscot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxSuggestions is the maximum number of commands suggested for an unknown command
+	maxSuggestions = 3
+)
+
+// commandSuggestion is a command suggested for an unknown command along with its distance to it
+type commandSuggestion struct {
+	namespace string
+	action    ActionDefinition
+	distance  int
+}
+
+// OptionDefaultAnswer sets the answerer of messages formatted as commands that no command answered. This replaces the default
+// answer suggesting the commands closest to what was asked (see suggestCommands)
+func OptionDefaultAnswer(answerer Answerer) Option {
+	return func(s *Slackscot) {
+		s.defaultAction = answerer
+	}
+}
+
+// suggestCommands is the default answerer of unknown commands. It suggests up to three registered commands whose usage is the
+// closest (by edit distance) to the unknown command. When the unknown command starts with the namespace of a plugin, only the
+// commands of that plugin are suggested along with its help
+func (s *Slackscot) suggestCommands(m *IncomingMessage) *Answer {
+	ws, err := s.workspaces.find(m.TeamID)
+	if err != nil {
+		ws = s.workspaces.defaultWorkspace()
+	}
+	prefix := s.cmdMatcherFor(ws).UsagePrefix()
+
+	text := strings.ToLower(strings.TrimSpace(m.NormalizedText))
+	suggestions := make([]commandSuggestion, 0)
+	var namespacePlugin *Plugin
+
+	for _, p := range s.plugins {
+		if matchedNamespace, stripped := s.stripNamespace(p, IncomingMessage{NormalizedText: text}); matchedNamespace && s.namespaceCommands && p.NamespaceCommands {
+			namespacePlugin = p
+			suggestions = s.closestCommands(m, p.Name, false, stripped.NormalizedText, p.Commands)
+			break
+		}
+	}
+
+	if namespacePlugin == nil {
+		for _, p := range s.plugins {
+			namespace := ""
+			if s.namespaceCommands && p.NamespaceCommands {
+				namespace = p.Name
+			}
+
+			suggestions = append(suggestions, s.closestCommands(m, namespace, namespace != "", text, p.Commands)...)
+		}
+	}
+
+	if len(suggestions) == 0 && namespacePlugin == nil {
+		return defaultAction(m)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "I don't understand.")
+
+	if len(suggestions) > 0 {
+		sort.SliceStable(suggestions, func(i, j int) bool {
+			return suggestions[i].distance < suggestions[j].distance
+		})
+		if len(suggestions) > maxSuggestions {
+			suggestions = suggestions[:maxSuggestions]
+		}
+
+		fmt.Fprintf(&b, " Did you mean:\n")
+		for _, suggestion := range suggestions {
+			appendActions(&b, prefix, suggestion.namespace, []ActionDefinition{suggestion.action})
+		}
+	} else {
+		fmt.Fprintf(&b, "\n")
+	}
+
+	if namespacePlugin != nil {
+		if actions := s.visibleCommands(m, namespacePlugin.Commands); len(actions) > 0 {
+			fmt.Fprintf(&b, "\nHere's what `%s` does:\n", namespacePlugin.Name)
+			appendActions(&b, prefix, namespacePlugin.Name, actions)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nAsk me for \"%s\" to get a list of things I do", helpPluginName)
+
+	return &Answer{Text: b.String()}
+}
+
+// closestCommands returns the commands (visible to the author of the message) whose usage is close enough to the text. When
+// withNamespace is true, the text is compared to the usage preceded by the namespace
+func (s *Slackscot) closestCommands(m *IncomingMessage, namespace string, withNamespace bool, text string, commands []ActionDefinition) (suggestions []commandSuggestion) {
+	suggestions = make([]commandSuggestion, 0)
+
+	for _, c := range s.visibleCommands(m, commands) {
+		literal := usageLiteral(c.Usage)
+		if literal == "" {
+			continue
+		}
+
+		if withNamespace {
+			literal = strings.ToLower(namespace) + " " + literal
+		}
+
+		if distance, isClose := usageDistance(text, literal); isClose {
+			suggestions = append(suggestions, commandSuggestion{namespace: namespace, action: c, distance: distance})
+		}
+	}
+
+	return suggestions
+}
+
+// visibleCommands returns the commands listed in the help that the author of the message is authorized to run
+func (s *Slackscot) visibleCommands(m *IncomingMessage, commands []ActionDefinition) (visible []ActionDefinition) {
+	visible = make([]ActionDefinition, 0)
+	for _, c := range filterNonHiddenActions(commands) {
+		if c.Usage != "" && s.isAuthorized(m, c.RequiredRoles) {
+			visible = append(visible, c)
+		}
+	}
+
+	return visible
+}
+
+// usageLiteral returns the literal words of a usage, that is, the words before the first placeholder (like `<count>` or [user])
+func usageLiteral(usage string) (literal string) {
+	words := make([]string, 0)
+	for _, w := range strings.Fields(strings.ToLower(usage)) {
+		if strings.ContainsAny(w, "`<[{") {
+			break
+		}
+
+		words = append(words, w)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// usageDistance returns the edit distance between the literal words of a usage and as many words at the start of the text
+// along with whether it's close enough for the usage to be suggested (about one edit for every three characters)
+func usageDistance(text string, literal string) (distance int, isClose bool) {
+	words := strings.Fields(text)
+	if n := len(strings.Fields(literal)); len(words) > n {
+		words = words[:n]
+	}
+
+	distance = editDistance(strings.Join(words, " "), literal)
+	maxDistance := len([]rune(literal)) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	return distance, distance <= maxDistance
+}
+
+// editDistance returns the optimal string alignment distance between a and b: the number of insertions, deletions,
+// substitutions and transpositions of adjacent characters needed to turn a into b
+func editDistance(a string, b string) (distance int) {
+	ra, rb := []rune(a), []rune(b)
+
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = smallest(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = smallest(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+// smallest returns the smallest of the values
+func smallest(first int, others ...int) (value int) {
+	value = first
+	for _, v := range others {
+		if v < value {
+			value = v
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,142 @@
+package slackscot
+
+import (
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newRankerPlugin returns a plugin with commands, not namespaced, having the given usages
+func newRankerPlugin(usages ...string) (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "ranker"
+	for _, usage := range usages {
+		p.Commands = append(p.Commands, ActionDefinition{
+			Match: func(m *IncomingMessage) bool {
+				return false
+			},
+			Usage:       usage,
+			Description: fmt.Sprintf("Rank by %s", usage),
+			Answer: func(m *IncomingMessage) *Answer {
+				return nil
+			},
+		})
+	}
+
+	return p
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		distance int
+	}{
+		{"top", "top", 0},
+		{"tpo", "top", 1},
+		{"to", "top", 1},
+		{"", "top", 3},
+		{"kitten", "sitting", 3},
+		{"krama top", "karma top", 1},
+		{"héllo", "hello", 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s->%s", tc.a, tc.b), func(t *testing.T) {
+			assert.Equal(t, tc.distance, editDistance(tc.a, tc.b))
+		})
+	}
+}
+
+func TestUsageLiteral(t *testing.T) {
+	assert.Equal(t, "top", usageLiteral("top `<count>`"))
+	assert.Equal(t, "create channel", usageLiteral("Create channel <name>"))
+	assert.Equal(t, "reset", usageLiteral("reset [user]"))
+	assert.Equal(t, "", usageLiteral("`<something>`"))
+}
+
+func TestSuggestionsForMistypedCommandInMatchedNamespace(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newKarmaLikePlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s karma tpo 10", formattedBotUserID), "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newTestPlugin()))
+
+	assert.Equal(t, []string{"<@Alphonse>: I don't understand. Did you mean:\n" +
+		"\t• `karma top <count>` - Show the top karma\n" +
+		"\nHere's what `karma` does:\n" +
+		"\t• `karma top <count>` - Show the top karma\n" +
+		"\t• `karma mine` - Show your own karma\n" +
+		"\nAsk me for \"help\" to get a list of things I do"}, sentTexts(sentMsgs))
+}
+
+func TestSuggestionsForMistypedNamespace(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newKarmaLikePlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", fmt.Sprintf("%s krama top 10", formattedBotUserID), "Alphonse", timestamp1)),
+	}, nil, optionRegisterPlugins(newTestPlugin()))
+
+	assert.Equal(t, []string{"<@Alphonse>: I don't understand. Did you mean:\n" +
+		"\t• `karma top <count>` - Show the top karma\n" +
+		"\nAsk me for \"help\" to get a list of things I do"}, sentTexts(sentMsgs))
+}
+
+func TestSuggestionsWithCommandPrefix(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newKarmaLikePlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "!karma mien", "Alphonse", timestamp1)),
+	}, nil, OptionCommandPrefix("!"))
+
+	assert.Equal(t, []string{"<@Alphonse>: I don't understand. Did you mean:\n" +
+		"\t• `!karma mine` - Show your own karma\n" +
+		"\nHere's what `karma` does:\n" +
+		"\t• `!karma top <count>` - Show the top karma\n" +
+		"\t• `!karma mine` - Show your own karma\n" +
+		"\nAsk me for \"help\" to get a list of things I do"}, sentTexts(sentMsgs))
+}
+
+func TestSuggestionsWithoutPluginNamespacing(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromUser", "mkae cake", "Alphonse", timestamp1)),
+		newRTMMessageEvent(newMessageEvent("DFromUser", "creat chanel birds", "Alphonse", timestamp2)),
+	}, nil, OptionNoPluginNamespacing())
+
+	assert.Equal(t, []string{
+		"I don't understand. Did you mean:\n" +
+			"\t• `make `<something>`` - Have the test bot make something for you\n" +
+			"\nAsk me for \"help\" to get a list of things I do",
+		"I don't understand. Did you mean:\n" +
+			"\t• `create channel <name>` - Creates a new channel with the given name\n" +
+			"\nAsk me for \"help\" to get a list of things I do",
+	}, sentTexts(sentMsgs))
+}
+
+func TestSuggestionsLimitedToClosestThree(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newRankerPlugin("tops", "top", "tap", "tip", "tup"), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromUser", "tp", "Alphonse", timestamp1)),
+	}, nil)
+
+	assert.Equal(t, []string{"I don't understand. Did you mean:\n" +
+		"\t• `top` - Rank by top\n" +
+		"\t• `tap` - Rank by tap\n" +
+		"\t• `tip` - Rank by tip\n" +
+		"\nAsk me for \"help\" to get a list of things I do"}, sentTexts(sentMsgs))
+}
+
+func TestNoSuggestionsForHiddenCommands(t *testing.T) {
+	p := newRankerPlugin("top")
+	p.Commands[0].Hidden = true
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, p, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromUser", "tpo", "Alphonse", timestamp1)),
+	}, nil)
+
+	assert.Equal(t, []string{"I don't understand. Ask me for \"help\" to get a list of things I do"}, sentTexts(sentMsgs))
+}
+
+func TestOptionDefaultAnswer(t *testing.T) {
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, newKarmaLikePlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("DFromUser", "karma tpo", "Alphonse", timestamp1)),
+	}, nil, OptionDefaultAnswer(func(m *IncomingMessage) *Answer {
+		return &Answer{Text: fmt.Sprintf("🦉 Hoo? `%s`", m.NormalizedText)}
+	}))
+
+	assert.Equal(t, []string{"🦉 Hoo? `karma tpo`"}, sentTexts(sentMsgs))
+}
@@ -1,11 +1,14 @@
 package assertplugin
 
 import (
+	"context"
 	"fmt"
 	"github.com/alexandre-normand/slackscot"
+	"github.com/alexandre-normand/slackscot/config"
 	"github.com/alexandre-normand/slackscot/schedule"
 	"github.com/alexandre-normand/slackscot/test/capture"
 	"github.com/slack-go/slack"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"log"
 	"strings"
@@ -15,9 +18,13 @@ import (
 // Asserter represents a plugin driver/asserter and holds the bot identifier that tests are using when
 // sending test messages for processing
 type Asserter struct {
-	botUserID string
-	t         *testing.T
-	logger    *log.Logger
+	botUserID        string
+	t                *testing.T
+	logger           *log.Logger
+	exclusiveRouting bool
+
+	// The slackscot instance plugin actions are invoked through, created on first use
+	bot *slackscot.Slackscot
 }
 
 // New creates a new asserter with the given botUserId
@@ -47,6 +54,14 @@ func OptionLog(logger *log.Logger) Option {
 	}
 }
 
+// OptionExclusiveRouting drives plugins with exclusive routing where only the first action (in order of priority) matching
+// a message answers it, like slackscot does with config.ExclusiveRoutingKey enabled
+func OptionExclusiveRouting() Option {
+	return func(a *Asserter) {
+		a.exclusiveRouting = true
+	}
+}
+
 // ResultValidator is a function to do further validation of the answers and emoji reactions resulting from
 // a plugin processing of all of its commands and hear actions. The return value is meant to be true if validation
 // is successful and false otherwise (following the testify convention)
@@ -84,6 +99,128 @@ func (a *Asserter) AnswersAndReactsWithUploads(p *slackscot.Plugin, m *slack.Msg
 	return validate(a.t, answers, emojis, fileUploads)
 }
 
+// Exchange is a message sent to a plugin during a conversation along with the validation of the answers and emoji reactions
+// resulting from its processing
+type Exchange struct {
+	Msg      *slack.Msg
+	Validate ResultValidator
+}
+
+// Converses drives a plugin through a scripted conversation where each message is processed in order and its answers and emoji
+// reactions are validated by the exchange's validator. Dialogs started by answers (see slackscot.AnswerStartingDialog) are
+// continued by the next messages of the same user in the channel and thread the answer is sent in, as slackscot does. It follows the style of
+// github.com/stretchr/testify/assert as far as returning true/false to indicate success for further nested testing.
+func (a *Asserter) Converses(p *slackscot.Plugin, exchanges ...Exchange) (valid bool) {
+	emojiCaptor, _, _ := a.injectServices(p)
+	dialogs := make(map[string]*slackscot.DialogState)
+
+	valid = true
+	for _, e := range exchanges {
+		seenEmojis := len(emojiCaptor.Emojis)
+		answers := a.converse(p, dialogs, e.Msg)
+
+		valid = e.Validate(a.t, answers, emojiCaptor.Emojis[seenEmojis:]) && valid
+	}
+
+	return valid
+}
+
+// converse drives a plugin with a message of a conversation and returns the answers. The message goes to the dialog of its sender, if any, or to the
+// plugin's actions otherwise
+func (a *Asserter) converse(p *slackscot.Plugin, dialogs map[string]*slackscot.DialogState, m *slack.Msg) (answers []*slackscot.Answer) {
+	key := newDialogKey(m.Channel, m.ThreadTimestamp, m.User)
+
+	if state, ok := dialogs[key]; ok {
+		for _, def := range p.Dialogs {
+			if def.Name == state.Dialog {
+				inMsg := slackscot.IncomingMessage{NormalizedText: strings.TrimPrefix(m.Text, fmt.Sprintf("<@%s> ", a.botUserID)), Msg: *m}
+
+				answer, ended := slackscot.TakeDialogTurn(def, state, &inMsg)
+				if ended {
+					delete(dialogs, key)
+				}
+
+				answers = make([]*slackscot.Answer, 0)
+				if answer != nil {
+					answers = append(answers, answer)
+				}
+
+				return answers
+			}
+		}
+	}
+
+	answers = a.driveActions(p, m)
+	for _, answer := range answers {
+		if state, started := slackscot.NewDialogState(p, answer); started {
+			dialogs[newDialogKey(m.Channel, answerThreadTimestamp(m, answer), m.User)] = state
+		}
+	}
+
+	return answers
+}
+
+// newDialogKey returns the key of the dialog of a user in a channel and thread
+func newDialogKey(channelID string, threadTimestamp string, userID string) (key string) {
+	return fmt.Sprintf("%s/%s/%s", channelID, threadTimestamp, userID)
+}
+
+// answerThreadTimestamp returns the timestamp of the thread an answer to a message is sent in or the thread timestamp of
+// the message if the answer isn't threaded
+func answerThreadTimestamp(m *slack.Msg, answer *slackscot.Answer) (threadTimestamp string) {
+	sendOpts := slackscot.ApplyAnswerOpts(answer.Options...)
+	if sendOpts[slackscot.ThreadedReplyOpt] != "true" {
+		return m.ThreadTimestamp
+	}
+
+	if threadTimestamp = sendOpts[slackscot.ThreadTimestamp]; threadTimestamp != "" {
+		return threadTimestamp
+	}
+
+	if m.ThreadTimestamp != "" {
+		return m.ThreadTimestamp
+	}
+
+	return m.Timestamp
+}
+
+// AnswersReaction drives a plugin's reaction actions and collects Answers as well as emoji reactions. Once all of those have been
+// collected, it passes handling to a validator to assert the expected answers and emoji reactions. It follows the style of
+// github.com/stretchr/testify/assert as far as returning true/false to indicate success for further nested testing.
+func (a *Asserter) AnswersReaction(p *slackscot.Plugin, r *slackscot.IncomingReaction, validate ResultValidator) (valid bool) {
+	emojiCaptor, _, _ := a.injectServices(p)
+
+	answers := make([]*slackscot.Answer, 0)
+	for _, action := range p.ReactionActions {
+		if action.Match(r) {
+			if answer := action.Answer(r); answer != nil {
+				answers = append(answers, answer)
+			}
+		}
+	}
+
+	return validate(a.t, answers, emojiCaptor.Emojis)
+}
+
+// AnswersInteraction drives a plugin's interaction actions and collects Answers as well as emoji reactions. Once all of those have been
+// collected, it passes handling to a validator to assert the expected answers and emoji reactions. The interaction's ActionID is the one
+// seen by the plugin's interaction actions (without the plugin namespace). It follows the style of github.com/stretchr/testify/assert as
+// far as returning true/false to indicate success for further nested testing.
+func (a *Asserter) AnswersInteraction(p *slackscot.Plugin, i *slackscot.IncomingInteraction, validate ResultValidator) (valid bool) {
+	emojiCaptor, _, _ := a.injectServices(p)
+
+	answers := make([]*slackscot.Answer, 0)
+	for _, action := range p.InteractionActions {
+		if action.Match(i) {
+			if answer := action.Answer(i); answer != nil {
+				answers = append(answers, answer)
+			}
+		}
+	}
+
+	return validate(a.t, answers, emojiCaptor.Emojis)
+}
+
 // RunsOnSchedule drives a plugin's scheduled actions that match the schedule definition being passed in (i.e. "Every 1 hour" will
 // run all actions scheduled to run every hour) and collects all the sent messages. Once all have been collected,
 // the results are passed to the ScheduleResultValidator as a map[string][]string where the key is the channel id
@@ -153,37 +290,43 @@ func getLogger(a *Asserter) (logger *log.Logger) {
 	return log.New(&b, "", 0)
 }
 
+// driveActions drives the commands or hear actions of a plugin, depending on the format of the message, the way slackscot
+// does (see slackscot.Slackscot.InvokePluginActions)
 func (a *Asserter) driveActions(p *slackscot.Plugin, m *slack.Msg) (answers []*slackscot.Answer) {
+	bot, err := a.getBot()
+	if !assert.NoError(a.t, err, "Error creating slackscot to drive plugin [%s]", p.Name) {
+		return make([]*slackscot.Answer, 0)
+	}
+
 	botMentionPrefix := fmt.Sprintf("<@%s> ", a.botUserID)
 
 	if strings.HasPrefix(m.Text, botMentionPrefix) {
 		normalizedText := strings.TrimPrefix(m.Text, botMentionPrefix)
 		inMsg := slackscot.IncomingMessage{NormalizedText: normalizedText, Msg: *m}
 
-		return runActions(p.Commands, &inMsg)
+		return bot.InvokePluginActions(context.Background(), p, slackscot.CommandActionType, p.Commands, &inMsg)
 	}
 
 	inMsg := slackscot.IncomingMessage{NormalizedText: m.Text, Msg: *m}
 
 	if strings.HasPrefix(m.Channel, "D") {
-		return runActions(p.Commands, &inMsg)
+		return bot.InvokePluginActions(context.Background(), p, slackscot.CommandActionType, p.Commands, &inMsg)
 	}
 
-	return runActions(p.HearActions, &inMsg)
+	return bot.InvokePluginActions(context.Background(), p, slackscot.HearActionType, p.HearActions, &inMsg)
 }
 
-func runActions(actions []slackscot.ActionDefinition, m *slackscot.IncomingMessage) (answers []*slackscot.Answer) {
-	answers = make([]*slackscot.Answer, 0)
+// getBot returns the slackscot instance plugin actions are invoked through, creating it on first use with the asserter's
+// routing and logger
+func (a *Asserter) getBot() (bot *slackscot.Slackscot, err error) {
+	if a.bot != nil {
+		return a.bot, nil
+	}
 
-	for _, action := range actions {
-		if action.Match(m) {
-			a := action.Answer(m)
+	v := viper.New()
+	v.Set(config.ExclusiveRoutingKey, a.exclusiveRouting)
 
-			if a != nil {
-				answers = append(answers, a)
-			}
-		}
-	}
+	a.bot, err = slackscot.New("assertplugin", v, slackscot.OptionLog(getLogger(a)))
 
-	return answers
+	return a.bot, err
 }
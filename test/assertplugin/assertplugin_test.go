@@ -1,6 +1,8 @@
 package assertplugin_test
 
 import (
+	"context"
+	"fmt"
 	"github.com/alexandre-normand/slackscot"
 	"github.com/alexandre-normand/slackscot/schedule"
 	"github.com/alexandre-normand/slackscot/test/assertanswer"
@@ -60,6 +62,33 @@ func newLittleTester() (mlt *myLittleTester) {
 		},
 	}
 
+	mlt.ReactionActions = []slackscot.ReactionActionDefinition{
+		{
+			Match: func(r *slackscot.IncomingReaction) bool {
+				return r.Emoji == "bird" && !r.Removed
+			},
+			Usage:       ":bird: a message",
+			Description: "Chirp about birds",
+			Answer: func(r *slackscot.IncomingReaction) *slackscot.Answer {
+				mlt.EmojiReactor.AddReaction("owl", slack.NewRefToMessage(r.ItemChannel, r.ItemTimestamp))
+
+				return &slackscot.Answer{Text: fmt.Sprintf("Chirp to you too, <@%s>", r.User)}
+			},
+		},
+	}
+
+	mlt.InteractionActions = []slackscot.InteractionActionDefinition{
+		{
+			Match: func(i *slackscot.IncomingInteraction) bool {
+				return i.ActionID == "favorite"
+			},
+			Description: "Record a favorite bird",
+			Answer: func(i *slackscot.IncomingInteraction) *slackscot.Answer {
+				return &slackscot.Answer{Text: fmt.Sprintf("<@%s>'s favorite bird is the %s", i.User, i.Value), Options: []slackscot.AnswerOption{slackscot.AnswerUpdatingOriginal()}}
+			},
+		},
+	}
+
 	mlt.ScheduledActions = []slackscot.ScheduledActionDefinition{
 		{Schedule: schedule.Definition{Interval: 1, Unit: schedule.Minutes}, Description: "Check health", Action: mlt.healthStatus},
 	}
@@ -157,6 +186,122 @@ func TestDirectCommandResultValid(t *testing.T) {
 	}))
 }
 
+func TestPluginMiddlewareApplied(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+	myLittleTester.Middlewares = []slackscot.Middleware{func(next slackscot.ActionInvoker) slackscot.ActionInvoker {
+		return func(ctx context.Context, inv *slackscot.ActionInvocation) *slackscot.Answer {
+			if a := next(ctx, inv); a != nil {
+				return &slackscot.Answer{Text: fmt.Sprintf("%s says: %s", inv.ActionID, a.Text)}
+			}
+
+			return nil
+		}
+	}}
+
+	assert.Equal(t, true, assertplugin.AnswersAndReacts(&myLittleTester.Plugin, &slack.Msg{Text: "are you up?"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "myLittleTester.hearAction[0] says: I'm 😴, you?")
+	}))
+}
+
+func answersWithTexts(texts ...string) assertplugin.ResultValidator {
+	return func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		actual := make([]string, 0)
+		for _, a := range answers {
+			actual = append(actual, a.Text)
+		}
+
+		return assert.Equal(t, texts, actual)
+	}
+}
+
+func TestActionsAnsweredInOrderOfPriority(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+	myLittleTester.HearActions[1].Priority = 10
+
+	assert.Equal(t, true, assertplugin.AnswersAndReacts(&myLittleTester.Plugin, &slack.Msg{Text: "hey, are you up?"}, answersWithTexts("hey wut?", "I'm 😴, you?")))
+}
+
+func TestExclusiveActionAnsweredAlone(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+	myLittleTester.HearActions[1].Priority = 10
+	myLittleTester.HearActions[1].Exclusive = true
+
+	assert.Equal(t, true, assertplugin.AnswersAndReacts(&myLittleTester.Plugin, &slack.Msg{Text: "hey, are you up?"}, answersWithTexts("hey wut?")))
+}
+
+func TestExclusiveRouting(t *testing.T) {
+	mockT := new(testing.T)
+	asserter := assertplugin.New(mockT, "bot", assertplugin.OptionExclusiveRouting())
+
+	assert.Equal(t, true, asserter.AnswersAndReacts(&newLittleTester().Plugin, &slack.Msg{Text: "hey, are you up?"}, answersWithTexts("I'm 😴, you?")))
+}
+
+func TestPanickingActionIsolated(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+	myLittleTester.HearActions[0].Answer = func(m *slackscot.IncomingMessage) *slackscot.Answer {
+		panic("no chickadee in sight")
+	}
+
+	assert.Equal(t, true, assertplugin.AnswersAndReacts(&myLittleTester.Plugin, &slack.Msg{Text: "hey, are you up?"}, answersWithTexts("hey wut?")))
+}
+
+func TestActionRequiringRoleNotAuthorized(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+	myLittleTester.HearActions[0].RequiredRoles = []string{slackscot.AdminRole}
+
+	assert.Equal(t, true, assertplugin.AnswersAndReacts(&myLittleTester.Plugin, &slack.Msg{Text: "are you up?", User: "Alphonse"}, answersWithTexts("🚫 Sorry, you're not authorized to do that")))
+}
+
+func TestReactionAnswered(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+
+	assert.Equal(t, true, assertplugin.AnswersReaction(&myLittleTester.Plugin, &slackscot.IncomingReaction{User: "Alphonse", Emoji: "bird", ItemChannel: "Cgeneral", ItemTimestamp: "1546833210.036900"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "Chirp to you too, <@Alphonse>") && assert.Equal(t, []string{"owl"}, emojis)
+	}))
+}
+
+func TestReactionRemovalNotAnswered(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+
+	assert.Equal(t, true, assertplugin.AnswersReaction(&myLittleTester.Plugin, &slackscot.IncomingReaction{User: "Alphonse", Emoji: "bird", ItemChannel: "Cgeneral", ItemTimestamp: "1546833210.036900", Removed: true}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Empty(t, answers) && assert.Empty(t, emojis)
+	}))
+}
+
+func TestInteractionAnswered(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+
+	assert.Equal(t, true, assertplugin.AnswersInteraction(&myLittleTester.Plugin, &slackscot.IncomingInteraction{ActionID: "favorite", Value: "chickadee", User: "Alphonse", Channel: "Cgeneral", MessageTimestamp: "1546833210.036900"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], "<@Alphonse>'s favorite bird is the chickadee") && assertanswer.HasOptions(t, answers[0], assertanswer.ResolvedAnswerOption{Key: slackscot.UpdateOriginalOpt, Value: "true"})
+	}))
+}
+
+func TestUnknownInteractionNotAnswered(t *testing.T) {
+	mockT := new(testing.T)
+	assertplugin := assertplugin.New(mockT, "bot")
+	myLittleTester := newLittleTester()
+
+	assert.Equal(t, true, assertplugin.AnswersInteraction(&myLittleTester.Plugin, &slackscot.IncomingInteraction{ActionID: "dislike", Value: "chickadee", User: "Alphonse"}, func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Empty(t, answers)
+	}))
+}
+
 func TestEmojiReaction(t *testing.T) {
 	mockT := new(testing.T)
 	assertplugin := assertplugin.New(mockT, "bot")
@@ -222,3 +367,94 @@ func TestDoesNotOnScheduleAssertWhenRunsOnSchedule(t *testing.T) {
 
 	assert.Equal(t, false, assertplugin.DoesNotRunOnSchedule(&myLittleTester.Plugin, schedule.Definition{Interval: 1, Unit: schedule.Minutes}))
 }
+
+func newBirdWatcher() (p *slackscot.Plugin) {
+	p = new(slackscot.Plugin)
+	p.Name = "birdWatcher"
+	p.Commands = []slackscot.ActionDefinition{{
+		Match: func(m *slackscot.IncomingMessage) bool {
+			return m.NormalizedText == "log sighting"
+		},
+		Answer: func(m *slackscot.IncomingMessage) *slackscot.Answer {
+			return &slackscot.Answer{Text: "Which bird?", Options: []slackscot.AnswerOption{slackscot.AnswerStartingDialog("sighting")}}
+		},
+	}}
+	p.Dialogs = []slackscot.DialogDefinition{{
+		Name:  "sighting",
+		Start: "bird",
+		Steps: map[string]slackscot.DialogStep{
+			"bird": func(t *slackscot.DialogTurn) *slackscot.Answer {
+				t.Values["bird"] = t.Message.NormalizedText
+				t.GoTo("where")
+				return &slackscot.Answer{Text: "Where?"}
+			},
+			"where": func(t *slackscot.DialogTurn) *slackscot.Answer {
+				t.End()
+				return &slackscot.Answer{Text: fmt.Sprintf("Logged a %s in the %s", t.Values["bird"], t.Message.NormalizedText)}
+			},
+		},
+	}}
+
+	return p
+}
+
+func answersWithText(text string) assertplugin.ResultValidator {
+	return func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+		return assert.Len(t, answers, 1) && assertanswer.HasText(t, answers[0], text)
+	}
+}
+
+func TestConversesWithDialog(t *testing.T) {
+	mockT := new(testing.T)
+	asserter := assertplugin.New(mockT, "bot")
+
+	assert.Equal(t, true, asserter.Converses(newBirdWatcher(),
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "<@bot> log sighting", User: "Alphonse"}, Validate: answersWithText("Which bird?")},
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "chickadee", User: "Ignatius"}, Validate: func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Empty(t, answers)
+		}},
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "chickadee", User: "Alphonse"}, Validate: answersWithText("Where?")},
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "tree", User: "Alphonse"}, Validate: answersWithText("Logged a chickadee in the tree")},
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "tree", User: "Alphonse"}, Validate: func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Empty(t, answers)
+		}},
+	))
+}
+
+func TestConversesWithDialogInThreadOfAnswer(t *testing.T) {
+	mockT := new(testing.T)
+	asserter := assertplugin.New(mockT, "bot")
+
+	p := newBirdWatcher()
+	p.Commands[0].Answer = func(m *slackscot.IncomingMessage) *slackscot.Answer {
+		return &slackscot.Answer{Text: "Which bird?", Options: []slackscot.AnswerOption{slackscot.AnswerStartingDialog("sighting"), slackscot.AnswerInThread()}}
+	}
+
+	assert.Equal(t, true, asserter.Converses(p,
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "<@bot> log sighting", User: "Alphonse", Timestamp: "1546833210.036900"}, Validate: answersWithText("Which bird?")},
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "chickadee", User: "Alphonse", Timestamp: "1546833211.036900"}, Validate: func(t *testing.T, answers []*slackscot.Answer, emojis []string) bool {
+			return assert.Empty(t, answers)
+		}},
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "chickadee", User: "Alphonse", Timestamp: "1546833212.036900", ThreadTimestamp: "1546833210.036900"}, Validate: answersWithText("Where?")},
+	))
+}
+
+func TestConversesWithCancelledDialog(t *testing.T) {
+	mockT := new(testing.T)
+	asserter := assertplugin.New(mockT, "bot")
+
+	assert.Equal(t, true, asserter.Converses(newBirdWatcher(),
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "<@bot> log sighting", User: "Alphonse"}, Validate: answersWithText("Which bird?")},
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "cancel", User: "Alphonse"}, Validate: answersWithText("Ok, never mind then")},
+	))
+}
+
+func TestConversesWithFailingExchange(t *testing.T) {
+	mockT := new(testing.T)
+	asserter := assertplugin.New(mockT, "bot")
+
+	assert.Equal(t, false, asserter.Converses(newBirdWatcher(),
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "<@bot> log sighting", User: "Alphonse"}, Validate: answersWithText("Where?")},
+		assertplugin.Exchange{Msg: &slack.Msg{Text: "chickadee", User: "Alphonse"}, Validate: answersWithText("Where?")},
+	))
+}
@@ -0,0 +1,121 @@
+package slackscot
+
+import (
+	"context"
+	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// Name of the slackscot tracer (and meter)
+	instrumentationName = "github.com/alexandre-normand/slackscot"
+)
+
+// Names of the spans of the message lifecycle
+const (
+	messageSpanName      = "slackscot.message"
+	reactionSpanName     = "slackscot.reaction"
+	interactionSpanName  = "slackscot.interaction"
+	slashCommandSpanName = "slackscot.slashCommand"
+	queueWaitSpanName    = "slackscot.queueWait"
+	routeMessageSpanName = "slackscot.routeMessage"
+	actionSpanName       = "slackscot.action"
+)
+
+// OptionTracerProvider sets the provider of the tracer creating the spans of slackscot. The default is the global
+// provider (see otel.SetTracerProvider). Each message event gets a span from its dispatch to the end of its processing
+// with children for the wait in its partition queue, its routing to plugins, each matched plugin action and each call
+// to the slack api
+func OptionTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Slackscot) {
+		s.tracerProvider = tp
+	}
+}
+
+// contextBinder is implemented by services that can make their calls on behalf of a context so that the spans of those
+// calls nest under the span of the context. bindContext returns a copy of the service bound to the context
+type contextBinder interface {
+	bindContext(ctx context.Context) interface{}
+}
+
+// bindContext returns the service bound to the context if it's a contextBinder or the service as is otherwise
+func bindContext(ctx context.Context, service interface{}) interface{} {
+	if b, ok := service.(contextBinder); ok {
+		return b.bindContext(ctx)
+	}
+
+	return service
+}
+
+// WithContext returns a copy of the services making their calls on behalf of ctx. When given the context of a
+// ContextAnswer, the spans of those calls nest under the span of the plugin action
+func (wss *WorkspaceServices) WithContext(ctx context.Context) (services *WorkspaceServices) {
+	services = new(WorkspaceServices)
+	*services = *wss
+
+	if uf, ok := bindContext(ctx, wss.UserInfoFinder).(UserInfoFinder); ok {
+		services.UserInfoFinder = uf
+	}
+
+	if er, ok := bindContext(ctx, wss.EmojiReactor).(EmojiReactor); ok {
+		services.EmojiReactor = er
+	}
+
+	if fu, ok := bindContext(ctx, wss.FileUploader).(FileUploader); ok {
+		services.FileUploader = fu
+	}
+
+	return services
+}
+
+// ContextServices returns the services injected in the plugin making their calls on behalf of ctx. When given the context
+// of a ContextAnswer, the spans of those calls nest under the span of the plugin action. For example:
+//
+//	func (p *myPlugin) greet(ctx context.Context, m *slackscot.IncomingMessage) *slackscot.Answer {
+//		user, err := p.ContextServices(ctx).UserInfoFinder.GetUserInfo(m.User)
+//		...
+//	}
+func (p *Plugin) ContextServices(ctx context.Context) (services *WorkspaceServices) {
+	services = &WorkspaceServices{UserInfoFinder: p.UserInfoFinder, EmojiReactor: p.EmojiReactor, FileUploader: p.FileUploader, RealTimeMsgSender: p.RealTimeMsgSender, SlackClient: p.SlackClient}
+
+	return services.WithContext(ctx)
+}
+
+// startMessageSpan starts the span of a message event along with the span of its wait in the partition queue
+func (ins *instrumenter) startMessageSpan(teamID string, msgEvent slack.MessageEvent) (ctx context.Context, queueWait trace.Span) {
+	return ins.startEventSpan(messageSpanName,
+		label.String("team", teamID),
+		label.String("channel", msgEvent.Channel),
+		label.String("ts", msgEvent.Timestamp),
+		label.String("subtype", msgEvent.SubType))
+}
+
+// startEventSpan starts the span of an event along with the span of its wait in the partition queue
+func (ins *instrumenter) startEventSpan(spanName string, attributes ...label.KeyValue) (ctx context.Context, queueWait trace.Span) {
+	ctx, _ = ins.tracer.Start(context.Background(), spanName, trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(attributes...))
+
+	_, queueWait = ins.tracer.Start(ctx, queueWaitSpanName)
+
+	return ctx, queueWait
+}
+
+// tracedAction returns the action with a span for each of its answers. Since answers are only requested once an action
+// matches, this gives a span for each matched action
+func (s *Slackscot) tracedAction(pluginName string, actionType string, actionID string, action ActionDefinition) (traced ActionDefinition) {
+	traced = action
+	traced.ContextAnswer = func(ctx context.Context, m *IncomingMessage) *Answer {
+		ctx, span := s.tracer.Start(ctx, actionSpanName, trace.WithAttributes(
+			label.String("plugin", pluginName),
+			label.String("actionType", actionType),
+			label.String("actionID", actionID)))
+		defer span.End()
+
+		answer := action.answer(ctx, m)
+		span.SetAttributes(label.Bool("answered", answer != nil))
+
+		return answer
+	}
+
+	return traced
+}
@@ -0,0 +1,262 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"log"
+	"sync"
+	"testing"
+)
+
+// spanRecorder is a trace.TracerProvider and trace.Tracer keeping the spans it starts
+type spanRecorder struct {
+	lock  sync.Mutex
+	spans []*recordedSpan
+}
+
+// recordedSpan is a trace.Span keeping its parent, attributes, status and whether it ended
+type recordedSpan struct {
+	recorder   *spanRecorder
+	name       string
+	kind       trace.SpanKind
+	parent     *recordedSpan
+	attributes map[label.Key]label.Value
+	status     codes.Code
+	err        error
+	ended      bool
+}
+
+// failingUserInfoFinder is a UserInfoFinder failing to find any user
+type failingUserInfoFinder struct {
+}
+
+func (f *failingUserInfoFinder) GetUserInfo(userID string) (user *slack.User, err error) {
+	return nil, fmt.Errorf("Error loading user [%s]", userID)
+}
+
+func (sr *spanRecorder) Tracer(instrumentationName string, opts ...trace.TracerOption) trace.Tracer {
+	return sr
+}
+
+func (sr *spanRecorder) Start(ctx context.Context, spanName string, opts ...trace.SpanOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanConfig(opts...)
+
+	span := &recordedSpan{recorder: sr, name: spanName, kind: cfg.SpanKind, attributes: make(map[label.Key]label.Value)}
+	if parent, ok := trace.SpanFromContext(ctx).(*recordedSpan); ok {
+		span.parent = parent
+	}
+	span.SetAttributes(cfg.Attributes...)
+
+	sr.lock.Lock()
+	sr.spans = append(sr.spans, span)
+	sr.lock.Unlock()
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// named returns the spans with the given name
+func (sr *spanRecorder) named(name string) (spans []*recordedSpan) {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+
+	spans = make([]*recordedSpan, 0)
+	for _, s := range sr.spans {
+		if s.name == name {
+			spans = append(spans, s)
+		}
+	}
+
+	return spans
+}
+
+func (s *recordedSpan) Tracer() trace.Tracer {
+	return s.recorder
+}
+
+func (s *recordedSpan) End(options ...trace.SpanOption) {
+	s.recorder.lock.Lock()
+	defer s.recorder.lock.Unlock()
+
+	s.ended = true
+}
+
+func (s *recordedSpan) AddEvent(name string, options ...trace.EventOption) {
+}
+
+func (s *recordedSpan) IsRecording() bool {
+	return true
+}
+
+func (s *recordedSpan) RecordError(err error, options ...trace.EventOption) {
+	s.err = err
+}
+
+func (s *recordedSpan) SpanContext() trace.SpanContext {
+	return trace.SpanContext{}
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, msg string) {
+	s.status = code
+}
+
+func (s *recordedSpan) SetName(name string) {
+	s.name = name
+}
+
+func (s *recordedSpan) SetAttributes(kv ...label.KeyValue) {
+	s.recorder.lock.Lock()
+	defer s.recorder.lock.Unlock()
+
+	for _, attr := range kv {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+func TestMessageLifecycleSpans(t *testing.T) {
+	recorder := new(spanRecorder)
+
+	runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil, OptionLog(log.New(&nullWriter{}, "", 0)), OptionTracerProvider(recorder))
+
+	messageSpans := recorder.named(messageSpanName)
+	require.Len(t, messageSpans, 1)
+	msgSpan := messageSpans[0]
+	assert.Nil(t, msgSpan.parent)
+	assert.True(t, msgSpan.ended)
+	assert.Equal(t, trace.SpanKindConsumer, msgSpan.kind)
+	assert.Equal(t, "Cgeneral", msgSpan.attributes["channel"].AsString())
+	assert.Equal(t, timestamp1, msgSpan.attributes["ts"].AsString())
+	assert.Equal(t, int64(0), msgSpan.attributes["partition"].AsInt64())
+
+	queueWaitSpans := recorder.named(queueWaitSpanName)
+	require.Len(t, queueWaitSpans, 1)
+	assert.Equal(t, msgSpan, queueWaitSpans[0].parent)
+	assert.True(t, queueWaitSpans[0].ended)
+
+	routeSpans := recorder.named(routeMessageSpanName)
+	require.Len(t, routeSpans, 1)
+	assert.Equal(t, msgSpan, routeSpans[0].parent)
+	assert.True(t, routeSpans[0].ended)
+
+	actionSpans := recorder.named(actionSpanName)
+	require.Len(t, actionSpans, 1)
+	assert.Equal(t, routeSpans[0], actionSpans[0].parent)
+	assert.True(t, actionSpans[0].ended)
+	assert.Equal(t, "noRules", actionSpans[0].attributes["plugin"].AsString())
+	assert.Equal(t, "noRules.hearAction[0]", actionSpans[0].attributes["actionID"].AsString())
+	assert.True(t, actionSpans[0].attributes["answered"].AsBool())
+}
+
+func TestSlashCommandSpans(t *testing.T) {
+	recorder := new(spanRecorder)
+
+	runSlackscotWithIncomingEvents(t, nil, newKarmaLikePlugin(), []slack.RTMEvent{
+		newSlashCommandEvent("/karma", "top 3"),
+	}, nil, OptionLog(log.New(&nullWriter{}, "", 0)), OptionTracerProvider(recorder))
+
+	slashCommandSpans := recorder.named(slashCommandSpanName)
+	require.Len(t, slashCommandSpans, 1)
+	cmdSpan := slashCommandSpans[0]
+	assert.Nil(t, cmdSpan.parent)
+	assert.True(t, cmdSpan.ended)
+	assert.Equal(t, "Cgeneral", cmdSpan.attributes["channel"].AsString())
+	assert.Equal(t, "/karma", cmdSpan.attributes["command"].AsString())
+
+	queueWaitSpans := recorder.named(queueWaitSpanName)
+	require.Len(t, queueWaitSpans, 1)
+	assert.Equal(t, cmdSpan, queueWaitSpans[0].parent)
+	assert.True(t, queueWaitSpans[0].ended)
+
+	actionSpans := recorder.named(actionSpanName)
+	require.Len(t, actionSpans, 1)
+	assert.Equal(t, cmdSpan, actionSpans[0].parent)
+	assert.True(t, actionSpans[0].ended)
+	assert.Equal(t, "karma.command[0]", actionSpans[0].attributes["actionID"].AsString())
+	assert.True(t, actionSpans[0].attributes["answered"].AsBool())
+}
+
+func TestNoActionSpanWithoutMatch(t *testing.T) {
+	recorder := new(spanRecorder)
+
+	runSlackscotWithIncomingEvents(t, nil, newTestPlugin(), []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "nothing to see here", "Alphonse", timestamp1)),
+	}, nil, OptionLog(log.New(&nullWriter{}, "", 0)), OptionTracerProvider(recorder))
+
+	assert.Len(t, recorder.named(messageSpanName), 1)
+	assert.Len(t, recorder.named(routeMessageSpanName), 1)
+	assert.Len(t, recorder.named(actionSpanName), 0)
+}
+
+func TestTelemetryDecoratorSpansNestUnderBoundContext(t *testing.T) {
+	recorder := new(spanRecorder)
+	ctx, parent := recorder.Start(context.Background(), actionSpanName)
+
+	finder := NewUserInfoFinderWithTelemetry(&userInfoFinder{}, "test", metric.Meter{}, recorder)
+	_, err := bindContext(ctx, finder).(UserInfoFinder).GetUserInfo("U123")
+	require.NoError(t, err)
+
+	spans := recorder.named("UserInfoFinder.GetUserInfo")
+	require.Len(t, spans, 1)
+	assert.Equal(t, parent, spans[0].parent)
+	assert.Equal(t, trace.SpanKindClient, spans[0].kind)
+	assert.True(t, spans[0].ended)
+	assert.Equal(t, codes.Unset, spans[0].status)
+}
+
+func TestTelemetryDecoratorSpanWithoutContextIsRoot(t *testing.T) {
+	recorder := new(spanRecorder)
+
+	finder := NewUserInfoFinderWithTelemetry(&failingUserInfoFinder{}, "test", metric.Meter{}, recorder)
+	_, err := finder.GetUserInfo("U123")
+	require.Error(t, err)
+
+	spans := recorder.named("UserInfoFinder.GetUserInfo")
+	require.Len(t, spans, 1)
+	assert.Nil(t, spans[0].parent)
+	assert.Equal(t, codes.Error, spans[0].status)
+	assert.Equal(t, err, spans[0].err)
+}
+
+func TestContextServicesNestUnderActionSpan(t *testing.T) {
+	recorder := new(spanRecorder)
+
+	// The services injected by slackscot aren't decorated in tests so the plugin answering gets its services from another
+	services := new(Plugin)
+	services.UserInfoFinder = NewUserInfoFinderWithTelemetry(&userInfoFinder{}, "test", metric.Meter{}, recorder)
+
+	p := newTestPlugin()
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return true
+		},
+		ContextAnswer: func(ctx context.Context, m *IncomingMessage) *Answer {
+			user, err := services.ContextServices(ctx).UserInfoFinder.GetUserInfo(m.User)
+			if err != nil {
+				return nil
+			}
+
+			return &Answer{Text: fmt.Sprintf("Hi %s", user.RealName)}
+		},
+	}}
+
+	sentMsgs, _, _, _ := runSlackscotWithIncomingEvents(t, nil, p, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "hello", "Alphonse", timestamp1)),
+	}, nil, OptionLog(log.New(&nullWriter{}, "", 0)), OptionTracerProvider(recorder))
+
+	require.Len(t, sentMsgs, 1)
+
+	actionSpans := recorder.named(actionSpanName)
+	require.Len(t, actionSpans, 1)
+
+	callSpans := recorder.named("UserInfoFinder.GetUserInfo")
+	require.Len(t, callSpans, 1)
+	assert.Equal(t, actionSpans[0], callSpans[0].parent)
+}
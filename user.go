@@ -1,6 +1,7 @@
 package slackscot
 
 import (
+	"context"
 	"fmt"
 	"github.com/alexandre-normand/slackscot/config"
 	"github.com/hashicorp/golang-lru"
@@ -49,6 +50,14 @@ func NewCachingUserInfoFinder(v *viper.Viper, loader UserInfoFinder, logger SLog
 	return cuf, nil
 }
 
+// bindContext returns a copy of the cachingUserInfoFinder, sharing its cache, with its loader bound to the context
+func (c *cachingUserInfoFinder) bindContext(ctx context.Context) interface{} {
+	bound := *c
+	bound.loader = bindContext(ctx, c.loader).(UserInfoFinder)
+
+	return &bound
+}
+
 // GetUserInfo gets the user info or returns an error and a nil user is not found or
 // an error occurred during retrieval
 func (c cachingUserInfoFinder) GetUserInfo(userID string) (u *slack.User, err error) {
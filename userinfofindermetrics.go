@@ -12,29 +12,50 @@ import (
 	"unicode"
 
 	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/label"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // UserInfoFinderWithTelemetry implements UserInfoFinder interface with all methods wrapped
-// with open telemetry metrics
+// with open telemetry metrics and spans
 type UserInfoFinderWithTelemetry struct {
 	base                     UserInfoFinder
 	methodCounters           map[string]metric.BoundInt64Counter
 	errCounters              map[string]metric.BoundInt64Counter
 	methodTimeValueRecorders map[string]metric.BoundInt64ValueRecorder
+	tracer                   trace.Tracer
+	ctx                      context.Context
 }
 
 // NewUserInfoFinderWithTelemetry returns an instance of the UserInfoFinder decorated with open telemetry timing and count metrics
-func NewUserInfoFinderWithTelemetry(base UserInfoFinder, name string, meter metric.Meter) UserInfoFinderWithTelemetry {
+// and a span for each call
+func NewUserInfoFinderWithTelemetry(base UserInfoFinder, name string, meter metric.Meter, tracer trace.Tracer) UserInfoFinderWithTelemetry {
 	return UserInfoFinderWithTelemetry{
 		base:                     base,
 		methodCounters:           newUserInfoFinderMethodCounters("Calls", name, meter),
 		errCounters:              newUserInfoFinderMethodCounters("Errors", name, meter),
 		methodTimeValueRecorders: newUserInfoFinderMethodTimeValueRecorders(name, meter),
+		tracer:                   tracer,
 	}
 }
 
+// bindContext returns a copy of the UserInfoFinderWithTelemetry starting the spans of its calls as children of the span in ctx
+func (_d UserInfoFinderWithTelemetry) bindContext(ctx context.Context) interface{} {
+	_d.ctx = ctx
+	return _d
+}
+
+// spanParent returns the context holding the parent span of calls
+func (_d UserInfoFinderWithTelemetry) spanParent() context.Context {
+	if _d.ctx == nil {
+		return context.Background()
+	}
+
+	return _d.ctx
+}
+
 func newUserInfoFinderMethodTimeValueRecorders(appName string, meter metric.Meter) (boundTimeValueRecorders map[string]metric.BoundInt64ValueRecorder) {
 	boundTimeValueRecorders = make(map[string]metric.BoundInt64ValueRecorder)
 	mt := metric.Must(meter)
@@ -62,10 +83,14 @@ func newUserInfoFinderMethodCounters(suffix string, appName string, meter metric
 // GetUserInfo implements UserInfoFinder
 func (_d UserInfoFinderWithTelemetry) GetUserInfo(userID string) (user *slack.User, err error) {
 	_since := time.Now()
+	_, _span := _d.tracer.Start(_d.spanParent(), "UserInfoFinder.GetUserInfo", trace.WithSpanKind(trace.SpanKindClient))
 	defer func() {
 		if err != nil {
 			errCounter := _d.errCounters["GetUserInfo"]
 			errCounter.Add(context.Background(), 1)
+
+			_span.RecordError(err)
+			_span.SetStatus(codes.Error, err.Error())
 		}
 
 		methodCounter := _d.methodCounters["GetUserInfo"]
@@ -73,6 +98,8 @@ func (_d UserInfoFinderWithTelemetry) GetUserInfo(userID string) (user *slack.Us
 
 		methodTimeMeasure := _d.methodTimeValueRecorders["GetUserInfo"]
 		methodTimeMeasure.Record(context.Background(), time.Since(_since).Milliseconds())
+
+		_span.End()
 	}()
 	return _d.base.GetUserInfo(userID)
 }
@@ -0,0 +1,222 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"reflect"
+	"sync"
+)
+
+// WorkspaceServices holds the slackscot services bound to the connection to a single workspace
+type WorkspaceServices struct {
+	UserInfoFinder    UserInfoFinder
+	EmojiReactor      EmojiReactor
+	FileUploader      FileUploader
+	RealTimeMsgSender RealTimeMessageSender
+	SlackClient       *slack.Client
+}
+
+// WorkspaceServicesFinder is implemented by any value that has the GetWorkspaceServices method. When slackscot
+// is connected to several workspaces, plugins use it to get the services of the workspace an event comes from
+// (as identified by the TeamID of an IncomingMessage, IncomingReaction or IncomingInteraction)
+type WorkspaceServicesFinder interface {
+	// GetWorkspaceServices returns the services of the workspace with the given team id. An empty team id
+	// returns the services of the default workspace
+	GetWorkspaceServices(teamID string) (services *WorkspaceServices, err error)
+}
+
+// workspace holds the connection to a slack workspace and the state slackscot keeps for it
+type workspace struct {
+	// The name of the workspace in the configuration. Empty for the workspace defined by config.TokenKey
+	name     string
+	token    string
+	appToken string
+
+	// The team id of the workspace, known once connected
+	teamID string
+
+	// Self identity in the workspace, cached once connected
+	selfIdentity selfIdentity
+
+	// Responses to triggering messages of this workspace
+	responseCache ResponseCache
+
+	// Incoming events, dependencies and services set up when running
+	events   <-chan slack.RTMEvent
+	deps     *runDependencies
+	services *WorkspaceServices
+
+	// Connection state, updated on connection events and read by the admin endpoints
+	stateLock       sync.Mutex
+	ready           bool
+	connectionCount int
+	connectedTeamID string
+	connectedBefore bool
+}
+
+// workspaceEvent is an event received from the connection to a workspace
+type workspaceEvent struct {
+	ws *workspace
+	slack.RTMEvent
+}
+
+// newWorkspace returns a new workspace for its configuration
+func newWorkspace(wc config.WorkspaceConfig, responseCache ResponseCache) (ws *workspace) {
+	ws = new(workspace)
+	ws.name = wc.Name
+	ws.token = wc.Token
+	ws.appToken = wc.AppToken
+	ws.responseCache = responseCache
+
+	return ws
+}
+
+// attach sets the incoming events and runtime dependencies of the connection to the workspace
+func (ws *workspace) attach(events <-chan slack.RTMEvent, deps *runDependencies) {
+	ws.events = events
+	ws.deps = deps
+}
+
+// markConnected records that the workspace is connected and that its self identity is loaded. It returns true if the
+// workspace was connected before
+func (ws *workspace) markConnected(connectionCount int) (reconnected bool) {
+	ws.stateLock.Lock()
+	defer ws.stateLock.Unlock()
+
+	reconnected = ws.connectedBefore
+	ws.connectedBefore = true
+	ws.ready = true
+	ws.connectionCount = connectionCount
+	ws.connectedTeamID = ws.teamID
+
+	return reconnected
+}
+
+// markDisconnected records that the workspace got disconnected
+func (ws *workspace) markDisconnected() {
+	ws.stateLock.Lock()
+	defer ws.stateLock.Unlock()
+
+	ws.ready = false
+}
+
+// connectionStatus returns whether the workspace is connected along with its connection count and team id
+func (ws *workspace) connectionStatus() (ready bool, connectionCount int, teamID string) {
+	ws.stateLock.Lock()
+	defer ws.stateLock.Unlock()
+
+	return ws.ready, ws.connectionCount, ws.connectedTeamID
+}
+
+// String returns the string representation of a workspace
+func (ws *workspace) String() string {
+	return fmt.Sprintf("Workspace{%s %s}", ws.name, ws.teamID)
+}
+
+// workspaceRegistry holds all workspaces and finds them by team id once connected. The first
+// workspace is the default one
+type workspaceRegistry struct {
+	all      []*workspace
+	byTeamID map[string]*workspace
+	lock     sync.RWMutex
+}
+
+// newWorkspaceRegistry returns a new workspaceRegistry for the given workspaces
+func newWorkspaceRegistry(workspaces []*workspace) (wr *workspaceRegistry) {
+	wr = new(workspaceRegistry)
+	wr.all = workspaces
+	wr.byTeamID = make(map[string]*workspace)
+
+	return wr
+}
+
+// defaultWorkspace returns the default workspace
+func (wr *workspaceRegistry) defaultWorkspace() (ws *workspace) {
+	return wr.all[0]
+}
+
+// register makes a workspace findable by its team id
+func (wr *workspaceRegistry) register(ws *workspace) {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+
+	wr.byTeamID[ws.teamID] = ws
+}
+
+// find returns the workspace with the given team id. An empty team id returns the default workspace
+func (wr *workspaceRegistry) find(teamID string) (ws *workspace, err error) {
+	if teamID == "" {
+		return wr.defaultWorkspace(), nil
+	}
+
+	wr.lock.RLock()
+	defer wr.lock.RUnlock()
+
+	ws, ok := wr.byTeamID[teamID]
+	if !ok {
+		return nil, fmt.Errorf("Unknown workspace with team id [%s]", teamID)
+	}
+
+	return ws, nil
+}
+
+// GetWorkspaceServices returns the services of the workspace with the given team id. An empty team id
+// returns the services of the default workspace
+func (wr *workspaceRegistry) GetWorkspaceServices(teamID string) (services *WorkspaceServices, err error) {
+	ws, err := wr.find(teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ws.services == nil {
+		return nil, fmt.Errorf("Services of workspace [%s] not available before running", ws)
+	}
+
+	return ws.services, nil
+}
+
+// workspaceEventReceiver receives the incoming events of all workspaces until a context is done
+type workspaceEventReceiver struct {
+	// The first case is the context's done channel followed by the incoming events of each workspace
+	cases      []reflect.SelectCase
+	workspaces []*workspace
+}
+
+// newWorkspaceEventReceiver returns a new workspaceEventReceiver for the incoming events of the workspaces
+func newWorkspaceEventReceiver(ctx context.Context, workspaces []*workspace) (r *workspaceEventReceiver) {
+	r = new(workspaceEventReceiver)
+	r.cases = []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}}
+	r.workspaces = make([]*workspace, 0)
+
+	for _, ws := range workspaces {
+		if ws.events != nil {
+			r.cases = append(r.cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ws.events)})
+			r.workspaces = append(r.workspaces, ws)
+		}
+	}
+
+	return r
+}
+
+// receive blocks until an event is received from any workspace. Events of a workspace are received in order. done is true
+// if the context is done and closed is true once the incoming events of all workspaces are closed
+func (r *workspaceEventReceiver) receive() (e workspaceEvent, done bool, closed bool) {
+	for len(r.cases) > 1 {
+		chosen, v, ok := reflect.Select(r.cases)
+		if chosen == 0 {
+			return e, true, false
+		}
+
+		if !ok {
+			r.cases = append(r.cases[:chosen], r.cases[chosen+1:]...)
+			r.workspaces = append(r.workspaces[:chosen-1], r.workspaces[chosen:]...)
+			continue
+		}
+
+		return workspaceEvent{ws: r.workspaces[chosen-1], RTMEvent: v.Interface().(slack.RTMEvent)}, false, false
+	}
+
+	return e, false, true
+}
@@ -0,0 +1,200 @@
+package slackscot
+
+import (
+	"context"
+	"fmt"
+	"github.com/alexandre-normand/slackscot/config"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"strings"
+	"testing"
+)
+
+type teamSelfFinder struct {
+	teamID string
+	userID string
+}
+
+func (f *teamSelfFinder) GetInfo() (user *slack.Info) {
+	return &slack.Info{User: &slack.UserDetails{ID: f.userID, Name: "chickadee"}, Team: &slack.Team{ID: f.teamID}}
+}
+
+type botUserInfoFinder struct {
+}
+
+func (u *botUserInfoFinder) GetUserInfo(userID string) (user *slack.User, err error) {
+	return &slack.User{ID: userID, Profile: slack.UserProfile{BotID: "b" + userID}}, nil
+}
+
+func newMultiWorkspaceConfig() (v *config.PluginConfig) {
+	v = config.NewViperWithDefaults()
+	v.Set(config.MessageProcessingPartitionCount, 1)
+	v.Set(config.WorkspacesKey, map[string]interface{}{
+		"initech": map[string]interface{}{config.TokenKey: "xoxb-initech"},
+		"acme":    map[string]interface{}{config.TokenKey: "xoxb-acme"},
+	})
+
+	return v
+}
+
+func newTeamAwarePlugin() (p *Plugin) {
+	p = new(Plugin)
+	p.Name = "teams"
+	p.Commands = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return m.NormalizedText == "ping"
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: fmt.Sprintf("pong from %s", m.TeamID)}
+		},
+	}}
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			return strings.Contains(m.NormalizedText, "blue jays")
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return &Answer{Text: fmt.Sprintf("heard about blue jays in %s", m.TeamID)}
+		},
+	}}
+
+	return p
+}
+
+func sentTexts(sentMsgs []sentMessage) (texts []string) {
+	texts = make([]string, 0)
+	for _, m := range sentMsgs {
+		texts = append(texts, applySlackOptions(m.msgOptions...).Get("text"))
+	}
+
+	return texts
+}
+
+func TestMultipleWorkspaces(t *testing.T) {
+	termination := make(chan bool)
+	s, err := New("chickadee", newMultiWorkspaceConfig(), OptionLog(log.New(&nullWriter{}, "", 0)), OptionNoPluginNamespacing(), OptionTestMode(termination))
+	require.NoError(t, err)
+
+	p := newTeamAwarePlugin()
+	s.RegisterPlugin(p)
+
+	require.Len(t, s.workspaces.all, 2)
+	acme, initech := s.workspaces.all[0], s.workspaces.all[1]
+	assert.Equal(t, "acme", acme.name)
+	assert.Equal(t, "xoxb-acme", acme.token)
+	assert.Equal(t, "initech", initech.name)
+	assert.Equal(t, "xoxb-initech", initech.token)
+
+	acmeEvents, initechEvents := make(chan slack.RTMEvent), make(chan slack.RTMEvent)
+	acmeDriver := inMemoryChatDriver{timeCursor: firstReplyTimestamp, sentMsgs: make([]sentMessage, 0)}
+	initechDriver := inMemoryChatDriver{timeCursor: firstReplyTimestamp, sentMsgs: make([]sentMessage, 0)}
+	acmeReactor, initechReactor := &emojiReactor{}, &emojiReactor{}
+
+	acme.attach(acmeEvents, &runDependencies{chatDriver: &acmeDriver, userInfoFinder: &botUserInfoFinder{}, emojiReactor: acmeReactor, selfInfoFinder: &teamSelfFinder{teamID: "T1", userID: "U1"}})
+	initech.attach(initechEvents, &runDependencies{chatDriver: &initechDriver, userInfoFinder: &botUserInfoFinder{}, emojiReactor: initechReactor, selfInfoFinder: &teamSelfFinder{teamID: "T2", userID: "U2"}})
+
+	go s.runInternal(context.Background())
+
+	acmeEvents <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}}
+	initechEvents <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{}}
+
+	// Commands are only recognized when mentioning the bot of the workspace
+	acmeEvents <- newRTMMessageEvent(newMessageEvent("Cgeneral", "<@U1> ping", "Alphonse", timestamp1))
+	initechEvents <- newRTMMessageEvent(newMessageEvent("Cgeneral", "<@U1> ping", "Alphonse", timestamp1))
+	initechEvents <- newRTMMessageEvent(newMessageEvent("Cgeneral", "<@U2> ping", "Alphonse", timestamp2))
+
+	// Messages from the bot are only ignored in its own workspace
+	initechEvents <- newRTMMessageEvent(newMessageEvent("Cbirds", "blue jays", "U2", timestamp1))
+	acmeEvents <- newRTMMessageEvent(newMessageEvent("Cbirds", "blue jays", "U2", timestamp1))
+
+	// Responses are cached by workspace so an update in one workspace doesn't update responses in another
+	initechEvents <- newRTMMessageEvent(newMessageEvent("Cshared", "blue jays", "Alphonse", timestamp1))
+	acmeEvents <- newRTMMessageEvent(newMessageEvent("Cshared", "blue jays", "Ignored", timestamp2, optionChangedMessage("blue jays eat acorns", "Alphonse", timestamp1)))
+
+	acmeEvents <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: true, Cause: slack.ErrRTMGoodbye}}
+	<-termination
+
+	assert.Equal(t, []string{"<@Alphonse>: pong from T1", "heard about blue jays in T1", "heard about blue jays in T1"}, sentTexts(acmeDriver.sentMsgs))
+	assert.Equal(t, []string{"<@Alphonse>: pong from T2", "heard about blue jays in T2"}, sentTexts(initechDriver.sentMsgs))
+	assert.Empty(t, acmeDriver.updatedMsgs)
+	assert.Empty(t, initechDriver.updatedMsgs)
+
+	// Plugins get the services of the default workspace and can find the services of the other workspaces
+	assert.Equal(t, acmeReactor, p.EmojiReactor)
+
+	services, err := p.Workspaces.GetWorkspaceServices("T2")
+	require.NoError(t, err)
+	assert.Equal(t, initechReactor, services.EmojiReactor)
+
+	services, err = p.Workspaces.GetWorkspaceServices("")
+	require.NoError(t, err)
+	assert.Equal(t, acmeReactor, services.EmojiReactor)
+
+	_, err = p.Workspaces.GetWorkspaceServices("T3")
+	assert.EqualError(t, err, "Unknown workspace with team id [T3]")
+}
+
+func TestIncomingMessageTeamIDFromWorkspace(t *testing.T) {
+	teamIDs := make([]string, 0)
+
+	p := new(Plugin)
+	p.Name = "teams"
+	p.HearActions = []ActionDefinition{{
+		Match: func(m *IncomingMessage) bool {
+			teamIDs = append(teamIDs, m.TeamID)
+			return false
+		},
+		Answer: func(m *IncomingMessage) *Answer {
+			return nil
+		},
+	}}
+
+	runSlackscotWithIncomingEvents(t, nil, p, []slack.RTMEvent{
+		newRTMMessageEvent(newMessageEvent("Cgeneral", "blue jays", "Alphonse", timestamp1)),
+	}, nil)
+
+	// The test self finder doesn't report a team so the team id is unknown
+	assert.Equal(t, []string{""}, teamIDs)
+}
+
+func TestEventsAPIRequiresSingleWorkspace(t *testing.T) {
+	v := newMultiWorkspaceConfig()
+	v.Set(config.SigningSecretKey, testSigningSecret)
+
+	s, err := New("chickadee", v, OptionEventsAPI(":0"))
+	require.NoError(t, err)
+
+	_, err = s.newEventSource(slack.New("xoxb-acme"), s.workspaces.defaultWorkspace())
+	assert.EqualError(t, err, "Events API is only supported with a single workspace but [2] are configured")
+}
+
+func TestWorkspaceEventReceiverClosedOnceAllEventsClosed(t *testing.T) {
+	first, second := make(chan slack.RTMEvent, 1), make(chan slack.RTMEvent, 1)
+
+	r := newWorkspaceEventReceiver(context.Background(), []*workspace{{name: "first", events: first}, {name: "second", events: second}, {name: "unattached"}})
+
+	second <- slack.RTMEvent{Type: "hello"}
+	close(first)
+
+	e, done, closed := r.receive()
+	require.False(t, done)
+	require.False(t, closed)
+	assert.Equal(t, "second", e.ws.name)
+	assert.Equal(t, "hello", e.Type)
+
+	close(second)
+	_, done, closed = r.receive()
+	assert.False(t, done)
+	assert.True(t, closed)
+}
+
+func TestWorkspaceEventReceiverDoneWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newWorkspaceEventReceiver(ctx, []*workspace{{name: "first", events: make(chan slack.RTMEvent)}})
+
+	cancel()
+	_, done, closed := r.receive()
+	assert.True(t, done)
+	assert.False(t, closed)
+}